@@ -0,0 +1,789 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/domains"
+	"github.com/raezil/linkup-go/linkup/history"
+)
+
+// fileConfig is the CLI configuration file, by default
+// $XDG_CONFIG_HOME/linkup/config.json (~/.config/linkup/config.json):
+//
+//	{
+//	  "defaultProfile": "work",
+//	  "profiles": {
+//	    "work": {
+//	      "apiKeyEnv": "WORK_LINKUP_KEY",
+//	      "depth": "deep",
+//	      "output": "sourcedAnswer",
+//	      "exclude": ["pinterest.com", "@lowquality"],
+//	      "retry": {"max": 5, "minBackoff": "500ms", "maxBackoff": "8s"}
+//	    }
+//	  },
+//	  "domainGroups": {
+//	    "news": ["reuters.com", "apnews.com"],
+//	    "lowquality": ["@~/.config/linkup/blocked.txt", "*.content-farm.io"]
+//	  }
+//	}
+//
+// Include and exclude lists, in profiles and flags alike, may name groups
+// (@news) and list files (@path); see package domains.
+type fileConfig struct {
+	DefaultProfile string              `json:"defaultProfile,omitempty"`
+	Profiles       map[string]*profile `json:"profiles,omitempty"`
+	DomainGroups   map[string][]string `json:"domainGroups,omitempty"`
+}
+
+// profile is a named set of defaults. The API key comes from APIKey, the
+// environment variable named by APIKeyEnv, or the file at APIKeyFile, in
+// that order.
+type profile struct {
+	APIKey     string       `json:"apiKey,omitempty"`
+	APIKeyEnv  string       `json:"apiKeyEnv,omitempty"`
+	APIKeyFile string       `json:"apiKeyFile,omitempty"`
+	BaseURL    string       `json:"baseURL,omitempty"`
+	UserAgent  string       `json:"userAgent,omitempty"`
+	Timeout    string       `json:"timeout,omitempty"`
+	Depth      string       `json:"depth,omitempty"`
+	Output     string       `json:"output,omitempty"`
+	Format     string       `json:"format,omitempty"`
+	Include    []string     `json:"include,omitempty"`
+	Exclude    []string     `json:"exclude,omitempty"`
+	Retry      *retryConfig `json:"retry,omitempty"`
+	// Record archives every search and fetch to HistoryDir (default
+	// <config dir>/linkup/history) for `linkup history`.
+	Record     bool   `json:"record,omitempty"`
+	HistoryDir string `json:"historyDir,omitempty"`
+	// Offline answers searches and fetches from HistoryDir only.
+	Offline bool `json:"offline,omitempty"`
+	// LocalFallback fetches and converts pages locally when /fetch fails.
+	LocalFallback bool `json:"localFallback,omitempty"`
+	// Filter post-filters search results locally; see filterConfig.
+	Filter *filterConfig `json:"filter,omitempty"`
+}
+
+type retryConfig struct {
+	Max        *int   `json:"max,omitempty"`
+	MinBackoff string `json:"minBackoff,omitempty"`
+	MaxBackoff string `json:"maxBackoff,omitempty"`
+}
+
+func configPath() string {
+	if p := os.Getenv("LINKUP_CONFIG"); p != "" {
+		return p
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "linkup.json"
+	}
+	return filepath.Join(dir, "linkup", "config.json")
+}
+
+// loadConfig reads the config file; a missing file yields an empty config.
+func loadConfig(path string) (*fileConfig, error) {
+	cfg := &fileConfig{Profiles: map[string]*profile{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]*profile{}
+	}
+	return cfg, nil
+}
+
+// profileName is the profile selected by the -profile flag value, then
+// LINKUP_PROFILE, then the config's default profile, then "default".
+func (cfg *fileConfig) profileName(flag string) string {
+	return firstNonEmpty(flag, os.Getenv("LINKUP_PROFILE"), cfg.DefaultProfile, "default")
+}
+
+func saveConfig(path string, cfg *fileConfig) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	// The file may hold API keys.
+	return os.WriteFile(path, append(b, '\n'), 0o600)
+}
+
+// settings are the effective options of a command after applying
+// flags > environment > profile > defaults.
+type settings struct {
+	Profile    string
+	APIKey     string
+	BaseURL    string
+	UserAgent  string
+	Timeout    time.Duration
+	Depth      string
+	Output     string
+	Format     string
+	Include    []string // expanded, see expandDomains
+	Exclude    []string
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	Record     bool
+	HistoryDir string
+	Offline    bool
+	Fuzzy      float64
+	// LocalFallback enables linkup.WithLocalFallback.
+	LocalFallback bool
+	// DomainGroups are the config file's named domain lists.
+	DomainGroups map[string][]string
+	// Filter enables linkup.WithResultFilter when not nil.
+	Filter *filterConfig
+}
+
+// commonFlags are the flags shared by every subcommand that talks to the API.
+type commonFlags struct {
+	fs      *flag.FlagSet
+	profile *string
+	base    *string
+	ua      *string
+	timeout *time.Duration
+	format  *string
+	record  *bool
+	offline *bool
+	fuzzy   *float64
+	local   *bool
+	filter  *string
+}
+
+// addCommonFlags registers the shared flags on fs. An empty defaultFormat
+// leaves -format to the command, for output that is not a printer format.
+func addCommonFlags(fs *flag.FlagSet, defaultTimeout time.Duration, defaultFormat string) *commonFlags {
+	cf := &commonFlags{
+		fs:      fs,
+		profile: fs.String("profile", "", "config profile (env LINKUP_PROFILE)"),
+		base:    fs.String("base", "", "override base URL (for testing)"),
+		ua:      fs.String("ua", "", "custom user-agent"),
+		timeout: fs.Duration("timeout", defaultTimeout, "request timeout"),
+		record:  fs.Bool("record", false, "archive searches and fetches for 'linkup history' (env LINKUP_RECORD)"),
+		offline: fs.Bool("offline", false, "answer only from the history archive, never the network (env LINKUP_OFFLINE)"),
+		fuzzy:   fs.Float64("fuzzy", 0, "with -offline: accept the most similar archived query scoring at least this (0..1)"),
+		local:   fs.Bool("local-fallback", false, "when the API cannot fetch a page, fetch it directly and convert it locally (env LINKUP_LOCAL_FALLBACK)"),
+		filter:  fs.String("filter", "", "post-filter search results with the rules in this JSON file (overrides the profile's filter)"),
+	}
+	if defaultFormat != "" {
+		cf.format = fs.String("format", defaultFormat, formatUsage)
+	}
+	return cf
+}
+
+// addProfileFlags registers only -profile and -format, for commands that
+// read settings but never call the API.
+func addProfileFlags(fs *flag.FlagSet, defaultFormat string) *commonFlags {
+	var (
+		base, ua string
+		timeout  time.Duration
+		record   bool
+		offline  bool
+		fuzzy    float64
+		local    bool
+		filter   string
+	)
+	return &commonFlags{
+		fs:      fs,
+		profile: fs.String("profile", "", "config profile (env LINKUP_PROFILE)"),
+		base:    &base,
+		ua:      &ua,
+		timeout: &timeout,
+		record:  &record,
+		offline: &offline,
+		fuzzy:   &fuzzy,
+		local:   &local,
+		filter:  &filter,
+		format:  fs.String("format", defaultFormat, formatUsage),
+	}
+}
+
+// isSet reports whether the named flag was given on the command line.
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// resolve computes the effective settings. It must be called after Parse.
+func (cf *commonFlags) resolve() (*settings, error) {
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		return nil, err
+	}
+	name := cfg.profileName(*cf.profile)
+	p := cfg.Profiles[name]
+	if p == nil {
+		if name != "default" {
+			return nil, fmt.Errorf("unknown profile %q", name)
+		}
+		p = &profile{}
+	}
+
+	s := &settings{
+		Profile:    name,
+		Depth:      string(linkup.DepthStandard),
+		Output:     string(linkup.OutputSearchResults),
+		Format:     "json",
+		Timeout:    *cf.timeout,
+		MaxRetries: 3,
+		MinBackoff: 250 * time.Millisecond,
+		MaxBackoff: 4 * time.Second,
+	}
+	if cf.format != nil {
+		// The flag's default is the command's built-in format.
+		s.Format = *cf.format
+	}
+
+	// Profile. The key is read only when LINKUP_API_KEY does not override
+	// it, so that a missing apiKeyFile does not fail every command.
+	if s.APIKey = os.Getenv("LINKUP_API_KEY"); s.APIKey == "" {
+		if s.APIKey, err = p.apiKey(); err != nil {
+			return nil, fmt.Errorf("profile %q: %w", name, err)
+		}
+	}
+	s.BaseURL, s.UserAgent = p.BaseURL, p.UserAgent
+	s.Depth = firstNonEmpty(p.Depth, s.Depth)
+	s.Output = firstNonEmpty(p.Output, s.Output)
+	s.Format = firstNonEmpty(p.Format, s.Format)
+	s.Include, s.Exclude = p.Include, p.Exclude
+	s.Record, s.HistoryDir, s.Offline = p.Record, p.HistoryDir, p.Offline
+	s.LocalFallback = p.LocalFallback
+	s.DomainGroups, s.Filter = cfg.DomainGroups, p.Filter
+	if p.Timeout != "" {
+		if s.Timeout, err = time.ParseDuration(p.Timeout); err != nil {
+			return nil, fmt.Errorf("profile %q: timeout: %w", name, err)
+		}
+	}
+	if r := p.Retry; r != nil {
+		if r.Max != nil {
+			s.MaxRetries = *r.Max
+		}
+		if r.MinBackoff != "" {
+			if s.MinBackoff, err = time.ParseDuration(r.MinBackoff); err != nil {
+				return nil, fmt.Errorf("profile %q: retry.minBackoff: %w", name, err)
+			}
+		}
+		if r.MaxBackoff != "" {
+			if s.MaxBackoff, err = time.ParseDuration(r.MaxBackoff); err != nil {
+				return nil, fmt.Errorf("profile %q: retry.maxBackoff: %w", name, err)
+			}
+		}
+	}
+
+	// Environment.
+	s.BaseURL = firstNonEmpty(os.Getenv("LINKUP_BASE_URL"), s.BaseURL)
+	s.UserAgent = firstNonEmpty(os.Getenv("LINKUP_USER_AGENT"), s.UserAgent)
+	s.Depth = firstNonEmpty(os.Getenv("LINKUP_DEPTH"), s.Depth)
+	s.Output = firstNonEmpty(os.Getenv("LINKUP_OUTPUT"), s.Output)
+	s.Format = firstNonEmpty(os.Getenv("LINKUP_FORMAT"), s.Format)
+	s.HistoryDir = firstNonEmpty(os.Getenv("LINKUP_HISTORY_DIR"), s.HistoryDir)
+	if v := os.Getenv("LINKUP_RECORD"); v != "" {
+		if s.Record, err = parseOnOff(v); err != nil {
+			return nil, fmt.Errorf("LINKUP_RECORD: %w", err)
+		}
+	}
+	if v := os.Getenv("LINKUP_OFFLINE"); v != "" {
+		if s.Offline, err = parseOnOff(v); err != nil {
+			return nil, fmt.Errorf("LINKUP_OFFLINE: %w", err)
+		}
+	}
+	if v := os.Getenv("LINKUP_LOCAL_FALLBACK"); v != "" {
+		if s.LocalFallback, err = parseOnOff(v); err != nil {
+			return nil, fmt.Errorf("LINKUP_LOCAL_FALLBACK: %w", err)
+		}
+	}
+	if v := os.Getenv("LINKUP_TIMEOUT"); v != "" {
+		if s.Timeout, err = time.ParseDuration(v); err != nil {
+			return nil, fmt.Errorf("LINKUP_TIMEOUT: %w", err)
+		}
+	}
+
+	// Flags.
+	s.BaseURL = firstNonEmpty(*cf.base, s.BaseURL)
+	s.UserAgent = firstNonEmpty(*cf.ua, s.UserAgent)
+	if isSet(cf.fs, "timeout") {
+		s.Timeout = *cf.timeout
+	}
+	if isSet(cf.fs, "record") {
+		s.Record = *cf.record
+	}
+	if isSet(cf.fs, "offline") {
+		s.Offline = *cf.offline
+	}
+	if isSet(cf.fs, "local-fallback") {
+		s.LocalFallback = *cf.local
+	}
+	if *cf.filter != "" {
+		if s.Filter, err = loadFilterConfig(*cf.filter); err != nil {
+			return nil, fmt.Errorf("-filter: %w", err)
+		}
+	}
+	if s.Fuzzy = *cf.fuzzy; s.Fuzzy < 0 || s.Fuzzy > 1 {
+		return nil, fmt.Errorf("-fuzzy must be between 0 and 1")
+	}
+	if s.HistoryDir == "" {
+		s.HistoryDir = defaultHistoryDir()
+	}
+	if cf.format != nil && isSet(cf.fs, "format") {
+		s.Format = *cf.format
+	}
+	if s.Include, err = s.expandDomains(s.Include); err != nil {
+		return nil, fmt.Errorf("profile %q: include: %w", name, err)
+	}
+	if s.Exclude, err = s.expandDomains(s.Exclude); err != nil {
+		return nil, fmt.Errorf("profile %q: exclude: %w", name, err)
+	}
+	return s, nil
+}
+
+// expandDomains resolves @group and @file entries and normalizes the
+// domains of an include or exclude list.
+func (s *settings) expandDomains(list []string) ([]string, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return domains.Expand(list, s.DomainGroups)
+}
+
+func (p *profile) apiKey() (string, error) {
+	if p.APIKey != "" {
+		return p.APIKey, nil
+	}
+	if p.APIKeyEnv != "" {
+		if v := os.Getenv(p.APIKeyEnv); v != "" {
+			return v, nil
+		}
+	}
+	if p.APIKeyFile != "" {
+		path := p.APIKeyFile
+		if rest, ok := strings.CutPrefix(path, "~/"); ok {
+			if home, err := os.UserHomeDir(); err == nil {
+				path = filepath.Join(home, rest)
+			}
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("apiKeyFile: %w", err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+	return "", nil
+}
+
+// client builds a Client from s, exiting when no API key is configured
+// (or, offline, when the history archive cannot be opened).
+func (s *settings) client() *linkup.Client {
+	if s.Offline {
+		store, err := history.Open(s.HistoryDir, history.Options{})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error: offline:", err)
+			os.Exit(1)
+		}
+		opts := []linkup.Option{
+			linkup.WithRecorder(offlineLookup{store}),
+			linkup.WithOfflineMode(),
+			linkup.WithFuzzyMatch(s.Fuzzy),
+		}
+		if s.Filter != nil {
+			opts = append(opts, s.filterOption())
+		}
+		return linkup.NewClient(s.APIKey, opts...)
+	}
+	if s.APIKey == "" {
+		fmt.Fprintln(os.Stderr, "missing API key: set LINKUP_API_KEY or configure a profile (linkup config set apiKeyEnv ...)")
+		os.Exit(2)
+	}
+	opts := []linkup.Option{
+		linkup.WithRetry(s.MaxRetries, s.MinBackoff, s.MaxBackoff),
+	}
+	if s.BaseURL != "" {
+		opts = append(opts, linkup.WithBaseURL(s.BaseURL))
+	}
+	if s.UserAgent != "" {
+		opts = append(opts, linkup.WithUserAgent(s.UserAgent))
+	}
+	if s.LocalFallback {
+		opts = append(opts, linkup.WithLocalFallback(linkup.LocalFallback{}))
+	}
+	opts = append(opts, linkup.WithDomainLimit(domains.DefaultLimit, func(format string, args ...any) {
+		fmt.Fprintf(os.Stderr, "warning: "+strings.TrimPrefix(format, "linkup: ")+"\n", args...)
+	}))
+	if s.Filter != nil {
+		opts = append(opts, s.filterOption())
+	}
+	if s.Record {
+		// Archiving is best effort: never fail a command because of it.
+		store, err := history.Open(s.HistoryDir, history.Options{})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "warning: history disabled:", err)
+		} else {
+			opts = append(opts, linkup.WithRecorder(warnRecorder{store}))
+		}
+	}
+	return linkup.NewClient(s.APIKey, opts...)
+}
+
+// warnRecorder reports archive failures on stderr.
+type warnRecorder struct{ *history.Store }
+
+func (r warnRecorder) Record(ctx context.Context, call linkup.Call) {
+	r.Store.Record(ctx, call)
+	if err := r.Store.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, "warning: history:", err)
+	}
+}
+
+// offlineLookup notes on stderr when a fuzzy match answers a search.
+type offlineLookup struct{ *history.Store }
+
+func (l offlineLookup) Lookup(ctx context.Context, req linkup.LookupRequest) (linkup.Call, error) {
+	call, err := l.Store.Lookup(ctx, req)
+	if err == nil && req.Op == linkup.OpSearch {
+		var asked, got linkup.SearchRequest
+		json.Unmarshal(req.Request, &asked)
+		json.Unmarshal(call.Request, &got)
+		if !strings.EqualFold(strings.Join(strings.Fields(asked.Q), " "), strings.Join(strings.Fields(got.Q), " ")) {
+			fmt.Fprintf(os.Stderr, "offline: answering with archived results for %q (%s)\n", got.Q, call.Time.Local().Format(time.DateTime))
+		}
+	}
+	return call, err
+}
+
+func defaultHistoryDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "linkup-history"
+	}
+	return filepath.Join(dir, "linkup", "history")
+}
+
+// mustResolve resolves settings and a printer, exiting on error.
+func (cf *commonFlags) mustResolve() (*settings, *printer) {
+	s, err := cf.resolve()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(2)
+	}
+	return s, mustPrinter(s.Format)
+}
+
+func firstNonEmpty(vs ...string) string {
+	for _, v := range vs {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// profileKeys maps `linkup config get/set` keys to profile fields.
+var profileKeys = map[string]struct {
+	get func(p *profile) string
+	set func(p *profile, v string) error
+}{
+	"apiKey":     {func(p *profile) string { return mask(p.APIKey) }, func(p *profile, v string) error { p.APIKey = v; return nil }},
+	"apiKeyEnv":  {func(p *profile) string { return p.APIKeyEnv }, func(p *profile, v string) error { p.APIKeyEnv = v; return nil }},
+	"apiKeyFile": {func(p *profile) string { return p.APIKeyFile }, func(p *profile, v string) error { p.APIKeyFile = v; return nil }},
+	"baseURL":    {func(p *profile) string { return p.BaseURL }, func(p *profile, v string) error { p.BaseURL = v; return nil }},
+	"userAgent":  {func(p *profile) string { return p.UserAgent }, func(p *profile, v string) error { p.UserAgent = v; return nil }},
+	"timeout":    {func(p *profile) string { return p.Timeout }, func(p *profile, v string) error { return setDuration(&p.Timeout, v) }},
+	"depth": {func(p *profile) string { return p.Depth }, func(p *profile, v string) error {
+		return setEnum(&p.Depth, v, string(linkup.DepthStandard), string(linkup.DepthDeep))
+	}},
+	"output": {func(p *profile) string { return p.Output }, func(p *profile, v string) error {
+		return setEnum(&p.Output, v, string(linkup.OutputSearchResults), string(linkup.OutputSourcedAnswer), string(linkup.OutputStructured))
+	}},
+	"format": {func(p *profile) string { return p.Format }, func(p *profile, v string) error {
+		if v != "" && !formats[v] {
+			return fmt.Errorf("unknown format %q", v)
+		}
+		p.Format = v
+		return nil
+	}},
+	"include": {func(p *profile) string { return strings.Join(p.Include, ",") }, func(p *profile, v string) error { p.Include = splitCSV(v); return nil }},
+	"exclude": {func(p *profile) string { return strings.Join(p.Exclude, ",") }, func(p *profile, v string) error { p.Exclude = splitCSV(v); return nil }},
+	"record": {func(p *profile) string {
+		if !p.Record {
+			return ""
+		}
+		return "on"
+	}, func(p *profile, v string) error {
+		if v == "" {
+			p.Record = false
+			return nil
+		}
+		on, err := parseOnOff(v)
+		p.Record = on
+		return err
+	}},
+	"historyDir": {func(p *profile) string { return p.HistoryDir }, func(p *profile, v string) error { p.HistoryDir = v; return nil }},
+	"offline": {func(p *profile) string {
+		if !p.Offline {
+			return ""
+		}
+		return "on"
+	}, func(p *profile, v string) error {
+		if v == "" {
+			p.Offline = false
+			return nil
+		}
+		on, err := parseOnOff(v)
+		p.Offline = on
+		return err
+	}},
+	"localFallback": {func(p *profile) string {
+		if !p.LocalFallback {
+			return ""
+		}
+		return "on"
+	}, func(p *profile, v string) error {
+		if v == "" {
+			p.LocalFallback = false
+			return nil
+		}
+		on, err := parseOnOff(v)
+		p.LocalFallback = on
+		return err
+	}},
+	"retry.max": {func(p *profile) string {
+		if p.Retry == nil || p.Retry.Max == nil {
+			return ""
+		}
+		return strconv.Itoa(*p.Retry.Max)
+	}, func(p *profile, v string) error {
+		r := p.retry()
+		if v == "" {
+			r.Max = nil
+			return nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("retry.max must be a non-negative integer")
+		}
+		r.Max = &n
+		return nil
+	}},
+	"retry.minBackoff": {func(p *profile) string {
+		if p.Retry == nil {
+			return ""
+		}
+		return p.Retry.MinBackoff
+	}, func(p *profile, v string) error { return setDuration(&p.retry().MinBackoff, v) }},
+	"retry.maxBackoff": {func(p *profile) string {
+		if p.Retry == nil {
+			return ""
+		}
+		return p.Retry.MaxBackoff
+	}, func(p *profile, v string) error { return setDuration(&p.retry().MaxBackoff, v) }},
+}
+
+func (p *profile) retry() *retryConfig {
+	if p.Retry == nil {
+		p.Retry = &retryConfig{}
+	}
+	return p.Retry
+}
+
+func setDuration(dst *string, v string) error {
+	if v != "" {
+		if _, err := time.ParseDuration(v); err != nil {
+			return err
+		}
+	}
+	*dst = v
+	return nil
+}
+
+func setEnum(dst *string, v string, allowed ...string) error {
+	if v == "" {
+		*dst = ""
+		return nil
+	}
+	for _, a := range allowed {
+		if v == a {
+			*dst = v
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of %s", strings.Join(allowed, ", "))
+}
+
+func mask(key string) string {
+	if len(key) <= 8 {
+		if key == "" {
+			return ""
+		}
+		return "****"
+	}
+	return key[:4] + "…" + key[len(key)-4:]
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func cmdConfig(args []string) {
+	fs := flag.NewFlagSet("config", flag.ExitOnError)
+	profileName := fs.String("profile", "", "profile to read or modify (default: the default profile)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), `Usage:
+  linkup config list                     show all profiles
+  linkup config get <key>  [-profile p]
+  linkup config set <key> <value> [-profile p]   (empty value unsets)
+  linkup config use <profile>            set the default profile
+  linkup config path                     print the config file location
+
+Keys: %s
+`, strings.Join(sortedKeys(profileKeys), ", "))
+	}
+	rest := parseInterleaved(fs, args)
+	if len(rest) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	path := configPath()
+	cfg, err := loadConfig(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	name := cfg.profileName(*profileName)
+
+	fail := func(format string, a ...any) {
+		fmt.Fprintf(os.Stderr, "error: "+format+"\n", a...)
+		os.Exit(2)
+	}
+	switch rest[0] {
+	case "path":
+		fmt.Println(path)
+	case "list":
+		for _, pn := range sortedKeys(cfg.Profiles) {
+			marker := " "
+			if pn == firstNonEmpty(cfg.DefaultProfile, "default") {
+				marker = "*"
+			}
+			fmt.Printf("%s %s\n", marker, pn)
+			for _, k := range sortedKeys(profileKeys) {
+				if v := profileKeys[k].get(cfg.Profiles[pn]); v != "" {
+					fmt.Printf("    %s = %s\n", k, v)
+				}
+			}
+		}
+	case "get":
+		if len(rest) != 2 {
+			fail("usage: linkup config get <key>")
+		}
+		k, ok := profileKeys[rest[1]]
+		if !ok {
+			fail("unknown key %q", rest[1])
+		}
+		if p := cfg.Profiles[name]; p != nil {
+			fmt.Println(k.get(p))
+		}
+	case "set":
+		if len(rest) != 3 {
+			fail("usage: linkup config set <key> <value>")
+		}
+		k, ok := profileKeys[rest[1]]
+		if !ok {
+			fail("unknown key %q", rest[1])
+		}
+		p := cfg.Profiles[name]
+		if p == nil {
+			p = &profile{}
+			cfg.Profiles[name] = p
+		}
+		if err := k.set(p, rest[2]); err != nil {
+			fail("%s: %v", rest[1], err)
+		}
+		if err := saveConfig(path, cfg); err != nil {
+			fail("%v", err)
+		}
+	case "use":
+		if len(rest) != 2 {
+			fail("usage: linkup config use <profile>")
+		}
+		if _, ok := cfg.Profiles[rest[1]]; !ok {
+			fail("unknown profile %q", rest[1])
+		}
+		cfg.DefaultProfile = rest[1]
+		if err := saveConfig(path, cfg); err != nil {
+			fail("%v", err)
+		}
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+// applySearchFlags overrides the search defaults with any search flags that
+// were given on the command line, exiting when a domain list is invalid.
+func (s *settings) applySearchFlags(fs *flag.FlagSet, depth, output, include, exclude string) {
+	if depth != "" {
+		s.Depth = depth
+	}
+	if output != "" {
+		s.Output = output
+	}
+	for _, f := range []struct {
+		name, value string
+		dst         *[]string
+	}{{"include", include, &s.Include}, {"exclude", exclude, &s.Exclude}} {
+		if !isSet(fs, f.name) {
+			continue
+		}
+		list, err := s.expandDomains(splitCSV(f.value))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: -%s: %v\n", f.name, err)
+			os.Exit(2)
+		}
+		*f.dst = list
+	}
+}
+
+// parseInterleaved parses fs from args, allowing flags before, between and
+// after positional arguments, and returns the positional arguments.
+func parseInterleaved(fs *flag.FlagSet, args []string) []string {
+	var rest []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return rest
+		}
+		rest = append(rest, args[0])
+		args = args[1:]
+	}
+}
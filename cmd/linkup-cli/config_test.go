@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func resolveArgs(t *testing.T, args ...string) *settings {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cf := addCommonFlags(fs, 30*time.Second, "json")
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	s, err := cf.resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestConfigSetThenResolve(t *testing.T) {
+	t.Setenv("LINKUP_CONFIG", filepath.Join(t.TempDir(), "config.json"))
+	for _, env := range []string{"LINKUP_API_KEY", "LINKUP_PROFILE", "LINKUP_DEPTH"} {
+		t.Setenv(env, "")
+	}
+
+	// Without -profile, `config set` writes the "default" profile, which
+	// commands must then use.
+	cmdConfig([]string{"set", "apiKey", "abc"})
+	cmdConfig([]string{"set", "depth", "deep"})
+	s := resolveArgs(t)
+	if s.Profile != "default" || s.APIKey != "abc" || s.Depth != "deep" {
+		t.Fatalf("settings = %+v", s)
+	}
+
+	cmdConfig([]string{"set", "apiKey", "work-key", "-profile", "work"})
+	if s := resolveArgs(t, "-profile", "work"); s.APIKey != "work-key" || s.Depth != "standard" {
+		t.Fatalf("work settings = %+v", s)
+	}
+	t.Setenv("LINKUP_PROFILE", "work")
+	if s := resolveArgs(t); s.APIKey != "work-key" {
+		t.Fatalf("LINKUP_PROFILE settings = %+v", s)
+	}
+	cmdConfig([]string{"use", "default"})
+	t.Setenv("LINKUP_PROFILE", "")
+	if s := resolveArgs(t); s.APIKey != "abc" {
+		t.Fatalf("after use default: %+v", s)
+	}
+}
+
+func TestResolveOrder(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("LINKUP_CONFIG", filepath.Join(dir, "config.json"))
+	os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"profiles": {"p": {
+		"apiKeyFile": "`+filepath.Join(dir, "missing")+`",
+		"baseURL": "https://profile.example", "userAgent": "profile-ua",
+		"timeout": "7s", "depth": "deep", "format": "text"}}}`), 0o600)
+	for _, env := range []string{"LINKUP_PROFILE", "LINKUP_BASE_URL", "LINKUP_DEPTH", "LINKUP_FORMAT"} {
+		t.Setenv(env, "")
+	}
+	t.Setenv("LINKUP_API_KEY", "env-key")
+	t.Setenv("LINKUP_USER_AGENT", "env-ua")
+	t.Setenv("LINKUP_TIMEOUT", "9s")
+
+	// Flags, then the environment, then the profile. The unreadable
+	// apiKeyFile does not matter while LINKUP_API_KEY is set.
+	for _, tc := range []struct {
+		args        []string
+		ua          string
+		timeout     time.Duration
+		base, depth string
+		key, format string
+	}{
+		{[]string{"-profile", "p"}, "env-ua", 9 * time.Second, "https://profile.example", "deep", "env-key", "text"},
+		{[]string{"-profile", "p", "-ua", "flag-ua", "-timeout", "2s", "-format", "csv"}, "flag-ua", 2 * time.Second, "https://profile.example", "deep", "env-key", "csv"},
+	} {
+		s := resolveArgs(t, tc.args...)
+		if s.UserAgent != tc.ua || s.Timeout != tc.timeout || s.BaseURL != tc.base || s.Depth != tc.depth || s.APIKey != tc.key || s.Format != tc.format {
+			t.Errorf("%q: settings = %+v", tc.args, s)
+		}
+	}
+
+	// Without the override, the profile's key file is read, and fails.
+	t.Setenv("LINKUP_API_KEY", "")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cf := addCommonFlags(fs, 30*time.Second, "json")
+	fs.Parse([]string{"-profile", "p"})
+	if _, err := cf.resolve(); err == nil || !strings.Contains(err.Error(), "apiKeyFile") {
+		t.Fatalf("err = %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "missing"), []byte("file-key\n"), 0o600)
+	if s := resolveArgs(t, "-profile", "p"); s.APIKey != "file-key" {
+		t.Fatalf("key = %q", s.APIKey)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/raezil/linkup-go/linkup/crawl"
+)
+
+func cmdCrawl(args []string) {
+	fs := flag.NewFlagSet("crawl", flag.ExitOnError)
+	scopes := fs.String("scope", "", "comma-separated host[/path] prefixes to follow links into (default: each seed's directory)")
+	maxDepth := fs.Int("max-depth", 0, "links to follow away from a seed (0 = no limit)")
+	maxPages := fs.Int("max-pages", 1000, "stop after this many pages, counting resumed runs")
+	delay := fs.Duration("delay", time.Second, "minimum time between requests to one host")
+	concurrency := fs.Int("concurrency", 4, "hosts crawled in parallel")
+	outDir := fs.String("o", "", "write pages as markdown files under this directory")
+	jsonlPath := fs.String("jsonl", "", "append pages as JSON lines to this file")
+	statePath := fs.String("state", "", "resume journal (default: <dir>/.crawl-state.jsonl or <jsonl>.state)")
+	rawHTML := fs.Bool("raw-html", false, "also fetch raw HTML and follow its links")
+	renderJS := fs.Bool("render-js", false, "render JavaScript before extracting")
+	ignoreRobots := fs.Bool("ignore-robots", false, "do not read robots.txt")
+	quiet := fs.Bool("quiet", false, "do not log each page")
+	common := addCommonFlags(fs, 0, "")
+	seeds := parseInterleaved(fs, args)
+	st, err := common.resolve()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(2)
+	}
+
+	if len(seeds) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: linkup crawl [flags] URL...")
+		os.Exit(2)
+	}
+	if (*outDir == "") == (*jsonlPath == "") {
+		fmt.Fprintln(os.Stderr, "pass exactly one of -o dir or -jsonl file")
+		os.Exit(2)
+	}
+	cfg := crawl.Config{
+		Seeds:          seeds,
+		MaxDepth:       *maxDepth,
+		MaxPages:       *maxPages,
+		Delay:          *delay,
+		Concurrency:    *concurrency,
+		IncludeRawHTML: *rawHTML,
+		RenderJS:       *renderJS,
+		IgnoreRobots:   *ignoreRobots,
+		UserAgent:      st.UserAgent,
+		StatePath:      *statePath,
+	}
+	for _, s := range splitCSV(*scopes) {
+		sc, err := crawl.ParseScope(s)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		cfg.Scopes = append(cfg.Scopes, sc)
+	}
+	var defaultState string
+	cfg.Sink, defaultState = openCrawlSink(*outDir, *jsonlPath)
+	cfg.StatePath = firstNonEmpty(cfg.StatePath, defaultState)
+	if !*quiet {
+		cfg.Logf = logStderr
+	}
+	runCrawl(st, cfg)
+}
+
+// openCrawlSink opens the -o directory or the -jsonl file and returns the
+// default journal path next to it.
+func openCrawlSink(outDir, jsonlPath string) (crawl.Sink, string) {
+	if outDir != "" {
+		sink, err := crawl.NewDirSink(outDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		return sink, filepath.Join(outDir, ".crawl-state.jsonl")
+	}
+	sink, err := crawl.OpenJSONL(jsonlPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	return sink, jsonlPath + ".state"
+}
+
+func logStderr(format string, args ...any) { fmt.Fprintf(os.Stderr, format+"\n", args...) }
+
+// runCrawl runs cfg until done or interrupted, prints a summary on stderr
+// and exits non-zero on failure.
+func runCrawl(st *settings, cfg crawl.Config) {
+	c, err := crawl.New(st.client(), cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if st.Timeout > 0 {
+		// -timeout bounds the whole crawl here.
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, st.Timeout)
+		defer cancel()
+	}
+	stats, err := c.Run(ctx)
+	if cerr := cfg.Sink.Close(); err == nil {
+		err = cerr
+	}
+	logStderr("crawled %d pages (%d from earlier runs), %d failed, %d disallowed by robots.txt, %d pending; ~%.3f credits",
+		stats.Pages, stats.Resumed, stats.Failed, stats.Skipped, stats.Pending, stats.Credits)
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		if cfg.StatePath != "" {
+			logStderr("interrupted; run the same command again to resume")
+		}
+		os.Exit(1)
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	case stats.Pending > 0:
+		logStderr("-max-pages reached; raise it and run again to continue")
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/raezil/linkup-go/linkup/domains"
+)
+
+// cmdDomains prints the expansion of domain list entries, or the configured
+// groups when none are given.
+func cmdDomains(args []string) {
+	fs := flag.NewFlagSet("domains", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), `Usage:
+  linkup domains                      list the config's domain groups
+  linkup domains @group|@file|domain...   print the expanded, normalized list
+`)
+	}
+	rest := parseInterleaved(fs, args)
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	if len(rest) == 0 {
+		names := make([]string, 0, len(cfg.DomainGroups))
+		for n := range cfg.DomainGroups {
+			names = append(names, n)
+		}
+		slices.Sort(names)
+		for _, n := range names {
+			list, err := domains.Expand([]string{"@" + n}, cfg.DomainGroups)
+			if err != nil {
+				fmt.Printf("@%s\terror: %v\n", n, err)
+				continue
+			}
+			fmt.Printf("@%s\t%d domains\n", n, len(list))
+		}
+		return
+	}
+	var entries []string
+	for _, a := range rest {
+		entries = append(entries, splitCSV(a)...)
+	}
+	list, err := domains.Expand(entries, cfg.DomainGroups)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(2)
+	}
+	for _, d := range list {
+		fmt.Println(d)
+	}
+	if n := len(domains.Chunk(list, domains.DefaultLimit)); n > 1 {
+		fmt.Fprintf(os.Stderr, "%d domains exceed the per-request limit of %d: include lists run %d searches, exclude lists are partly filtered locally\n",
+			len(list), domains.DefaultLimit, n)
+	}
+}
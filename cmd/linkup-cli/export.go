@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/export"
+	"github.com/raezil/linkup-go/linkup/history"
+)
+
+func cmdExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	q := fs.String("q", "", "run this search and export its results")
+	depth := fs.String("depth", "", "depth: standard|deep (default standard)")
+	out := fs.String("output", "", "output: searchResults|sourcedAnswer|structured (default searchResults)")
+	include := fs.String("include", "", "comma-separated include domains, @group or @file")
+	exclude := fs.String("exclude", "", "comma-separated exclude domains, @group or @file")
+	schema := fs.String("schema", "", "structured output schema (JSON string)")
+	pages := fs.String("url", "", "comma-separated pages to fetch and export")
+	fromHistory := fs.Bool("history", false, "export archived calls from the history instead")
+	op := fs.String("op", "", "with -history: only search or fetch entries")
+	since := fs.String("since", "", "with -history: only entries on or after YYYY-MM-DD")
+	until := fs.String("until", "", "with -history: only entries before YYYY-MM-DD")
+	format := fs.String("format", "csv", "csv|ndjson|parquet")
+	dir := fs.String("o", "", "append to date=YYYY-MM-DD partitions under this directory (default: stdout)")
+	common := addCommonFlags(fs, 30*time.Second, "")
+	fs.Parse(args)
+	st, err := common.resolve()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(2)
+	}
+	st.applySearchFlags(fs, *depth, *out, *include, *exclude)
+	f, err := export.ParseFormat(*format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *q == "" && *pages == "" && !*fromHistory {
+		fmt.Fprintln(os.Stderr, "usage: linkup export -q query | -url page[,page...] | -history [-format csv|ndjson|parquet] [-o dir]")
+		os.Exit(2)
+	}
+
+	var rows []export.Row
+	if *fromHistory {
+		rows = historyRows(st, *op, *since, *until)
+	}
+	if *q != "" || *pages != "" {
+		client := st.client()
+		ctx, cancel := context.WithTimeout(context.Background(), st.Timeout)
+		defer cancel()
+		if *q != "" {
+			req := linkup.SearchRequest{
+				Q:              *q,
+				Depth:          linkup.Depth(st.Depth),
+				OutputType:     linkup.OutputType(st.Output),
+				IncludeDomains: st.Include,
+				ExcludeDomains: st.Exclude,
+			}
+			if *schema != "" {
+				req.StructuredOutputSchema = schema
+			}
+			resp, err := client.Search(ctx, req)
+			if err == nil {
+				var rs []export.Row
+				rs, err = export.FromSearch(req, resp, time.Now())
+				rows = append(rows, rs...)
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "error:", err)
+				os.Exit(1)
+			}
+		}
+		for _, p := range splitCSV(*pages) {
+			req := linkup.FetchRequest{URL: p}
+			resp, err := client.Fetch(ctx, req)
+			if err == nil {
+				var rs []export.Row
+				rs, err = export.FromFetch(req, resp, time.Now())
+				rows = append(rows, rs...)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %s: %v\n", p, err)
+				os.Exit(1)
+			}
+		}
+	}
+
+	if *dir != "" {
+		files, err := export.WriteDir(*dir, f, rows)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		for _, file := range files {
+			fmt.Fprintln(os.Stderr, file)
+		}
+		logStderr("exported %d rows", len(rows))
+		return
+	}
+	w, _ := export.NewWriter(os.Stdout, f)
+	err = w.Write(rows...)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// historyRows flattens the archived calls matching the flags, oldest
+// first. Entries that cannot be flattened are reported and skipped.
+func historyRows(st *settings, op, since, until string) []export.Row {
+	store, err := history.Open(st.HistoryDir, history.Options{})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+	f := history.Filter{Op: op, Limit: -1}
+	for _, d := range []struct {
+		flag string
+		dst  *time.Time
+	}{{since, &f.Since}, {until, &f.Until}} {
+		if d.flag == "" {
+			continue
+		}
+		if *d.dst, err = time.ParseInLocation("2006-01-02", d.flag, time.Local); err != nil {
+			fmt.Fprintln(os.Stderr, "error: dates must be YYYY-MM-DD")
+			os.Exit(2)
+		}
+	}
+	entries, err := store.List(f)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	var rows []export.Row
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		rs, err := export.FromCall(linkup.Call{Op: e.Op, Time: e.Time, Request: e.Request, Response: e.Response})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: skipping history entry %s: %v\n", e.ID, err)
+			continue
+		}
+		rows = append(rows, rs...)
+	}
+	return rows
+}
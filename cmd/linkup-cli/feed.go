@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/feed"
+)
+
+// savedFeed is one entry of the -saved file:
+//
+//	[{"name": "go", "title": "Go releases", "request": {"q": "Go release notes", "includeDomains": ["go.dev"]}}]
+type savedFeed struct {
+	Name    string               `json:"name"`
+	Title   string               `json:"title"`
+	Request linkup.SearchRequest `json:"request"`
+}
+
+// monitorFile is the part of a linkup-monitord config the feed server
+// needs to serve each saved search's history.
+type monitorFile struct {
+	DataDir  string `json:"dataDir"`
+	Searches []struct {
+		Name    string               `json:"name"`
+		Request linkup.SearchRequest `json:"request"`
+	} `json:"searches"`
+}
+
+func cmdFeed(args []string) {
+	fs := flag.NewFlagSet("feed", flag.ExitOnError)
+	q := fs.String("q", "", "query text")
+	format := fs.String("format", "rss", "feed format: rss|atom|json")
+	title := fs.String("title", "", "feed title (default: the query)")
+	outPath := fs.String("o", "", "write the feed to this file instead of stdout")
+	depth := fs.String("depth", "", "depth: standard|deep (default standard)")
+	include := fs.String("include", "", "comma-separated include domains, @group or @file")
+	exclude := fs.String("exclude", "", "comma-separated exclude domains, @group or @file")
+	serve := fs.String("serve", "", "serve feeds over HTTP on this address instead")
+	saved := fs.String("saved", "", "with -serve: JSON file of saved queries to serve live")
+	monitorCfg := fs.String("monitor", "", "with -serve: linkup-monitord config whose history to serve")
+	ttl := fs.Duration("ttl", 15*time.Minute, "with -serve: how long a built feed is reused")
+	limit := fs.Int("limit", 50, "with -monitor: items per history feed")
+	common := addCommonFlags(fs, 60*time.Second, "")
+	fs.Parse(args)
+	st, err := common.resolve()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(2)
+	}
+	st.applySearchFlags(fs, *depth, "", *include, *exclude)
+
+	if *serve != "" {
+		serveFeeds(st, *serve, *saved, *monitorCfg, *ttl, *limit)
+		return
+	}
+
+	ff, err := feed.ParseFormat(*format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *q == "" {
+		fmt.Fprintln(os.Stderr, "missing -q (or use -serve)")
+		os.Exit(2)
+	}
+	req := linkup.SearchRequest{
+		Q:              *q,
+		Depth:          linkup.Depth(st.Depth),
+		IncludeDomains: st.Include,
+		ExcludeDomains: st.Exclude,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), st.Timeout)
+	defer cancel()
+	f, err := feed.SearchSource(st.client(), firstNonEmpty(*title, *q), req)(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	out := os.Stdout
+	if *outPath != "" {
+		if out, err = os.Create(*outPath); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+	}
+	if err := f.Write(out, ff); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	if out != os.Stdout {
+		if err := out.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func serveFeeds(st *settings, addr, savedPath, monitorPath string, ttl time.Duration, limit int) {
+	h := &feed.Handler{Feeds: map[string]feed.Source{}, TTL: ttl, Timeout: st.Timeout}
+	if savedPath != "" {
+		var saved []savedFeed
+		if err := readJSON(savedPath, &saved); err != nil {
+			log.Fatal(err)
+		}
+		client := st.client()
+		for _, s := range saved {
+			if s.Name == "" || s.Request.Q == "" {
+				log.Fatalf("%s: every saved feed needs a name and request.q", savedPath)
+			}
+			h.Feeds[s.Name] = feed.SearchSource(client, firstNonEmpty(s.Title, s.Request.Q), s.Request)
+		}
+	}
+	if monitorPath != "" {
+		var mf monitorFile
+		if err := readJSON(monitorPath, &mf); err != nil {
+			log.Fatal(err)
+		}
+		// Same layout as linkup-monitord.
+		store := filepath.Join(firstNonEmpty(mf.DataDir, "."), "results.jsonl")
+		for _, s := range mf.Searches {
+			if _, dup := h.Feeds[s.Name]; dup {
+				log.Fatalf("feed %q is defined twice", s.Name)
+			}
+			h.Feeds[s.Name] = feed.HistorySource(store, s.Name, s.Name+": "+s.Request.Q, s.Request.Q, limit)
+		}
+	}
+	if len(h.Feeds) == 0 {
+		log.Fatal("nothing to serve: pass -saved and/or -monitor")
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/feeds/", h)
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	log.Printf("serving %d feeds on http://%s/feeds/", len(h.Feeds), addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
+
+func readJSON(path string, v any) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/filter"
+)
+
+// filterConfig is the JSON form of filter.Config, in a profile's "filter"
+// or a -filter file:
+//
+//	{
+//	  "block": ["@lowquality", "@~/lists/blocked.txt"],
+//	  "rules": [{"name": "gambling", "pattern": "(?i)\\bcasino\\b"},
+//	            {"name": "sponsored", "field": "url", "pattern": "[?&]sponsored=", "action": "annotate"}],
+//	  "minLength": 80,
+//	  "languages": ["en", "de"],
+//	  "maxAge": "365d",
+//	  "action": "drop"
+//	}
+type filterConfig struct {
+	Block       []string     `json:"block,omitempty"`
+	Allow       []string     `json:"allow,omitempty"`
+	Rules       []filterRule `json:"rules,omitempty"`
+	MinLength   int          `json:"minLength,omitempty"`
+	Languages   []string     `json:"languages,omitempty"`
+	After       string       `json:"after,omitempty"`  // YYYY-MM-DD
+	Before      string       `json:"before,omitempty"` // YYYY-MM-DD
+	MaxAge      string       `json:"maxAge,omitempty"` // e.g. "720h" or "30d"
+	RequireDate bool         `json:"requireDate,omitempty"`
+	Action      string       `json:"action,omitempty"` // drop|annotate
+}
+
+type filterRule struct {
+	Name    string `json:"name,omitempty"`
+	Field   string `json:"field,omitempty"` // title|content|url; default title and content
+	Pattern string `json:"pattern"`
+	Action  string `json:"action,omitempty"`
+}
+
+// loadFilterConfig reads a -filter file.
+func loadFilterConfig(path string) (*filterConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fc filterConfig
+	if err := json.Unmarshal(b, &fc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &fc, nil
+}
+
+// build converts fc to a Filter; groups resolve @name entries.
+func (fc *filterConfig) build(groups map[string][]string) (*filter.Filter, error) {
+	cfg := filter.Config{
+		BlockDomains:     fc.Block,
+		AllowDomains:     fc.Allow,
+		Groups:           groups,
+		MinContentLength: fc.MinLength,
+		Languages:        fc.Languages,
+		RequireDate:      fc.RequireDate,
+		Action:           filter.Action(fc.Action),
+	}
+	for _, r := range fc.Rules {
+		cfg.Rules = append(cfg.Rules, filter.Rule{Name: r.Name, Field: filter.Field(r.Field), Pattern: r.Pattern, Action: filter.Action(r.Action)})
+	}
+	var err error
+	for _, d := range []struct {
+		name, v string
+		dst     *time.Time
+	}{{"after", fc.After, &cfg.PublishedAfter}, {"before", fc.Before, &cfg.PublishedBefore}} {
+		if d.v == "" {
+			continue
+		}
+		if *d.dst, err = time.Parse(time.DateOnly, d.v); err != nil {
+			return nil, fmt.Errorf("filter: %s: dates must be YYYY-MM-DD", d.name)
+		}
+	}
+	if fc.MaxAge != "" {
+		if days, ok := strings.CutSuffix(fc.MaxAge, "d"); ok {
+			n, err := strconv.Atoi(days)
+			if err != nil {
+				return nil, fmt.Errorf("filter: maxAge: %w", err)
+			}
+			cfg.MaxAge = time.Duration(n) * 24 * time.Hour
+		} else if cfg.MaxAge, err = time.ParseDuration(fc.MaxAge); err != nil {
+			return nil, fmt.Errorf("filter: maxAge: %w", err)
+		}
+	}
+	return filter.New(cfg)
+}
+
+// filterOption builds the client option for s.Filter, exiting when the
+// configuration is invalid. Reports of searches that dropped or annotated
+// anything go to stderr.
+func (s *settings) filterOption() linkup.Option {
+	f, err := s.Filter.build(s.DomainGroups)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(2)
+	}
+	return linkup.WithResultFilter(f, func(_ context.Context, r linkup.FilterReport) {
+		if len(r.Decisions) > 0 {
+			fmt.Fprint(os.Stderr, "filter: ", r.String())
+		}
+	})
+}
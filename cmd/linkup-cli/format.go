@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/canonical"
+	"github.com/raezil/linkup-go/linkup/cite"
+)
+
+const formatUsage = "output format: json|jsonl|table|markdown|csv|text"
+
+// formats lists the accepted -format values.
+var formats = map[string]bool{"json": true, "jsonl": true, "table": true, "markdown": true, "csv": true, "text": true}
+
+// csvHeader is the stable column set for search results and answer sources.
+var csvHeader = []string{"rank", "type", "title", "domain", "url", "content"}
+
+// printer renders API responses in one of the supported formats.
+type printer struct {
+	w      io.Writer
+	format string
+	color  bool
+}
+
+// newPrinter returns a printer writing to stdout. Colors are used only when
+// stdout is a terminal and NO_COLOR is unset.
+func newPrinter(format string) (*printer, error) {
+	if !formats[format] {
+		return nil, fmt.Errorf("unknown format %q (%s)", format, formatUsage)
+	}
+	color := isTerminal(int(os.Stdout.Fd())) && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+	return &printer{w: os.Stdout, format: format, color: color}, nil
+}
+
+func (p *printer) paint(code, s string) string {
+	if !p.color || s == "" {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+func (p *printer) bold(s string) string { return p.paint("1", s) }
+func (p *printer) dim(s string) string  { return p.paint("2", s) }
+func (p *printer) cyan(s string) string { return p.paint("36", s) }
+
+// row is a search result or answer source flattened for display.
+type row struct {
+	Rank    int    `json:"rank"`
+	Type    string `json:"type,omitempty"`
+	Title   string `json:"title"`
+	Domain  string `json:"domain"`
+	URL     string `json:"url"`
+	Content string `json:"content,omitempty"`
+}
+
+func resultRows(results []linkup.SearchResult) []row {
+	out := make([]row, len(results))
+	for i, r := range results {
+		out[i] = row{Rank: i + 1, Type: r.Type, Title: r.Name, Domain: canonical.Host(r.URL), URL: r.URL, Content: r.Content}
+	}
+	return out
+}
+
+func sourceRows(sources []linkup.AnswerSource) []row {
+	out := make([]row, len(sources))
+	for i, s := range sources {
+		out[i] = row{Rank: i + 1, Type: "source", Title: s.Label(), Domain: canonical.Host(s.URL), URL: s.URL, Content: s.Snippet}
+	}
+	return out
+}
+
+// search prints a /search response according to the request's output type.
+func (p *printer) search(outputType linkup.OutputType, resp linkup.SearchResponse) error {
+	if p.format == "json" {
+		return p.prettyJSON(resp.RawJSON())
+	}
+	switch outputType {
+	case linkup.OutputSearchResults:
+		results, err := resp.Results()
+		if err != nil {
+			return p.prettyJSON(resp.RawJSON())
+		}
+		return p.rows(resultRows(results), "")
+	case linkup.OutputSourcedAnswer:
+		ans, err := resp.SourcedAnswer()
+		if err != nil {
+			return p.prettyJSON(resp.RawJSON())
+		}
+		switch p.format {
+		case "markdown":
+			out, _ := cite.Render(ans, cite.Markdown)
+			_, err := fmt.Fprintln(p.w, strings.TrimRight(out, "\n"))
+			return err
+		case "text":
+			out, _ := cite.Render(ans, cite.Text)
+			_, err := fmt.Fprintln(p.w, strings.TrimRight(out, "\n"))
+			return err
+		case "jsonl":
+			return p.compactJSON(resp.RawJSON())
+		}
+		return p.rows(sourceRows(ans.Sources), ans.Answer)
+	}
+	// Structured output has no fixed shape.
+	switch p.format {
+	case "jsonl":
+		return p.compactJSON(resp.RawJSON())
+	case "markdown":
+		fmt.Fprintln(p.w, "```json")
+		defer fmt.Fprintln(p.w, "```")
+	}
+	return p.prettyJSON(resp.RawJSON())
+}
+
+// rows prints ranked rows; answer, when set, precedes them in table/text.
+func (p *printer) rows(rows []row, answer string) error {
+	switch p.format {
+	case "jsonl":
+		enc := json.NewEncoder(p.w)
+		for _, r := range rows {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		cw := csv.NewWriter(p.w)
+		cw.Write(csvHeader)
+		for _, r := range rows {
+			cw.Write([]string{strconv.Itoa(r.Rank), r.Type, r.Title, r.Domain, r.URL, r.Content})
+		}
+		cw.Flush()
+		return cw.Error()
+	case "table":
+		if answer != "" {
+			fmt.Fprintf(p.w, "%s\n\n", answer)
+		}
+		cells := [][]string{{"RANK", "TITLE", "DOMAIN", "URL"}}
+		for _, r := range rows {
+			cells = append(cells, []string{strconv.Itoa(r.Rank), truncateRunes(oneLine(r.Title), 60), r.Domain, r.URL})
+		}
+		return p.table(cells, func(row, col int, s string) string {
+			switch {
+			case row == 0:
+				return p.bold(s)
+			case col == 0:
+				return p.cyan(s)
+			case col == 3:
+				return p.dim(s)
+			}
+			return s
+		})
+	case "markdown":
+		for _, r := range rows {
+			fmt.Fprintf(p.w, "%d. [%s](%s) — %s\n", r.Rank, escapeMarkdown(oneLine(r.Title)), r.URL, r.Domain)
+			if c := truncateRunes(oneLine(r.Content), 300); c != "" {
+				fmt.Fprintf(p.w, "   > %s\n", c)
+			}
+		}
+		return nil
+	default: // text
+		for _, r := range rows {
+			fmt.Fprintf(p.w, "%s %s\n    %s\n", p.cyan(fmt.Sprintf("%2d.", r.Rank)), p.bold(oneLine(r.Title)), p.dim(r.URL))
+			if c := truncateRunes(oneLine(r.Content), 200); c != "" {
+				fmt.Fprintf(p.w, "    %s\n", c)
+			}
+		}
+		return nil
+	}
+}
+
+// table prints cells in columns two spaces apart, as tabwriter would.
+// style colors each cell after it is padded: tabwriter would count the
+// escape codes in the column widths and misalign the colored rows.
+func (p *printer) table(cells [][]string, style func(row, col int, s string) string) error {
+	var widths []int
+	for _, r := range cells {
+		for j, c := range r {
+			if j == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[j] = max(widths[j], utf8.RuneCountInString(c))
+		}
+	}
+	var b strings.Builder
+	for i, r := range cells {
+		for j, c := range r {
+			b.WriteString(style(i, j, c))
+			if j < len(r)-1 {
+				b.WriteString(strings.Repeat(" ", widths[j]-utf8.RuneCountInString(c)+2))
+			}
+		}
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(p.w, b.String())
+	return err
+}
+
+// fetch prints a /fetch response.
+func (p *printer) fetch(url string, resp linkup.SearchResponse) error {
+	switch p.format {
+	case "json":
+		return p.prettyJSON(resp.RawJSON())
+	case "jsonl":
+		return p.compactJSON(resp.RawJSON())
+	}
+	page, err := resp.Page()
+	if err != nil {
+		return p.prettyJSON(resp.RawJSON())
+	}
+	switch p.format {
+	case "csv":
+		cw := csv.NewWriter(p.w)
+		cw.Write([]string{"url", "markdown_chars", "images", "markdown"})
+		cw.Write([]string{url, strconv.Itoa(len(page.Markdown)), strconv.Itoa(len(page.Images)), page.Markdown})
+		cw.Flush()
+		return cw.Error()
+	case "table":
+		cells := [][]string{
+			{"URL", url},
+			{"MARKDOWN CHARS", strconv.Itoa(len(page.Markdown))},
+			{"RAW HTML CHARS", strconv.Itoa(len(page.RawHTML))},
+			{"IMAGES", strconv.Itoa(len(page.Images))},
+		}
+		// Present with -meta.
+		for _, row := range [][2]string{
+			{"TITLE", page.Title},
+			{"DESCRIPTION", page.Description},
+			{"CANONICAL", page.Canonical},
+			{"LANGUAGE", page.Language},
+			{"AUTHOR", page.Author},
+			{"PUBLISHED", dateOrEmpty(page.Published)},
+			{"MODIFIED", dateOrEmpty(page.Modified)},
+		} {
+			if row[1] != "" {
+				cells = append(cells, row[:])
+			}
+		}
+		if len(page.Links) > 0 {
+			cells = append(cells, []string{"LINKS", strconv.Itoa(len(page.Links))})
+		}
+		return p.table(cells, func(row, col int, s string) string {
+			if col == 0 {
+				return p.bold(s)
+			}
+			return s
+		})
+	case "text":
+		fmt.Fprintln(p.w, p.dim("--- "+url+" ---"))
+	}
+	_, err = fmt.Fprintln(p.w, strings.TrimRight(page.Markdown, "\n"))
+	return err
+}
+
+// balance prints a balance response.
+func (p *printer) balance(bal linkup.BalanceResponse) error {
+	v := strconv.FormatFloat(bal.Balance, 'f', -1, 64)
+	var err error
+	switch p.format {
+	case "json":
+		var b []byte
+		b, err = json.MarshalIndent(bal, "", "  ")
+		fmt.Fprintln(p.w, string(b))
+	case "jsonl":
+		err = json.NewEncoder(p.w).Encode(bal)
+	case "csv":
+		_, err = fmt.Fprintf(p.w, "balance\n%s\n", v)
+	case "table":
+		_, err = fmt.Fprintf(p.w, "%s\n%s\n", p.bold("BALANCE"), v)
+	case "markdown":
+		_, err = fmt.Fprintf(p.w, "**Balance:** %s credits\n", v)
+	default:
+		_, err = fmt.Fprintf(p.w, "%s credits\n", v)
+	}
+	return err
+}
+
+func (p *printer) prettyJSON(raw []byte) error {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "", "  "); err != nil {
+		_, err = fmt.Fprintln(p.w, string(raw))
+		return err
+	}
+	_, err := fmt.Fprintln(p.w, buf.String())
+	return err
+}
+
+func (p *printer) compactJSON(raw []byte) error {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		_, err = fmt.Fprintln(p.w, string(raw))
+		return err
+	}
+	_, err := fmt.Fprintln(p.w, buf.String())
+	return err
+}
+
+func oneLine(s string) string { return strings.Join(strings.Fields(s), " ") }
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer("[", `\[`, "]", `\]`).Replace(s)
+}
+
+func dateOrEmpty(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
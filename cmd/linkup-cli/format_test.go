@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+var ansiRe = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// TestPrinterGolden prints a results search, an answer, structured output,
+// a fetch and a balance in every format, with and without colors, and
+// compares them with testdata/format/<format>[-color].golden. Rewrite the
+// files with -update after an intended change, and read the diff.
+func TestPrinterGolden(t *testing.T) {
+	// Ten results, so that the rank column has two widths.
+	var results linkup.SearchResults
+	for i, title := range []string{"Go", "Café résumé", "A title that is long enough to be truncated in the table format, surely", "", "x", "Five", "Six", "Seven", "Eight", "Ten\tand  tabs"} {
+		results.Results = append(results.Results, linkup.SearchResult{
+			Type: "text", Name: title, URL: fmt.Sprintf("https://www.site%d.example/page/%d", i%3, i), Content: fmt.Sprintf("Content %d [with] brackets.", i),
+		})
+	}
+	raw, _ := json.Marshal(results)
+	searchResp := linkup.SearchResponse{Raw: raw}
+	answerResp := linkup.SearchResponse{Raw: []byte(`{"answer":"Go was released in 2009 [1]. It is fast [1, 2].","sources":[{"name":"Go","url":"https://go.dev/","snippet":"The Go language"},{"url":"https://en.wikipedia.org/wiki/Go_(programming_language)"}]}`)}
+	structuredResp := linkup.SearchResponse{Raw: []byte(`{"name":"Go","year":2009}`)}
+	fetchResp := linkup.SearchResponse{Raw: []byte(`{"markdown":"# Go\n\nGo is a language.\n","rawHtml":"<h1>Go</h1>","images":[{"url":"https://go.dev/logo.png"}],"title":"The Go Language","language":"en"}`)}
+	balance := linkup.BalanceResponse{Balance: 12.5}
+
+	plain := map[string]string{}
+	for _, color := range []bool{false, true} {
+		for _, format := range []string{"text", "table", "json", "jsonl", "markdown", "csv"} {
+			name := format
+			if color {
+				name += "-color"
+			}
+			t.Run(name, func(t *testing.T) {
+				var b bytes.Buffer
+				p := &printer{w: &b, format: format, color: color}
+				for _, step := range []struct {
+					title string
+					print func() error
+				}{
+					{"searchResults", func() error { return p.search(linkup.OutputSearchResults, searchResp) }},
+					{"sourcedAnswer", func() error { return p.search(linkup.OutputSourcedAnswer, answerResp) }},
+					{"structured", func() error { return p.search(linkup.OutputStructured, structuredResp) }},
+					{"fetch", func() error { return p.fetch("https://go.dev/", fetchResp) }},
+					{"balance", func() error { return p.balance(balance) }},
+				} {
+					fmt.Fprintf(&b, "==> %s <==\n", step.title)
+					if err := step.print(); err != nil {
+						t.Fatalf("%s: %v", step.title, err)
+					}
+				}
+				got := b.String()
+				if color {
+					// Colors change nothing but the escape codes, so columns
+					// stay aligned.
+					if stripped := ansiRe.ReplaceAllString(got, ""); stripped != plain[format] {
+						t.Errorf("without escape codes, the output differs from the plain one:\n%s", stripped)
+					}
+				} else {
+					plain[format] = got
+				}
+
+				golden := filepath.Join("testdata", "format", name+".golden")
+				if *update {
+					if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+						t.Fatal(err)
+					}
+					if err := os.WriteFile(golden, b.Bytes(), 0o644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != string(want) {
+					t.Errorf("output differs from %s; if intended, rerun with -update\ngot:\n%s", golden, got)
+				}
+			})
+		}
+	}
+}
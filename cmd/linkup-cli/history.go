@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/history"
+)
+
+// historyItem is the JSON shape of a listed or matching entry.
+type historyItem struct {
+	ID      string    `json:"id"`
+	Time    time.Time `json:"time"`
+	Op      string    `json:"op"`
+	Subject string    `json:"subject"`
+	Credits float64   `json:"credits"`
+	Score   float64   `json:"score,omitempty"`
+	Snippet string    `json:"snippet,omitempty"`
+}
+
+func cmdHistory(args []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	dir := fs.String("dir", "", "archive directory (default: profile historyDir or <config dir>/linkup/history)")
+	op := fs.String("op", "", "only search or fetch entries")
+	since := fs.String("since", "", "only entries on or after YYYY-MM-DD")
+	until := fs.String("until", "", "only entries before YYYY-MM-DD")
+	limit := fs.Int("limit", 20, "maximum entries (-1 for all)")
+	common := addProfileFlags(fs, "text")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), `Usage:
+  linkup history search "terms" [flags]   full-text search (word*, -word)
+  linkup history list [flags]             most recent entries
+  linkup history show <id> [flags]        print an archived response
+
+Record calls with -record, LINKUP_RECORD=1 or "linkup config set record on".
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	rest := parseInterleaved(fs, args)
+	if len(rest) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	st, pr := common.mustResolve()
+	if *dir != "" {
+		st.HistoryDir = *dir
+	}
+	store, err := history.Open(st.HistoryDir, history.Options{})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	f := history.Filter{Op: *op, Limit: *limit}
+	for _, d := range []struct {
+		flag string
+		dst  *time.Time
+	}{{*since, &f.Since}, {*until, &f.Until}} {
+		if d.flag == "" {
+			continue
+		}
+		if *d.dst, err = time.ParseInLocation("2006-01-02", d.flag, time.Local); err != nil {
+			fmt.Fprintln(os.Stderr, "error: dates must be YYYY-MM-DD")
+			os.Exit(2)
+		}
+	}
+
+	switch rest[0] {
+	case "search":
+		if len(rest) < 2 {
+			fmt.Fprintln(os.Stderr, `usage: linkup history search "terms"`)
+			os.Exit(2)
+		}
+		hits, err := store.Search(strings.Join(rest[1:], " "), f)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		items := make([]historyItem, len(hits))
+		for i, h := range hits {
+			items[i] = newHistoryItem(h.Entry)
+			items[i].Score, items[i].Snippet = h.Score, h.Snippet
+		}
+		printHistory(pr, items)
+	case "list":
+		entries, err := store.List(f)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		items := make([]historyItem, len(entries))
+		for i, e := range entries {
+			items[i] = newHistoryItem(e)
+		}
+		printHistory(pr, items)
+	case "show":
+		if len(rest) != 2 {
+			fmt.Fprintln(os.Stderr, "usage: linkup history show <id>")
+			os.Exit(2)
+		}
+		e, err := store.Get(rest[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		if e.Op == linkup.OpFetch {
+			err = pr.fetch(e.Subject(), e.SearchResponse())
+		} else {
+			req, _ := e.SearchRequest()
+			err = pr.search(req.OutputType, e.SearchResponse())
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+func newHistoryItem(e *history.Entry) historyItem {
+	return historyItem{ID: e.ID, Time: e.Time, Op: e.Op, Subject: e.Subject(), Credits: e.Credits}
+}
+
+func printHistory(pr *printer, items []historyItem) {
+	switch pr.format {
+	case "json":
+		b, _ := json.MarshalIndent(items, "", "  ")
+		fmt.Fprintln(pr.w, string(b))
+	case "jsonl":
+		for _, it := range items {
+			b, _ := json.Marshal(it)
+			fmt.Fprintln(pr.w, string(b))
+		}
+	default:
+		if len(items) == 0 {
+			fmt.Fprintln(pr.w, "no matches")
+			return
+		}
+		for i, it := range items {
+			fmt.Fprintf(pr.w, "%2d. %s  %-6s  %s  %s\n", i+1, it.Time.Local().Format("2006-01-02 15:04"), it.Op,
+				pr.dim(it.ID), oneLine(it.Subject))
+			if it.Snippet != "" {
+				fmt.Fprintf(pr.w, "    %s\n", it.Snippet)
+			}
+		}
+	}
+}
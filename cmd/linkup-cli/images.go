@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/images"
+)
+
+func cmdImages(args []string) {
+	fs := flag.NewFlagSet("images", flag.ExitOnError)
+	q := fs.String("q", "", "search for images matching this query")
+	depth := fs.String("depth", "", "depth: standard|deep (default standard)")
+	include := fs.String("include", "", "comma-separated include domains, @group or @file")
+	exclude := fs.String("exclude", "", "comma-separated exclude domains, @group or @file")
+	pages := fs.String("url", "", "comma-separated pages to take images from (fetched with image extraction)")
+	outDir := fs.String("o", "images", "directory for the images and manifest.json")
+	limit := fs.Int("max", 50, "download at most this many images (0 = all)")
+	concurrency := fs.Int("concurrency", 4, "images downloaded in parallel")
+	maxBytes := fs.Int64("max-bytes", 10<<20, "skip images larger than this")
+	minWidth := fs.Int("min-width", 0, "skip images narrower than this")
+	minHeight := fs.Int("min-height", 0, "skip images shorter than this")
+	types := fs.String("types", "", "comma-separated MIME types to keep (default jpeg, png, gif and webp)")
+	distance := fs.Int("distance", images.DefaultMaxDistance, "dHash bits two images may differ by and still be near-duplicates (-1 = exact copies only)")
+	quiet := fs.Bool("quiet", false, "do not log each image")
+	common := addCommonFlags(fs, 30*time.Second, "")
+	fs.Parse(args)
+	st, err := common.resolve()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(2)
+	}
+	st.applySearchFlags(fs, *depth, "", *include, *exclude)
+	if *q == "" && *pages == "" {
+		fmt.Fprintln(os.Stderr, "usage: linkup images -q query | -url page[,page...] [-o dir]")
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	client := st.client()
+	var srcs []images.Source
+	if *q != "" {
+		sctx, cancel := context.WithTimeout(ctx, st.Timeout)
+		found, err := images.Search(sctx, client, linkup.SearchRequest{
+			Q:              *q,
+			Depth:          linkup.Depth(st.Depth),
+			IncludeDomains: st.Include,
+			ExcludeDomains: st.Exclude,
+		})
+		cancel()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		srcs = append(srcs, found...)
+	}
+	for _, p := range splitCSV(*pages) {
+		fctx, cancel := context.WithTimeout(ctx, st.Timeout)
+		resp, err := client.Fetch(fctx, linkup.FetchRequest{URL: p, ExtractImages: true})
+		cancel()
+		var page linkup.FetchResult
+		if err == nil {
+			page, err = resp.Page()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", p, err)
+			os.Exit(1)
+		}
+		srcs = append(srcs, images.FromPage(p, page)...)
+	}
+	if *limit > 0 && len(srcs) > *limit {
+		srcs = srcs[:*limit]
+	}
+
+	cfg := images.Config{
+		Dir:         *outDir,
+		UserAgent:   st.UserAgent,
+		Concurrency: *concurrency,
+		MaxBytes:    *maxBytes,
+		MinWidth:    *minWidth,
+		MinHeight:   *minHeight,
+		Types:       splitCSV(*types),
+		MaxDistance: *distance,
+	}
+	if !*quiet {
+		cfg.Logf = logStderr
+	}
+	d, err := images.New(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	m, err := d.Download(ctx, srcs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	dups := 0
+	for _, e := range m.Images {
+		dups += len(e.Duplicates)
+	}
+	logStderr("%d images kept, %d duplicates dropped, %d skipped; manifest: %s",
+		len(m.Images), dups, len(m.Skipped), filepath.Join(*outDir, images.ManifestName))
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// errInterrupted is returned by readLine when the user presses Ctrl-C.
+var errInterrupted = errors.New("interrupted")
+
+// lineEditor reads lines with Emacs-style editing and history when stdin is
+// a terminal, and falls back to plain buffered reads otherwise.
+type lineEditor struct {
+	in      *os.File
+	out     io.Writer
+	br      *bufio.Reader
+	history []string
+	maxHist int
+}
+
+func newLineEditor(in *os.File, out io.Writer) *lineEditor {
+	return &lineEditor{in: in, out: out, br: bufio.NewReader(in), maxHist: 1000}
+}
+
+// addHistory appends a non-empty line that differs from the previous one.
+func (e *lineEditor) addHistory(line string) {
+	if line == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > e.maxHist {
+		e.history = e.history[len(e.history)-e.maxHist:]
+	}
+}
+
+func (e *lineEditor) loadHistory(path string) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	for _, l := range strings.Split(string(b), "\n") {
+		e.addHistory(l)
+	}
+}
+
+func (e *lineEditor) saveHistory(path string) error {
+	return os.WriteFile(path, []byte(strings.Join(e.history, "\n")+"\n"), 0o600)
+}
+
+// readLine prints prompt and returns the entered line. It returns io.EOF on
+// Ctrl-D at an empty line and errInterrupted on Ctrl-C.
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	fd := int(e.in.Fd())
+	if !isTerminal(fd) {
+		return e.readPlain(prompt)
+	}
+	restore, err := makeRaw(fd)
+	if err != nil {
+		return e.readPlain(prompt)
+	}
+	defer restore()
+	return e.readRaw(prompt)
+}
+
+func (e *lineEditor) readPlain(prompt string) (string, error) {
+	fmt.Fprint(e.out, prompt)
+	line, err := e.br.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (e *lineEditor) readRaw(prompt string) (string, error) {
+	var (
+		buf   []rune
+		pos   int
+		hist  = len(e.history) // index into history; len = the line being edited
+		draft []rune
+	)
+	redraw := func() {
+		// Return to column 0, print, clear the rest, then place the cursor.
+		fmt.Fprintf(e.out, "\r%s%s\x1b[K", prompt, string(buf))
+		if back := len(buf) - pos; back > 0 {
+			fmt.Fprintf(e.out, "\x1b[%dD", back)
+		}
+	}
+	setLine := func(r []rune) {
+		buf = append(buf[:0], r...)
+		pos = len(buf)
+	}
+	redraw()
+	for {
+		r, err := e.readRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\r\n")
+			return string(buf), nil
+		case 3: // Ctrl-C
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", errInterrupted
+		case 4: // Ctrl-D
+			if len(buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			if pos < len(buf) {
+				buf = append(buf[:pos], buf[pos+1:]...)
+			}
+		case 1: // Ctrl-A
+			pos = 0
+		case 5: // Ctrl-E
+			pos = len(buf)
+		case 2: // Ctrl-B
+			if pos > 0 {
+				pos--
+			}
+		case 6: // Ctrl-F
+			if pos < len(buf) {
+				pos++
+			}
+		case 11: // Ctrl-K
+			buf = buf[:pos]
+		case 21: // Ctrl-U
+			buf = append(buf[:0], buf[pos:]...)
+			pos = 0
+		case 23: // Ctrl-W
+			start := pos
+			for start > 0 && buf[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && buf[start-1] != ' ' {
+				start--
+			}
+			buf = append(buf[:start], buf[pos:]...)
+			pos = start
+		case 127, 8: // Backspace
+			if pos > 0 {
+				buf = append(buf[:pos-1], buf[pos:]...)
+				pos--
+			}
+		case 27: // escape sequence
+			key, err := e.readEscape()
+			if err != nil {
+				return "", err
+			}
+			switch key {
+			case "up":
+				if hist > 0 {
+					if hist == len(e.history) {
+						draft = append(draft[:0], buf...)
+					}
+					hist--
+					setLine([]rune(e.history[hist]))
+				}
+			case "down":
+				if hist < len(e.history) {
+					hist++
+					if hist == len(e.history) {
+						setLine(draft)
+					} else {
+						setLine([]rune(e.history[hist]))
+					}
+				}
+			case "left":
+				if pos > 0 {
+					pos--
+				}
+			case "right":
+				if pos < len(buf) {
+					pos++
+				}
+			case "home":
+				pos = 0
+			case "end":
+				pos = len(buf)
+			case "delete":
+				if pos < len(buf) {
+					buf = append(buf[:pos], buf[pos+1:]...)
+				}
+			}
+		default:
+			if r < 32 {
+				continue
+			}
+			buf = append(buf, 0)
+			copy(buf[pos+1:], buf[pos:])
+			buf[pos] = r
+			pos++
+		}
+		redraw()
+	}
+}
+
+func (e *lineEditor) readRune() (rune, error) {
+	var b [utf8.UTFMax]byte
+	n := 0
+	for {
+		c, err := e.br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		b[n] = c
+		n++
+		if utf8.FullRune(b[:n]) || n == len(b) {
+			r, _ := utf8.DecodeRune(b[:n])
+			return r, nil
+		}
+	}
+}
+
+// readEscape decodes the rest of a CSI/SS3 sequence after ESC.
+func (e *lineEditor) readEscape() (string, error) {
+	c, err := e.br.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	if c != '[' && c != 'O' {
+		return "", nil
+	}
+	var seq []byte
+	for {
+		c, err := e.br.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		seq = append(seq, c)
+		if c >= 0x40 && c <= 0x7e {
+			break
+		}
+	}
+	switch string(seq) {
+	case "A":
+		return "up", nil
+	case "B":
+		return "down", nil
+	case "C":
+		return "right", nil
+	case "D":
+		return "left", nil
+	case "H", "1~", "7~":
+		return "home", nil
+	case "F", "4~", "8~":
+		return "end", nil
+	case "3~":
+		return "delete", nil
+	}
+	return "", nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testEditor returns an editor reading keys from in, as readRaw does once
+// the terminal is in raw mode.
+func testEditor(in string, history ...string) (*lineEditor, *strings.Builder) {
+	out := new(strings.Builder)
+	e := &lineEditor{out: out, br: bufio.NewReader(strings.NewReader(in)), maxHist: 1000}
+	for _, h := range history {
+		e.addHistory(h)
+	}
+	return e, out
+}
+
+func TestReadRaw(t *testing.T) {
+	const (
+		up, down, left, right = "\x1b[A", "\x1b[B", "\x1b[D", "\x1b[C"
+		home, end, del        = "\x1b[H", "\x1b[4~", "\x1b[3~"
+	)
+	for _, tc := range []struct {
+		name, keys string
+		want       string
+		err        error
+	}{
+		{"plain", "hello\r", "hello", nil},
+		{"newline", "hello\n", "hello", nil},
+		{"utf-8", "café ☕\r", "café ☕", nil},
+		{"backspace", "hex\x7fllq\x7fo\r", "hello", nil},
+		{"ctrl-h", "ab\x08c\r", "ac", nil},
+		{"insert mid-line", "hllo" + left + left + left + "e\r", "hello", nil},
+		{"ctrl-a ctrl-e", "ello\x01h\x05!\r", "hello!", nil},
+		{"ctrl-b ctrl-f", "ac\x02b\x06d\r", "abcd", nil},
+		{"home end", "bc" + home + "a" + end + "d\r", "abcd", nil},
+		{"ss3 home", "bc\x1bOHa\r", "abc", nil},
+		{"left at start", left + "a\r", "a", nil},
+		{"right at end", "a" + right + "b\r", "ab", nil},
+		{"delete", "abc" + home + del + "\r", "bc", nil},
+		{"ctrl-d deletes", "abc\x01\x04\r", "bc", nil},
+		{"ctrl-k", "hello world\x01\x06\x06\x06\x06\x06\x0b\r", "hello", nil},
+		{"ctrl-u", "hello world\x02\x02\x02\x02\x02\x15\r", "world", nil},
+		{"ctrl-w", "deep  search query\x17\r", "deep  search ", nil},
+		{"ctrl-w spaces", "a b  \x17\r", "a ", nil},
+		{"control chars ignored", "a\x07\x10b\r", "ab", nil},
+		{"unknown escape", "a\x1b[2~\x1bxb\r", "ab", nil},
+		{"ctrl-c", "abc\x03", "", errInterrupted},
+		{"ctrl-d on empty line", "\x04", "", io.EOF},
+		{"eof", "abc", "", io.EOF},
+		{"eof in escape", "abc\x1b[", "", io.EOF},
+		// History: up walks back, down returns to the line being edited.
+		{"up", up + "\r", "third", nil},
+		{"up up", up + up + "\r", "second", nil},
+		{"up past oldest", up + up + up + up + "\r", "first", nil},
+		{"up edit", up + "!\r", "third!", nil},
+		{"down restores draft", "dra" + up + up + down + down + "ft\r", "draft", nil},
+		{"down at draft", "x" + down + "\r", "x", nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e, _ := testEditor(tc.keys, "first", "second", "third")
+			got, err := e.readRaw("> ")
+			if got != tc.want || !errors.Is(err, tc.err) {
+				t.Fatalf("readRaw = %q, %v; want %q, %v", got, err, tc.want, tc.err)
+			}
+		})
+	}
+}
+
+func TestReadRawRedraw(t *testing.T) {
+	e, out := testEditor("ab" + "\x1b[D" + "\r")
+	if _, err := e.readRaw("> "); err != nil {
+		t.Fatal(err)
+	}
+	// Each key redraws the whole line and puts the cursor back.
+	want := "\r> \x1b[K" + "\r> a\x1b[K" + "\r> ab\x1b[K" + "\r> ab\x1b[K\x1b[1D" + "\r\n"
+	if out.String() != want {
+		t.Errorf("output = %s, want %s", strconv.Quote(out.String()), strconv.Quote(want))
+	}
+}
+
+func TestReadPlain(t *testing.T) {
+	e, out := testEditor("one\r\ntwo\nthree")
+	for _, want := range []string{"one", "two", "three"} {
+		if got, err := e.readPlain("> "); got != want || err != nil {
+			t.Fatalf("readPlain = %q, %v; want %q", got, err, want)
+		}
+	}
+	if _, err := e.readPlain("> "); err != io.EOF {
+		t.Fatalf("err = %v, want EOF", err)
+	}
+	if out.String() != "> > > > " {
+		t.Errorf("prompts = %q", out.String())
+	}
+}
+
+func TestHistory(t *testing.T) {
+	e, _ := testEditor("")
+	e.maxHist = 3
+	for _, l := range []string{"a", "", "b", "b", "c", "d"} {
+		e.addHistory(l)
+	}
+	// Empty lines and repeats are skipped, and the oldest entries dropped.
+	if want := []string{"b", "c", "d"}; !slices.Equal(e.history, want) {
+		t.Fatalf("history = %q, want %q", e.history, want)
+	}
+
+	path := filepath.Join(t.TempDir(), "history")
+	if err := e.saveHistory(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, _ := testEditor("\x1b[A\x1b[A\r")
+	loaded.loadHistory(path)
+	loaded.loadHistory(filepath.Join(t.TempDir(), "missing"))
+	if !slices.Equal(loaded.history, e.history) {
+		t.Fatalf("loaded %q, want %q", loaded.history, e.history)
+	}
+	if got, _ := loaded.readRaw("> "); got != "c" {
+		t.Errorf("up up = %q, want c", got)
+	}
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"flag"
@@ -25,6 +24,26 @@ func main() {
 		cmdFetch(os.Args[2:])
 	case "balance":
 		cmdBalance(os.Args[2:])
+	case "repl":
+		cmdRepl(os.Args[2:])
+	case "watch":
+		cmdWatch(os.Args[2:])
+	case "feed":
+		cmdFeed(os.Args[2:])
+	case "history":
+		cmdHistory(os.Args[2:])
+	case "crawl":
+		cmdCrawl(os.Args[2:])
+	case "fetch-sitemap":
+		cmdFetchSitemap(os.Args[2:])
+	case "images":
+		cmdImages(os.Args[2:])
+	case "export":
+		cmdExport(os.Args[2:])
+	case "domains":
+		cmdDomains(os.Args[2:])
+	case "config":
+		cmdConfig(os.Args[2:])
 	case "-h", "--help", "help":
 		usage()
 	default:
@@ -35,56 +54,62 @@ func main() {
 }
 
 func usage() {
-	fmt.Println(`linkup CLI (unofficial)
+	fmt.Print(`linkup CLI (unofficial)
 Usage:
   linkup search [flags]
   linkup fetch  [flags]
   linkup balance [flags]
+  linkup repl    [flags]
+  linkup watch   -q ... [-every 1h] [-jsonl file] [-webhook URL]
+  linkup feed    -q ... [-format rss|atom|json] | -serve addr -saved file -monitor file
+  linkup history search "terms" | list | show <id>
+  linkup crawl   [-o dir | -jsonl file] [-scope host/path] [-max-pages N] URL...
+  linkup fetch-sitemap [-since 2025-01-01] [-list | -o dir | -jsonl file] URL
+  linkup images  -q ... | -url page [-o dir] [-min-width 100] [-distance 5]
+  linkup export  -q ... | -url page | -history [-format csv|ndjson|parquet] [-o dir]
+  linkup domains [@group|@file|domain...]
+  linkup config  list|get|set|use|path
+
+Every API command accepts -profile, -base, -ua, -timeout, -format, -record,
+-offline [-fuzzy 0.6], -local-fallback and -filter rules.json. -include and
+-exclude take domains, @group names from the config's domainGroups and
+@file lists.
+Settings are taken from flags, then the environment, then the selected
+profile in the config file, then built-in defaults.
 
 Env:
-  LINKUP_API_KEY   Your Linkup API key
+  LINKUP_API_KEY      Your Linkup API key
+  LINKUP_PROFILE      profile to use (default: the config's defaultProfile)
+  LINKUP_CONFIG       config file (default: ~/.config/linkup/config.json)
+  LINKUP_BASE_URL, LINKUP_USER_AGENT, LINKUP_TIMEOUT,
+  LINKUP_DEPTH, LINKUP_OUTPUT, LINKUP_FORMAT,
+  LINKUP_RECORD, LINKUP_HISTORY_DIR, LINKUP_OFFLINE,
+  LINKUP_LOCAL_FALLBACK
 `)
 }
 
 func cmdSearch(args []string) {
 	fs := flag.NewFlagSet("search", flag.ExitOnError)
 	q := fs.String("q", "", "query text")
-	depth := fs.String("depth", string(linkup.DepthStandard), "depth: standard|deep")
-	out := fs.String("output", string(linkup.OutputSearchResults), "output: sourcedAnswer|searchResults|structured")
+	depth := fs.String("depth", "", "depth: standard|deep (default standard)")
+	out := fs.String("output", "", "output: sourcedAnswer|searchResults|structured (default searchResults)")
 	from := fs.String("from", "", "from date YYYY-MM-DD")
 	to := fs.String("to", "", "to date YYYY-MM-DD")
-	include := fs.String("include", "", "comma-separated include domains")
-	exclude := fs.String("exclude", "", "comma-separated exclude domains")
+	include := fs.String("include", "", "comma-separated include domains, @group or @file")
+	exclude := fs.String("exclude", "", "comma-separated exclude domains, @group or @file")
 	withImgs := fs.Bool("images", false, "include images")
 	inlineCite := fs.Bool("inline", false, "include inline citations")
 	withSources := fs.Bool("sources", false, "include sources in response")
 	schema := fs.String("schema", "", "structured output schema (JSON string)")
-
-	timeout := fs.Duration("timeout", 30*time.Second, "request timeout")
-	baseURL := fs.String("base", "", "override base URL (for testing)")
-	ua := fs.String("ua", "", "custom user-agent")
+	common := addCommonFlags(fs, 30*time.Second, "json")
 
 	fs.Parse(args)
+	st, pr := common.mustResolve()
+	st.applySearchFlags(fs, *depth, *out, *include, *exclude)
 
-	apiKey := os.Getenv("LINKUP_API_KEY")
-	if apiKey == "" {
-		fmt.Fprintln(os.Stderr, "missing LINKUP_API_KEY")
-		os.Exit(2)
-	}
-
-	clientOpts := []linkup.Option{
-		linkup.WithRetry(3, 250*time.Millisecond, 4*time.Second),
-	}
-	if *baseURL != "" {
-		clientOpts = append(clientOpts, linkup.WithBaseURL(*baseURL))
-	}
-	if *ua != "" {
-		clientOpts = append(clientOpts, linkup.WithUserAgent(*ua))
-	}
-
-	client := linkup.NewClient(apiKey, clientOpts...)
+	client := st.client()
 	// Override timeout through context.
-	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), st.Timeout)
 	defer cancel()
 
 	var schemaPtr *string
@@ -94,13 +119,13 @@ func cmdSearch(args []string) {
 
 	req := linkup.SearchRequest{
 		Q:                      *q,
-		Depth:                  linkup.Depth(*depth),
-		OutputType:             linkup.OutputType(*out),
+		Depth:                  linkup.Depth(st.Depth),
+		OutputType:             linkup.OutputType(st.Output),
 		IncludeImages:          *withImgs,
 		FromDate:               *from,
 		ToDate:                 *to,
-		ExcludeDomains:         splitCSV(*exclude),
-		IncludeDomains:         splitCSV(*include),
+		ExcludeDomains:         st.Exclude,
+		IncludeDomains:         st.Include,
 		IncludeInlineCitations: *inlineCite,
 		StructuredOutputSchema: schemaPtr,
 		IncludeSources:         *withSources,
@@ -111,13 +136,19 @@ func cmdSearch(args []string) {
 		fmt.Fprintln(os.Stderr, "error:", err)
 		os.Exit(1)
 	}
+	if err := pr.search(req.OutputType, resp); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
 
-	var buf bytes.Buffer
-	if err := json.Indent(&buf, resp.RawJSON(), "", "  "); err == nil {
-		fmt.Println(buf.String())
-		return
+func mustPrinter(format string) *printer {
+	pr, err := newPrinter(format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
-	fmt.Println(string(resp.RawJSON()))
+	return pr
 }
 
 func splitCSV(s string) []string {
@@ -141,26 +172,13 @@ func cmdFetch(args []string) {
 	raw := fs.Bool("rawhtml", false, "include raw HTML")
 	render := fs.Bool("render", false, "render JavaScript")
 	images := fs.Bool("images", false, "extract images")
-	timeout := fs.Duration("timeout", 30*time.Second, "request timeout")
-	baseURL := fs.String("base", "", "override base URL (for testing)")
-	ua := fs.String("ua", "", "custom user-agent")
+	meta := fs.Bool("meta", false, "add title, description, canonical URL, dates, OpenGraph, JSON-LD and links (best with -rawhtml)")
+	common := addCommonFlags(fs, 30*time.Second, "json")
 	fs.Parse(args)
+	st, pr := common.mustResolve()
 
-	apiKey := os.Getenv("LINKUP_API_KEY")
-	if apiKey == "" {
-		fmt.Fprintln(os.Stderr, "missing LINKUP_API_KEY")
-		os.Exit(2)
-	}
-	clientOpts := []linkup.Option{}
-	if *baseURL != "" {
-		clientOpts = append(clientOpts, linkup.WithBaseURL(*baseURL))
-	}
-	if *ua != "" {
-		clientOpts = append(clientOpts, linkup.WithUserAgent(*ua))
-	}
-
-	client := linkup.NewClient(apiKey, clientOpts...)
-	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	client := st.client()
+	ctx, cancel := context.WithTimeout(context.Background(), st.Timeout)
 	defer cancel()
 
 	resp, err := client.Fetch(ctx, linkup.FetchRequest{
@@ -173,37 +191,38 @@ func cmdFetch(args []string) {
 		fmt.Fprintln(os.Stderr, "error:", err)
 		os.Exit(1)
 	}
+	if *meta {
+		if resp, err = withMetadata(*urlStr, resp); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+	}
+	if err := pr.fetch(*urlStr, resp); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
 
-	var buf bytes.Buffer
-	if err := json.Indent(&buf, resp.RawJSON(), "", "  "); err == nil {
-		fmt.Println(buf.String())
-		return
+// withMetadata re-encodes a fetch response with the fields
+// FetchResult.Extract fills.
+func withMetadata(url string, resp linkup.SearchResponse) (linkup.SearchResponse, error) {
+	page, err := resp.Page()
+	if err != nil {
+		return resp, err
 	}
-	fmt.Println(string(resp.RawJSON()))
+	page.Extract(url)
+	b, err := json.Marshal(page)
+	return linkup.SearchResponse{Raw: b}, err
 }
 
 func cmdBalance(args []string) {
 	fs := flag.NewFlagSet("balance", flag.ExitOnError)
-	baseURL := fs.String("base", "", "override base URL (for testing)")
-	ua := fs.String("ua", "", "custom user-agent")
-	timeout := fs.Duration("timeout", 15*time.Second, "request timeout")
+	common := addCommonFlags(fs, 15*time.Second, "json")
 	fs.Parse(args)
+	st, pr := common.mustResolve()
 
-	apiKey := os.Getenv("LINKUP_API_KEY")
-	if apiKey == "" {
-		fmt.Fprintln(os.Stderr, "missing LINKUP_API_KEY")
-		os.Exit(2)
-	}
-	clientOpts := []linkup.Option{}
-	if *baseURL != "" {
-		clientOpts = append(clientOpts, linkup.WithBaseURL(*baseURL))
-	}
-	if *ua != "" {
-		clientOpts = append(clientOpts, linkup.WithUserAgent(*ua))
-	}
-
-	client := linkup.NewClient(apiKey, clientOpts...)
-	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	client := st.client()
+	ctx, cancel := context.WithTimeout(context.Background(), st.Timeout)
 	defer cancel()
 
 	bal, err := client.GetBalance(ctx)
@@ -211,6 +230,8 @@ func cmdBalance(args []string) {
 		fmt.Fprintln(os.Stderr, "error:", err)
 		os.Exit(1)
 	}
-	out, _ := json.MarshalIndent(bal, "", "  ")
-	fmt.Println(string(out))
+	if err := pr.balance(bal); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
 }
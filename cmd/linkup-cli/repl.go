@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/domains"
+)
+
+const replHelp = `Type a query to search with the current settings, or a command:
+  :depth standard|deep           set search depth
+  :output searchResults|sourcedAnswer|structured
+  :include a.com,@group,@file    restrict to domains (no argument clears)
+  :exclude a.com,@group,@file    exclude domains (no argument clears)
+  :from YYYY-MM-DD / :to YYYY-MM-DD   date range (no argument clears)
+  :images on|off  :inline on|off  :sources on|off
+  :schema <json>                 structured output schema (no argument clears)
+  :format text|table|markdown|json|jsonl|csv
+  :fetch N [render]              fetch result/source N of the last search
+  :save file.json                save the last response
+  :balance                       refresh the credit balance
+  :set                           show current settings
+  :help  :quit
+`
+
+// replSession holds sticky settings and the last results.
+type replSession struct {
+	client  *linkup.Client
+	timeout time.Duration
+	out     io.Writer
+	pr      *printer
+
+	req      linkup.SearchRequest // sticky settings; Q is set per query
+	last     []byte               // last raw response
+	lastURLs []string             // numbered URLs of the last search
+	balance  string
+	groups   map[string][]string // for :include and :exclude
+}
+
+func cmdRepl(args []string) {
+	fs := flag.NewFlagSet("repl", flag.ExitOnError)
+	depth := fs.String("depth", "", "initial depth: standard|deep (default standard)")
+	out := fs.String("output", "", "initial output: sourcedAnswer|searchResults|structured (default searchResults)")
+	histFile := fs.String("history", defaultHistoryFile(), "history file (empty disables)")
+	common := addCommonFlags(fs, 60*time.Second, "text")
+	fs.Parse(args)
+	st, pr := common.mustResolve()
+	st.applySearchFlags(fs, *depth, *out, "", "")
+
+	s := &replSession{
+		client:  st.client(),
+		timeout: st.Timeout,
+		out:     os.Stdout,
+		pr:      pr,
+		groups:  st.DomainGroups,
+		req: linkup.SearchRequest{
+			Depth:          linkup.Depth(st.Depth),
+			OutputType:     linkup.OutputType(st.Output),
+			IncludeDomains: st.Include,
+			ExcludeDomains: st.Exclude,
+		},
+	}
+	ed := newLineEditor(os.Stdin, os.Stdout)
+	if *histFile != "" {
+		ed.loadHistory(*histFile)
+		defer ed.saveHistory(*histFile)
+	}
+
+	fmt.Fprintln(s.out, "linkup repl — :help for commands, :quit or Ctrl-D to exit")
+	s.refreshBalance()
+	for {
+		line, err := ed.readLine(s.prompt())
+		if errors.Is(err, errInterrupted) {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		ed.addHistory(line)
+		if quit := s.exec(line); quit {
+			return
+		}
+	}
+}
+
+func defaultHistoryFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	dir = filepath.Join(dir, "linkup")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "repl_history")
+}
+
+func (s *replSession) prompt() string {
+	return fmt.Sprintf("linkup [%s %s · %s]> ", s.req.Depth, s.req.OutputType, s.balance)
+}
+
+// ctx returns a request context that Ctrl-C cancels without leaving the REPL.
+func (s *replSession) ctx() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	return ctx, func() { cancel(); stop() }
+}
+
+func (s *replSession) refreshBalance() {
+	ctx, cancel := s.ctx()
+	defer cancel()
+	bal, err := s.client.GetBalance(ctx)
+	if err != nil {
+		s.balance = "balance ?"
+		return
+	}
+	s.balance = fmt.Sprintf("%.3f cr", bal.Balance)
+}
+
+// exec runs one REPL line and reports whether the session should end.
+func (s *replSession) exec(line string) bool {
+	if !strings.HasPrefix(line, ":") {
+		s.search(line)
+		return false
+	}
+	cmd, arg, _ := strings.Cut(line[1:], " ")
+	arg = strings.TrimSpace(arg)
+	switch cmd {
+	case "q", "quit", "exit":
+		return true
+	case "h", "help":
+		fmt.Fprint(s.out, replHelp)
+	case "depth":
+		switch linkup.Depth(arg) {
+		case linkup.DepthStandard, linkup.DepthDeep:
+			s.req.Depth = linkup.Depth(arg)
+		default:
+			s.errorf("depth must be standard or deep")
+		}
+	case "output":
+		switch linkup.OutputType(arg) {
+		case linkup.OutputSearchResults, linkup.OutputSourcedAnswer, linkup.OutputStructured:
+			s.req.OutputType = linkup.OutputType(arg)
+		default:
+			s.errorf("output must be searchResults, sourcedAnswer or structured")
+		}
+	case "include", "exclude":
+		list, err := domains.Expand(splitCSV(arg), s.groups)
+		if err != nil {
+			s.errorf("%v", err)
+		} else if cmd == "include" {
+			s.req.IncludeDomains = list
+		} else {
+			s.req.ExcludeDomains = list
+		}
+	case "from":
+		s.req.FromDate = arg
+	case "to":
+		s.req.ToDate = arg
+	case "images", "inline", "sources":
+		on, err := parseOnOff(arg)
+		if err != nil {
+			s.errorf("%v", err)
+			break
+		}
+		switch cmd {
+		case "images":
+			s.req.IncludeImages = on
+		case "inline":
+			s.req.IncludeInlineCitations = on
+		case "sources":
+			s.req.IncludeSources = on
+		}
+	case "schema":
+		if arg == "" {
+			s.req.StructuredOutputSchema = nil
+		} else if !json.Valid([]byte(arg)) {
+			s.errorf("schema is not valid JSON")
+		} else {
+			s.req.StructuredOutputSchema = &arg
+		}
+	case "format":
+		pr, err := newPrinter(arg)
+		if err != nil {
+			s.errorf("%v", err)
+			break
+		}
+		pr.w = s.out
+		s.pr = pr
+	case "fetch":
+		s.fetch(arg)
+	case "save":
+		s.save(arg)
+	case "balance":
+		s.refreshBalance()
+		fmt.Fprintln(s.out, s.balance)
+	case "set":
+		b, _ := json.MarshalIndent(s.req, "", "  ")
+		fmt.Fprintln(s.out, string(b))
+	default:
+		s.errorf("unknown command :%s (try :help)", cmd)
+	}
+	return false
+}
+
+func (s *replSession) errorf(format string, a ...any) {
+	fmt.Fprintf(s.out, "error: "+format+"\n", a...)
+}
+
+func (s *replSession) search(q string) {
+	req := s.req
+	req.Q = q
+	ctx, cancel := s.ctx()
+	defer cancel()
+	resp, err := s.client.Search(ctx, req)
+	if err != nil {
+		s.errorf("%v", err)
+		return
+	}
+	s.last = resp.RawJSON()
+	s.lastURLs = nil
+
+	// Number URLs the same way the printer numbers rows.
+	switch req.OutputType {
+	case linkup.OutputSearchResults:
+		if results, err := resp.Results(); err == nil {
+			for _, r := range results {
+				s.lastURLs = append(s.lastURLs, r.URL)
+			}
+		}
+	case linkup.OutputSourcedAnswer:
+		if ans, err := resp.SourcedAnswer(); err == nil {
+			for _, src := range ans.Sources {
+				s.lastURLs = append(s.lastURLs, src.URL)
+			}
+		}
+	}
+	if err := s.pr.search(req.OutputType, resp); err != nil {
+		s.errorf("%v", err)
+	}
+	s.refreshBalance()
+}
+
+func (s *replSession) fetch(arg string) {
+	fields := strings.Fields(arg)
+	if len(fields) == 0 {
+		s.errorf("usage: :fetch N [render]")
+		return
+	}
+	n, err := strconv.Atoi(fields[0])
+	if err != nil || n < 1 || n > len(s.lastURLs) {
+		s.errorf("no result #%s in the last search (%d available)", fields[0], len(s.lastURLs))
+		return
+	}
+	req := linkup.FetchRequest{URL: s.lastURLs[n-1], RenderJS: len(fields) > 1 && fields[1] == "render"}
+	ctx, cancel := s.ctx()
+	defer cancel()
+	resp, err := s.client.Fetch(ctx, req)
+	if err != nil {
+		s.errorf("%v", err)
+		return
+	}
+	s.last = resp.RawJSON()
+	if err := s.pr.fetch(req.URL, resp); err != nil {
+		s.errorf("%v", err)
+	}
+	s.refreshBalance()
+}
+
+func (s *replSession) save(path string) {
+	if path == "" {
+		s.errorf("usage: :save file.json")
+		return
+	}
+	if s.last == nil {
+		s.errorf("nothing to save yet")
+		return
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, s.last, "", "  "); err != nil {
+		buf.Reset()
+		buf.Write(s.last)
+	}
+	buf.WriteByte('\n')
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		s.errorf("%v", err)
+		return
+	}
+	fmt.Fprintf(s.out, "saved %s\n", path)
+}
+
+func parseOnOff(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "on", "true", "yes", "1":
+		return true, nil
+	case "off", "false", "no", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected on or off, got %q", s)
+}
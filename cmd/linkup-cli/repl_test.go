@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+)
+
+func TestReplExec(t *testing.T) {
+	var searches []linkup.SearchRequest
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/credits/balance":
+			fmt.Fprint(w, `{"balance": 1.5}`)
+		case "/search":
+			var req linkup.SearchRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			searches = append(searches, req)
+			switch {
+			case req.Q == "fail":
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"message":"bad query"}`)
+			case req.OutputType == linkup.OutputSourcedAnswer:
+				fmt.Fprint(w, `{"answer":"An answer [1].","sources":[{"name":"S","url":"https://s.example/"}]}`)
+			default:
+				fmt.Fprint(w, `{"results":[{"type":"text","name":"One","url":"https://a.example/1"},{"type":"text","name":"Two","url":"https://b.example/2"}]}`)
+			}
+		case "/fetch":
+			var req linkup.FetchRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			fmt.Fprintf(w, `{"markdown":"page %s render=%v"}`, req.URL, req.RenderJS)
+		}
+	}))
+	defer api.Close()
+
+	var out strings.Builder
+	s := &replSession{
+		client:  linkup.NewClient("k", linkup.WithBaseURL(api.URL), linkup.WithRetry(0, time.Millisecond, time.Millisecond)),
+		timeout: 5 * time.Second,
+		out:     &out,
+		pr:      &printer{w: &out, format: "text"},
+		groups:  map[string][]string{"news": {"a.com", "b.com"}},
+		req:     linkup.SearchRequest{Depth: linkup.DepthStandard, OutputType: linkup.OutputSearchResults},
+	}
+	saved := filepath.Join(t.TempDir(), "last.json")
+	for _, step := range []struct {
+		line string
+		want string // in the output; "" for none
+		quit bool
+	}{
+		{":help", ":fetch N [render]", false},
+		{":save " + saved, "error: nothing to save yet", false},
+		{":fetch 1", "error: no result #1 in the last search (0 available)", false},
+		{":fetch", "error: usage: :fetch N [render]", false},
+		{":depth deep", "", false},
+		{":depth shallow", "error: depth must be standard or deep", false},
+		{":output nope", "error: output must be searchResults, sourcedAnswer or structured", false},
+		{":include @news, c.com", "", false},
+		{":include @missing", "error: domains: unknown group @missing", false},
+		{":exclude d.com", "", false},
+		{":from 2025-01-01", "", false},
+		{":images on", "", false},
+		{":inline maybe", `error: expected on or off, got "maybe"`, false},
+		{":schema {", "error: schema is not valid JSON", false},
+		{":set", `"includeDomains": [`, false},
+		{"golang news", "https://b.example/2", false},
+		{":fetch 2 render", "page https://b.example/2 render=true", false},
+		{":fetch 3", "error: no result #3 in the last search (2 available)", false},
+		{":save " + saved, "saved " + saved, false},
+		{":format nope", `error: unknown format "nope"`, false},
+		{":format table", "", false},
+		{":output sourcedAnswer", "", false},
+		{"why", "RANK  TITLE  DOMAIN     URL", false},
+		{":fetch 1", "URL             https://s.example/", false},
+		{"fail", "error: linkup api error: bad query (status=400)", false},
+		{":bogus", "error: unknown command :bogus (try :help)", false},
+		{":balance", "1.500 cr", false},
+		{":quit", "", true},
+	} {
+		out.Reset()
+		quit := s.exec(step.line)
+		got := out.String()
+		if quit != step.quit || (step.want == "") != (got == "") || !strings.Contains(got, step.want) {
+			t.Errorf("%s: quit = %v, output:\n%s\nwant %q", step.line, quit, got, step.want)
+		}
+	}
+
+	if len(searches) != 3 {
+		t.Fatalf("%d searches", len(searches))
+	}
+	// Settings stick until changed; rejected values leave them as they were.
+	first := searches[0]
+	if first.Q != "golang news" || first.Depth != linkup.DepthDeep || first.OutputType != linkup.OutputSearchResults ||
+		!slices.Equal(first.IncludeDomains, []string{"a.com", "b.com", "c.com"}) || !slices.Equal(first.ExcludeDomains, []string{"d.com"}) ||
+		first.FromDate != "2025-01-01" || !first.IncludeImages || first.IncludeInlineCitations || first.StructuredOutputSchema != nil {
+		t.Errorf("first search = %+v", first)
+	}
+	if second := searches[1]; second.Q != "why" || second.OutputType != linkup.OutputSourcedAnswer || second.Depth != linkup.DepthDeep {
+		t.Errorf("second search = %+v", second)
+	}
+	// :save writes the last response, here the fetch.
+	if b, err := os.ReadFile(saved); err != nil || !strings.Contains(string(b), `"markdown": "page https://b.example/2 render=true"`) {
+		t.Errorf("saved %s, %v", b, err)
+	}
+	if got, want := s.prompt(), "linkup [deep sourcedAnswer · 1.500 cr]> "; got != want {
+		t.Errorf("prompt = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/crawl"
+	"github.com/raezil/linkup-go/linkup/sitemap"
+)
+
+func cmdFetchSitemap(args []string) {
+	fs := flag.NewFlagSet("fetch-sitemap", flag.ExitOnError)
+	since := fs.String("since", "", "only pages with a lastmod at or after this date (YYYY-MM-DD or RFC 3339)")
+	undated := fs.Bool("undated", false, "with -since: also fetch pages that have no lastmod")
+	list := fs.Bool("list", false, "print the matching URLs and the estimated cost instead of fetching")
+	limit := fs.Int("max", 0, "fetch at most this many pages, newest first (0 = all)")
+	delay := fs.Duration("delay", time.Second, "minimum time between requests to one host")
+	concurrency := fs.Int("concurrency", 4, "hosts fetched in parallel")
+	outDir := fs.String("o", "", "write pages as markdown files under this directory")
+	jsonlPath := fs.String("jsonl", "", "append pages as JSON lines to this file")
+	statePath := fs.String("state", "", "journal making the run resumable (off by default, since pages change between runs)")
+	rawHTML := fs.Bool("raw-html", false, "also fetch raw HTML")
+	renderJS := fs.Bool("render-js", false, "render JavaScript before extracting")
+	ignoreRobots := fs.Bool("ignore-robots", false, "do not read robots.txt")
+	quiet := fs.Bool("quiet", false, "do not log each page")
+	common := addCommonFlags(fs, 0, "")
+	rest := parseInterleaved(fs, args)
+	st, err := common.resolve()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(2)
+	}
+
+	if len(rest) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: linkup fetch-sitemap [flags] SITEMAP-or-SITE-URL")
+		os.Exit(2)
+	}
+	var opts sitemap.Options
+	if *since != "" {
+		if opts.Since, err = sitemap.ParseLastMod(*since); err != nil {
+			fmt.Fprintln(os.Stderr, "-since:", err)
+			os.Exit(2)
+		}
+		opts.IncludeUndated = *undated
+	}
+	if !*list && (*outDir == "") == (*jsonlPath == "") {
+		fmt.Fprintln(os.Stderr, "pass exactly one of -o dir or -jsonl file (or -list)")
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	f := &sitemap.Fetcher{UserAgent: st.UserAgent, Logf: logStderr}
+	maps := []string{rest[0]}
+	if !isSitemapURL(rest[0]) {
+		if maps, err = f.Discover(ctx, rest[0]); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+	}
+	var entries []sitemap.Entry
+	seen := map[string]bool{}
+	for _, m := range maps {
+		es, err := f.Fetch(ctx, m, opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		for _, e := range es {
+			if u, err := url.Parse(e.Loc); err != nil || (u.Scheme != "http" && u.Scheme != "https") || seen[e.Loc] {
+				continue
+			}
+			seen[e.Loc] = true
+			entries = append(entries, e)
+		}
+	}
+	if *limit > 0 && len(entries) > *limit {
+		entries = entries[:*limit]
+	}
+	cost := float64(len(entries)) * linkup.FetchCost(linkup.FetchRequest{RenderJS: *renderJS})
+
+	if *list {
+		for _, e := range entries {
+			lastmod := "-"
+			if !e.LastMod.IsZero() {
+				lastmod = e.LastMod.UTC().Format(time.DateOnly)
+			}
+			fmt.Printf("%s\t%s\n", lastmod, e.Loc)
+		}
+		logStderr("%d pages; fetching them costs ~%.3f credits", len(entries), cost)
+		return
+	}
+	if len(entries) == 0 {
+		logStderr("no pages match")
+		return
+	}
+	logStderr("fetching %d pages (~%.3f credits)", len(entries), cost)
+
+	cfg := crawl.Config{
+		NoFollow:       true,
+		MaxPages:       math.MaxInt, // the seeds bound the run
+		Delay:          *delay,
+		Concurrency:    *concurrency,
+		IncludeRawHTML: *rawHTML,
+		RenderJS:       *renderJS,
+		IgnoreRobots:   *ignoreRobots,
+		UserAgent:      st.UserAgent,
+		StatePath:      *statePath,
+	}
+	for _, e := range entries {
+		cfg.Seeds = append(cfg.Seeds, e.Loc)
+	}
+	cfg.Sink, _ = openCrawlSink(*outDir, *jsonlPath)
+	if !*quiet {
+		cfg.Logf = logStderr
+	}
+	stop()
+	runCrawl(st, cfg)
+}
+
+// isSitemapURL tells a sitemap from a page of the site to discover
+// sitemaps for.
+func isSitemapURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	p := strings.ToLower(u.Path)
+	p = strings.TrimSuffix(p, ".gz")
+	switch path.Ext(p) {
+	case ".xml", ".txt":
+		return true
+	}
+	return strings.Contains(path.Base(p), "sitemap")
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package main
+
+import "errors"
+
+func makeRaw(fd int) (func(), error) { return nil, errors.New("raw mode not supported") }
+
+func isTerminal(fd int) bool { return false }
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/canonical"
+)
+
+// watchState is what `linkup watch` remembers between runs.
+type watchState struct {
+	Query   string               `json:"query"`
+	LastRun time.Time            `json:"lastRun"`
+	Seen    map[string]time.Time `json:"seen"` // canonical URL -> first seen
+	Answer  string               `json:"answer,omitempty"`
+}
+
+// watchEvent describes what changed since the previous run.
+type watchEvent struct {
+	Query          string                `json:"query"`
+	Time           time.Time             `json:"time"`
+	Since          time.Time             `json:"since"`
+	NewResults     []linkup.SearchResult `json:"newResults,omitempty"`
+	NewSources     []linkup.AnswerSource `json:"newSources,omitempty"`
+	AnswerChanged  bool                  `json:"answerChanged,omitempty"`
+	PreviousAnswer string                `json:"previousAnswer,omitempty"`
+	Answer         string                `json:"answer,omitempty"`
+}
+
+func (e *watchEvent) empty() bool {
+	return len(e.NewResults) == 0 && len(e.NewSources) == 0 && !e.AnswerChanged
+}
+
+func cmdWatch(args []string) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	q := fs.String("q", "", "query text")
+	every := fs.Duration("every", time.Hour, "interval between runs")
+	once := fs.Bool("once", false, "run a single check and exit")
+	depth := fs.String("depth", "", "depth: standard|deep (default standard)")
+	out := fs.String("output", "", "output: searchResults|sourcedAnswer (default searchResults)")
+	include := fs.String("include", "", "comma-separated include domains, @group or @file")
+	exclude := fs.String("exclude", "", "comma-separated exclude domains, @group or @file")
+	stateFile := fs.String("state", "", "state file (default: per-query file in the config directory)")
+	jsonlPath := fs.String("jsonl", "", "append change events to this JSONL file")
+	webhook := fs.String("webhook", "", "POST change events as JSON to this URL")
+	quiet := fs.Bool("quiet", false, "do not print change events to stdout")
+	common := addCommonFlags(fs, 60*time.Second, "text")
+	fs.Parse(args)
+	st, _ := common.mustResolve()
+	st.applySearchFlags(fs, *depth, *out, *include, *exclude)
+
+	if *q == "" {
+		fmt.Fprintln(os.Stderr, "missing -q")
+		os.Exit(2)
+	}
+	if st.Format != "text" && st.Format != "json" && st.Format != "jsonl" {
+		fmt.Fprintln(os.Stderr, "watch supports -format text, json or jsonl")
+		os.Exit(2)
+	}
+	if *every <= 0 {
+		fmt.Fprintln(os.Stderr, "-every must be positive")
+		os.Exit(2)
+	}
+	req := linkup.SearchRequest{
+		Q:              *q,
+		Depth:          linkup.Depth(st.Depth),
+		OutputType:     linkup.OutputType(st.Output),
+		IncludeDomains: st.Include,
+		ExcludeDomains: st.Exclude,
+		IncludeSources: st.Output == string(linkup.OutputSourcedAnswer),
+	}
+	if req.OutputType != linkup.OutputSearchResults && req.OutputType != linkup.OutputSourcedAnswer {
+		fmt.Fprintln(os.Stderr, "watch supports -output searchResults or sourcedAnswer")
+		os.Exit(2)
+	}
+	if *stateFile == "" {
+		*stateFile = defaultWatchStateFile(req)
+	}
+
+	w := &watcher{
+		client:    st.client(),
+		req:       req,
+		timeout:   st.Timeout,
+		stateFile: *stateFile,
+		jsonlPath: *jsonlPath,
+		webhook:   *webhook,
+		http:      &http.Client{Timeout: 15 * time.Second},
+	}
+	if !*quiet {
+		w.stdout = os.Stdout
+		w.jsonOut = st.Format != "text"
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	for {
+		if err := w.check(ctx); err != nil && ctx.Err() == nil {
+			fmt.Fprintln(os.Stderr, "watch:", err)
+		}
+		if *once {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(*every):
+		}
+	}
+}
+
+// defaultWatchStateFile derives a state file from the request, so that
+// different queries (or filters) never share state.
+func defaultWatchStateFile(req linkup.SearchRequest) string {
+	key, _ := json.Marshal(req)
+	sum := sha256.Sum256(key)
+	name := hex.EncodeToString(sum[:8]) + ".json"
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "linkup-watch-" + name
+	}
+	return filepath.Join(dir, "linkup", "watch", name)
+}
+
+type watcher struct {
+	client    *linkup.Client
+	req       linkup.SearchRequest
+	timeout   time.Duration
+	stateFile string
+	jsonlPath string
+	webhook   string
+	http      *http.Client
+	stdout    io.Writer
+	jsonOut   bool
+}
+
+// check runs the search once, compares it with the saved state, saves the
+// new state and emits any changes. The state is saved first so that a
+// failing sink (e.g. a webhook that is down) does not get the same event
+// again on every run.
+func (w *watcher) check(ctx context.Context) error {
+	prev, err := loadWatchState(w.stateFile)
+	if err != nil {
+		return err
+	}
+	req := w.req
+	if prev != nil && req.FromDate == "" && req.OutputType == linkup.OutputSearchResults {
+		// Day granularity: re-read the day of the last run, the seen set
+		// filters what was already reported. Answers are not narrowed: a
+		// narrower request would change the answer it is compared with.
+		req.FromDate = prev.LastRun.Format("2006-01-02")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+	resp, err := w.client.Search(ctx, req)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	next, ev, err := diffWatch(prev, w.req.Q, w.req.OutputType, resp, now)
+	if err != nil {
+		return err
+	}
+	if err := saveWatchState(w.stateFile, next); err != nil {
+		return err
+	}
+	if prev == nil {
+		fmt.Fprintf(os.Stderr, "watch: baseline recorded (%d URLs), reporting changes from now on\n", len(next.Seen))
+		return nil
+	}
+	if ev.empty() {
+		return nil
+	}
+	return w.emit(ctx, ev)
+}
+
+// diffWatch compares resp with prev and returns the next state and the
+// changes. prev may be nil for the first run.
+func diffWatch(prev *watchState, q string, ot linkup.OutputType, resp linkup.SearchResponse, now time.Time) (*watchState, *watchEvent, error) {
+	next := &watchState{Query: q, LastRun: now, Seen: map[string]time.Time{}}
+	ev := &watchEvent{Query: q, Time: now}
+	if prev != nil {
+		for k, t := range prev.Seen {
+			next.Seen[k] = t
+		}
+		next.Answer = prev.Answer
+		ev.Since = prev.LastRun
+	}
+	seen := func(u string) bool {
+		k := canonical.Key(u)
+		if _, ok := next.Seen[k]; ok {
+			return true
+		}
+		next.Seen[k] = now
+		return false
+	}
+
+	switch ot {
+	case linkup.OutputSourcedAnswer:
+		ans, err := resp.SourcedAnswer()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, src := range ans.Sources {
+			if !seen(src.URL) {
+				ev.NewSources = append(ev.NewSources, src)
+			}
+		}
+		if prev != nil && normalizeSpace(prev.Answer) != normalizeSpace(ans.Answer) {
+			ev.AnswerChanged = true
+			ev.PreviousAnswer = prev.Answer
+			ev.Answer = ans.Answer
+		}
+		next.Answer = ans.Answer
+	default:
+		results, err := resp.Results()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, r := range linkup.DedupeResults(results) {
+			if !seen(r.URL) {
+				ev.NewResults = append(ev.NewResults, r)
+			}
+		}
+	}
+	return next, ev, nil
+}
+
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func (w *watcher) emit(ctx context.Context, ev *watchEvent) error {
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	if w.stdout != nil {
+		if w.jsonOut {
+			fmt.Fprintln(w.stdout, string(line))
+		} else {
+			printWatchEvent(w.stdout, ev)
+		}
+	}
+	if w.jsonlPath != "" {
+		if err := appendLine(w.jsonlPath, line); err != nil {
+			errs = append(errs, fmt.Errorf("jsonl: %w", err))
+		}
+	}
+	if w.webhook != "" {
+		if err := w.post(ctx, line); err != nil {
+			errs = append(errs, fmt.Errorf("webhook: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (w *watcher) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("http %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func printWatchEvent(out io.Writer, ev *watchEvent) {
+	fmt.Fprintf(out, "== %s — %s\n", ev.Time.Local().Format("2006-01-02 15:04"), ev.Query)
+	for _, r := range ev.NewResults {
+		fmt.Fprintf(out, "+ %s\n  %s\n", oneLine(r.Name), r.URL)
+	}
+	if ev.AnswerChanged {
+		fmt.Fprintf(out, "answer changed:\n%s\n", ev.Answer)
+	}
+	for _, s := range ev.NewSources {
+		fmt.Fprintf(out, "+ source: %s\n  %s\n", oneLine(s.Label()), s.URL)
+	}
+}
+
+func appendLine(path string, line []byte) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func loadWatchState(path string) (*watchState, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var st watchState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if st.Seen == nil {
+		st.Seen = map[string]time.Time{}
+	}
+	return &st, nil
+}
+
+// saveWatchState writes the state atomically.
+func saveWatchState(path string, st *watchState) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/canonical"
+)
+
+func TestDiffWatch(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Hour)
+	results := func(urls ...string) string {
+		var rs []linkup.SearchResult
+		for _, u := range urls {
+			rs = append(rs, linkup.SearchResult{Type: "text", Name: u, URL: u})
+		}
+		b, _ := json.Marshal(linkup.SearchResults{Results: rs})
+		return string(b)
+	}
+	answer := func(text string, urls ...string) string {
+		a := linkup.SourcedAnswer{Answer: text}
+		for _, u := range urls {
+			a.Sources = append(a.Sources, linkup.AnswerSource{Name: u, URL: u})
+		}
+		b, _ := json.Marshal(a)
+		return string(b)
+	}
+	state := func(answer string, urls ...string) *watchState {
+		st := &watchState{Query: "q", LastRun: t0, Seen: map[string]time.Time{}, Answer: answer}
+		for _, u := range urls {
+			st.Seen[canonical.Key(u)] = t0
+		}
+		return st
+	}
+
+	for _, tc := range []struct {
+		name    string
+		prev    *watchState
+		ot      linkup.OutputType
+		resp    string
+		newURLs string // NewResults or NewSources
+		changed bool
+		seen    int
+	}{
+		{"baseline", nil, linkup.OutputSearchResults, results("https://a.com/1", "https://b.com/2"), "https://a.com/1 https://b.com/2", false, 2},
+		{"unchanged", state("", "https://a.com/1"), linkup.OutputSearchResults, results("https://a.com/1"), "", false, 1},
+		{"new URL", state("", "https://a.com/1"), linkup.OutputSearchResults, results("https://a.com/1", "https://c.com/3"), "https://c.com/3", false, 2},
+		{"same page, other URL form", state("", "https://a.com/1"), linkup.OutputSearchResults, results("https://www.a.com/1/?utm_source=x"), "", false, 1},
+		// A URL that drops out is remembered, so it is not new when it returns.
+		{"removed URL", state("", "https://a.com/1", "https://b.com/2"), linkup.OutputSearchResults, results("https://b.com/2"), "", false, 2},
+		{"answer changed", state("Old answer.", "https://a.com/1"), linkup.OutputSourcedAnswer, answer("New answer.", "https://a.com/1"), "", true, 1},
+		{"answer whitespace only", state("Same  answer.", "https://a.com/1"), linkup.OutputSourcedAnswer, answer("Same answer.\n", "https://a.com/1"), "", false, 1},
+		{"new source", state("A.", "https://a.com/1"), linkup.OutputSourcedAnswer, answer("A.", "https://a.com/1", "https://d.com/4"), "https://d.com/4", false, 2},
+		{"answer baseline", nil, linkup.OutputSourcedAnswer, answer("A.", "https://a.com/1"), "https://a.com/1", false, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			next, ev, err := diffWatch(tc.prev, "q", tc.ot, linkup.SearchResponse{Raw: []byte(tc.resp)}, t1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var urls []string
+			for _, r := range ev.NewResults {
+				urls = append(urls, r.URL)
+			}
+			for _, s := range ev.NewSources {
+				urls = append(urls, s.URL)
+			}
+			if got := strings.Join(urls, " "); got != tc.newURLs {
+				t.Errorf("new = %q, want %q", got, tc.newURLs)
+			}
+			if ev.AnswerChanged != tc.changed {
+				t.Errorf("AnswerChanged = %v", ev.AnswerChanged)
+			}
+			if len(next.Seen) != tc.seen || !next.LastRun.Equal(t1) {
+				t.Errorf("next = %+v", next)
+			}
+			if tc.prev != nil && !ev.Since.Equal(t0) {
+				t.Errorf("Since = %v", ev.Since)
+			}
+		})
+	}
+}
+
+func TestWatchCheck(t *testing.T) {
+	var fromDates []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req linkup.SearchRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		fromDates = append(fromDates, req.FromDate)
+		n := min(len(fromDates), 2) // unchanged after the second run
+		fmt.Fprintf(w, `{"answer":"A %d.","sources":[{"url":"https://a.com/%d"}]}`, n, n)
+	}))
+	defer api.Close()
+	var posts int
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer hook.Close()
+
+	w := &watcher{
+		client:    linkup.NewClient("k", linkup.WithBaseURL(api.URL), linkup.WithRetry(0, time.Millisecond, time.Millisecond)),
+		req:       linkup.SearchRequest{Q: "q", OutputType: linkup.OutputSourcedAnswer, IncludeSources: true},
+		timeout:   5 * time.Second,
+		stateFile: filepath.Join(t.TempDir(), "state.json"),
+		webhook:   hook.URL,
+		http:      hook.Client(),
+	}
+	ctx := context.Background()
+	if err := w.check(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// The webhook fails, but the state is saved: the event is not sent twice.
+	if err := w.check(ctx); err == nil || !strings.Contains(err.Error(), "webhook") {
+		t.Fatalf("err = %v", err)
+	}
+	st, err := loadWatchState(w.stateFile)
+	if err != nil || st.Answer != "A 2." || len(st.Seen) != 2 {
+		t.Fatalf("state = %+v, %v", st, err)
+	}
+	if err := w.check(ctx); err != nil || posts != 1 {
+		t.Fatalf("%d posts, err = %v", posts, err)
+	}
+	// Answers are compared with the same request every run.
+	if strings.Join(fromDates, ",") != ",," {
+		t.Fatalf("fromDate = %q", fromDates)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// config is the gateway's JSON configuration file.
+//
+//	{
+//	  "listen": "127.0.0.1:8080",
+//	  "usageLog": "/var/log/linkup-gateway/usage.jsonl",
+//	  "cache": {"ttl": "10m", "maxEntries": 2000},
+//	  "clients": [
+//	    {"name": "search-svc", "tokenSha256": "…", "ratePerMinute": 60, "burst": 10,
+//	     "creditQuota": 20, "quotaPeriod": "month"}
+//	  ]
+//	}
+type config struct {
+	Listen   string         `json:"listen"`
+	UsageLog string         `json:"usageLog"`
+	Cache    cacheConfig    `json:"cache"`
+	Clients  []clientConfig `json:"clients"`
+}
+
+type cacheConfig struct {
+	TTL        duration `json:"ttl"`
+	MaxEntries int      `json:"maxEntries"`
+}
+
+type clientConfig struct {
+	Name string `json:"name"`
+	// Token is the caller's bearer token in clear text. Prefer TokenSHA256
+	// (hex sha256 of the token) so the config file holds no secrets.
+	Token       string `json:"token,omitempty"`
+	TokenSHA256 string `json:"tokenSha256,omitempty"`
+	// RatePerMinute and Burst configure a token bucket; 0 disables limiting.
+	RatePerMinute float64 `json:"ratePerMinute,omitempty"`
+	Burst         int     `json:"burst,omitempty"`
+	// CreditQuota caps estimated credits spent per QuotaPeriod ("day" or
+	// "month", calendar UTC); 0 means unlimited.
+	CreditQuota float64 `json:"creditQuota,omitempty"`
+	QuotaPeriod string  `json:"quotaPeriod,omitempty"`
+	// AllowBalance lets a client without a quota read the account balance
+	// on /credits/balance; others get 403.
+	AllowBalance bool `json:"allowBalance,omitempty"`
+}
+
+// duration unmarshals from a Go duration string such as "10m".
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+func loadConfig(path string) (*config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &config{}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if len(cfg.Clients) == 0 {
+		return nil, errors.New("config: no clients defined")
+	}
+	seen := map[string]bool{}
+	for i := range cfg.Clients {
+		c := &cfg.Clients[i]
+		if c.Name == "" {
+			return nil, fmt.Errorf("config: client %d has no name", i)
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("config: duplicate client %q", c.Name)
+		}
+		seen[c.Name] = true
+		if c.TokenSHA256 == "" {
+			if c.Token == "" {
+				return nil, fmt.Errorf("config: client %q has no token", c.Name)
+			}
+			c.TokenSHA256 = hashToken(c.Token)
+		}
+		c.TokenSHA256 = strings.ToLower(c.TokenSHA256)
+		switch c.QuotaPeriod {
+		case "":
+			c.QuotaPeriod = "month"
+		case "day", "month":
+		default:
+			return nil, fmt.Errorf("config: client %q: quotaPeriod must be day or month", c.Name)
+		}
+	}
+	return cfg, nil
+}
+
+func hashToken(tok string) string {
+	sum := sha256.Sum256([]byte(tok))
+	return hex.EncodeToString(sum[:])
+}
+
+// periodStart returns the start of the quota period containing t.
+func periodStart(period string, t time.Time) time.Time {
+	t = t.UTC()
+	if period == "day" {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+)
+
+// caller is an authenticated gateway client.
+type caller struct {
+	cfg    clientConfig
+	bucket *bucket
+	quota  *quota
+}
+
+type gateway struct {
+	client  *linkup.Client
+	callers map[string]*caller // keyed by token sha256
+	byName  map[string]*caller
+	cache   *cache
+	usage   *usageLog
+	timeout time.Duration
+	now     func() time.Time
+}
+
+func newGateway(cfg *config, client *linkup.Client, usage *usageLog, timeout time.Duration) *gateway {
+	g := &gateway{
+		client:  client,
+		callers: make(map[string]*caller),
+		byName:  make(map[string]*caller),
+		cache:   newCache(time.Duration(cfg.Cache.TTL), cfg.Cache.MaxEntries),
+		usage:   usage,
+		timeout: timeout,
+		now:     time.Now,
+	}
+	for _, cc := range cfg.Clients {
+		c := &caller{
+			cfg:    cc,
+			bucket: newBucket(cc.RatePerMinute, cc.Burst),
+			quota:  &quota{limit: cc.CreditQuota, period: cc.QuotaPeriod},
+		}
+		g.callers[cc.TokenSHA256] = c
+		g.byName[cc.Name] = c
+	}
+	return g
+}
+
+func (g *gateway) routes() http.Handler {
+	mux := http.NewServeMux()
+	for _, prefix := range []string{"", "/v1"} {
+		mux.HandleFunc("POST "+prefix+"/search", g.handleSearch)
+		mux.HandleFunc("POST "+prefix+"/fetch", g.handleFetch)
+		mux.HandleFunc("GET "+prefix+"/credits/balance", g.handleBalance)
+	}
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok\n")) })
+	return mux
+}
+
+// authenticate resolves the bearer token and applies the rate limit.
+func (g *gateway) authenticate(w http.ResponseWriter, r *http.Request) *caller {
+	tok, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	c := g.callers[hashToken(strings.TrimSpace(tok))]
+	if !ok || c == nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return nil
+	}
+	if ok, wait := c.bucket.allow(g.now()); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+		g.log(c, r, http.StatusTooManyRequests, false, 0, 0, "")
+		return nil
+	}
+	return c
+}
+
+func (g *gateway) handleSearch(w http.ResponseWriter, r *http.Request) {
+	c := g.authenticate(w, r)
+	if c == nil {
+		return
+	}
+	var req linkup.SearchRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	g.proxy(w, r, c, "search", req, linkup.SearchCost(req), req.Q, func(ctx context.Context) ([]byte, error) {
+		resp, err := g.client.Search(ctx, req)
+		return resp.RawJSON(), err
+	})
+}
+
+func (g *gateway) handleFetch(w http.ResponseWriter, r *http.Request) {
+	c := g.authenticate(w, r)
+	if c == nil {
+		return
+	}
+	var req linkup.FetchRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	g.proxy(w, r, c, "fetch", req, linkup.FetchCost(req), req.URL, func(ctx context.Context) ([]byte, error) {
+		resp, err := g.client.Fetch(ctx, req)
+		return resp.RawJSON(), err
+	})
+}
+
+// handleBalance reports the caller's remaining quota when it has one, and
+// otherwise the account balance to callers with AllowBalance.
+func (g *gateway) handleBalance(w http.ResponseWriter, r *http.Request) {
+	c := g.authenticate(w, r)
+	if c == nil {
+		return
+	}
+	start := g.now()
+	if c.quota.limit > 0 {
+		spent, _ := c.quota.snapshot(start)
+		writeJSON(w, http.StatusOK, linkup.BalanceResponse{Balance: math.Max(0, c.quota.limit-spent)})
+		g.log(c, r, http.StatusOK, false, 0, 0, "")
+		return
+	}
+	if !c.cfg.AllowBalance {
+		writeError(w, http.StatusForbidden, "balance not allowed for this client")
+		g.log(c, r, http.StatusForbidden, false, 0, 0, "")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), g.timeout)
+	defer cancel()
+	bal, err := g.client.GetBalance(ctx)
+	if err != nil {
+		status := g.writeUpstreamError(w, err)
+		g.log(c, r, status, false, 0, time.Since(start), "")
+		return
+	}
+	writeJSON(w, http.StatusOK, bal)
+	g.log(c, r, http.StatusOK, false, 0, time.Since(start), "")
+}
+
+// proxy serves a cacheable, billable call: cache lookup, quota reservation,
+// upstream call through the shared Client, then logging.
+func (g *gateway) proxy(w http.ResponseWriter, r *http.Request, c *caller, endpoint string, req any, cost float64, subject string, call func(context.Context) ([]byte, error)) {
+	start := g.now()
+	key := cacheKey(endpoint, req)
+	if body, ok := g.cache.get(key, start); ok {
+		w.Header().Set("X-Cache", "HIT")
+		writeRaw(w, body)
+		g.log(c, r, http.StatusOK, true, 0, time.Since(start), subject)
+		return
+	}
+	if !c.quota.reserve(start, cost) {
+		writeError(w, http.StatusPaymentRequired, "credit quota exhausted for this period")
+		g.log(c, r, http.StatusPaymentRequired, false, 0, 0, subject)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), g.timeout)
+	defer cancel()
+	body, err := call(ctx)
+	if err != nil {
+		c.quota.refund(cost)
+		status := g.writeUpstreamError(w, err)
+		g.log(c, r, status, false, 0, time.Since(start), subject)
+		return
+	}
+	g.cache.put(key, body, g.now())
+	w.Header().Set("X-Cache", "MISS")
+	writeRaw(w, body)
+	g.log(c, r, http.StatusOK, false, cost, time.Since(start), subject)
+}
+
+// writeUpstreamError maps Client errors onto gateway responses.
+func (g *gateway) writeUpstreamError(w http.ResponseWriter, err error) int {
+	var apiErr *linkup.APIError
+	switch {
+	case errors.As(err, &apiErr):
+		writeJSON(w, apiErr.Status, apiErr)
+		return apiErr.Status
+	case errors.Is(err, linkup.ErrUnauthorized), errors.Is(err, linkup.ErrForbidden):
+		log.Printf("upstream rejected gateway credentials: %v", err)
+		writeError(w, http.StatusBadGateway, "upstream rejected gateway credentials")
+		return http.StatusBadGateway
+	case errors.Is(err, context.DeadlineExceeded):
+		writeError(w, http.StatusGatewayTimeout, "upstream timeout")
+		return http.StatusGatewayTimeout
+	default:
+		log.Printf("upstream: %v", err)
+		writeError(w, http.StatusBadGateway, "upstream error")
+		return http.StatusBadGateway
+	}
+}
+
+func (g *gateway) log(c *caller, r *http.Request, status int, cached bool, cost float64, d time.Duration, subject string) {
+	g.usage.write(usageRecord{
+		Time:       g.now().UTC(),
+		Client:     c.cfg.Name,
+		Endpoint:   strings.TrimPrefix(r.URL.Path, "/v1"),
+		Status:     status,
+		Cached:     cached,
+		Cost:       cost,
+		DurationMS: d.Milliseconds(),
+		Subject:    subject,
+	})
+}
+
+func cacheKey(endpoint string, req any) string {
+	b, _ := json.Marshal(req)
+	sum := sha256.Sum256(append([]byte(endpoint+"\n"), b...))
+	return hex.EncodeToString(sum[:])
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeRaw(w http.ResponseWriter, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, linkup.APIError{Status: status, Message: msg})
+}
+
+// usageRecord is one line of the usage log.
+type usageRecord struct {
+	Time       time.Time `json:"time"`
+	Client     string    `json:"client"`
+	Endpoint   string    `json:"endpoint"`
+	Status     int       `json:"status"`
+	Cached     bool      `json:"cached,omitempty"`
+	Cost       float64   `json:"cost"`
+	DurationMS int64     `json:"durationMs"`
+	Subject    string    `json:"subject,omitempty"` // query or URL
+}
+
+// usageLog appends usage records as JSON lines. A nil *usageLog writes to
+// the standard logger only.
+type usageLog struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+func openUsageLog(path string) (*usageLog, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &usageLog{f: f}, nil
+}
+
+func (u *usageLog) write(rec usageRecord) {
+	log.Printf("%s %s %d cached=%t cost=%.4f %dms", rec.Client, rec.Endpoint, rec.Status, rec.Cached, rec.Cost, rec.DurationMS)
+	if u == nil {
+		return
+	}
+	b, _ := json.Marshal(rec)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if _, err := u.f.Write(append(b, '\n')); err != nil {
+		log.Printf("usage log: %v", err)
+	}
+}
+
+// replay restores quota spending recorded in the current periods so a
+// restart does not reset client quotas.
+func (u *usageLog) replay(g *gateway) error {
+	if u == nil {
+		return nil
+	}
+	if _, err := u.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	sc := bufio.NewScanner(u.f)
+	for sc.Scan() {
+		var rec usageRecord
+		if json.Unmarshal(sc.Bytes(), &rec) != nil || rec.Cost == 0 {
+			continue
+		}
+		if c := g.byName[rec.Client]; c != nil {
+			c.quota.add(rec.Time, rec.Cost)
+		}
+	}
+	_, err := u.f.Seek(0, io.SeekEnd)
+	if err == nil {
+		err = sc.Err()
+	}
+	return err
+}
+
+func (u *usageLog) Close() error {
+	if u == nil {
+		return nil
+	}
+	return u.f.Close()
+}
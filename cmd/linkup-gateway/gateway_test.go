@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+)
+
+// testGateway starts a fake upstream and a gateway in front of it. The
+// upstream fails searches for the query "fail".
+func testGateway(t *testing.T, clients ...clientConfig) (*gateway, *httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.Header.Get("Authorization") != "Bearer real-key" {
+			t.Errorf("upstream Authorization = %q", r.Header.Get("Authorization"))
+		}
+		switch r.URL.Path {
+		case "/credits/balance":
+			fmt.Fprint(w, `{"balance": 42.5}`)
+		case "/search":
+			var req linkup.SearchRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.Q == "fail" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"message":"bad query"}`)
+				return
+			}
+			fmt.Fprintf(w, `{"results":[{"url":"https://example.com/%s"}]}`, req.Q)
+		default:
+			fmt.Fprint(w, `{"markdown":"page"}`)
+		}
+	}))
+	t.Cleanup(upstream.Close)
+
+	for i := range clients {
+		clients[i].TokenSHA256 = hashToken(clients[i].Token)
+		if clients[i].QuotaPeriod == "" {
+			clients[i].QuotaPeriod = "month"
+		}
+	}
+	cfg := &config{Cache: cacheConfig{TTL: duration(time.Minute)}, Clients: clients}
+	client := linkup.NewClient("real-key", linkup.WithBaseURL(upstream.URL), linkup.WithRetry(0, time.Millisecond, time.Millisecond))
+	g := newGateway(cfg, client, nil, 5*time.Second)
+	srv := httptest.NewServer(g.routes())
+	t.Cleanup(srv.Close)
+	return g, srv, &calls
+}
+
+func call(t *testing.T, srv *httptest.Server, method, path, token, body string) (*http.Response, string) {
+	t.Helper()
+	req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(b)
+}
+
+func TestAuth(t *testing.T) {
+	_, srv, calls := testGateway(t, clientConfig{Name: "svc", Token: "secret"})
+	for _, tok := range []string{"", "wrong"} {
+		if resp, _ := call(t, srv, "POST", "/search", tok, `{"q":"go"}`); resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("token %q: status %d", tok, resp.StatusCode)
+		}
+	}
+	if calls.Load() != 0 {
+		t.Fatal("unauthenticated requests reached upstream")
+	}
+	resp, body := call(t, srv, "POST", "/v1/search", "secret", `{"q":"go"}`)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "example.com/go") {
+		t.Fatalf("status %d: %s", resp.StatusCode, body)
+	}
+	if resp, _ := call(t, srv, "POST", "/search", "secret", `{"q":"go","bogus":1}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown field: status %d", resp.StatusCode)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	g, srv, _ := testGateway(t, clientConfig{Name: "svc", Token: "secret", RatePerMinute: 60, Burst: 2})
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	g.now = func() time.Time { return now }
+	for i := range 2 {
+		if resp, _ := call(t, srv, "POST", "/fetch", "secret", `{"url":"https://a.com"}`); resp.StatusCode != http.StatusOK {
+			t.Fatalf("request %d: status %d", i, resp.StatusCode)
+		}
+	}
+	resp, _ := call(t, srv, "POST", "/fetch", "secret", `{"url":"https://a.com"}`)
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "1" {
+		t.Fatalf("status %d, Retry-After %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	now = now.Add(time.Second) // one token refilled
+	if resp, _ := call(t, srv, "POST", "/fetch", "secret", `{"url":"https://a.com"}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("after refill: status %d", resp.StatusCode)
+	}
+}
+
+func TestQuota(t *testing.T) {
+	// Room for two standard searches (0.005 each), not three.
+	_, srv, calls := testGateway(t, clientConfig{Name: "svc", Token: "secret", CreditQuota: 0.012})
+
+	// A failed upstream call is refunded.
+	if resp, body := call(t, srv, "POST", "/search", "secret", `{"q":"fail"}`); resp.StatusCode != http.StatusBadRequest || !strings.Contains(body, "bad query") {
+		t.Fatalf("fail: status %d: %s", resp.StatusCode, body)
+	}
+	for _, q := range []string{"a", "b"} {
+		if resp, _ := call(t, srv, "POST", "/search", "secret", `{"q":"`+q+`"}`); resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: status %d", q, resp.StatusCode)
+		}
+	}
+	if resp, _ := call(t, srv, "POST", "/search", "secret", `{"q":"c"}`); resp.StatusCode != http.StatusPaymentRequired {
+		t.Fatalf("over quota: status %d", resp.StatusCode)
+	}
+	// Cached responses cost nothing, so they are served past the quota.
+	if resp, _ := call(t, srv, "POST", "/search", "secret", `{"q":"a"}`); resp.StatusCode != http.StatusOK || resp.Header.Get("X-Cache") != "HIT" {
+		t.Fatalf("cached: status %d, X-Cache %q", resp.StatusCode, resp.Header.Get("X-Cache"))
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("%d upstream calls, want 3", calls.Load())
+	}
+	_, body := call(t, srv, "GET", "/credits/balance", "secret", "")
+	var bal linkup.BalanceResponse
+	json.Unmarshal([]byte(body), &bal)
+	if bal.Balance < 0.0019 || bal.Balance > 0.0021 {
+		t.Fatalf("remaining quota = %v, want 0.002", bal.Balance)
+	}
+	if calls.Load() != 3 {
+		t.Fatal("a quota balance must not call upstream")
+	}
+}
+
+func TestCache(t *testing.T) {
+	g, srv, calls := testGateway(t, clientConfig{Name: "svc", Token: "secret"}, clientConfig{Name: "other", Token: "other"})
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	g.now = func() time.Time { return now }
+
+	resp, first := call(t, srv, "POST", "/search", "secret", `{"q":"go"}`)
+	if resp.Header.Get("X-Cache") != "MISS" {
+		t.Fatalf("first X-Cache = %q", resp.Header.Get("X-Cache"))
+	}
+	// Shared across clients and across the /v1 prefix.
+	resp, second := call(t, srv, "POST", "/v1/search", "other", `{"q":"go"}`)
+	if resp.Header.Get("X-Cache") != "HIT" || second != first {
+		t.Fatalf("second X-Cache = %q", resp.Header.Get("X-Cache"))
+	}
+	if resp, _ := call(t, srv, "POST", "/search", "secret", `{"q":"go","depth":"deep"}`); resp.Header.Get("X-Cache") != "MISS" {
+		t.Fatal("a different request must not hit the cache")
+	}
+	now = now.Add(2 * time.Minute)
+	if resp, _ := call(t, srv, "POST", "/search", "secret", `{"q":"go"}`); resp.Header.Get("X-Cache") != "MISS" {
+		t.Fatal("expired entry served")
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("%d upstream calls, want 3", calls.Load())
+	}
+}
+
+func TestBalance(t *testing.T) {
+	_, srv, calls := testGateway(t,
+		clientConfig{Name: "svc", Token: "secret"},
+		clientConfig{Name: "admin", Token: "admin", AllowBalance: true},
+	)
+	if resp, _ := call(t, srv, "GET", "/credits/balance", "secret", ""); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("without allowBalance: status %d", resp.StatusCode)
+	}
+	if calls.Load() != 0 {
+		t.Fatal("a refused balance request reached upstream")
+	}
+	resp, body := call(t, srv, "GET", "/v1/credits/balance", "admin", "")
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "42.5") {
+		t.Fatalf("with allowBalance: status %d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestBucketAndQuotaPeriods(t *testing.T) {
+	if newBucket(0, 5) != nil {
+		t.Fatal("rate 0 should disable limiting")
+	}
+	b := newBucket(6, 0) // one token per 10s, burst 1
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if ok, _ := b.allow(now); !ok {
+		t.Fatal("first request refused")
+	}
+	if ok, wait := b.allow(now.Add(4 * time.Second)); ok || wait.Round(time.Millisecond) != 6*time.Second {
+		t.Fatalf("allow = %v, wait %v", ok, wait)
+	}
+
+	q := &quota{limit: 1, period: "day"}
+	if !q.reserve(now, 1) || q.reserve(now, 0.5) {
+		t.Fatal("quota not enforced")
+	}
+	if !q.reserve(now.Add(24*time.Hour), 1) {
+		t.Fatal("quota should reset with the period")
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// bucket is a token-bucket rate limiter.
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(perMinute float64, burst int) *bucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	b := float64(burst)
+	if b < 1 {
+		b = 1
+	}
+	return &bucket{rate: perMinute / 60, burst: b, tokens: b}
+}
+
+// allow takes a token, or reports how long until one is available.
+func (b *bucket) allow(now time.Time) (bool, time.Duration) {
+	if b == nil {
+		return true, 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// quota tracks estimated credits spent in the current period.
+type quota struct {
+	mu     sync.Mutex
+	limit  float64 // 0 = unlimited
+	period string
+	start  time.Time
+	spent  float64
+}
+
+func (q *quota) roll(now time.Time) {
+	if s := periodStart(q.period, now); !s.Equal(q.start) {
+		q.start, q.spent = s, 0
+	}
+}
+
+// reserve books cost if it fits in the remaining quota.
+func (q *quota) reserve(now time.Time, cost float64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.roll(now)
+	if q.limit > 0 && q.spent+cost > q.limit+1e-9 {
+		return false
+	}
+	q.spent += cost
+	return true
+}
+
+// refund returns a reservation (e.g. when the upstream call failed).
+func (q *quota) refund(cost float64) {
+	q.mu.Lock()
+	q.spent -= cost
+	if q.spent < 0 {
+		q.spent = 0
+	}
+	q.mu.Unlock()
+}
+
+// add records spending replayed from the usage log.
+func (q *quota) add(at time.Time, cost float64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.roll(time.Now())
+	if !at.Before(q.start) {
+		q.spent += cost
+	}
+}
+
+func (q *quota) snapshot(now time.Time) (spent float64, start time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.roll(now)
+	return q.spent, q.start
+}
+
+// cache is a size-bounded TTL cache of upstream response bodies.
+type cache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	max     int
+	ll      *list.List
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key     string
+	body    []byte
+	expires time.Time
+}
+
+func newCache(ttl time.Duration, max int) *cache {
+	if ttl <= 0 {
+		return nil
+	}
+	if max <= 0 {
+		max = 1000
+	}
+	return &cache{ttl: ttl, max: max, ll: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (c *cache) get(key string, now time.Time) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*cacheEntry)
+	if now.After(e.expires) {
+		c.ll.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return e.body, true
+}
+
+func (c *cache) put(key string, body []byte, now time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*cacheEntry)
+		e.body, e.expires = body, now.Add(c.ttl)
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.ll.PushFront(&cacheEntry{key: key, body: body, expires: now.Add(c.ttl)})
+	for c.ll.Len() > c.max {
+		old := c.ll.Back()
+		c.ll.Remove(old)
+		delete(c.entries, old.Value.(*cacheEntry).key)
+	}
+}
@@ -0,0 +1,88 @@
+// Command linkup-gateway is an HTTP gateway in front of the Linkup API. It
+// mirrors /search, /fetch and /credits/balance, authenticates callers with
+// their own tokens, injects the real API key, and applies caching, rate
+// limits, per-client credit quotas and usage logging. Upstream calls go
+// through linkup.Client, so retry behavior matches the SDK.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+)
+
+func main() {
+	cfgPath := flag.String("config", "linkup-gateway.json", "path to the gateway config file")
+	listen := flag.String("listen", "", "listen address (overrides config)")
+	timeout := flag.Duration("timeout", 90*time.Second, "upstream request timeout")
+	baseURL := flag.String("base", "", "override upstream base URL (for testing)")
+	ua := flag.String("ua", "", "custom user-agent")
+	hash := flag.String("hash-token", "", "print the sha256 of a client token for tokenSha256 and exit")
+	flag.Parse()
+
+	if *hash != "" {
+		fmt.Println(hashToken(*hash))
+		return
+	}
+
+	apiKey := os.Getenv("LINKUP_API_KEY")
+	if apiKey == "" {
+		fmt.Fprintln(os.Stderr, "missing LINKUP_API_KEY")
+		os.Exit(2)
+	}
+	cfg, err := loadConfig(*cfgPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *listen != "" {
+		cfg.Listen = *listen
+	}
+	if cfg.Listen == "" {
+		cfg.Listen = "127.0.0.1:8080"
+	}
+
+	clientOpts := []linkup.Option{
+		linkup.WithRetry(3, 250*time.Millisecond, 4*time.Second),
+	}
+	if *baseURL != "" {
+		clientOpts = append(clientOpts, linkup.WithBaseURL(*baseURL))
+	}
+	if *ua != "" {
+		clientOpts = append(clientOpts, linkup.WithUserAgent(*ua))
+	}
+
+	usage, err := openUsageLog(cfg.UsageLog)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer usage.Close()
+
+	g := newGateway(cfg, linkup.NewClient(apiKey, clientOpts...), usage, *timeout)
+	if err := usage.replay(g); err != nil {
+		log.Fatalf("replay usage log: %v", err)
+	}
+
+	srv := &http.Server{Addr: cfg.Listen, Handler: g.routes(), ReadHeaderTimeout: 10 * time.Second}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("linkup-gateway listening on %s (%d clients)", cfg.Listen, len(cfg.Clients))
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
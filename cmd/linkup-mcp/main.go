@@ -0,0 +1,102 @@
+// Command linkup-mcp is a Model Context Protocol server exposing Linkup
+// search, fetch and balance as tools. It speaks MCP over stdio by default,
+// or over streamable HTTP when -http is set.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/tools"
+)
+
+func main() {
+	httpAddr := flag.String("http", "", "serve streamable HTTP on this address (e.g. 127.0.0.1:8808) instead of stdio")
+	defaultsFile := flag.String("defaults", "", `JSON file with per-tool default arguments, e.g. {"linkup_search":{"depth":"deep"}}`)
+	allow := flag.String("allow-domains", "", "comma-separated domains the tools may search and fetch (subdomains included)")
+	maxResults := flag.Int("max-results", 10, "max search results returned to the model")
+	maxChars := flag.Int("max-chars", 600, "max characters of content per search result")
+	maxFetch := flag.Int("max-fetch-chars", 12000, "max characters of fetched markdown")
+	timeout := flag.Duration("timeout", 60*time.Second, "per tool call timeout")
+	baseURL := flag.String("base", "", "override base URL (for testing)")
+	ua := flag.String("ua", "", "custom user-agent")
+	flag.Parse()
+
+	// stdout carries the protocol; keep logs on stderr.
+	log.SetOutput(os.Stderr)
+	log.SetPrefix("linkup-mcp: ")
+
+	apiKey := os.Getenv("LINKUP_API_KEY")
+	if apiKey == "" {
+		fmt.Fprintln(os.Stderr, "missing LINKUP_API_KEY")
+		os.Exit(2)
+	}
+	clientOpts := []linkup.Option{
+		linkup.WithRetry(3, 250*time.Millisecond, 4*time.Second),
+	}
+	if *baseURL != "" {
+		clientOpts = append(clientOpts, linkup.WithBaseURL(*baseURL))
+	}
+	if *ua != "" {
+		clientOpts = append(clientOpts, linkup.WithUserAgent(*ua))
+	}
+
+	defaults := map[string]map[string]any{}
+	if *defaultsFile != "" {
+		b, err := os.ReadFile(*defaultsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := json.Unmarshal(b, &defaults); err != nil {
+			log.Fatalf("parse %s: %v", *defaultsFile, err)
+		}
+	}
+
+	srv := &server{tools: &toolset{
+		dispatcher: &tools.Dispatcher{
+			Client:        linkup.NewClient(apiKey, clientOpts...),
+			Defaults:      defaults,
+			AllowDomains:  splitCSV(*allow),
+			MaxResults:    *maxResults,
+			MaxChars:      *maxChars,
+			MaxFetchChars: *maxFetch,
+		},
+		timeout: *timeout,
+	}}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var err error
+	if *httpAddr != "" {
+		err = srv.serveHTTP(ctx, *httpAddr)
+	} else {
+		err = srv.serveStdio(ctx, os.Stdin, os.Stdout)
+	}
+	if err != nil && ctx.Err() == nil {
+		log.Fatal(err)
+	}
+}
+
+func splitCSV(s string) []string {
+	if s == "" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitCSV(t *testing.T) {
+	for in, want := range map[string][]string{
+		"":                      nil,
+		"a.com":                 {"a.com"},
+		" A.com, ,b.org ,":      {"a.com", "b.org"},
+		"Docs.Example.COM,x.io": {"docs.example.com", "x.io"},
+	} {
+		if got := splitCSV(in); !slices.Equal(got, want) {
+			t.Errorf("splitCSV(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
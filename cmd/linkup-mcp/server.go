@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	serverName    = "linkup-mcp"
+	serverVersion = "0.1.0"
+	latestVersion = "2025-06-18"
+)
+
+var supportedVersions = map[string]bool{
+	"2024-11-05": true,
+	"2025-03-26": true,
+	"2025-06-18": true,
+}
+
+// JSON-RPC 2.0 error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+func (r *rpcRequest) isNotification() bool { return len(r.ID) == 0 }
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type server struct {
+	tools *toolset
+
+	mu       sync.Mutex
+	inflight map[string]context.CancelFunc
+}
+
+// handle processes one message and returns the response to send, or nil for
+// notifications.
+func (s *server) handle(ctx context.Context, req *rpcRequest) *rpcResponse {
+	result, rerr := s.dispatch(ctx, req)
+	if req.isNotification() {
+		return nil
+	}
+	resp := &rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rerr}
+	if rerr == nil && result == nil {
+		resp.Result = struct{}{}
+	}
+	return resp
+}
+
+func (s *server) dispatch(ctx context.Context, req *rpcRequest) (any, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		var p struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		_ = json.Unmarshal(req.Params, &p)
+		version := latestVersion
+		if supportedVersions[p.ProtocolVersion] {
+			version = p.ProtocolVersion
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{"listChanged": false}},
+			"serverInfo":      map[string]any{"name": serverName, "version": serverVersion},
+			"instructions":    "Use linkup_search to find current information on the web, linkup_fetch to read a specific page, and linkup_balance to check remaining credits.",
+		}, nil
+	case "ping", "notifications/initialized":
+		return nil, nil
+	case "notifications/cancelled":
+		var p struct {
+			RequestID json.RawMessage `json:"requestId"`
+		}
+		if json.Unmarshal(req.Params, &p) == nil {
+			s.cancel(string(p.RequestID))
+		}
+		return nil, nil
+	case "tools/list":
+		return map[string]any{"tools": s.tools.list()}, nil
+	case "tools/call":
+		var p struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &p); err != nil || p.Name == "" {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "tools/call requires a tool name"}
+		}
+		if !s.tools.has(p.Name) {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "unknown tool: " + p.Name}
+		}
+		ctx, done := s.track(ctx, req.ID)
+		defer done()
+		text, err := s.tools.call(ctx, p.Name, p.Arguments)
+		if err != nil {
+			// Tool failures are results the model can read, not protocol errors.
+			return toolResult(err.Error(), true), nil
+		}
+		return toolResult(text, false), nil
+	}
+	if req.isNotification() {
+		return nil, nil
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+}
+
+func toolResult(text string, isError bool) map[string]any {
+	return map[string]any{
+		"content": []map[string]any{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
+
+// track registers a cancellable context for request id so that
+// notifications/cancelled can abort it.
+func (s *server) track(ctx context.Context, id json.RawMessage) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	key := string(id)
+	s.mu.Lock()
+	if s.inflight == nil {
+		s.inflight = make(map[string]context.CancelFunc)
+	}
+	s.inflight[key] = cancel
+	s.mu.Unlock()
+	return ctx, func() {
+		s.mu.Lock()
+		delete(s.inflight, key)
+		s.mu.Unlock()
+		cancel()
+	}
+}
+
+func (s *server) cancel(id string) {
+	s.mu.Lock()
+	cancel := s.inflight[id]
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// serveStdio reads newline-delimited JSON-RPC messages from r and writes
+// responses to w. Requests are handled concurrently so a slow tool call does
+// not block pings or cancellations.
+func (s *server) serveStdio(ctx context.Context, r io.Reader, w io.Writer) error {
+	var (
+		wmu sync.Mutex
+		wg  sync.WaitGroup
+		enc = json.NewEncoder(w)
+	)
+	write := func(resp *rpcResponse) {
+		wmu.Lock()
+		defer wmu.Unlock()
+		if err := enc.Encode(resp); err != nil {
+			log.Printf("write: %v", err)
+		}
+	}
+	defer wg.Wait()
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		req := new(rpcRequest)
+		if err := json.Unmarshal(line, req); err != nil {
+			write(&rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: codeParseError, Message: err.Error()}})
+			continue
+		}
+		if req.JSONRPC != "2.0" || req.Method == "" {
+			if !req.isNotification() {
+				write(&rpcResponse{JSONRPC: "2.0", ID: req.ID, Error: &rpcError{Code: codeInvalidRequest, Message: "invalid request"}})
+			}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if resp := s.handle(ctx, req); resp != nil {
+				write(resp)
+			}
+		}()
+	}
+	return sc.Err()
+}
+
+// serveHTTP serves the streamable HTTP transport on addr at /mcp. Each POST
+// carries one JSON-RPC message; responses are returned as application/json.
+func (s *server) serveHTTP(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mcp", s.handleHTTP)
+	hs := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = hs.Shutdown(shutdownCtx)
+	}()
+	log.Printf("listening on http://%s/mcp", addr)
+	if err := hs.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *server) handleHTTP(w http.ResponseWriter, r *http.Request) {
+	if !localOrigin(r.Header.Get("Origin")) {
+		http.Error(w, "forbidden origin", http.StatusForbidden)
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent) // sessions are stateless
+		return
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	req := new(rpcRequest)
+	if err := json.NewDecoder(io.LimitReader(r.Body, 16<<20)).Decode(req); err != nil {
+		writeJSON(w, http.StatusBadRequest, &rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: codeParseError, Message: err.Error()}})
+		return
+	}
+	resp := s.handle(r.Context(), req)
+	if resp == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// localOrigin guards against DNS rebinding: browsers may only reach the
+// server from a loopback origin.
+func localOrigin(origin string) bool {
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
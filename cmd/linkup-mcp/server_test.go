@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/tools"
+)
+
+// testServer returns an MCP server backed by a fake Linkup API. The API
+// fails searches for the query "fail" and holds searches for "slow" until
+// the request is cancelled, signalling started when it gets one.
+func testServer(t *testing.T) (srv *server, started chan struct{}) {
+	t.Helper()
+	started = make(chan struct{}, 1)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/credits/balance":
+			fmt.Fprint(w, `{"balance": 42.5}`)
+		case "/search":
+			var req linkup.SearchRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			switch req.Q {
+			case "fail":
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"message":"bad query"}`)
+			case "slow":
+				started <- struct{}{}
+				<-r.Context().Done()
+			default:
+				fmt.Fprintf(w, `{"results":[{"type":"text","name":"A","url":"https://example.com/%s","content":"a"}]}`, req.Q)
+			}
+		default:
+			fmt.Fprint(w, `{"markdown":"page"}`)
+		}
+	}))
+	t.Cleanup(api.Close)
+	client := linkup.NewClient("k", linkup.WithBaseURL(api.URL), linkup.WithRetry(0, time.Millisecond, time.Millisecond))
+	return &server{tools: &toolset{dispatcher: &tools.Dispatcher{Client: client}, timeout: 5 * time.Second}}, started
+}
+
+func TestServeStdio(t *testing.T) {
+	srv, _ := testServer(t)
+	for _, tc := range []struct {
+		name string
+		in   string
+		want []string // substrings of the response; none for no response
+	}{
+		{"initialize supported version", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`,
+			[]string{`"id":1`, `"protocolVersion":"2024-11-05"`, `"name":"linkup-mcp"`, `"tools":{"listChanged":false}`}},
+		{"initialize unknown version", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}`,
+			[]string{`"protocolVersion":"` + latestVersion + `"`}},
+		{"initialize without params", `{"jsonrpc":"2.0","id":1,"method":"initialize"}`,
+			[]string{`"protocolVersion":"` + latestVersion + `"`}},
+		{"ping", `{"jsonrpc":"2.0","id":"a","method":"ping"}`, []string{`"id":"a","result":{}`}},
+		{"initialized notification", `{"jsonrpc":"2.0","method":"notifications/initialized"}`, nil},
+		{"unknown method", `{"jsonrpc":"2.0","id":2,"method":"resources/list"}`,
+			[]string{`"id":2`, `"code":-32601`, `method not found: resources/list`}},
+		{"unknown notification", `{"jsonrpc":"2.0","method":"notifications/progress"}`, nil},
+		{"parse error", `{"jsonrpc":`, []string{`"id":null`, `"code":-32700`}},
+		{"wrong version", `{"jsonrpc":"1.0","id":3,"method":"ping"}`, []string{`"id":3`, `"code":-32600`}},
+		{"missing method", `{"jsonrpc":"2.0","id":3}`, []string{`"code":-32600`}},
+		{"tools/list", `{"jsonrpc":"2.0","id":4,"method":"tools/list"}`,
+			[]string{`"name":"linkup_search"`, `"name":"linkup_fetch"`, `"name":"linkup_balance"`, `"inputSchema":{`}},
+		{"call balance", `{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"linkup_balance"}}`,
+			[]string{`"text":"Remaining Linkup credits: 42.5"`, `"isError":false`}},
+		{"call search", `{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"linkup_search","arguments":{"q":"go"}}}`,
+			[]string{`https://example.com/go`, `"isError":false`}},
+		{"call fetch", `{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"linkup_fetch","arguments":{"url":"https://example.com/"}}}`,
+			[]string{`Source: https://example.com/\n\npage`, `"isError":false`}},
+		// Tool failures are results the model reads, not protocol errors.
+		{"call API error", `{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"linkup_search","arguments":{"q":"fail"}}}`,
+			[]string{`"result":`, `bad query (status=400)`, `"isError":true`}},
+		{"call bad arguments", `{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"linkup_search","arguments":{}}}`,
+			[]string{`"result":`, `q is required`, `"isError":true`}},
+		{"call unknown tool", `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"nope"}}`,
+			[]string{`"error":`, `"code":-32602`, `unknown tool: nope`}},
+		{"call without name", `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{}}`,
+			[]string{`"error":`, `"code":-32602`}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var out strings.Builder
+			if err := srv.serveStdio(context.Background(), strings.NewReader(tc.in+"\n"), &out); err != nil {
+				t.Fatal(err)
+			}
+			got := out.String()
+			if tc.want == nil && got != "" {
+				t.Fatalf("response to a notification: %s", got)
+			}
+			if tc.want != nil && strings.Count(got, "\n") != 1 {
+				t.Fatalf("want one response, got %q", got)
+			}
+			for _, w := range tc.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("response %s\nlacks %s", got, w)
+				}
+			}
+		})
+	}
+}
+
+func TestServeStdio_Cancelled(t *testing.T) {
+	srv, started := testServer(t)
+	in, w := io.Pipe()
+	out, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- srv.serveStdio(context.Background(), in, pw) }()
+	responses := bufio.NewScanner(out)
+
+	fmt.Fprintln(w, `{"jsonrpc":"2.0","id":9,"method":"tools/call","params":{"name":"linkup_search","arguments":{"q":"slow"}}}`)
+	<-started
+	// A slow call does not block other requests.
+	fmt.Fprintln(w, `{"jsonrpc":"2.0","id":10,"method":"ping"}`)
+	if !responses.Scan() || !strings.Contains(responses.Text(), `"id":10`) {
+		t.Fatalf("ping: %q", responses.Text())
+	}
+	// Cancelling another request leaves the call running.
+	fmt.Fprintln(w, `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":8}}`)
+	fmt.Fprintln(w, `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":9,"reason":"user"}}`)
+	if !responses.Scan() {
+		t.Fatal(responses.Err())
+	}
+	got := responses.Text()
+	if !strings.Contains(got, `"id":9`) || !strings.Contains(got, `context canceled`) || !strings.Contains(got, `"isError":true`) {
+		t.Fatalf("cancelled call: %s", got)
+	}
+	w.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if len(srv.inflight) != 0 {
+		t.Errorf("inflight = %v", srv.inflight)
+	}
+}
+
+func TestHandleHTTP(t *testing.T) {
+	srv, _ := testServer(t)
+	ts := httptest.NewServer(http.HandlerFunc(srv.handleHTTP))
+	defer ts.Close()
+	const ping = `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+	for _, tc := range []struct {
+		name, method, origin, body string
+		status                     int
+		want                       string
+	}{
+		{"no origin", "POST", "", ping, http.StatusOK, `"result":{}`},
+		{"localhost", "POST", "http://localhost:3000", ping, http.StatusOK, `"result":{}`},
+		{"loopback IPv4", "POST", "http://127.0.0.1", ping, http.StatusOK, `"result":{}`},
+		{"loopback IPv6", "POST", "http://[::1]:8808", ping, http.StatusOK, `"result":{}`},
+		{"remote origin", "POST", "https://evil.example", ping, http.StatusForbidden, "forbidden origin"},
+		{"localhost suffix", "POST", "http://localhost.evil.example", ping, http.StatusForbidden, "forbidden origin"},
+		{"opaque origin", "POST", "null", ping, http.StatusForbidden, "forbidden origin"},
+		{"remote origin DELETE", "DELETE", "https://evil.example", "", http.StatusForbidden, "forbidden origin"},
+		{"notification", "POST", "", `{"jsonrpc":"2.0","method":"notifications/initialized"}`, http.StatusAccepted, ""},
+		{"tools/call", "POST", "", `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"linkup_balance"}}`, http.StatusOK, `42.5`},
+		{"unknown method", "POST", "", `{"jsonrpc":"2.0","id":3,"method":"nope"}`, http.StatusOK, `"code":-32601`},
+		{"parse error", "POST", "", `{`, http.StatusBadRequest, `"code":-32700`},
+		{"delete session", "DELETE", "", "", http.StatusNoContent, ""},
+		{"get", "GET", "", "", http.StatusMethodNotAllowed, "method not allowed"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tc.method, ts.URL, strings.NewReader(tc.body))
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			b, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tc.status || !strings.Contains(string(b), tc.want) {
+				t.Fatalf("%d %s, want %d with %q", resp.StatusCode, b, tc.status, tc.want)
+			}
+			if tc.status == http.StatusOK && resp.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("Content-Type = %q", resp.Header.Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/raezil/linkup-go/linkup/jsonschema"
+	"github.com/raezil/linkup-go/linkup/tools"
+)
+
+// toolset adapts tools.Dispatcher to MCP.
+type toolset struct {
+	dispatcher *tools.Dispatcher
+	timeout    time.Duration
+}
+
+type toolDef struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	InputSchema *jsonschema.Schema `json:"inputSchema"`
+}
+
+func (t *toolset) has(name string) bool { return t.dispatcher.Has(name) }
+
+func (t *toolset) list() []toolDef {
+	defs := t.dispatcher.Definitions()
+	out := make([]toolDef, len(defs))
+	for i, d := range defs {
+		out[i] = toolDef{Name: d.Name, Description: d.Description, InputSchema: d.Parameters}
+	}
+	return out
+}
+
+func (t *toolset) call(ctx context.Context, name string, args json.RawMessage) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	return t.dispatcher.Dispatch(ctx, name, args)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/monitor"
+)
+
+// config is the daemon's JSON configuration file.
+//
+//	{
+//	  "dataDir": "/var/lib/linkup-monitord",
+//	  "status": "127.0.0.1:8091",
+//	  "minBalance": 1.0,
+//	  "notify": {"stdout": true, "webhook": "http://localhost:9000/hook"},
+//	  "searches": [
+//	    {"name": "openssl-cves", "schedule": "0 * * * *",
+//	     "request": {"q": "OpenSSL CVE", "depth": "standard", "outputType": "searchResults"}},
+//	    {"name": "acme-release", "schedule": "30 8 * * mon-fri",
+//	     "request": {"q": "latest Acme release", "outputType": "sourcedAnswer"},
+//	     "notify": {"smtp": {"addr": "localhost:25", "from": "monitor@example.com", "to": ["team@example.com"]}}}
+//	  ]
+//	}
+type config struct {
+	DataDir         string         `json:"dataDir"`
+	Status          string         `json:"status"`
+	MinBalance      float64        `json:"minBalance,omitempty"`
+	BalanceInterval duration       `json:"balanceInterval,omitempty"`
+	Timeout         duration       `json:"timeout,omitempty"`
+	NotifyInitial   bool           `json:"notifyInitial,omitempty"`
+	Notify          notifyConfig   `json:"notify"`
+	Searches        []searchConfig `json:"searches"`
+}
+
+type searchConfig struct {
+	Name     string               `json:"name"`
+	Schedule string               `json:"schedule"`
+	Request  linkup.SearchRequest `json:"request"`
+	// Notify replaces the global notify settings for this search.
+	Notify *notifyConfig `json:"notify,omitempty"`
+}
+
+type notifyConfig struct {
+	Stdout bool `json:"stdout,omitempty"`
+	// JSON prints stdout events as JSON lines instead of text.
+	JSON    bool              `json:"json,omitempty"`
+	File    string            `json:"file,omitempty"`
+	Webhook string            `json:"webhook,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	SMTP    *smtpConfig       `json:"smtp,omitempty"`
+}
+
+type smtpConfig struct {
+	Addr     string   `json:"addr"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+}
+
+// duration unmarshals from a Go duration string such as "10m".
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+func loadConfig(path string) (*config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &config{}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if len(cfg.Searches) == 0 {
+		return nil, errors.New("config: no searches defined")
+	}
+	if cfg.DataDir == "" {
+		cfg.DataDir = "."
+	}
+	return cfg, nil
+}
+
+// monitorConfig converts the file config into a monitor.Config.
+func (c *config) monitorConfig(store *monitor.Store, stdout io.Writer) (monitor.Config, error) {
+	notifiers, err := c.Notify.notifiers(stdout)
+	if err != nil {
+		return monitor.Config{}, fmt.Errorf("config: notify: %w", err)
+	}
+	mc := monitor.Config{
+		Store:           store,
+		Notifiers:       notifiers,
+		MinBalance:      c.MinBalance,
+		BalanceInterval: time.Duration(c.BalanceInterval),
+		Timeout:         time.Duration(c.Timeout),
+		NotifyInitial:   c.NotifyInitial,
+	}
+	for _, s := range c.Searches {
+		sched, err := monitor.ParseSchedule(s.Schedule)
+		if err != nil {
+			return mc, fmt.Errorf("config: search %q: %w", s.Name, err)
+		}
+		ms := monitor.Search{Name: s.Name, Schedule: sched, Request: s.Request}
+		if s.Notify != nil {
+			if ms.Notifiers, err = s.Notify.notifiers(stdout); err != nil {
+				return mc, fmt.Errorf("config: search %q: notify: %w", s.Name, err)
+			}
+		}
+		mc.Searches = append(mc.Searches, ms)
+	}
+	return mc, nil
+}
+
+func (n *notifyConfig) notifiers(stdout io.Writer) ([]monitor.Notifier, error) {
+	out := []monitor.Notifier{}
+	if n.Stdout {
+		out = append(out, &monitor.WriterNotifier{W: stdout, JSON: n.JSON})
+	}
+	if n.File != "" {
+		out = append(out, &monitor.FileNotifier{Path: n.File})
+	}
+	if n.Webhook != "" {
+		out = append(out, &monitor.WebhookNotifier{URL: n.Webhook, Headers: n.Headers})
+	}
+	if s := n.SMTP; s != nil {
+		host, _, err := net.SplitHostPort(s.Addr)
+		if err != nil {
+			return nil, fmt.Errorf("smtp.addr: %w", err)
+		}
+		if s.From == "" || len(s.To) == 0 {
+			return nil, errors.New("smtp: from and to are required")
+		}
+		sn := &monitor.SMTPNotifier{Addr: s.Addr, From: s.From, To: s.To}
+		if s.Username != "" {
+			sn.Auth = smtp.PlainAuth("", s.Username, s.Password, host)
+		}
+		out = append(out, sn)
+	}
+	return out, nil
+}
+
+func storePath(dataDir string) string {
+	return filepath.Join(dataDir, "results.jsonl")
+}
@@ -0,0 +1,111 @@
+// Command linkup-monitord runs saved Linkup searches on cron schedules,
+// stores what they return, and notifies (stdout, JSONL file, webhook or
+// SMTP) about results that were not seen before and about changed answers.
+// A small HTTP endpoint reports status, and searching pauses while the
+// credit balance is below a threshold.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/monitor"
+)
+
+func main() {
+	cfgPath := flag.String("config", "linkup-monitord.json", "path to the monitor config file")
+	status := flag.String("status", "", "status listen address (overrides config; empty disables)")
+	runNow := flag.String("run", "", "run the named search once, print its event and exit")
+	baseURL := flag.String("base", "", "override base URL (for testing)")
+	ua := flag.String("ua", "", "custom user-agent")
+	flag.Parse()
+
+	apiKey := os.Getenv("LINKUP_API_KEY")
+	if apiKey == "" {
+		fmt.Fprintln(os.Stderr, "missing LINKUP_API_KEY")
+		os.Exit(2)
+	}
+	cfg, err := loadConfig(*cfgPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *status != "" {
+		cfg.Status = *status
+	}
+
+	clientOpts := []linkup.Option{
+		linkup.WithRetry(3, 250*time.Millisecond, 4*time.Second),
+	}
+	if *baseURL != "" {
+		clientOpts = append(clientOpts, linkup.WithBaseURL(*baseURL))
+	}
+	if *ua != "" {
+		clientOpts = append(clientOpts, linkup.WithUserAgent(*ua))
+	}
+
+	store, err := monitor.OpenStore(storePath(cfg.DataDir))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+	mc, err := cfg.monitorConfig(store, os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mc.Logf = log.Printf
+	m, err := monitor.New(linkup.NewClient(apiKey, clientOpts...), mc)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *runNow != "" {
+		ev, err := m.RunSearch(ctx, *runNow)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if ev == nil {
+			log.Printf("search %q: baseline recorded", *runNow)
+		} else if ev.Empty() {
+			log.Printf("search %q: no changes", *runNow)
+		}
+		return
+	}
+
+	if cfg.Status != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/status", m.Handler())
+		mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok\n"))
+		})
+		srv := &http.Server{Addr: cfg.Status, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = srv.Shutdown(shutdownCtx)
+		}()
+		go func() {
+			if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				log.Fatal(err)
+			}
+		}()
+		log.Printf("status on http://%s/status", cfg.Status)
+	}
+
+	log.Printf("linkup-monitord running %d searches", len(cfg.Searches))
+	if err := m.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,235 @@
+// Package canonical normalizes result URLs so that the same page reached
+// through tracking links, AMP/mobile mirrors, www. aliases or plain http
+// collapses to a single key, and deduplicates slices keyed on that URL.
+package canonical
+
+import (
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// trackingParams are query keys dropped outright. Keys with a prefix listed in
+// trackingPrefixes (e.g. utm_source) are dropped as well.
+var trackingParams = map[string]bool{
+	"fbclid":      true,
+	"gclid":       true,
+	"dclid":       true,
+	"gbraid":      true,
+	"wbraid":      true,
+	"msclkid":     true,
+	"yclid":       true,
+	"igshid":      true,
+	"mc_cid":      true,
+	"mc_eid":      true,
+	"_ga":         true,
+	"_gl":         true,
+	"_hsenc":      true,
+	"_hsmi":       true,
+	"mkt_tok":     true,
+	"oly_anon_id": true,
+	"oly_enc_id":  true,
+	"vero_id":     true,
+	"ref_src":     true,
+	"ref_url":     true,
+	"spm":         true,
+	"amp":         true,
+}
+
+var trackingPrefixes = []string{"utm_", "pk_", "hsa_", "__hs"}
+
+// hostPrefixes are mobile/AMP/www labels stripped from the host.
+var hostPrefixes = []string{"www.", "m.", "mobile.", "amp."}
+
+// IsTrackingParam reports whether the query key k is dropped by URL.
+func IsTrackingParam(k string) bool {
+	k = strings.ToLower(k)
+	if trackingParams[k] {
+		return true
+	}
+	for _, p := range trackingPrefixes {
+		if strings.HasPrefix(k, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// URL returns the canonical form of raw:
+//   - scheme forced to https, host lowercased, default port removed
+//   - www., m., mobile. and amp. host labels removed
+//   - Google AMP cache and ampproject.org URLs unwrapped to the origin
+//   - trailing /amp path segments and .amp suffixes removed
+//   - tracking parameters (utm_*, fbclid, gclid, ...) removed
+//   - remaining query parameters sorted, fragment dropped
+//   - trailing slash removed from non-root paths
+//
+// Two URLs with the same canonical form are treated as the same page.
+func URL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" && u.Scheme == "" && !strings.HasPrefix(raw, "/") {
+		// "example.com/path" — treat the first segment as the host.
+		if u, err = url.Parse("https://" + strings.TrimSpace(raw)); err != nil {
+			return "", err
+		}
+	}
+	if inner, ok := unwrapAMPCache(u); ok {
+		u = inner
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "":
+		u.Scheme = "https"
+	default:
+		u.Scheme = strings.ToLower(u.Scheme)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	for _, p := range hostPrefixes {
+		if strings.HasPrefix(host, p) && strings.Count(host, ".") >= 2 {
+			host = strings.TrimPrefix(host, p)
+			break
+		}
+	}
+	host = strings.TrimSuffix(host, ".")
+	if port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+	u.Host = host
+	u.User = nil
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	u.Path = cleanPath(u.Path)
+	u.RawPath = ""
+	u.RawQuery = cleanQuery(u.Query())
+	return u.String(), nil
+}
+
+// Key returns the canonical form of raw, or raw lowercased and trimmed when
+// it cannot be parsed. Use it as a map key when errors are not interesting.
+func Key(raw string) string {
+	if c, err := URL(raw); err == nil {
+		return c
+	}
+	return strings.ToLower(strings.TrimSpace(raw))
+}
+
+// Host returns the canonical host of raw (e.g. "example.com" for
+// "http://www.example.com/a"), or "" when raw has no host.
+func Host(raw string) string {
+	c, err := URL(raw)
+	if err != nil {
+		return ""
+	}
+	u, err := url.Parse(c)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// Dedupe collapses items whose URLs share a canonical key. The first
+// occurrence keeps its position; every later duplicate is folded into it with
+// merge (which may be nil to simply drop duplicates).
+func Dedupe[T any](items []T, urlOf func(T) string, merge func(kept, dup T) T) []T {
+	out := make([]T, 0, len(items))
+	index := make(map[string]int, len(items))
+	for _, it := range items {
+		k := Key(urlOf(it))
+		if i, ok := index[k]; ok {
+			if merge != nil {
+				out[i] = merge(out[i], it)
+			}
+			continue
+		}
+		index[k] = len(out)
+		out = append(out, it)
+	}
+	return out
+}
+
+func cleanPath(p string) string {
+	if p == "" || p == "/" {
+		return ""
+	}
+	p = path.Clean(p)
+	p = strings.TrimSuffix(p, ".amp")
+	for _, suffix := range []string{"/amp", "/amp.html"} {
+		if strings.HasSuffix(strings.ToLower(p), suffix) {
+			p = p[:len(p)-len(suffix)]
+		}
+	}
+	if p == "/" || p == "." {
+		return ""
+	}
+	return strings.TrimRight(p, "/")
+}
+
+func cleanQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		if IsTrackingParam(k) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		vs := append([]string(nil), q[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(url.QueryEscape(k))
+			b.WriteByte('=')
+			b.WriteString(url.QueryEscape(v))
+		}
+	}
+	return b.String()
+}
+
+// unwrapAMPCache recognizes Google AMP viewer and AMP cache URLs, e.g.
+//
+//	https://www.google.com/amp/s/example.com/post
+//	https://example-com.cdn.ampproject.org/c/s/example.com/post
+//
+// and returns the origin URL they point at.
+func unwrapAMPCache(u *url.URL) (*url.URL, bool) {
+	host := strings.ToLower(u.Hostname())
+	var rest string
+	switch {
+	case strings.HasSuffix(host, ".cdn.ampproject.org"):
+		rest = u.Path
+		for _, p := range []string{"/c/", "/v/", "/i/"} {
+			rest = strings.TrimPrefix(rest, p)
+		}
+	case (host == "google.com" || host == "www.google.com") && strings.HasPrefix(u.Path, "/amp/"):
+		rest = strings.TrimPrefix(u.Path, "/amp/")
+	default:
+		return nil, false
+	}
+	scheme := "http"
+	if strings.HasPrefix(rest, "s/") {
+		scheme, rest = "https", rest[2:]
+	}
+	if rest == "" {
+		return nil, false
+	}
+	inner, err := url.Parse(scheme + "://" + rest)
+	if err != nil || inner.Host == "" {
+		return nil, false
+	}
+	inner.RawQuery = u.RawQuery
+	return inner, true
+}
@@ -0,0 +1,64 @@
+package canonical
+
+import "testing"
+
+func TestURL_Variants(t *testing.T) {
+	want := "https://example.com/news/post?id=7&page=2"
+	variants := []string{
+		"https://example.com/news/post?id=7&page=2",
+		"http://example.com/news/post/?page=2&id=7",
+		"https://www.example.com/news/post?id=7&page=2&utm_source=x&utm_medium=y",
+		"https://m.example.com/news/post?page=2&id=7&fbclid=abc#comments",
+		"https://EXAMPLE.com:443/news//post?id=7&page=2&gclid=1",
+		"https://example.com/news/post/amp?id=7&page=2",
+		"https://amp.example.com/news/post?id=7&page=2&amp=1",
+		"https://www.google.com/amp/s/example.com/news/post?id=7&page=2",
+		"https://example-com.cdn.ampproject.org/c/s/example.com/news/post?id=7&page=2",
+	}
+	for _, v := range variants {
+		got, err := URL(v)
+		if err != nil {
+			t.Fatalf("URL(%q): %v", v, err)
+		}
+		if got != want {
+			t.Errorf("URL(%q) = %q, want %q", v, got, want)
+		}
+	}
+}
+
+func TestURL_KeepsMeaningfulParts(t *testing.T) {
+	got, err := URL("http://example.com:8080/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "https://example.com:8080" {
+		t.Fatalf("got %q", got)
+	}
+	if Key("https://example.com/a") == Key("https://example.com/b") {
+		t.Fatal("different paths collapsed")
+	}
+	if Host("https://www.Example.com/x") != "example.com" {
+		t.Fatalf("host = %q", Host("https://www.Example.com/x"))
+	}
+}
+
+func TestDedupe_MergesIntoFirst(t *testing.T) {
+	type item struct{ url, snippet string }
+	in := []item{
+		{"https://example.com/a", "short"},
+		{"https://other.org/", "x"},
+		{"http://www.example.com/a/?utm_source=feed", "a much longer snippet"},
+	}
+	out := Dedupe(in, func(i item) string { return i.url }, func(kept, dup item) item {
+		if len(dup.snippet) > len(kept.snippet) {
+			kept.snippet = dup.snippet
+		}
+		return kept
+	})
+	if len(out) != 2 {
+		t.Fatalf("len = %d", len(out))
+	}
+	if out[0].url != "https://example.com/a" || out[0].snippet != "a much longer snippet" {
+		t.Fatalf("unexpected first: %+v", out[0])
+	}
+}
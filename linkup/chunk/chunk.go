@@ -0,0 +1,310 @@
+// Package chunk splits fetched markdown into overlapping, size-bounded
+// chunks for embedding. Chunks follow headings and paragraphs, carry their
+// heading breadcrumb and byte offsets into the source, and never cut through
+// a fenced code block or a table.
+package chunk
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+)
+
+// Options configures Split. The zero value is usable.
+type Options struct {
+	// MaxTokens is the soft upper bound of a chunk. Code blocks and tables
+	// larger than this are emitted whole. Default 512.
+	MaxTokens int
+	// Overlap is how many tokens of trailing context from the previous chunk
+	// (within the same section) start the next one. Default 0.
+	Overlap int
+	// Tokens estimates the token count of s. Default ApproxTokens.
+	Tokens func(s string) int
+}
+
+// Chunk is a contiguous slice of the source markdown.
+type Chunk struct {
+	Index    int      `json:"index"`
+	Text     string   `json:"text"`
+	Headings []string `json:"headings,omitempty"` // breadcrumb, outermost first
+	URL      string   `json:"url,omitempty"`
+	Start    int      `json:"start"` // byte offset in the source
+	End      int      `json:"end"`
+	Tokens   int      `json:"tokens"`
+}
+
+// Breadcrumb joins the heading trail with " > ".
+func (c Chunk) Breadcrumb() string { return strings.Join(c.Headings, " > ") }
+
+// ApproxTokens is a cheap tokenizer-free estimate (~4 characters per token).
+func ApproxTokens(s string) int {
+	return (utf8.RuneCountInString(s) + 3) / 4
+}
+
+// FromPage chunks the markdown of a fetched page, tagging chunks with url.
+func FromPage(url string, page linkup.FetchResult, opts Options) []Chunk {
+	return Split(page.Markdown, url, opts)
+}
+
+type kind int
+
+const (
+	kindText kind = iota
+	kindHeading
+	kindAtomic // code block or table: never split
+)
+
+// unit is the smallest piece a chunk is built from.
+type unit struct {
+	kind       kind
+	start, end int
+	tokens     int
+	level      int    // heading level
+	heading    string // heading text
+}
+
+// Split chunks markdown taken from url according to opts.
+func Split(markdown, url string, opts Options) []Chunk {
+	if opts.MaxTokens <= 0 {
+		opts.MaxTokens = 512
+	}
+	if opts.Overlap < 0 || opts.Overlap >= opts.MaxTokens {
+		opts.Overlap = 0
+	}
+	if opts.Tokens == nil {
+		opts.Tokens = ApproxTokens
+	}
+
+	var (
+		out    []Chunk
+		cur    []unit
+		curTok int
+		trail  []string // heading stack; trail[i] is level i+1
+		crumbs []string // breadcrumb of cur
+	)
+	flush := func(keepOverlap bool) {
+		if len(cur) == 0 {
+			return
+		}
+		s, e := cur[0].start, cur[len(cur)-1].end
+		text := strings.TrimSpace(markdown[s:e])
+		if text != "" {
+			out = append(out, Chunk{
+				Index:    len(out),
+				Text:     text,
+				Headings: crumbs,
+				URL:      url,
+				Start:    s,
+				End:      e,
+				Tokens:   opts.Tokens(text),
+			})
+		}
+		var keep []unit
+		kept := 0
+		if keepOverlap && opts.Overlap > 0 {
+			for i := len(cur) - 1; i > 0; i-- {
+				if cur[i].kind != kindText || kept+cur[i].tokens > opts.Overlap {
+					break
+				}
+				kept += cur[i].tokens
+				keep = append([]unit{cur[i]}, keep...)
+			}
+		}
+		cur, curTok = keep, kept
+	}
+
+	for _, u := range parse(markdown, opts) {
+		if u.kind == kindHeading {
+			flush(false)
+			if u.level > len(trail) {
+				for len(trail) < u.level-1 {
+					trail = append(trail, "")
+				}
+				trail = append(trail, u.heading)
+			} else {
+				trail = append(trail[:u.level-1], u.heading)
+			}
+			crumbs = compact(trail)
+		}
+		if curTok+u.tokens > opts.MaxTokens && len(cur) > 0 && !onlyHeadings(cur) {
+			flush(true)
+		}
+		cur = append(cur, u)
+		curTok += u.tokens
+	}
+	flush(false)
+	return out
+}
+
+func onlyHeadings(us []unit) bool {
+	for _, u := range us {
+		if u.kind != kindHeading {
+			return false
+		}
+	}
+	return true
+}
+
+func compact(trail []string) []string {
+	out := make([]string, 0, len(trail))
+	for _, h := range trail {
+		if h != "" {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// parse turns markdown into units. Paragraphs larger than MaxTokens are
+// broken at sentence, then word boundaries; code blocks and tables are kept
+// as single atomic units.
+func parse(md string, opts Options) []unit {
+	var out []unit
+	lines := splitLines(md)
+	for i := 0; i < len(lines); {
+		ln := lines[i]
+		trimmed := strings.TrimSpace(md[ln.start:ln.end])
+		switch {
+		case trimmed == "":
+			i++
+		case isFence(trimmed):
+			fence := trimmed[:3]
+			j := i + 1
+			for j < len(lines) && !strings.HasPrefix(strings.TrimSpace(md[lines[j].start:lines[j].end]), fence) {
+				j++
+			}
+			if j < len(lines) {
+				j++ // include closing fence
+			}
+			out = append(out, mkUnit(md, kindAtomic, ln.start, lines[j-1].end, opts))
+			i = j
+		case isHeading(trimmed):
+			level := strings.IndexFunc(trimmed, func(r rune) bool { return r != '#' })
+			u := mkUnit(md, kindHeading, ln.start, ln.end, opts)
+			u.level = level
+			u.heading = strings.TrimSpace(strings.TrimRight(trimmed[level:], "#"))
+			out = append(out, u)
+			i++
+		case isTableRow(trimmed):
+			j := i + 1
+			for j < len(lines) && isTableRow(strings.TrimSpace(md[lines[j].start:lines[j].end])) {
+				j++
+			}
+			out = append(out, mkUnit(md, kindAtomic, ln.start, lines[j-1].end, opts))
+			i = j
+		default:
+			j := i + 1
+			for j < len(lines) {
+				t := strings.TrimSpace(md[lines[j].start:lines[j].end])
+				if t == "" || isFence(t) || isHeading(t) || isTableRow(t) {
+					break
+				}
+				j++
+			}
+			out = append(out, splitText(md, ln.start, lines[j-1].end, opts)...)
+			i = j
+		}
+	}
+	return out
+}
+
+type line struct{ start, end int }
+
+func splitLines(md string) []line {
+	var out []line
+	start := 0
+	for i := 0; i < len(md); i++ {
+		if md[i] == '\n' {
+			out = append(out, line{start, i + 1})
+			start = i + 1
+		}
+	}
+	if start < len(md) {
+		out = append(out, line{start, len(md)})
+	}
+	return out
+}
+
+func mkUnit(md string, k kind, start, end int, opts Options) unit {
+	return unit{kind: k, start: start, end: end, tokens: opts.Tokens(md[start:end])}
+}
+
+func isFence(t string) bool { return strings.HasPrefix(t, "```") || strings.HasPrefix(t, "~~~") }
+
+func isHeading(t string) bool {
+	n := 0
+	for n < len(t) && t[n] == '#' {
+		n++
+	}
+	return n >= 1 && n <= 6 && (n == len(t) || t[n] == ' ')
+}
+
+func isTableRow(t string) bool { return strings.HasPrefix(t, "|") && strings.Count(t, "|") >= 2 }
+
+// splitText returns md[start:end] as one unit, or as several when it
+// exceeds MaxTokens.
+func splitText(md string, start, end int, opts Options) []unit {
+	u := mkUnit(md, kindText, start, end, opts)
+	if u.tokens <= opts.MaxTokens {
+		return []unit{u}
+	}
+	var out []unit
+	for _, b := range boundaries(md, start, end, isSentenceEnd) {
+		su := mkUnit(md, kindText, b[0], b[1], opts)
+		if su.tokens <= opts.MaxTokens {
+			out = append(out, su)
+			continue
+		}
+		for _, w := range packWords(md, b[0], b[1], opts) {
+			out = append(out, mkUnit(md, kindText, w[0], w[1], opts))
+		}
+	}
+	return out
+}
+
+// boundaries cuts [start,end) after every position where cut reports true.
+func boundaries(md string, start, end int, cut func(md string, i int) bool) [][2]int {
+	var out [][2]int
+	s := start
+	for i := start; i < end; i++ {
+		if cut(md, i) && i+1 < end {
+			out = append(out, [2]int{s, i + 1})
+			s = i + 1
+		}
+	}
+	return append(out, [2]int{s, end})
+}
+
+func isSentenceEnd(md string, i int) bool {
+	switch md[i] {
+	case '\n':
+		return true
+	case ' ':
+		return i > 0 && strings.ContainsRune(".!?", rune(md[i-1]))
+	}
+	return false
+}
+
+// packWords greedily groups whitespace-separated words into ranges that fit
+// in MaxTokens.
+func packWords(md string, start, end int, opts Options) [][2]int {
+	var out [][2]int
+	s := start
+	last := start // end of the last word that still fit
+	for i := start; i <= end; i++ {
+		if i < end && !unicode.IsSpace(rune(md[i])) {
+			continue
+		}
+		if opts.Tokens(md[s:i]) > opts.MaxTokens && last > s {
+			out = append(out, [2]int{s, last})
+			s = last
+		}
+		last = i
+	}
+	if s < end {
+		out = append(out, [2]int{s, end})
+	}
+	return out
+}
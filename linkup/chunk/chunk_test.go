@@ -0,0 +1,79 @@
+package chunk
+
+import (
+	"strings"
+	"testing"
+)
+
+const doc = `# Guide
+
+Intro paragraph one. It has two sentences.
+
+## Install
+
+Run the installer and follow the prompts.
+
+` + "```sh\n$ go install ./...\n\n$ linkup search -q hi\n```" + `
+
+| flag | meaning |
+|------|---------|
+| -q   | query   |
+
+### Windows
+
+Use PowerShell.
+`
+
+func TestSplit_HeadingsAndAtomicBlocks(t *testing.T) {
+	chunks := Split(doc, "https://example.com/guide", Options{MaxTokens: 20})
+	if len(chunks) < 3 {
+		t.Fatalf("expected several chunks, got %d", len(chunks))
+	}
+	for i, c := range chunks {
+		if c.Index != i || c.URL != "https://example.com/guide" {
+			t.Fatalf("bad metadata: %+v", c)
+		}
+		if strings.TrimSpace(doc[c.Start:c.End]) != c.Text {
+			t.Fatalf("offsets do not match text for chunk %d", i)
+		}
+		if strings.Count(c.Text, "```")%2 != 0 {
+			t.Fatalf("code block split in chunk %d: %q", i, c.Text)
+		}
+		if strings.Contains(c.Text, "| -q") && !strings.Contains(c.Text, "| flag") {
+			t.Fatalf("table split in chunk %d: %q", i, c.Text)
+		}
+	}
+	last := chunks[len(chunks)-1]
+	if got := last.Breadcrumb(); got != "Guide > Install > Windows" {
+		t.Fatalf("breadcrumb = %q", got)
+	}
+	if chunks[0].Breadcrumb() != "Guide" {
+		t.Fatalf("first breadcrumb = %q", chunks[0].Breadcrumb())
+	}
+}
+
+func TestSplit_LongParagraphWithOverlap(t *testing.T) {
+	sentence := "The quick brown fox jumps over the lazy dog. "
+	md := strings.Repeat(sentence, 40)
+	chunks := Split(md, "", Options{MaxTokens: 50, Overlap: 12})
+	if len(chunks) < 4 {
+		t.Fatalf("expected the paragraph to be split, got %d chunks", len(chunks))
+	}
+	for i, c := range chunks {
+		if c.Tokens > 50 {
+			t.Fatalf("chunk %d has %d tokens", i, c.Tokens)
+		}
+		if i > 0 && c.Start >= chunks[i-1].End {
+			t.Fatalf("chunk %d does not overlap previous", i)
+		}
+	}
+}
+
+func TestSplit_HugeWordlessText(t *testing.T) {
+	md := strings.Repeat("word ", 500)
+	for _, c := range Split(md, "", Options{MaxTokens: 30}) {
+		if c.Tokens > 30 {
+			t.Fatalf("chunk too large: %d", c.Tokens)
+		}
+	}
+}
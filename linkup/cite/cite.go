@@ -0,0 +1,185 @@
+// Package cite renders sourced answers with inline citations ("[1]",
+// "[2, 3]") as Markdown footnotes, HTML anchors or plain numbered
+// references, and checks that every citation points at a listed source.
+package cite
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/internal/citation"
+)
+
+// Format selects the output of Render.
+type Format string
+
+const (
+	Markdown Format = "markdown"
+	HTML     Format = "html"
+	Text     Format = "text"
+)
+
+// Report describes how the inline citations of an answer line up with its
+// sources. Indexes are 1-based, as written in the answer.
+type Report struct {
+	// Citations is the number of citation references found in the answer.
+	Citations int `json:"citations"`
+	// Orphans are cited indexes with no matching entry in Sources.
+	Orphans []int `json:"orphans,omitempty"`
+	// Uncited are source indexes never referenced by the answer.
+	Uncited []int `json:"uncited,omitempty"`
+}
+
+// Valid reports whether every citation maps to a source.
+func (r Report) Valid() bool { return len(r.Orphans) == 0 }
+
+// Error returns an error listing orphan citations, or nil when r is valid.
+func (r Report) Error() error {
+	if r.Valid() {
+		return nil
+	}
+	return fmt.Errorf("cite: citations without a source: %v", r.Orphans)
+}
+
+// Validate checks the inline citations of a against its sources.
+func Validate(a linkup.SourcedAnswer) Report {
+	return validate(a, citation.Scan(a.Answer))
+}
+
+func validate(a linkup.SourcedAnswer, markers []citation.Marker) Report {
+	var r Report
+	cited := make(map[int]bool)
+	orphan := make(map[int]bool)
+	for _, m := range markers {
+		for _, n := range m.Refs {
+			r.Citations++
+			if n < 1 || n > len(a.Sources) {
+				if !orphan[n] {
+					orphan[n] = true
+					r.Orphans = append(r.Orphans, n)
+				}
+				continue
+			}
+			cited[n] = true
+		}
+	}
+	for i := range a.Sources {
+		if !cited[i+1] {
+			r.Uncited = append(r.Uncited, i+1)
+		}
+	}
+	return r
+}
+
+// Render formats a in f and reports citation problems. Orphan citations are
+// left as written rather than linked.
+func Render(a linkup.SourcedAnswer, f Format) (string, Report) {
+	markers := citation.Scan(a.Answer)
+	rep := validate(a, markers)
+	valid := func(n int) bool { return n >= 1 && n <= len(a.Sources) }
+
+	var b strings.Builder
+	switch f {
+	case HTML:
+		seen := make(map[int]int)
+		body := citation.Replace(a.Answer, markers, html.EscapeString, func(m citation.Marker) string {
+			var s strings.Builder
+			for _, n := range m.Refs {
+				if !valid(n) {
+					fmt.Fprintf(&s, "[%d]", n)
+					continue
+				}
+				seen[n]++
+				fmt.Fprintf(&s, `<sup id="ref-%d-%d"><a href="#cite-%d">[%d]</a></sup>`, n, seen[n], n, n)
+			}
+			return s.String()
+		})
+		for _, p := range strings.Split(body, "\n\n") {
+			if p = strings.TrimSpace(p); p != "" {
+				fmt.Fprintf(&b, "<p>%s</p>\n", strings.ReplaceAll(p, "\n", "<br>\n"))
+			}
+		}
+		if len(a.Sources) > 0 {
+			b.WriteString("<ol class=\"sources\">\n")
+			for i, s := range a.Sources {
+				if linkable(s.URL) {
+					fmt.Fprintf(&b, "<li id=\"cite-%d\"><a href=\"%s\">%s</a></li>\n",
+						i+1, html.EscapeString(s.URL), html.EscapeString(s.Label()))
+				} else {
+					fmt.Fprintf(&b, "<li id=\"cite-%d\">%s</li>\n", i+1, html.EscapeString(plainRef(s)))
+				}
+			}
+			b.WriteString("</ol>\n")
+		}
+	case Markdown:
+		b.WriteString(citation.Replace(a.Answer, markers, nil, func(m citation.Marker) string {
+			var s strings.Builder
+			for _, n := range m.Refs {
+				if valid(n) {
+					fmt.Fprintf(&s, "[^%d]", n)
+				} else {
+					fmt.Fprintf(&s, "[%d]", n)
+				}
+			}
+			return s.String()
+		}))
+		if len(a.Sources) > 0 {
+			b.WriteString("\n\n")
+			for i, s := range a.Sources {
+				if linkable(s.URL) {
+					fmt.Fprintf(&b, "[^%d]: [%s](%s)\n", i+1, escapeMarkdownLabel(s.Label()), s.URL)
+				} else {
+					fmt.Fprintf(&b, "[^%d]: %s\n", i+1, escapeMarkdownText(plainRef(s)))
+				}
+			}
+		}
+	default:
+		b.WriteString(a.Answer)
+		if len(a.Sources) > 0 {
+			b.WriteString("\n\nSources:\n")
+			for i, s := range a.Sources {
+				if label := s.Label(); label != s.URL {
+					fmt.Fprintf(&b, "[%d] %s - %s\n", i+1, label, s.URL)
+				} else {
+					fmt.Fprintf(&b, "[%d] %s\n", i+1, s.URL)
+				}
+			}
+		}
+	}
+	return b.String(), rep
+}
+
+// Renumber rewrites every citation index n in answer to mapping(n), e.g.
+// to merge answers into one bibliography. A mapping of 0 removes the
+// reference, e.g. for a dropped source, and a marker left with none is
+// removed. Markdown links are left alone.
+func Renumber(answer string, mapping func(n int) int) string {
+	return citation.Renumber(answer, mapping)
+}
+
+func escapeMarkdownLabel(s string) string {
+	return strings.NewReplacer("[", `\[`, "]", `\]`).Replace(s)
+}
+
+// escapeMarkdownText also escapes "<", so that no autolink is formed.
+func escapeMarkdownText(s string) string {
+	return strings.NewReplacer("[", `\[`, "]", `\]`, "<", `\<`).Replace(s)
+}
+
+// linkable reports whether a source URL may be used as a link target. Only
+// absolute http and https URLs are, which rules out javascript: and data:.
+func linkable(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// plainRef is the text shown for a source that is not linked.
+func plainRef(s linkup.AnswerSource) string {
+	if label := s.Label(); label != s.URL {
+		return label + " - " + s.URL
+	}
+	return s.URL
+}
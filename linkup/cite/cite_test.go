@@ -0,0 +1,112 @@
+package cite
+
+import (
+	"strings"
+	"testing"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+)
+
+var answer = linkup.SourcedAnswer{
+	Answer: "Go 1.23 added range-over-func [1]. Iterators live in the iter package [1, 2]. See [docs](https://go.dev) & more [4].",
+	Sources: []linkup.AnswerSource{
+		{Name: "Go 1.23 Release Notes", URL: "https://go.dev/doc/go1.23"},
+		{Name: "iter package", URL: "https://pkg.go.dev/iter"},
+		{URL: "https://example.com/unused"},
+	},
+}
+
+func TestValidate(t *testing.T) {
+	r := Validate(answer)
+	if r.Citations != 4 {
+		t.Fatalf("citations = %d", r.Citations)
+	}
+	if len(r.Orphans) != 1 || r.Orphans[0] != 4 || r.Valid() || r.Error() == nil {
+		t.Fatalf("orphans = %v", r.Orphans)
+	}
+	if len(r.Uncited) != 1 || r.Uncited[0] != 3 {
+		t.Fatalf("uncited = %v", r.Uncited)
+	}
+}
+
+func TestRender_Markdown(t *testing.T) {
+	out, _ := Render(answer, Markdown)
+	for _, want := range []string{
+		"range-over-func [^1].",
+		"package [^1][^2].",
+		"more [4].",
+		"[docs](https://go.dev)",
+		"[^2]: [iter package](https://pkg.go.dev/iter)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestRender_HTML(t *testing.T) {
+	out, _ := Render(answer, HTML)
+	for _, want := range []string{
+		`<sup id="ref-1-1"><a href="#cite-1">[1]</a></sup>`,
+		`<sup id="ref-1-2"><a href="#cite-1">[1]</a></sup>`,
+		`&amp; more [4].`,
+		`<li id="cite-3"><a href="https://example.com/unused">https://example.com/unused</a></li>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestRender_UnsafeURLs(t *testing.T) {
+	a := linkup.SourcedAnswer{
+		Answer: "x [1][2][3]",
+		Sources: []linkup.AnswerSource{
+			{Name: "Evil", URL: "JavaScript:alert(1)"},
+			{URL: "data:text/html,<script>alert(1)</script>"},
+			{Name: "OK", URL: "HTTPS://example.com/a"},
+		},
+	}
+	out, _ := Render(a, HTML)
+	for _, want := range []string{
+		`<li id="cite-1">Evil - JavaScript:alert(1)</li>`,
+		`<li id="cite-2">data:text/html,&lt;script&gt;alert(1)&lt;/script&gt;</li>`,
+		`<li id="cite-3"><a href="HTTPS://example.com/a">OK</a></li>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	out, _ = Render(a, Markdown)
+	for _, want := range []string{
+		"[^1]: Evil - JavaScript:alert(1)\n",
+		`[^2]: data:text/html,\<script>alert(1)\</script>`,
+		"[^3]: [OK](HTTPS://example.com/a)\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestRender_Text(t *testing.T) {
+	out, rep := Render(answer, Text)
+	if !strings.HasPrefix(out, answer.Answer) || !strings.Contains(out, "[1] Go 1.23 Release Notes - https://go.dev/doc/go1.23") {
+		t.Fatalf("unexpected:\n%s", out)
+	}
+	if rep.Valid() {
+		t.Fatal("expected orphan to be reported")
+	}
+}
+
+func TestRenumber(t *testing.T) {
+	got := Renumber("a [1] b [2, 1] c [x](https://e.com) [3]", func(n int) int { return n + 10 })
+	if want := "a [11] b [12, 11] c [x](https://e.com) [13]"; got != want {
+		t.Fatalf("got %q", got)
+	}
+	// Mapping to 0 drops a reference, and an empty marker with its spaces.
+	got = Renumber("a [1]. b [2, 3]. c [2].", func(n int) int { return []int{0, 0, 1, 2}[n] })
+	if want := "a. b [1, 2]. c [1]."; got != want {
+		t.Fatalf("got %q", got)
+	}
+}
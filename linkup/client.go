@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
 	"strconv"
 	"strings"
@@ -20,13 +21,23 @@ const (
 
 // Client is a minimal HTTP client for Linkup Search API.
 type Client struct {
-	apiKey    string
-	baseURL   string
-	ua        string
-	http      *http.Client
+	apiKey     string
+	baseURL    string
+	ua         string
+	http       *http.Client
 	maxRetries int
 	minBackoff time.Duration
 	maxBackoff time.Duration
+	recorder   Recorder
+	offline    bool
+	fuzzy      float64
+	local      *LocalFallback
+
+	domainLimit int
+	domainLogf  func(format string, args ...any)
+
+	filter       ResultFilter
+	filterReport func(context.Context, FilterReport)
 }
 
 // Option configures the Client.
@@ -98,24 +109,24 @@ const (
 type OutputType string
 
 const (
-	OutputSourcedAnswer  OutputType = "sourcedAnswer"
-	OutputSearchResults  OutputType = "searchResults"
-	OutputStructured     OutputType = "structured"
+	OutputSourcedAnswer OutputType = "sourcedAnswer"
+	OutputSearchResults OutputType = "searchResults"
+	OutputStructured    OutputType = "structured"
 )
 
 // SearchRequest models the request body for /search.
 type SearchRequest struct {
-	Q                      string    `json:"q"`
-	Depth                  Depth     `json:"depth"`                 // "standard" | "deep"
-	OutputType             OutputType`json:"outputType"`            // "sourcedAnswer" | "searchResults" | "structured"
-	IncludeImages          bool      `json:"includeImages,omitempty"`
-	FromDate               string    `json:"fromDate,omitempty"`       // YYYY-MM-DD
-	ToDate                 string    `json:"toDate,omitempty"`         // YYYY-MM-DD
-	ExcludeDomains         []string  `json:"excludeDomains,omitempty"` // e.g. ["wikipedia.com"]
-	IncludeDomains         []string  `json:"includeDomains,omitempty"` // e.g. ["microsoft.com"]
-	IncludeInlineCitations bool      `json:"includeInlineCitations,omitempty"`
-	StructuredOutputSchema *string   `json:"structuredOutputSchema,omitempty"`
-	IncludeSources         bool      `json:"includeSources,omitempty"`
+	Q                      string     `json:"q"`
+	Depth                  Depth      `json:"depth"`      // "standard" | "deep"
+	OutputType             OutputType `json:"outputType"` // "sourcedAnswer" | "searchResults" | "structured"
+	IncludeImages          bool       `json:"includeImages,omitempty"`
+	FromDate               string     `json:"fromDate,omitempty"`       // YYYY-MM-DD
+	ToDate                 string     `json:"toDate,omitempty"`         // YYYY-MM-DD
+	ExcludeDomains         []string   `json:"excludeDomains,omitempty"` // e.g. ["wikipedia.com"]
+	IncludeDomains         []string   `json:"includeDomains,omitempty"` // e.g. ["microsoft.com"]
+	IncludeInlineCitations bool       `json:"includeInlineCitations,omitempty"`
+	StructuredOutputSchema *string    `json:"structuredOutputSchema,omitempty"`
+	IncludeSources         bool       `json:"includeSources,omitempty"`
 }
 
 // APIError models an error payload from the API, if any.
@@ -153,43 +164,75 @@ func (r SearchResponse) DecodeInto(v any) error {
 
 // Search calls POST /search and returns the raw JSON payload for maximum flexibility.
 func (c *Client) Search(ctx context.Context, req SearchRequest) (SearchResponse, error) {
+	resp, err := c.search(ctx, req)
+	if err != nil || c.filter == nil {
+		return resp, err
+	}
+	return c.filterResponse(ctx, req, resp)
+}
+
+// search is Search before the result filter.
+func (c *Client) search(ctx context.Context, req SearchRequest) (SearchResponse, error) {
+	if c.overDomainLimit(req) {
+		return c.searchChunked(ctx, req)
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return SearchResponse{}, err
+	}
+	if c.offline {
+		return c.replay(ctx, OpSearch, req.Q, body)
+	}
 	if c.apiKey == "" {
 		return SearchResponse{}, errors.New("linkup: API key is empty")
 	}
-	body, err := json.Marshal(req)
+	start := time.Now()
+	b, err := c.do(ctx, http.MethodPost, "/search", body)
 	if err != nil {
 		return SearchResponse{}, err
 	}
+	c.record(ctx, OpSearch, start, body, b, SearchCost(req))
+	return SearchResponse{Raw: b}, nil
+}
 
-	url := c.baseURL + "/search"
-		retries := c.maxRetries
+// do sends a request to path and returns the 2xx response body. Transient
+// network errors, 429 and 5xx are retried with backoff, honoring Retry-After.
+func (c *Client) do(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	url := c.baseURL + path
+	retries := c.maxRetries
 
 	for attempt := 0; ; attempt++ {
-		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		var rd io.Reader
+		if body != nil {
+			rd = bytes.NewReader(body)
+		}
+		httpReq, err := http.NewRequestWithContext(ctx, method, url, rd)
 		if err != nil {
-			return SearchResponse{}, err
+			return nil, err
 		}
 		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-		httpReq.Header.Set("Content-Type", "application/json")
+		if body != nil {
+			httpReq.Header.Set("Content-Type", "application/json")
+		}
 		httpReq.Header.Set("User-Agent", c.ua)
 
 		res, err := c.http.Do(httpReq)
 		if err != nil {
 			// Only retry transient network issues.
-			if attempt < retries {
-				sleep := backoff(attempt, c.minBackoff, c.maxBackoff)
-				time.Sleep(sleep)
-								continue
+			if attempt < retries && ctx.Err() == nil {
+				if err := sleepCtx(ctx, backoff(attempt, c.minBackoff, c.maxBackoff)); err != nil {
+					return nil, err
+				}
+				continue
 			}
-			return SearchResponse{}, err
+			return nil, err
 		}
 
-		defer res.Body.Close()
-
 		// Handle non-2xx
 		if res.StatusCode < 200 || res.StatusCode >= 300 {
 			// Read body (bounded) to attempt decoding API error.
 			b, _ := io.ReadAll(io.LimitReader(res.Body, 1<<20)) // 1 MiB
+			res.Body.Close()
 			apiErr := &APIError{Status: res.StatusCode}
 			_ = json.Unmarshal(b, apiErr)
 
@@ -197,40 +240,51 @@ func (c *Client) Search(ctx context.Context, req SearchRequest) (SearchResponse,
 			shouldRetry := res.StatusCode == http.StatusTooManyRequests || (res.StatusCode >= 500 && res.StatusCode <= 599)
 			if shouldRetry && attempt < retries {
 				// Honor Retry-After if present.
+				sleep := backoff(attempt, c.minBackoff, c.maxBackoff)
 				if ra := res.Header.Get("Retry-After"); ra != "" {
 					if secs, err := strconv.Atoi(ra); err == nil && secs > 0 {
-						time.Sleep(time.Duration(secs) * time.Second)
-					} else {
-						time.Sleep(backoff(attempt, c.minBackoff, c.maxBackoff))
+						sleep = time.Duration(secs) * time.Second
 					}
-				} else {
-					time.Sleep(backoff(attempt, c.minBackoff, c.maxBackoff))
 				}
-								continue
+				if err := sleepCtx(ctx, sleep); err != nil {
+					return nil, err
+				}
+				continue
 			}
 
 			switch res.StatusCode {
 			case http.StatusUnauthorized:
-				return SearchResponse{}, ErrUnauthorized
+				return nil, ErrUnauthorized
 			case http.StatusForbidden:
-				return SearchResponse{}, ErrForbidden
+				return nil, ErrForbidden
 			default:
 				if apiErr.Message != "" {
-					return SearchResponse{}, apiErr
+					return nil, apiErr
 				}
-				return SearchResponse{}, fmt.Errorf("linkup: http %d", res.StatusCode)
+				return nil, fmt.Errorf("linkup: http %d", res.StatusCode)
 			}
 		}
 
 		// Success
 		b, err := io.ReadAll(res.Body)
+		res.Body.Close()
 		if err != nil {
-			return SearchResponse{}, err
+			return nil, err
 		}
-		return SearchResponse{Raw: append([]byte(nil), b...)}, nil
+		return b, nil
+	}
+}
+
+// sleepCtx sleeps for d or until ctx is done.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	// unreachable
-	
 }
 
 // SearchStructured calls c.Search and decodes into a typed struct.
@@ -254,19 +308,10 @@ func backoff(attempt int, min, max time.Duration) time.Duration {
 		d = max
 	}
 	// jitter +/- 20%
-	j := time.Duration(float64(d) * (0.8 + 0.4*randFloat()))
+	j := time.Duration(float64(d) * (0.8 + 0.4*rand.Float64()))
 	return j
 }
 
-// randFloat returns [0,1). Simple LCG to avoid extra deps and keep deterministic-ish behavior per process.
-var lcg = uint64(time.Now().UnixNano())
-
-func randFloat() float64 {
-	lcg = lcg*2862933555777941757 + 3037000493
-	return float64(lcg%10000) / 10000.0
-}
-
-
 // FetchRequest models POST /fetch.
 type FetchRequest struct {
 	URL            string `json:"url"`
@@ -277,9 +322,6 @@ type FetchRequest struct {
 
 // Fetch calls POST /fetch and returns raw JSON (usually includes markdown).
 func (c *Client) Fetch(ctx context.Context, req FetchRequest) (SearchResponse, error) {
-	if c.apiKey == "" {
-		return SearchResponse{}, errors.New("linkup: API key is empty")
-	}
 	if req.URL == "" {
 		return SearchResponse{}, errors.New("linkup: fetch url is empty")
 	}
@@ -287,41 +329,22 @@ func (c *Client) Fetch(ctx context.Context, req FetchRequest) (SearchResponse, e
 	if err != nil {
 		return SearchResponse{}, err
 	}
-	url := c.baseURL + "/fetch"
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
-	if err != nil {
-		return SearchResponse{}, err
+	if c.offline {
+		return c.replay(ctx, OpFetch, req.URL, body)
 	}
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("User-Agent", c.ua)
-
-	res, err := c.http.Do(httpReq)
-	if err != nil {
-		return SearchResponse{}, err
-	}
-	defer res.Body.Close()
-
-	if res.StatusCode == http.StatusUnauthorized {
-		return SearchResponse{}, ErrUnauthorized
-	}
-	if res.StatusCode == http.StatusForbidden {
-		return SearchResponse{}, ErrForbidden
-	}
-	if res.StatusCode < 200 || res.StatusCode >= 300 {
-		b, _ := io.ReadAll(io.LimitReader(res.Body, 1<<20))
-		apiErr := &APIError{Status: res.StatusCode}
-		_ = json.Unmarshal(b, apiErr)
-		if apiErr.Message != "" {
-			return SearchResponse{}, apiErr
-		}
-		return SearchResponse{}, fmt.Errorf("linkup: http %d", res.StatusCode)
+	if c.apiKey == "" {
+		return SearchResponse{}, errors.New("linkup: API key is empty")
 	}
-	b, err := io.ReadAll(res.Body)
+	start := time.Now()
+	b, err := c.do(ctx, http.MethodPost, "/fetch", body)
 	if err != nil {
+		if c.local != nil && ctx.Err() == nil && c.local.When(err) {
+			return c.fetchLocal(ctx, req, body, err)
+		}
 		return SearchResponse{}, err
 	}
-	return SearchResponse{Raw: append([]byte(nil), b...)}, nil
+	c.record(ctx, OpFetch, start, body, b, FetchCost(req))
+	return SearchResponse{Raw: b}, nil
 }
 
 // BalanceResponse models GET /credits/balance response.
@@ -331,40 +354,18 @@ type BalanceResponse struct {
 
 // GetBalance calls GET /credits/balance and returns credits balance.
 func (c *Client) GetBalance(ctx context.Context) (BalanceResponse, error) {
+	if c.offline {
+		return BalanceResponse{}, &NotCachedError{Op: "balance"}
+	}
 	if c.apiKey == "" {
 		return BalanceResponse{}, errors.New("linkup: API key is empty")
 	}
-	url := c.baseURL + "/credits/balance"
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	b, err := c.do(ctx, http.MethodGet, "/credits/balance", nil)
 	if err != nil {
 		return BalanceResponse{}, err
 	}
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-	httpReq.Header.Set("User-Agent", c.ua)
-
-	res, err := c.http.Do(httpReq)
-	if err != nil {
-		return BalanceResponse{}, err
-	}
-	defer res.Body.Close()
-
-	if res.StatusCode == http.StatusUnauthorized {
-		return BalanceResponse{}, ErrUnauthorized
-	}
-	if res.StatusCode == http.StatusForbidden {
-		return BalanceResponse{}, ErrForbidden
-	}
-	if res.StatusCode < 200 || res.StatusCode >= 300 {
-		b, _ := io.ReadAll(io.LimitReader(res.Body, 1<<20))
-		apiErr := &APIError{Status: res.StatusCode}
-		_ = json.Unmarshal(b, apiErr)
-		if apiErr.Message != "" {
-			return BalanceResponse{}, apiErr
-		}
-		return BalanceResponse{}, fmt.Errorf("linkup: http %d", res.StatusCode)
-	}
 	var out BalanceResponse
-	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+	if err := json.Unmarshal(b, &out); err != nil {
 		return BalanceResponse{}, err
 	}
 	return out, nil
@@ -3,9 +3,12 @@ package linkup
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -243,3 +246,71 @@ func TestSearch_MissingKey(t *testing.T) {
 		t.Fatal("expected error for empty key")
 	}
 }
+
+type stubLookup struct{ calls []LookupRequest }
+
+func (s *stubLookup) Record(context.Context, Call) {}
+
+func (s *stubLookup) Lookup(_ context.Context, req LookupRequest) (Call, error) {
+	s.calls = append(s.calls, req)
+	if req.Op == OpSearch {
+		return Call{Op: OpSearch, Response: []byte(`{"answer":"stored"}`)}, nil
+	}
+	return Call{}, ErrNotCached
+}
+
+func TestOfflineMode(t *testing.T) {
+	var hits int32
+	_, srv := newTestClient(t, func(w http.ResponseWriter, r *http.Request) { atomic.AddInt32(&hits, 1) })
+	defer srv.Close()
+	store := &stubLookup{}
+	// No API key: offline calls never need one.
+	c := NewClient("", WithBaseURL(srv.URL), WithRecorder(store), WithOfflineMode(), WithFuzzyMatch(0.5))
+	ctx := context.Background()
+
+	resp, err := c.Search(ctx, SearchRequest{Q: "hello", OutputType: OutputSourcedAnswer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, _ := resp.SourcedAnswer(); a.Answer != "stored" {
+		t.Fatalf("resp = %s", resp.Raw)
+	}
+	if len(store.calls) != 1 || store.calls[0].MinSimilarity != 0.5 || !strings.Contains(string(store.calls[0].Request), `"q":"hello"`) {
+		t.Fatalf("lookup = %+v", store.calls)
+	}
+
+	_, err = c.Fetch(ctx, FetchRequest{URL: "https://example.com"})
+	var nc *NotCachedError
+	if !errors.Is(err, ErrNotCached) || !errors.As(err, &nc) || nc.Op != OpFetch || nc.Key != "https://example.com" {
+		t.Fatalf("fetch err = %v", err)
+	}
+	if _, err := c.GetBalance(ctx); !errors.Is(err, ErrNotCached) {
+		t.Fatalf("balance err = %v", err)
+	}
+	// Without a Lookup every call misses.
+	if _, err := NewClient("", WithOfflineMode()).Search(ctx, SearchRequest{Q: "x"}); !errors.Is(err, ErrNotCached) {
+		t.Fatalf("err = %v", err)
+	}
+	if hits != 0 {
+		t.Fatalf("offline client made %d requests", hits)
+	}
+}
+
+func TestBackoff_JitterBoundsConcurrent(t *testing.T) {
+	// Clients retry from many goroutines; run with -race.
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for attempt := range 4 {
+				d := backoff(attempt, 100*time.Millisecond, time.Second)
+				base := min(100*time.Millisecond<<attempt, time.Second)
+				if lo, hi := base*8/10, base*12/10; d < lo || d > hi {
+					t.Errorf("backoff(%d) = %v, want within [%v, %v]", attempt, d, lo, hi)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
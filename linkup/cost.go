@@ -0,0 +1,27 @@
+package linkup
+
+// Approximate list prices per call, in credits (Linkup credits map 1:1 to
+// euros). They are estimates for budgeting and quotas; the balance reported
+// by GetBalance is authoritative.
+var (
+	CostStandardSearch = 0.005
+	CostDeepSearch     = 0.05
+	CostFetch          = 0.001
+	CostFetchRenderJS  = 0.005
+)
+
+// SearchCost estimates the credits a Search with req will consume.
+func SearchCost(req SearchRequest) float64 {
+	if req.Depth == DepthDeep {
+		return CostDeepSearch
+	}
+	return CostStandardSearch
+}
+
+// FetchCost estimates the credits a Fetch with req will consume.
+func FetchCost(req FetchRequest) float64 {
+	if req.RenderJS {
+		return CostFetchRenderJS
+	}
+	return CostFetch
+}
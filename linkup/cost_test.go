@@ -0,0 +1,12 @@
+package linkup
+
+import "testing"
+
+func TestCostEstimates(t *testing.T) {
+	if SearchCost(SearchRequest{Depth: DepthDeep}) <= SearchCost(SearchRequest{Depth: DepthStandard}) {
+		t.Fatal("deep search should cost more than standard")
+	}
+	if FetchCost(FetchRequest{RenderJS: true}) <= FetchCost(FetchRequest{}) {
+		t.Fatal("JS rendering should cost more")
+	}
+}
@@ -0,0 +1,511 @@
+// Package crawl ingests whole sites through Client.Fetch. Starting from
+// seed URLs it fetches each page, extracts links from the returned
+// markdown (and raw HTML, when requested) and follows those inside the
+// configured scopes, breadth first, up to a depth and page limit. It obeys
+// robots.txt, spaces requests to the same host by a politeness delay, and
+// journals its progress so an interrupted crawl resumes where it stopped.
+package crawl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/canonical"
+	"github.com/raezil/linkup-go/linkup/robots"
+)
+
+// DefaultUserAgent is the robots.txt product token used when
+// Config.UserAgent is empty.
+const DefaultUserAgent = "linkup-go"
+
+// Scope is a host and path prefix a crawl may follow links into.
+type Scope struct {
+	Host       string // compared after canonicalization, so www. is optional
+	PathPrefix string // default "/"
+}
+
+// ParseScope parses "host" or "host/path/prefix" (a scheme is ignored).
+func ParseScope(s string) (Scope, error) {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "://"); i >= 0 {
+		s = s[i+3:]
+	}
+	host, path, _ := strings.Cut(s, "/")
+	if host == "" {
+		return Scope{}, fmt.Errorf("crawl: scope %q has no host", s)
+	}
+	return Scope{Host: host, PathPrefix: "/" + path}, nil
+}
+
+func (s Scope) String() string { return s.Host + s.prefix() }
+
+func (s Scope) prefix() string {
+	if s.PathPrefix == "" {
+		return "/"
+	}
+	return s.PathPrefix
+}
+
+// Contains reports whether rawURL is inside s.
+func (s Scope) Contains(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || canonical.Host(rawURL) != canonical.Host("https://"+s.Host) {
+		return false
+	}
+	p := u.Path
+	if p == "" {
+		p = "/"
+	}
+	return strings.HasPrefix(p, s.prefix())
+}
+
+// seedScope is the default scope of a seed: its host and directory, so
+// https://example.com/docs/intro covers https://example.com/docs/.
+func seedScope(seed string) (Scope, error) {
+	u, err := url.Parse(seed)
+	if err != nil || u.Host == "" {
+		return Scope{}, fmt.Errorf("crawl: bad seed %q", seed)
+	}
+	p := u.Path
+	if i := strings.LastIndexByte(p, '/'); i >= 0 {
+		p = p[:i+1]
+	} else {
+		p = "/"
+	}
+	return Scope{Host: u.Host, PathPrefix: p}, nil
+}
+
+// Config configures a Crawler.
+type Config struct {
+	Seeds []string
+	// Scopes limit which links are followed. Default: the host and
+	// directory of each seed. Seeds are crawled even when out of scope.
+	Scopes []Scope
+	// MaxDepth is how many links away from a seed to go; 0 means no limit.
+	MaxDepth int
+	// NoFollow fetches only the seeds, e.g. URLs listed by a sitemap.
+	NoFollow bool
+	// MaxPages caps the pages fetched, counting pages from earlier runs
+	// of a resumed crawl. Default 1000.
+	MaxPages int
+	// Delay is the minimum time between requests to the same host. A
+	// larger robots.txt Crawl-delay wins. Default 1s.
+	Delay time.Duration
+	// Concurrency is how many hosts are fetched in parallel; each host
+	// still sees one request at a time. Default 4.
+	Concurrency int
+	// IncludeRawHTML also requests raw HTML, whose links are extracted
+	// too. Pages whose navigation is dropped from the markdown need it.
+	IncludeRawHTML bool
+	RenderJS       bool
+	// IgnoreRobots skips robots.txt.
+	IgnoreRobots bool
+	// UserAgent is matched against robots.txt groups and sent when
+	// fetching it. Default DefaultUserAgent.
+	UserAgent string
+	// HTTPClient fetches robots.txt. Default: a client with a 10s timeout.
+	HTTPClient *http.Client
+	// Sink receives every fetched page. Required.
+	Sink Sink
+	// StatePath is a journal of queued and finished URLs. When it exists
+	// the crawl resumes from it; pages already written are not fetched
+	// again. Empty disables resuming.
+	StatePath string
+	// Logf receives diagnostics. Default: discard.
+	Logf func(format string, args ...any)
+}
+
+// Page is a fetched page as written to the Sink.
+type Page struct {
+	URL       string    `json:"url"`
+	Depth     int       `json:"depth"`
+	Parent    string    `json:"parent,omitempty"` // the page it was found on
+	Title     string    `json:"title,omitempty"`
+	Markdown  string    `json:"markdown"`
+	RawHTML   string    `json:"rawHtml,omitempty"`
+	Links     []string  `json:"links,omitempty"` // every link found, in or out of scope
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// Stats summarizes a run.
+type Stats struct {
+	Pages   int     `json:"pages"`   // fetched in this run
+	Resumed int     `json:"resumed"` // fetched by earlier runs
+	Failed  int     `json:"failed"`
+	Skipped int     `json:"skipped"` // disallowed by robots.txt
+	Pending int     `json:"pending"` // queued but not fetched (limit, interruption or robots.txt unavailable)
+	Credits float64 `json:"credits"` // estimated cost of this run
+}
+
+// Crawler crawls sites through a Client.
+type Crawler struct {
+	client *linkup.Client
+	cfg    Config
+	robots *robots.Fetcher
+}
+
+// New validates cfg and returns a Crawler.
+func New(client *linkup.Client, cfg Config) (*Crawler, error) {
+	if cfg.Sink == nil {
+		return nil, errors.New("crawl: Config.Sink is required")
+	}
+	if len(cfg.Seeds) == 0 {
+		return nil, errors.New("crawl: no seeds")
+	}
+	for _, s := range cfg.Seeds {
+		u, err := url.Parse(s)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("crawl: seed %q is not an http(s) URL", s)
+		}
+	}
+	if len(cfg.Scopes) == 0 && !cfg.NoFollow {
+		for _, s := range cfg.Seeds {
+			sc, err := seedScope(s)
+			if err != nil {
+				return nil, err
+			}
+			cfg.Scopes = append(cfg.Scopes, sc)
+		}
+	}
+	if cfg.MaxPages <= 0 {
+		cfg.MaxPages = 1000
+	}
+	if cfg.Delay <= 0 {
+		cfg.Delay = time.Second
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = DefaultUserAgent
+	}
+	if cfg.Logf == nil {
+		cfg.Logf = func(string, ...any) {}
+	}
+	return &Crawler{
+		client: client,
+		cfg:    cfg,
+		robots: &robots.Fetcher{Client: cfg.HTTPClient, UserAgent: cfg.UserAgent},
+	}, nil
+}
+
+// inScope reports whether a link should be followed.
+func (c *Crawler) inScope(rawURL string) bool {
+	for _, s := range c.cfg.Scopes {
+		if s.Contains(rawURL) {
+			return true
+		}
+	}
+	return false
+}
+
+type item struct {
+	url    string
+	depth  int
+	parent string
+}
+
+type hostState struct {
+	queue []item
+	next  time.Time // earliest time of the next request
+	busy  bool
+	rules *robots.Rules
+	// robotsFailures counts failed robots.txt fetches in a row; past
+	// robotsRetries the host is stalled and its queue is left for the next
+	// run.
+	robotsFailures int
+	stalled        bool
+}
+
+// robotsRetries and robotsBackoff bound the retries of a robots.txt that
+// fails with a 5xx or a network error: the wait doubles from robotsBackoff.
+var (
+	robotsRetries = 3
+	robotsBackoff = 5 * time.Second
+)
+
+type result struct {
+	it        item
+	host      string
+	page      *Page
+	rules     *robots.Rules // set when robots.txt was fetched
+	robotsErr error         // set when fetching robots.txt failed
+	requested bool          // whether any request was made
+	skipped   bool
+	err       error
+}
+
+// Run crawls until every in-scope page is fetched, MaxPages is reached or
+// ctx is done. Pages already fetched by an earlier run with the same
+// StatePath are not fetched again. Errors fetching single pages are
+// counted in Stats.Failed and logged; Run fails on Sink and journal
+// errors, on authorization errors, and with ctx.Err() when interrupted.
+func (c *Crawler) Run(ctx context.Context) (Stats, error) {
+	var (
+		st      Stats
+		jr      *journal
+		entries []journalEntry
+		err     error
+	)
+	if c.cfg.StatePath != "" {
+		if jr, entries, err = openJournal(c.cfg.StatePath); err != nil {
+			return st, err
+		}
+		defer jr.close()
+	}
+
+	hosts := map[string]*hostState{}
+	var order []string // hosts in first-seen order, for fair, stable dispatch
+	known := map[string]bool{}
+	push := func(it item) {
+		h := hostKey(it.url)
+		hs := hosts[h]
+		if hs == nil {
+			hs = &hostState{}
+			hosts[h] = hs
+			order = append(order, h)
+		}
+		hs.queue = append(hs.queue, it)
+	}
+	for _, e := range entries {
+		known[canonical.Key(e.URL)] = true
+		switch e.State {
+		case stateQueued:
+			push(item{url: e.URL, depth: e.Depth, parent: e.Parent})
+		case stateDone:
+			st.Resumed++
+		}
+	}
+	for _, s := range c.cfg.Seeds {
+		if k := canonical.Key(s); !known[k] {
+			known[k] = true
+			if err := jr.add(journalEntry{URL: s, State: stateQueued}); err != nil {
+				return st, err
+			}
+			push(item{url: s})
+		}
+	}
+	if st.Resumed > 0 {
+		c.cfg.Logf("crawl: resuming after %d pages", st.Resumed)
+	}
+
+	results := make(chan result)
+	inflight := 0
+	var fatal error
+	for {
+		now := time.Now()
+		var wake time.Time
+		if ctx.Err() == nil && fatal == nil {
+			for _, h := range order {
+				hs := hosts[h]
+				if inflight >= c.cfg.Concurrency || st.Resumed+st.Pages+inflight >= c.cfg.MaxPages {
+					break
+				}
+				if hs.busy || hs.stalled || len(hs.queue) == 0 {
+					continue
+				}
+				if hs.next.After(now) {
+					if wake.IsZero() || hs.next.Before(wake) {
+						wake = hs.next
+					}
+					continue
+				}
+				it := hs.queue[0]
+				hs.queue = hs.queue[1:]
+				hs.busy = true
+				inflight++
+				go func(h string, rules *robots.Rules) {
+					results <- c.fetch(ctx, h, rules, it)
+				}(h, hs.rules)
+			}
+		}
+		if inflight == 0 && wake.IsZero() {
+			break
+		}
+
+		var (
+			t     *time.Timer
+			timer <-chan time.Time
+		)
+		if !wake.IsZero() {
+			t = time.NewTimer(time.Until(wake))
+			timer = t.C
+		}
+		select {
+		case r := <-results:
+			inflight--
+			if err := c.handle(ctx, r, hosts[r.host], &st, known, push, jr); err != nil && fatal == nil {
+				fatal = err
+			}
+		case <-timer:
+		case <-ctx.Done():
+		}
+		if t != nil {
+			t.Stop()
+		}
+		if ctx.Err() != nil && inflight == 0 {
+			break
+		}
+	}
+	for _, hs := range hosts {
+		st.Pending += len(hs.queue)
+	}
+	if fatal != nil {
+		return st, fatal
+	}
+	return st, ctx.Err()
+}
+
+// hostKey groups URLs for politeness: the scheme-less host with port.
+func hostKey(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		return strings.ToLower(u.Host)
+	}
+	return ""
+}
+
+// fetch runs in its own goroutine and must not touch Crawler state.
+func (c *Crawler) fetch(ctx context.Context, host string, rules *robots.Rules, it item) result {
+	r := result{it: it, host: host}
+	if !c.cfg.IgnoreRobots {
+		if rules == nil {
+			var err error
+			r.requested = true
+			if rules, err = c.robots.Get(ctx, it.url); err != nil {
+				// The host's rules are unknown: handle retries later.
+				r.robotsErr = err
+				return r
+			}
+			r.rules = rules
+		}
+		if !rules.AllowedURL(c.cfg.UserAgent, it.url) {
+			r.skipped = true
+			return r
+		}
+	}
+	req := linkup.FetchRequest{URL: it.url, IncludeRawHTML: c.cfg.IncludeRawHTML, RenderJS: c.cfg.RenderJS}
+	r.requested = true
+	resp, err := c.client.Fetch(ctx, req)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	fr, err := resp.Page()
+	if err != nil {
+		r.err = err
+		return r
+	}
+	fr.Extract(it.url)
+	r.page = &Page{
+		URL:       it.url,
+		Depth:     it.depth,
+		Parent:    it.parent,
+		Title:     fr.Title,
+		Markdown:  fr.Markdown,
+		RawHTML:   fr.RawHTML,
+		Links:     ExtractLinks(it.url, fr.Markdown, fr.RawHTML),
+		FetchedAt: time.Now(),
+	}
+	return r
+}
+
+// handle records a finished fetch and queues the page's in-scope links.
+func (c *Crawler) handle(ctx context.Context, r result, hs *hostState, st *Stats, known map[string]bool, push func(item), jr *journal) error {
+	hs.busy = false
+	if r.rules != nil {
+		hs.rules, hs.robotsFailures = r.rules, 0
+	}
+	if r.requested {
+		delay := c.cfg.Delay
+		if hs.rules != nil {
+			delay = max(delay, hs.rules.CrawlDelay(c.cfg.UserAgent))
+		}
+		hs.next = time.Now().Add(delay)
+	}
+	entry := journalEntry{URL: r.it.url, Depth: r.it.depth, Parent: r.it.parent}
+
+	switch {
+	case r.robotsErr != nil:
+		// Keep the URL queued; hs.rules stays nil, so robots.txt is fetched
+		// again before the host's next page.
+		hs.queue = append([]item{r.it}, hs.queue...)
+		if ctx.Err() != nil {
+			return nil
+		}
+		hs.robotsFailures++
+		if hs.robotsFailures > robotsRetries {
+			hs.stalled = true
+			c.cfg.Logf("crawl: %s: %v; leaving its %d URLs queued for the next run", r.host, r.robotsErr, len(hs.queue))
+			return nil
+		}
+		wait := robotsBackoff << (hs.robotsFailures - 1)
+		hs.next = time.Now().Add(max(wait, c.cfg.Delay))
+		c.cfg.Logf("crawl: %s: %v; retrying in %v", r.host, r.robotsErr, wait)
+		return nil
+	case r.skipped:
+		st.Skipped++
+		c.cfg.Logf("crawl: robots.txt disallows %s", r.it.url)
+		entry.State = stateSkipped
+		return jr.add(entry)
+	case r.err != nil:
+		if ctx.Err() != nil {
+			// Interrupted: leave it queued for the next run.
+			hs.queue = append([]item{r.it}, hs.queue...)
+			return nil
+		}
+		st.Failed++
+		c.cfg.Logf("crawl: %s: %v", r.it.url, r.err)
+		entry.State, entry.Error = stateFailed, r.err.Error()
+		if err := jr.add(entry); err != nil {
+			return err
+		}
+		return fatalErr(r.err)
+	}
+
+	// Written before it is journaled as done: after a crash the page may
+	// be written twice, but never lost.
+	if err := c.cfg.Sink.Write(r.page); err != nil {
+		return fmt.Errorf("crawl: write %s: %w", r.it.url, err)
+	}
+	st.Pages++
+	st.Credits += linkup.FetchCost(linkup.FetchRequest{URL: r.it.url, RenderJS: c.cfg.RenderJS})
+	c.cfg.Logf("crawl: %s (%d links)", r.it.url, len(r.page.Links))
+	entry.State = stateDone
+	if err := jr.add(entry); err != nil {
+		return err
+	}
+	if c.cfg.NoFollow || c.cfg.MaxDepth > 0 && r.it.depth >= c.cfg.MaxDepth {
+		return nil
+	}
+	for _, l := range r.page.Links {
+		k := canonical.Key(l)
+		if known[k] || !c.inScope(l) {
+			continue
+		}
+		known[k] = true
+		next := item{url: l, depth: r.it.depth + 1, parent: r.it.url}
+		if err := jr.add(journalEntry{URL: next.url, Depth: next.depth, Parent: next.parent, State: stateQueued}); err != nil {
+			return err
+		}
+		push(next)
+	}
+	return nil
+}
+
+// fatalErr returns err when no further fetch can succeed either.
+func fatalErr(err error) error {
+	var apiErr *linkup.APIError
+	switch {
+	case errors.Is(err, linkup.ErrUnauthorized), errors.Is(err, linkup.ErrForbidden):
+		return err
+	case errors.As(err, &apiErr) && apiErr.Status == http.StatusPaymentRequired:
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package crawl
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+)
+
+type memSink struct{ pages []*Page }
+
+func (s *memSink) Write(p *Page) error { s.pages = append(s.pages, p); return nil }
+func (s *memSink) Close() error        { return nil }
+
+func (s *memSink) paths() []string {
+	var out []string
+	for _, p := range s.pages {
+		u, _ := url.Parse(p.URL)
+		out = append(out, u.Path)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// newSite starts a site that only serves robots.txt, and a fake Linkup API
+// whose /fetch answers for the site's pages.
+func newSite(t *testing.T) (site string, client *linkup.Client, fetched func() []string) {
+	return newSiteWithRobots(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nDisallow: /docs/secret\n"))
+	})
+}
+
+// newSiteWithRobots is newSite with robots.txt served by robotsTxt.
+func newSiteWithRobots(t *testing.T, robotsTxt http.HandlerFunc) (site string, client *linkup.Client, fetched func() []string) {
+	t.Helper()
+	siteSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots.txt" {
+			t.Errorf("site request for %s", r.URL.Path)
+		}
+		robotsTxt(w, r)
+	}))
+	t.Cleanup(siteSrv.Close)
+	pages := map[string]string{
+		"/docs/":       "# Docs\n[A](a) [B](/docs/b) [Out](/blog/x) [Ext](https://other.example/) [Secret](secret) ![logo](/docs/logo.png)",
+		"/docs/a":      "# A\n[back](/docs/) [C](c#usage)",
+		"/docs/b":      "# B\n[C](" + siteSrv.URL + "/docs/c)",
+		"/docs/c":      "# C\nno links",
+		"/docs/secret": "# must not be fetched",
+	}
+	var (
+		mu  sync.Mutex
+		got []string
+	)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req linkup.FetchRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		u, _ := url.Parse(req.URL)
+		mu.Lock()
+		got = append(got, u.Path)
+		mu.Unlock()
+		md, ok := pages[u.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"not found"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(linkup.FetchResult{Markdown: md})
+	}))
+	t.Cleanup(api.Close)
+	client = linkup.NewClient("k", linkup.WithBaseURL(api.URL), linkup.WithRetry(0, 0, 0))
+	return siteSrv.URL, client, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), got...)
+	}
+}
+
+func TestRun(t *testing.T) {
+	site, client, fetched := newSite(t)
+	sink := &memSink{}
+	c, err := New(client, Config{Seeds: []string{site + "/docs/"}, Sink: sink, Delay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := c.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/docs/", "/docs/a", "/docs/b", "/docs/c"}; !reflect.DeepEqual(sink.paths(), want) {
+		t.Fatalf("pages = %v", sink.paths())
+	}
+	if st.Pages != 4 || st.Skipped != 1 || st.Failed != 0 || st.Pending != 0 {
+		t.Fatalf("stats = %+v", st)
+	}
+	if len(fetched()) != 4 {
+		t.Fatalf("fetched %v", fetched())
+	}
+	p := sink.pages[0]
+	if p.Title != "Docs" || p.Depth != 0 || len(p.Links) != 5 {
+		t.Fatalf("page = %+v", p)
+	}
+}
+
+func TestRun_RobotsUnavailable(t *testing.T) {
+	defer func(b time.Duration) { robotsBackoff = b }(robotsBackoff)
+	robotsBackoff = time.Millisecond
+	var failures atomic.Int32
+	failures.Store(2)
+	robotsTxt := func(w http.ResponseWriter, r *http.Request) {
+		if failures.Add(-1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("User-agent: *\nDisallow: /docs/secret\n"))
+	}
+
+	// Two 503s, then robots.txt: the crawl completes as usual.
+	site, client, _ := newSiteWithRobots(t, robotsTxt)
+	sink := &memSink{}
+	c, _ := New(client, Config{Seeds: []string{site + "/docs/"}, Sink: sink, Delay: time.Millisecond})
+	st, err := c.Run(context.Background())
+	if err != nil || st.Pages != 4 || st.Skipped != 1 || st.Pending != 0 {
+		t.Fatalf("stats = %+v, %v", st, err)
+	}
+
+	// Down for good: the seeds stay queued for the next run, none skipped.
+	failures.Store(100)
+	state := filepath.Join(t.TempDir(), "state.jsonl")
+	cfg := Config{Seeds: []string{site + "/docs/", site + "/docs/a"}, Sink: &memSink{}, Delay: time.Millisecond, StatePath: state}
+	c, _ = New(client, cfg)
+	st, err = c.Run(context.Background())
+	if err != nil || st.Pages != 0 || st.Skipped != 0 || st.Pending != 2 {
+		t.Fatalf("stats = %+v, %v", st, err)
+	}
+	if n := 100 - failures.Load(); n != int32(robotsRetries+1) {
+		t.Fatalf("robots.txt fetched %d times", n)
+	}
+	failures.Store(0)
+	sink = &memSink{}
+	cfg.Sink = sink
+	c, _ = New(client, cfg)
+	if st, err = c.Run(context.Background()); err != nil || st.Pages != 4 {
+		t.Fatalf("resumed stats = %+v, %v", st, err)
+	}
+}
+
+func TestRun_MaxDepth(t *testing.T) {
+	site, client, _ := newSite(t)
+	sink := &memSink{}
+	c, _ := New(client, Config{Seeds: []string{site + "/docs/"}, Sink: sink, Delay: time.Millisecond, MaxDepth: 1})
+	if _, err := c.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/docs/", "/docs/a", "/docs/b"}; !reflect.DeepEqual(sink.paths(), want) {
+		t.Fatalf("pages = %v", sink.paths())
+	}
+}
+
+func TestRun_NoFollow(t *testing.T) {
+	site, client, _ := newSite(t)
+	sink := &memSink{}
+	c, _ := New(client, Config{Seeds: []string{site + "/docs/a", site + "/docs/c"}, Sink: sink, Delay: time.Millisecond, NoFollow: true})
+	if _, err := c.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/docs/a", "/docs/c"}; !reflect.DeepEqual(sink.paths(), want) {
+		t.Fatalf("pages = %v", sink.paths())
+	}
+}
+
+func TestRun_Resume(t *testing.T) {
+	site, client, fetched := newSite(t)
+	state := filepath.Join(t.TempDir(), "state.jsonl")
+	cfg := Config{Seeds: []string{site + "/docs/"}, Delay: time.Millisecond, StatePath: state, MaxPages: 2}
+
+	first := &memSink{}
+	cfg.Sink = first
+	c, _ := New(client, cfg)
+	st, err := c.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Pages != 2 || st.Pending == 0 {
+		t.Fatalf("first run = %+v", st)
+	}
+
+	// A torn line from a crash is ignored.
+	f, _ := os.OpenFile(state, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString(`{"url":"http://x`)
+	f.Close()
+
+	second := &memSink{}
+	cfg.Sink, cfg.MaxPages = second, 0
+	c, _ = New(client, cfg)
+	if st, err = c.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if st.Resumed != 2 || st.Pages != 2 || st.Pending != 0 {
+		t.Fatalf("second run = %+v", st)
+	}
+	all := append(first.paths(), second.paths()...)
+	sort.Strings(all)
+	if want := []string{"/docs/", "/docs/a", "/docs/b", "/docs/c"}; !reflect.DeepEqual(all, want) {
+		t.Fatalf("pages = %v", all)
+	}
+	if len(fetched()) != 4 {
+		t.Fatalf("fetched %v", fetched())
+	}
+}
+
+func TestExtractLinks(t *testing.T) {
+	md := "See [the guide](guide.md \"Guide\"), <https://go.dev/doc>, [top](#top) and [mail](mailto:a@b.c).\n\n[ref]: /ref/page\n"
+	raw := `<base href="/v2/"><a class="x" href="api?a=1&amp;b=2">API</a> <a href='https://cdn.example/x.js'>js</a>`
+	got := ExtractLinks("https://example.com/docs/intro", md, raw)
+	want := []string{
+		"https://example.com/docs/guide.md",
+		"https://example.com/ref/page",
+		"https://go.dev/doc",
+		"https://example.com/v2/api?a=1&b=2",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("links =\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestScope(t *testing.T) {
+	sc, err := ParseScope("https://www.example.com/docs/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for u, want := range map[string]bool{
+		"https://example.com/docs/a":     true,
+		"http://www.example.com/docs/":   true,
+		"https://example.com/blog/":      false,
+		"https://docs.example.com/docs/": false,
+	} {
+		if sc.Contains(u) != want {
+			t.Errorf("Contains(%s) = %v", u, !want)
+		}
+	}
+}
+
+func TestPagePath(t *testing.T) {
+	for in, want := range map[string]string{
+		"https://example.com":              "example.com/index.md",
+		"https://example.com/docs/":        "example.com/docs/index.md",
+		"https://example.com/docs/intro":   "example.com/docs/intro.md",
+		"https://example.com/a/b.html":     "example.com/a/b.md",
+		"https://example.com/../etc/x":     "example.com/etc/x.md",
+		"https://example.com:8080/a%2Fb/c": "example.com_8080/a_b/c.md",
+	} {
+		got, err := PagePath(in)
+		if err != nil || got != filepath.FromSlash(want) {
+			t.Errorf("PagePath(%s) = %s, %v", in, got, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package crawl
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+)
+
+var (
+	mdLinkRe = regexp.MustCompile(`\]\(\s*<?([^)\s>]+)>?(?:\s+["'(][^)]*)?\)`)
+	mdAutoRe = regexp.MustCompile(`<(https?://[^>\s]+)>`)
+	mdRefRe  = regexp.MustCompile(`(?m)^\s{0,3}\[[^\]]+\]:\s*<?(\S+?)>?(?:\s|$)`)
+)
+
+// skipExt lists extensions of files that are not pages.
+var skipExt = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true, ".svg": true, ".ico": true,
+	".css": true, ".js": true, ".mjs": true, ".map": true, ".json": true, ".xml": true, ".rss": true,
+	".zip": true, ".gz": true, ".tgz": true, ".tar": true, ".7z": true, ".dmg": true, ".exe": true,
+	".mp3": true, ".mp4": true, ".webm": true, ".woff": true, ".woff2": true, ".ttf": true, ".eot": true,
+}
+
+// ExtractLinks returns the absolute http(s) links of a fetched page, from
+// its markdown and, when present, its raw HTML, without duplicates,
+// fragments or links back to the page itself. Links to images, scripts,
+// archives and similar non-page files are dropped.
+func ExtractLinks(pageURL, markdown, rawHTML string) []string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	var refs []string
+	for _, m := range mdLinkRe.FindAllStringSubmatch(markdown, -1) {
+		refs = append(refs, m[1])
+	}
+	for _, m := range mdRefRe.FindAllStringSubmatch(markdown, -1) {
+		refs = append(refs, m[1])
+	}
+	for _, m := range mdAutoRe.FindAllStringSubmatch(markdown, -1) {
+		refs = append(refs, m[1])
+	}
+	if rawHTML != "" {
+		// Already absolute, honoring <base>.
+		for _, l := range linkup.ExtractLinks(pageURL, rawHTML) {
+			refs = append(refs, l.URL)
+		}
+	}
+
+	self := *base
+	self.Fragment, self.RawFragment = "", ""
+	seen := map[string]bool{self.String(): true}
+	var out []string
+	for _, ref := range refs {
+		u, err := base.Parse(strings.TrimSpace(ref))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			continue
+		}
+		u.Fragment, u.RawFragment = "", ""
+		if skipExt[strings.ToLower(path.Ext(u.Path))] {
+			continue
+		}
+		s := u.String()
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,161 @@
+package crawl
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sink receives crawled pages. Write is never called concurrently.
+type Sink interface {
+	Write(p *Page) error
+	Close() error
+}
+
+// JSONLSink appends one JSON Page per line to a file. Opening an existing
+// file appends to it, so a resumed crawl continues the same output.
+type JSONLSink struct {
+	mu sync.Mutex
+	f  *os.File
+	w  *bufio.Writer
+}
+
+// OpenJSONL opens (or creates) path for appending.
+func OpenJSONL(path string) (*JSONLSink, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONLSink{f: f, w: bufio.NewWriter(f)}, nil
+}
+
+func (s *JSONLSink) Write(p *Page) error {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.w.Write(b)
+	s.w.WriteByte('\n')
+	// Flush per page so an interrupted crawl keeps what it paid for.
+	return s.w.Flush()
+}
+
+func (s *JSONLSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.w.Flush(); err != nil {
+		s.f.Close()
+		return err
+	}
+	return s.f.Close()
+}
+
+// DirSink writes each page as a markdown file under Dir, mirroring the
+// site's layout: https://example.com/docs/intro becomes
+// <Dir>/example.com/docs/intro.md and a path ending in "/" becomes
+// index.md. A short front matter block records the URL and fetch time.
+type DirSink struct {
+	Dir string
+}
+
+// NewDirSink creates dir if needed.
+func NewDirSink(dir string) (*DirSink, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DirSink{Dir: dir}, nil
+}
+
+func (s *DirSink) Write(p *Page) error {
+	rel, err := PagePath(p.URL)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(s.Dir, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "---\nurl: %s\n", p.URL)
+	if p.Title != "" {
+		fmt.Fprintf(&b, "title: %q\n", p.Title)
+	}
+	fmt.Fprintf(&b, "fetched: %s\n---\n\n", p.FetchedAt.UTC().Format(time.RFC3339))
+	b.WriteString(p.Markdown)
+	if !strings.HasSuffix(p.Markdown, "\n") {
+		b.WriteByte('\n')
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *DirSink) Close() error { return nil }
+
+// PagePath maps a page URL to the relative file path DirSink writes it to.
+// A query string is folded into the name as a short hash.
+func PagePath(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("crawl: no host in %q", rawURL)
+	}
+	var segs []string
+	for _, seg := range strings.Split(u.EscapedPath(), "/") {
+		if seg = safeName(seg); seg != "" {
+			segs = append(segs, seg)
+		}
+	}
+	name := "index"
+	if len(segs) > 0 && !strings.HasSuffix(u.Path, "/") {
+		name = segs[len(segs)-1]
+		segs = segs[:len(segs)-1]
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	if u.RawQuery != "" {
+		sum := sha256.Sum256([]byte(u.RawQuery))
+		name += "_" + hex.EncodeToString(sum[:4])
+	}
+	parts := append([]string{safeName(u.Host)}, segs...)
+	return filepath.Join(append(parts, name+".md")...), nil
+}
+
+// safeName keeps a path segment usable as a file name on every platform.
+func safeName(seg string) string {
+	if s, err := url.PathUnescape(seg); err == nil {
+		seg = s
+	}
+	seg = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		if r < ' ' {
+			return -1
+		}
+		return r
+	}, seg)
+	if seg == "." || seg == ".." {
+		return ""
+	}
+	return seg
+}
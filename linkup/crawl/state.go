@@ -0,0 +1,103 @@
+package crawl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Journal states.
+const (
+	stateQueued  = "queued"
+	stateDone    = "done"
+	stateFailed  = "failed"
+	stateSkipped = "skipped" // disallowed by robots.txt
+)
+
+// journalEntry is one line of the state file. Each URL is journaled when
+// it is queued and again when it is finished; on resume, URLs whose last
+// entry is "queued" are crawled again.
+type journalEntry struct {
+	URL    string `json:"url"`
+	Depth  int    `json:"depth"`
+	Parent string `json:"parent,omitempty"`
+	State  string `json:"state"`
+	Error  string `json:"error,omitempty"`
+}
+
+type journal struct {
+	f *os.File
+	w *bufio.Writer
+}
+
+// openJournal replays the state file at path, returning every URL's last
+// entry in the order the URLs were first queued, and opens the file for
+// appending. A torn last line (from a crash mid-write) is ignored and
+// terminated.
+func openJournal(path string) (*journal, []journalEntry, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, nil, err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := io.ReadAll(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	var (
+		order []string
+		last  = map[string]journalEntry{}
+	)
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		var e journalEntry
+		if len(line) == 0 || json.Unmarshal(line, &e) != nil || e.URL == "" {
+			continue
+		}
+		if _, ok := last[e.URL]; !ok {
+			order = append(order, e.URL)
+		}
+		last[e.URL] = e
+	}
+	entries := make([]journalEntry, len(order))
+	for i, u := range order {
+		entries[i] = last[u]
+	}
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	j := &journal{f: f, w: bufio.NewWriter(f)}
+	if len(b) > 0 && b[len(b)-1] != '\n' {
+		j.w.WriteByte('\n')
+	}
+	return j, entries, nil
+}
+
+func (j *journal) add(e journalEntry) error {
+	if j == nil {
+		return nil
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	j.w.Write(b)
+	j.w.WriteByte('\n')
+	return j.w.Flush()
+}
+
+func (j *journal) close() error {
+	if j == nil {
+		return nil
+	}
+	return errors.Join(j.w.Flush(), j.f.Close())
+}
@@ -0,0 +1,136 @@
+package linkup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/raezil/linkup-go/linkup/canonical"
+	"github.com/raezil/linkup-go/linkup/domains"
+)
+
+// WithDomainLimit makes Search keep IncludeDomains and ExcludeDomains within
+// max domains each (domains.DefaultLimit when max <= 0) instead of sending
+// lists the API rejects:
+//
+//   - searchResults with too many include domains run one search per chunk
+//     of max domains and interleave the result lists by rank, deduplicated
+//     by canonical URL (each chunk is billed as a search);
+//   - sourcedAnswer and structured searches only use the first chunk;
+//   - exclude domains past the first max are applied client-side to search
+//     results and answer sources, renumbering the answer's citations; the
+//     answer text itself cannot be filtered, nor can structured output.
+//
+// logf receives a warning whenever a request is chunked or truncated, and
+// an error when an answer cites a source on an excluded domain; nil discards
+// them.
+func WithDomainLimit(max int, logf func(format string, args ...any)) Option {
+	return func(c *Client) {
+		if max <= 0 {
+			max = domains.DefaultLimit
+		}
+		if logf == nil {
+			logf = func(string, ...any) {}
+		}
+		c.domainLimit, c.domainLogf = max, logf
+	}
+}
+
+// overDomainLimit reports whether req needs searchChunked.
+func (c *Client) overDomainLimit(req SearchRequest) bool {
+	return c.domainLimit > 0 && (len(req.IncludeDomains) > c.domainLimit || len(req.ExcludeDomains) > c.domainLimit)
+}
+
+// searchChunked runs req, whose domain lists exceed the limit, as requests
+// within it (see WithDomainLimit).
+func (c *Client) searchChunked(ctx context.Context, req SearchRequest) (SearchResponse, error) {
+	n := c.domainLimit
+	exclude, overflow := req.ExcludeDomains, []string(nil)
+	if len(exclude) > n {
+		exclude, overflow = exclude[:n:n], exclude[n:]
+	}
+	chunks := domains.Chunk(req.IncludeDomains, n)
+	if len(chunks) == 0 {
+		chunks = [][]string{nil}
+	}
+	sub := func(include []string) SearchRequest {
+		r := req
+		r.IncludeDomains, r.ExcludeDomains = include, exclude
+		return r
+	}
+
+	switch req.OutputType {
+	case OutputSearchResults, "":
+		if len(chunks) > 1 {
+			c.domainLogf("linkup: %d include domains exceed the limit of %d; running %d searches", len(req.IncludeDomains), n, len(chunks))
+		}
+		if overflow != nil {
+			c.domainLogf("linkup: %d exclude domains exceed the limit of %d; filtering the other %d locally", len(req.ExcludeDomains), n, len(overflow))
+		}
+		lists := make([][]SearchResult, len(chunks))
+		for i, include := range chunks {
+			resp, err := c.search(ctx, sub(include))
+			if err != nil {
+				if len(chunks) > 1 {
+					err = fmt.Errorf("linkup: search %d of %d: %w", i+1, len(chunks), err)
+				}
+				return SearchResponse{}, err
+			}
+			if lists[i], err = resp.Results(); err != nil {
+				return SearchResponse{}, err
+			}
+		}
+		var merged []SearchResult
+		for rank := 0; ; rank++ {
+			more := false
+			for _, l := range lists {
+				if rank < len(l) {
+					merged, more = append(merged, l[rank]), true
+				}
+			}
+			if !more {
+				break
+			}
+		}
+		merged = DedupeResults(excluded(merged, func(r SearchResult) string { return r.URL }, overflow))
+		b, err := json.Marshal(SearchResults{Results: merged})
+		if err != nil {
+			return SearchResponse{}, err
+		}
+		return SearchResponse{Raw: b}, nil
+	}
+
+	if len(chunks) > 1 {
+		c.domainLogf("linkup: %d include domains exceed the limit of %d; %s search uses the first %d", len(req.IncludeDomains), n, req.OutputType, n)
+	}
+	if overflow != nil && req.OutputType != OutputSourcedAnswer {
+		c.domainLogf("linkup: %d exclude domains exceed the limit of %d; %s output cannot filter the other %d", len(req.ExcludeDomains), n, req.OutputType, len(overflow))
+	} else if overflow != nil {
+		c.domainLogf("linkup: %d exclude domains exceed the limit of %d; the answer may still draw on the other %d, which are only removed from its sources and citations", len(req.ExcludeDomains), n, len(overflow))
+	}
+	resp, err := c.search(ctx, sub(chunks[0]))
+	if err != nil || overflow == nil || req.OutputType != OutputSourcedAnswer {
+		return resp, err
+	}
+	resp, cited, err := dropSources(resp, func(sources []AnswerSource) []AnswerSource {
+		return excluded(sources, func(s AnswerSource) string { return s.URL }, overflow)
+	})
+	if len(cited) > 0 {
+		c.domainLogf("linkup: error: the answer cites sources %s on excluded domains; their citations were removed, but the answer text relies on them", ranks(cited))
+	}
+	return resp, err
+}
+
+// excluded drops the items whose URL is on one of the domains in list.
+func excluded[T any](items []T, url func(T) string, list []string) []T {
+	if len(list) == 0 {
+		return items
+	}
+	out := make([]T, 0, len(items))
+	for _, it := range items {
+		if !domains.Match(canonical.Host(url(it)), list) {
+			out = append(out, it)
+		}
+	}
+	return out
+}
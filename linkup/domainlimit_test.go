@@ -0,0 +1,96 @@
+package linkup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDomainLimit(t *testing.T) {
+	var reqs []SearchRequest
+	c, srv := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req SearchRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		reqs = append(reqs, req)
+		if req.OutputType == OutputSourcedAnswer {
+			fmt.Fprint(w, `{"answer":"S [1]. T [2].","sources":[{"name":"s","url":"https://www.blocked.org/x"},{"name":"t","url":"https://ok.org/y"}],"extra":1}`)
+			return
+		}
+		// Two results per include chunk, plus one duplicate and one excluded page.
+		first := req.IncludeDomains[0]
+		fmt.Fprintf(w, `{"results":[{"url":"https://%s/1","content":"a"},{"url":"https://%s/2"},{"url":"https://dup.org/x"},{"url":"https://sub.blocked.org/z"}]}`, first, first)
+	})
+	defer srv.Close()
+	var warnings []string
+	WithDomainLimit(2, func(format string, args ...any) { warnings = append(warnings, fmt.Sprintf(format, args...)) })(c)
+	ctx := context.Background()
+
+	req := SearchRequest{
+		Q:              "q",
+		OutputType:     OutputSearchResults,
+		IncludeDomains: []string{"a.com", "b.com", "c.com", "d.com", "e.com"},
+		ExcludeDomains: []string{"x.com", "y.com", "blocked.org"},
+	}
+	resp, err := c.Search(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 3 {
+		t.Fatalf("%d searches, want 3", len(reqs))
+	}
+	for i, r := range reqs {
+		if len(r.IncludeDomains) > 2 || len(r.ExcludeDomains) != 2 {
+			t.Errorf("request %d: include %q exclude %q", i, r.IncludeDomains, r.ExcludeDomains)
+		}
+	}
+	if reqs[2].IncludeDomains[0] != "e.com" {
+		t.Errorf("last chunk = %q", reqs[2].IncludeDomains)
+	}
+	results, err := resp.Results()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var urls []string
+	for _, r := range results {
+		urls = append(urls, r.URL)
+	}
+	want := "https://a.com/1 https://c.com/1 https://e.com/1 https://a.com/2 https://c.com/2 https://e.com/2 https://dup.org/x"
+	if got := strings.Join(urls, " "); got != want {
+		t.Fatalf("merged = %s\nwant     %s", got, want)
+	}
+	if len(warnings) != 2 {
+		t.Fatalf("warnings = %q", warnings)
+	}
+
+	reqs = nil
+	req.OutputType = OutputSourcedAnswer
+	resp, err = c.Search(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 1 || strings.Join(reqs[0].IncludeDomains, ",") != "a.com,b.com" {
+		t.Fatalf("answer requests = %+v", reqs)
+	}
+	ans, err := resp.SourcedAnswer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ans.Answer != "S. T [1]." || len(ans.Sources) != 1 || ans.Sources[0].URL != "https://ok.org/y" || !strings.Contains(string(resp.Raw), `"extra":1`) {
+		t.Fatalf("answer = %s", resp.Raw)
+	}
+	if len(warnings) != 5 || !strings.Contains(warnings[4], "error: the answer cites sources #1 on excluded domains") {
+		t.Fatalf("warnings = %q", warnings)
+	}
+
+	// Within the limit, requests go through untouched.
+	reqs = nil
+	if _, err := c.Search(ctx, SearchRequest{Q: "q", IncludeDomains: []string{"a.com", "b.com"}, ExcludeDomains: []string{"x.com"}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 1 || len(reqs[0].ExcludeDomains) != 1 {
+		t.Fatalf("requests = %+v", reqs)
+	}
+}
@@ -0,0 +1,218 @@
+// Package domains manages the domain lists given to
+// SearchRequest.IncludeDomains and ExcludeDomains. Lists can be written
+// inline, loaded from files (one domain per line, # comments allowed) and
+// composed from named groups such as @news; Expand turns such entries into
+// a normalized, deduplicated list, and Chunk splits it to fit the API.
+package domains
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// DefaultLimit is the number of domains one include or exclude list is
+// assumed to accept per request.
+const DefaultLimit = 100
+
+// Normalize turns a domain as people write it into the form the API
+// matches: lowercase, without scheme, port, path, trailing dot, a leading
+// "www." or a wildcard ("*.example.com" and ".example.com" both mean
+// example.com, whose subdomains the API matches anyway).
+func Normalize(s string) (string, error) {
+	d := strings.ToLower(strings.TrimSpace(s))
+	if i := strings.Index(d, "://"); i >= 0 {
+		d = d[i+3:]
+	}
+	if i := strings.IndexAny(d, "/?#"); i >= 0 {
+		d = d[:i]
+	}
+	if i := strings.LastIndexByte(d, '@'); i >= 0 {
+		d = d[i+1:] // userinfo
+	}
+	if i := strings.LastIndexByte(d, ':'); i >= 0 && !strings.Contains(d[:i], ":") {
+		d = d[:i] // port
+	}
+	d = strings.TrimPrefix(d, "*")
+	d = strings.Trim(d, ".")
+	d = strings.TrimPrefix(d, "www.")
+	if d == "" {
+		return "", fmt.Errorf("domains: %q is not a domain", s)
+	}
+	for label := range strings.SplitSeq(d, ".") {
+		if label == "" || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") ||
+			strings.ContainsFunc(label, func(r rune) bool { return r != '-' && r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+			return "", fmt.Errorf("domains: %q is not a domain", s)
+		}
+	}
+	return d, nil
+}
+
+// Parse reads a domain list: entries separated by newlines, spaces or
+// commas, with # starting a comment. Entries are returned as written, so
+// they may still be @group or @file references (see Expand).
+func Parse(r io.Reader) ([]string, error) {
+	var out []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		out = append(out, strings.FieldsFunc(line, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })...)
+	}
+	return out, sc.Err()
+}
+
+// Load parses the list file at path; a leading "~/" is the home directory.
+func Load(path string) ([]string, error) {
+	f, err := os.Open(expandHome(path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// IsGroupName reports whether name (without the @) can name a group:
+// letters, digits, '-' and '_'. Other @ references are files.
+func IsGroupName(name string) bool {
+	return name != "" && !strings.ContainsFunc(name, func(r rune) bool {
+		return r != '-' && r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Expand resolves entries into a normalized domain list. An entry is a
+// domain, "@name" for the group of that name in groups, or "@path" for a
+// list file (any reference that is not a group name, e.g. @./blocked.txt
+// or @~/lists/news.txt). Groups and files may reference other groups and
+// files. Duplicates and subdomains of listed domains are dropped; the
+// first occurrence keeps its place.
+func Expand(entries []string, groups map[string][]string) ([]string, error) {
+	var out []string
+	var errs []error
+	var expand func(entries []string, stack []string)
+	expand = func(entries []string, stack []string) {
+		for _, e := range entries {
+			ref, ok := strings.CutPrefix(e, "@")
+			if !ok {
+				d, err := Normalize(e)
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				out = append(out, d)
+				continue
+			}
+			if slices.Contains(stack, ref) {
+				errs = append(errs, fmt.Errorf("domains: @%s includes itself (%s)", ref, strings.Join(append(stack, ref), " -> @")))
+				continue
+			}
+			var sub []string
+			if IsGroupName(ref) {
+				g, ok := groups[ref]
+				if !ok {
+					errs = append(errs, fmt.Errorf("domains: unknown group @%s%s", ref, known(groups)))
+					continue
+				}
+				sub = g
+			} else {
+				var err error
+				if sub, err = Load(ref); err != nil {
+					errs = append(errs, fmt.Errorf("domains: %w", err))
+					continue
+				}
+			}
+			expand(sub, append(stack, ref))
+		}
+	}
+	expand(entries, nil)
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return Compact(out), nil
+}
+
+func known(groups map[string][]string) string {
+	if len(groups) == 0 {
+		return " (no groups are configured)"
+	}
+	names := make([]string, 0, len(groups))
+	for n := range groups {
+		names = append(names, "@"+n)
+	}
+	slices.Sort(names)
+	return " (have " + strings.Join(names, ", ") + ")"
+}
+
+// Compact drops duplicates and domains whose parent domain is also in
+// list, keeping the order of the rest. Entries must be normalized.
+func Compact(list []string) []string {
+	set := make(map[string]bool, len(list))
+	for _, d := range list {
+		set[d] = true
+	}
+	out := make([]string, 0, len(set))
+	for _, d := range list {
+		if !set[d] || covered(d, set) {
+			continue
+		}
+		set[d] = false // emit once
+		out = append(out, d)
+	}
+	return out
+}
+
+// covered reports whether a parent domain of d is in set (with any value).
+func covered(d string, set map[string]bool) bool {
+	for i := strings.IndexByte(d, '.'); i >= 0; i = strings.IndexByte(d, '.') {
+		d = d[i+1:]
+		if _, ok := set[d]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether host is one of the normalized domains in list or a
+// subdomain of one.
+func Match(host string, list []string) bool {
+	host = strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(host), "."), "www.")
+	for _, d := range list {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// Chunk splits list into consecutive parts of at most n domains.
+func Chunk(list []string, n int) [][]string {
+	if n <= 0 {
+		n = DefaultLimit
+	}
+	var out [][]string
+	for len(list) > n {
+		out = append(out, list[:n:n])
+		list = list[n:]
+	}
+	if len(list) > 0 {
+		out = append(out, list)
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package domains
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	for in, want := range map[string]string{
+		"Example.COM":                          "example.com",
+		"www.example.com":                      "example.com",
+		"*.example.com":                        "example.com",
+		".example.com":                         "example.com",
+		"example.com.":                         "example.com",
+		"https://www.example.com/a?b#c":        "example.com",
+		"http://user:pw@news.example.com:8080": "news.example.com",
+		"  blog.example.co.uk  ":               "blog.example.co.uk",
+		"bücher.de":                            "bücher.de",
+	} {
+		got, err := Normalize(in)
+		if err != nil || got != want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "*", "a..b", "-bad.com", "exa mple.com", "foo!.com"} {
+		if got, err := Normalize(in); err == nil {
+			t.Errorf("Normalize(%q) = %q, want error", in, got)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	got, err := Parse(strings.NewReader("# blocked\nspam.com\n\n  junk.net, more.org # trailing\n@news\t@./x.txt\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"spam.com", "junk.net", "more.org", "@news", "@./x.txt"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Parse = %q, want %q", got, want)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "blocked.txt")
+	os.WriteFile(file, []byte("# low quality\nspam.com\n*.content-farm.io\n@news\n"), 0o644)
+	groups := map[string][]string{
+		"news":       {"reuters.com", "apnews.com", "www.bbc.co.uk"},
+		"lowquality": {"@" + file, "spam.com", "sub.spam.com"},
+		"loop":       {"@loop2"},
+		"loop2":      {"@loop"},
+	}
+
+	got, err := Expand([]string{"https://www.Reuters.com/world", "@lowquality", "news.bbc.co.uk", "extra.org"}, groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"reuters.com", "spam.com", "content-farm.io", "apnews.com", "bbc.co.uk", "extra.org"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Expand = %q, want %q", got, want)
+	}
+
+	for _, bad := range [][]string{{"@missing"}, {"@loop"}, {"@" + filepath.Join(dir, "none.txt")}, {"not a domain"}} {
+		if _, err := Expand(bad, groups); err == nil {
+			t.Errorf("Expand(%q): want error", bad)
+		}
+	}
+	if _, err := Expand([]string{"@missing"}, groups); err == nil || !strings.Contains(err.Error(), "@lowquality") {
+		t.Errorf("unknown group error should list groups: %v", err)
+	}
+}
+
+func TestMatchAndChunk(t *testing.T) {
+	list := []string{"example.com", "news.org"}
+	for host, want := range map[string]bool{
+		"example.com":     true,
+		"www.example.com": true,
+		"a.b.example.com": true,
+		"notexample.com":  false,
+		"news.org.evil":   false,
+		"NEWS.ORG.":       true,
+	} {
+		if got := Match(host, list); got != want {
+			t.Errorf("Match(%q) = %v", host, got)
+		}
+	}
+
+	var many []string
+	for i := range 250 {
+		many = append(many, strings.Repeat("a", i%5+1)+".com")
+	}
+	chunks := Chunk(many, 0)
+	if len(chunks) != 3 || len(chunks[0]) != DefaultLimit || len(chunks[2]) != 50 {
+		t.Fatalf("chunk sizes = %d", len(chunks))
+	}
+	chunks[0] = append(chunks[0], "x.com")
+	if many[DefaultLimit] == "x.com" {
+		t.Fatal("appending to a chunk overwrote the next one")
+	}
+	if Chunk(nil, 10) != nil {
+		t.Fatal("empty list should have no chunks")
+	}
+}
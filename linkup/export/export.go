@@ -0,0 +1,261 @@
+// Package export flattens Linkup responses into one tabular schema for
+// analytics tools and writes it as CSV, NDJSON or Parquet. Search results,
+// sourced answers (the answer and each source) and fetched pages all
+// become Rows; WriteDir appends them under Hive-style date=YYYY-MM-DD
+// partition directories.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/canonical"
+)
+
+// Row kinds.
+const (
+	KindResult     = "result"     // a search result
+	KindAnswer     = "answer"     // a sourced answer; Content is the answer
+	KindSource     = "source"     // a source of a sourced answer
+	KindStructured = "structured" // a structured search; Content is the JSON output
+	KindPage       = "page"       // a fetched page; Content is its markdown
+)
+
+// Columns is the schema, in column order. It only ever grows at the end.
+var Columns = []string{"query", "rank", "url", "domain", "title", "content", "fetched_at", "depth", "kind"}
+
+// Row is one record of the export schema.
+type Row struct {
+	Query     string    `json:"query"`  // empty for fetched pages
+	Rank      int       `json:"rank"`   // 1-based; 0 for answers, structured output and pages
+	URL       string    `json:"url"`    // empty for answers and structured output
+	Domain    string    `json:"domain"` // canonical host of URL
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	FetchedAt time.Time `json:"fetched_at"`
+	Depth     string    `json:"depth"` // search depth; empty for fetched pages
+	Kind      string    `json:"kind"`
+}
+
+// record returns r's column values as text, for CSV.
+func (r Row) record() []string {
+	return []string{r.Query, strconv.Itoa(r.Rank), r.URL, r.Domain, r.Title, r.Content,
+		r.FetchedAt.UTC().Format(time.RFC3339Nano), r.Depth, r.Kind}
+}
+
+// FromSearch flattens a /search response for req, fetched at at.
+func FromSearch(req linkup.SearchRequest, resp linkup.SearchResponse, at time.Time) ([]Row, error) {
+	base := Row{Query: req.Q, FetchedAt: at, Depth: string(req.Depth)}
+	switch req.OutputType {
+	case linkup.OutputSearchResults, "":
+		results, err := resp.Results()
+		if err != nil {
+			return nil, fmt.Errorf("export: search results: %w", err)
+		}
+		rows := make([]Row, len(results))
+		for i, r := range results {
+			rows[i] = base.with(KindResult, i+1, r.URL, r.Name, r.Content)
+		}
+		return rows, nil
+	case linkup.OutputSourcedAnswer:
+		ans, err := resp.SourcedAnswer()
+		if err != nil {
+			return nil, fmt.Errorf("export: sourced answer: %w", err)
+		}
+		rows := []Row{base.with(KindAnswer, 0, "", "", ans.Answer)}
+		for i, s := range ans.Sources {
+			rows = append(rows, base.with(KindSource, i+1, s.URL, s.Label(), s.Snippet))
+		}
+		return rows, nil
+	default:
+		if !json.Valid(resp.Raw) {
+			return nil, fmt.Errorf("export: structured output is not JSON")
+		}
+		return []Row{base.with(KindStructured, 0, "", "", string(resp.Raw))}, nil
+	}
+}
+
+// FromFetch flattens a /fetch response for req, fetched at at. The title
+// comes from the page (see linkup.FetchResult.Extract).
+func FromFetch(req linkup.FetchRequest, resp linkup.SearchResponse, at time.Time) ([]Row, error) {
+	page, err := resp.Page()
+	if err != nil {
+		return nil, fmt.Errorf("export: page: %w", err)
+	}
+	page.Extract(req.URL)
+	return []Row{(Row{FetchedAt: at}).with(KindPage, 0, req.URL, page.Title, page.Markdown)}, nil
+}
+
+// FromCall flattens a recorded call, such as a history entry.
+func FromCall(call linkup.Call) ([]Row, error) {
+	switch call.Op {
+	case linkup.OpSearch:
+		var req linkup.SearchRequest
+		if err := json.Unmarshal(call.Request, &req); err != nil {
+			return nil, fmt.Errorf("export: search request: %w", err)
+		}
+		return FromSearch(req, linkup.SearchResponse{Raw: call.Response}, call.Time)
+	case linkup.OpFetch:
+		var req linkup.FetchRequest
+		if err := json.Unmarshal(call.Request, &req); err != nil {
+			return nil, fmt.Errorf("export: fetch request: %w", err)
+		}
+		return FromFetch(req, linkup.SearchResponse{Raw: call.Response}, call.Time)
+	}
+	return nil, fmt.Errorf("export: unknown op %q", call.Op)
+}
+
+func (r Row) with(kind string, rank int, url, title, content string) Row {
+	r.Kind, r.Rank, r.URL, r.Title, r.Content = kind, rank, url, title, content
+	if url != "" {
+		r.Domain = canonical.Host(url)
+	}
+	return r
+}
+
+// Format is an output format.
+type Format string
+
+const (
+	CSV     Format = "csv"
+	NDJSON  Format = "ndjson"
+	Parquet Format = "parquet"
+)
+
+// ParseFormat parses "csv", "ndjson" (or "jsonl") and "parquet".
+func ParseFormat(s string) (Format, error) {
+	switch s {
+	case "csv", "ndjson", "parquet":
+		return Format(s), nil
+	case "jsonl":
+		return NDJSON, nil
+	}
+	return "", fmt.Errorf("export: unknown format %q (csv|ndjson|parquet)", s)
+}
+
+// Writer writes rows to a stream. Close flushes buffered output; it does
+// not close the underlying writer.
+type Writer interface {
+	Write(rows ...Row) error
+	Close() error
+}
+
+// NewWriter returns a Writer for f. CSV output starts with a header row.
+func NewWriter(w io.Writer, f Format) (Writer, error) {
+	switch f {
+	case CSV:
+		return NewCSVWriter(w, true), nil
+	case NDJSON:
+		return NewNDJSONWriter(w), nil
+	case Parquet:
+		return NewParquetWriter(w), nil
+	}
+	return nil, fmt.Errorf("export: unknown format %q", f)
+}
+
+// Partition is the partition directory of t: date=YYYY-MM-DD, in UTC.
+func Partition(t time.Time) string {
+	return "date=" + t.UTC().Format(time.DateOnly)
+}
+
+var fileSeq atomic.Uint64
+
+// WriteDir appends rows under dir, each to the partition of its FetchedAt,
+// and returns the files written. CSV and NDJSON rows are appended to one
+// results.csv or results.ndjson per partition (a new CSV starts with the
+// header). Parquet files cannot be appended to, so every call adds a new
+// part-*.parquet file to each partition, written atomically.
+func WriteDir(dir string, f Format, rows []Row) ([]string, error) {
+	if _, err := ParseFormat(string(f)); err != nil {
+		return nil, err
+	}
+	byPart := map[string][]Row{}
+	for _, r := range rows {
+		p := Partition(r.FetchedAt)
+		byPart[p] = append(byPart[p], r)
+	}
+	parts := make([]string, 0, len(byPart))
+	for p := range byPart {
+		parts = append(parts, p)
+	}
+	slices.Sort(parts)
+
+	var files []string
+	for _, p := range parts {
+		pdir := filepath.Join(dir, p)
+		if err := os.MkdirAll(pdir, 0o755); err != nil {
+			return files, err
+		}
+		var path string
+		var err error
+		if f == Parquet {
+			path = filepath.Join(pdir, fmt.Sprintf("part-%d-%d.parquet", time.Now().UnixNano(), fileSeq.Add(1)))
+			err = writeParquetFile(path, byPart[p])
+		} else {
+			path = filepath.Join(pdir, "results."+string(f))
+			err = appendFile(path, f, byPart[p])
+		}
+		if err != nil {
+			return files, err
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+func appendFile(path string, f Format, rows []Row) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	var w Writer
+	if f == CSV {
+		fi, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return err
+		}
+		w = NewCSVWriter(file, fi.Size() == 0)
+	} else {
+		w = NewNDJSONWriter(file)
+	}
+	err = w.Write(rows...)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func writeParquetFile(path string, rows []Row) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".part-*")
+	if err != nil {
+		return err
+	}
+	w := NewParquetWriter(tmp)
+	err = tmp.Chmod(0o644)
+	if err == nil {
+		err = w.Write(rows...)
+	}
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,353 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+var at = time.Date(2025, 3, 4, 23, 30, 0, 123456000, time.FixedZone("EST", -5*3600))
+
+func TestFromSearchAndFetch(t *testing.T) {
+	rows, err := FromSearch(
+		linkup.SearchRequest{Q: "go generics", Depth: linkup.DepthDeep, OutputType: linkup.OutputSearchResults},
+		linkup.SearchResponse{Raw: []byte(`{"results":[{"type":"text","name":"Tutorial","url":"https://www.go.dev/doc/tutorial/generics","content":"Learn"},{"type":"image","name":"Gopher","url":"https://go.dev/g.png"}]}`)},
+		at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Row{Query: "go generics", Rank: 1, URL: "https://www.go.dev/doc/tutorial/generics", Domain: "go.dev", Title: "Tutorial",
+		Content: "Learn", FetchedAt: at, Depth: "deep", Kind: KindResult}
+	if len(rows) != 2 || rows[0] != want || rows[1].Rank != 2 || rows[1].Title != "Gopher" {
+		t.Fatalf("rows = %+v", rows)
+	}
+
+	rows, err = FromSearch(
+		linkup.SearchRequest{Q: "why", Depth: linkup.DepthStandard, OutputType: linkup.OutputSourcedAnswer},
+		linkup.SearchResponse{Raw: []byte(`{"answer":"Because.","sources":[{"name":"A","url":"https://a.com/x","snippet":"s"}]}`)},
+		at)
+	if err != nil || len(rows) != 2 || rows[0].Kind != KindAnswer || rows[0].Content != "Because." || rows[0].Rank != 0 || rows[0].URL != "" ||
+		rows[1].Kind != KindSource || rows[1].Rank != 1 || rows[1].Title != "A" || rows[1].Domain != "a.com" || rows[1].Content != "s" {
+		t.Fatalf("rows = %+v, err = %v", rows, err)
+	}
+
+	rows, err = FromSearch(linkup.SearchRequest{Q: "q", OutputType: linkup.OutputStructured}, linkup.SearchResponse{Raw: []byte(`{"n":1}`)}, at)
+	if err != nil || len(rows) != 1 || rows[0].Kind != KindStructured || rows[0].Content != `{"n":1}` {
+		t.Fatalf("rows = %+v, err = %v", rows, err)
+	}
+
+	call := linkup.Call{Op: linkup.OpFetch, Time: at, Request: []byte(`{"url":"https://blog.example.com/p"}`),
+		Response: []byte(`{"markdown":"# Post\n\nBody"}`)}
+	rows, err = FromCall(call)
+	if err != nil || len(rows) != 1 || rows[0] != (Row{URL: "https://blog.example.com/p", Domain: "blog.example.com", Title: "Post",
+		Content: "# Post\n\nBody", FetchedAt: at, Kind: KindPage}) {
+		t.Fatalf("rows = %+v, err = %v", rows, err)
+	}
+	if _, err := FromCall(linkup.Call{Op: linkup.OpSearch, Request: []byte(`{"q":"x"}`), Response: []byte(`{"results":{}}`)}); err == nil {
+		t.Fatal("bad response accepted")
+	}
+}
+
+func sampleRows() []Row {
+	return []Row{
+		{Query: "q, \"quoted\"", Rank: 1, URL: "https://a.com/", Domain: "a.com", Title: "A", Content: "line 1\nline 2 <b>", FetchedAt: at, Depth: "standard", Kind: KindResult},
+		{Query: "q", Rank: 2, URL: "https://b.com/", Domain: "b.com", Title: "Ünïcode ✓", FetchedAt: at.Add(time.Hour), Depth: "standard", Kind: KindResult},
+		{URL: "https://c.com/", Domain: "c.com", Content: strings.Repeat("x", 5000), FetchedAt: at.Add(-48 * time.Hour), Kind: KindPage},
+	}
+}
+
+func TestCSVAndNDJSON(t *testing.T) {
+	var b bytes.Buffer
+	w, _ := NewWriter(&b, CSV)
+	w.Write(sampleRows()[:2]...)
+	w.Close()
+	recs, err := csv.NewReader(&b).ReadAll()
+	if err != nil || len(recs) != 3 || !slices.Equal(recs[0], Columns) {
+		t.Fatalf("csv = %q, err = %v", recs, err)
+	}
+	if recs[1][0] != `q, "quoted"` || recs[1][1] != "1" || recs[1][5] != "line 1\nline 2 <b>" || recs[1][6] != "2025-03-05T04:30:00.123456Z" {
+		t.Fatalf("row = %q", recs[1])
+	}
+
+	b.Reset()
+	w, _ = NewWriter(&b, NDJSON)
+	w.Write(sampleRows()...)
+	w.Close()
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], `"content":"line 1\nline 2 <b>"`) || !strings.Contains(lines[0], `"fetched_at":"2025-03-05T04:30:00.123456Z"`) {
+		t.Fatalf("ndjson = %s", b.String())
+	}
+	var keys map[string]any
+	json.Unmarshal([]byte(lines[1]), &keys)
+	for _, c := range Columns {
+		if _, ok := keys[c]; !ok {
+			t.Errorf("ndjson misses column %q", c)
+		}
+	}
+}
+
+func TestWriteDir(t *testing.T) {
+	dir := t.TempDir()
+	for range 2 {
+		for _, f := range []Format{CSV, NDJSON, Parquet} {
+			if _, err := WriteDir(dir, f, sampleRows()); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// at is 2025-03-04 in EST but 2025-03-05 in UTC.
+	for _, p := range []string{"date=2025-03-03", "date=2025-03-05"} {
+		if _, err := os.Stat(filepath.Join(dir, p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, _ := os.ReadFile(filepath.Join(dir, "date=2025-03-05", "results.csv"))
+	if recs, err := csv.NewReader(bytes.NewReader(b)).ReadAll(); err != nil || len(recs) != 5 || recs[3][0] == "query" {
+		t.Fatalf("appended csv = %q (%v)", recs, err)
+	}
+	b, _ = os.ReadFile(filepath.Join(dir, "date=2025-03-05", "results.ndjson"))
+	if n := strings.Count(string(b), "\n"); n != 4 {
+		t.Fatalf("appended ndjson has %d lines", n)
+	}
+	parts, _ := filepath.Glob(filepath.Join(dir, "date=2025-03-05", "part-*.parquet"))
+	if len(parts) != 2 {
+		t.Fatalf("parquet files = %v", parts)
+	}
+	b, _ = os.ReadFile(parts[0])
+	if got := readParquet(t, b); len(got) != 2 || got[1].Title != "Ünïcode ✓" {
+		t.Fatalf("parquet rows = %+v", got)
+	}
+	if _, err := WriteDir(dir, "xlsx", nil); err == nil {
+		t.Fatal("unknown format accepted")
+	}
+}
+
+func TestParquet(t *testing.T) {
+	var b bytes.Buffer
+	w := NewParquetWriter(&b)
+	rows := sampleRows()
+	if err := w.Write(rows...); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got := readParquet(t, b.Bytes())
+	if len(got) != len(rows) {
+		t.Fatalf("read %d rows", len(got))
+	}
+	for i := range rows {
+		if want := rows[i]; got[i] != (Row{want.Query, want.Rank, want.URL, want.Domain, want.Title, want.Content,
+			want.FetchedAt.UTC(), want.Depth, want.Kind}) {
+			t.Errorf("row %d = %+v, want %+v", i, got[i], want)
+		}
+	}
+
+	// Several row groups, and an empty file.
+	b.Reset()
+	w = NewParquetWriter(&b)
+	for range rowGroupRows + 10 {
+		w.Write(rows[0])
+	}
+	w.Close()
+	if got := readParquet(t, b.Bytes()); len(got) != rowGroupRows+10 || got[rowGroupRows+9] != got[0] {
+		t.Fatalf("read %d rows", len(got))
+	}
+	b.Reset()
+	w = NewParquetWriter(&b)
+	w.Close()
+	if got := readParquet(t, b.Bytes()); len(got) != 0 {
+		t.Fatalf("read %d rows", len(got))
+	}
+}
+
+// TestParquetGolden pins the bytes of a small file, so that field IDs,
+// types and encodings cannot change unnoticed. Its pages are uncompressed,
+// since gzip output may change between Go releases. After an intended
+// format change, rewrite testdata/sample.parquet with -update and check it
+// with a Parquet reader, e.g.
+//
+//	python3 -c 'import pyarrow.parquet as pq; print(pq.read_table("testdata/sample.parquet").to_pylist())'
+func TestParquetGolden(t *testing.T) {
+	var b bytes.Buffer
+	w := NewParquetWriter(&b)
+	w.codec = codecUncompressed
+	w.Write(sampleRows()...)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "sample.parquet")
+	if *update {
+		if err := os.WriteFile(golden, b.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.Bytes(), want) {
+		t.Fatalf("output differs from %s (%d bytes, want %d); if intended, rerun with -update", golden, b.Len(), len(want))
+	}
+	got := readParquet(t, want)
+	for i, r := range sampleRows() {
+		r.FetchedAt = r.FetchedAt.UTC()
+		if len(got) != 3 || got[i] != r {
+			t.Fatalf("read %+v", got)
+		}
+	}
+}
+
+// readParquet decodes a file written by ParquetWriter, checking the
+// metadata readers rely on.
+func readParquet(t *testing.T, file []byte) []Row {
+	t.Helper()
+	if len(file) < 12 || string(file[:4]) != "PAR1" || string(file[len(file)-4:]) != "PAR1" {
+		t.Fatal("missing magic")
+	}
+	n := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	meta := decodeStruct(t, bytes.NewReader(file[len(file)-8-n:len(file)-8]))
+
+	schema := meta[2].([]any)
+	root := schema[0].(map[int16]any)
+	if string(root[4].([]byte)) != "schema" || root[5].(int64) != int64(len(Columns)) {
+		t.Fatalf("root = %v", root)
+	}
+	for i, c := range Columns {
+		el := schema[i+1].(map[int16]any)
+		if string(el[4].([]byte)) != c || el[3].(int64) != repetitionRequired {
+			t.Fatalf("schema[%d] = %v", i+1, el)
+		}
+		if c == "fetched_at" {
+			ts := el[10].(map[int16]any)[8].(map[int16]any)
+			if el[6].(int64) != convertedTimestampMicros || ts[1] != true || ts[2].(map[int16]any)[2] == nil {
+				t.Fatalf("fetched_at = %v", el)
+			}
+		}
+	}
+
+	var rows []Row
+	for _, g := range meta[4].([]any) {
+		g := g.(map[int16]any)
+		numRows := int(g[3].(int64))
+		group := make([]Row, numRows)
+		for i, c := range g[1].([]any) {
+			cm := c.(map[int16]any)[3].(map[int16]any)
+			codec := cm[4].(int64)
+			if (codec != codecGzip && codec != codecUncompressed) || cm[5].(int64) != int64(numRows) || string(cm[3].([]any)[0].([]byte)) != Columns[i] {
+				t.Fatalf("column meta = %v", cm)
+			}
+			off := cm[9].(int64)
+			r := bytes.NewReader(file[off:])
+			ph := decodeStruct(t, r)
+			hdrLen := int64(len(file[off:])) - int64(r.Len())
+			if hdrLen+ph[3].(int64) != cm[7].(int64) || hdrLen+ph[2].(int64) != cm[6].(int64) {
+				t.Fatalf("sizes: header %d, page %v, meta %v", hdrLen, ph, cm)
+			}
+			var page io.Reader = io.LimitReader(r, ph[3].(int64))
+			if codec == codecGzip {
+				zr, err := gzip.NewReader(page)
+				if err != nil {
+					t.Fatal(err)
+				}
+				page = zr
+			}
+			vals, _ := io.ReadAll(page)
+			if int64(len(vals)) != ph[2].(int64) || ph[5].(map[int16]any)[1].(int64) != int64(numRows) {
+				t.Fatalf("page %v with %d bytes", ph, len(vals))
+			}
+			for j := range group {
+				switch Columns[i] {
+				case "rank":
+					group[j].Rank = int(int32(binary.LittleEndian.Uint32(vals)))
+					vals = vals[4:]
+				case "fetched_at":
+					group[j].FetchedAt = time.UnixMicro(int64(binary.LittleEndian.Uint64(vals))).UTC()
+					vals = vals[8:]
+				default:
+					n := binary.LittleEndian.Uint32(vals)
+					s := string(vals[4 : 4+n])
+					vals = vals[4+n:]
+					*map[string]*string{"query": &group[j].Query, "url": &group[j].URL, "domain": &group[j].Domain,
+						"title": &group[j].Title, "content": &group[j].Content, "depth": &group[j].Depth, "kind": &group[j].Kind}[Columns[i]] = s
+				}
+			}
+		}
+		rows = append(rows, group...)
+	}
+	if meta[3].(int64) != int64(len(rows)) {
+		t.Fatalf("num_rows = %v, read %d", meta[3], len(rows))
+	}
+	return rows
+}
+
+// decodeStruct reads a Thrift compact struct into field ID -> value, with
+// integers as int64, binaries as []byte, lists as []any and structs as
+// map[int16]any.
+func decodeStruct(t *testing.T, r *bytes.Reader) map[int16]any {
+	t.Helper()
+	out := map[int16]any{}
+	var id int16
+	for {
+		h, err := r.ReadByte()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h == 0 {
+			return out
+		}
+		if d := int16(h >> 4); d != 0 {
+			id += d
+		} else {
+			v, _ := binary.ReadUvarint(r)
+			id = int16(v>>1) ^ -int16(v&1)
+		}
+		out[id] = decodeValue(t, r, h&0x0f)
+	}
+}
+
+func decodeValue(t *testing.T, r *bytes.Reader, typ byte) any {
+	switch typ {
+	case thriftTrue:
+		return true
+	case thriftFalse:
+		return false
+	case thriftI32, thriftI64:
+		v, _ := binary.ReadUvarint(r)
+		return int64(v>>1) ^ -int64(v&1)
+	case thriftBinary:
+		n, _ := binary.ReadUvarint(r)
+		b := make([]byte, n)
+		io.ReadFull(r, b)
+		return b
+	case thriftList:
+		h, _ := r.ReadByte()
+		n := uint64(h >> 4)
+		if n == 15 {
+			n, _ = binary.ReadUvarint(r)
+		}
+		out := make([]any, n)
+		for i := range out {
+			out[i] = decodeValue(t, r, h&0x0f)
+		}
+		return out
+	case thriftStruct:
+		return decodeStruct(t, r)
+	}
+	t.Fatalf("unexpected thrift type %d", typ)
+	return nil
+}
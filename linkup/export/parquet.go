@@ -0,0 +1,355 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Parquet support is written from the format specification
+// (github.com/apache/parquet-format): every column is REQUIRED and stored
+// as one PLAIN-encoded, gzip-compressed v1 data page per row group, with
+// the footer in the Thrift compact protocol. That is the subset every
+// reader understands, with no dependency beyond the standard library.
+
+// Physical types, converted types and other enum values used here.
+const (
+	typeInt32     = 1
+	typeInt64     = 2
+	typeByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMicros = 10
+
+	repetitionRequired = 0
+	encodingPlain      = 0
+	encodingRLE        = 3
+	codecUncompressed  = 0
+	codecGzip          = 2
+	pageData           = 0
+)
+
+// Row groups are flushed after this many rows or bytes of values.
+const (
+	rowGroupRows  = 100_000
+	rowGroupBytes = 64 << 20
+)
+
+var parquetMagic = []byte("PAR1")
+
+// parquetColumn describes how a Row field is stored.
+type parquetColumn struct {
+	typ       int32
+	timestamp bool                          // INT64 microseconds since the epoch, UTC
+	encode    func(b []byte, r *Row) []byte // appends the PLAIN value
+}
+
+var parquetColumns = []parquetColumn{
+	{typ: typeByteArray, encode: func(b []byte, r *Row) []byte { return plainString(b, r.Query) }},
+	{typ: typeInt32, encode: func(b []byte, r *Row) []byte { return binary.LittleEndian.AppendUint32(b, uint32(int32(r.Rank))) }},
+	{typ: typeByteArray, encode: func(b []byte, r *Row) []byte { return plainString(b, r.URL) }},
+	{typ: typeByteArray, encode: func(b []byte, r *Row) []byte { return plainString(b, r.Domain) }},
+	{typ: typeByteArray, encode: func(b []byte, r *Row) []byte { return plainString(b, r.Title) }},
+	{typ: typeByteArray, encode: func(b []byte, r *Row) []byte { return plainString(b, r.Content) }},
+	{typ: typeInt64, timestamp: true, encode: func(b []byte, r *Row) []byte {
+		return binary.LittleEndian.AppendUint64(b, uint64(r.FetchedAt.UnixMicro()))
+	}},
+	{typ: typeByteArray, encode: func(b []byte, r *Row) []byte { return plainString(b, r.Depth) }},
+	{typ: typeByteArray, encode: func(b []byte, r *Row) []byte { return plainString(b, r.Kind) }},
+}
+
+func plainString(b []byte, s string) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+// ParquetWriter writes rows as a Parquet file. Rows are buffered into row
+// groups; Close writes the last group and the footer, and must be called
+// for the output to be readable.
+type ParquetWriter struct {
+	w      io.Writer
+	codec  int32 // codecGzip; tests pin codecUncompressed output
+	off    int64 // bytes written so far
+	rows   []Row
+	size   int // approximate bytes of buffered values
+	groups []rowGroupMeta
+	total  int64
+	err    error
+	closed bool
+}
+
+type chunkMeta struct {
+	offset                   int64
+	uncompressed, compressed int64
+}
+
+type rowGroupMeta struct {
+	rows   int64
+	chunks []chunkMeta
+}
+
+func NewParquetWriter(w io.Writer) *ParquetWriter {
+	return &ParquetWriter{w: w, codec: codecGzip}
+}
+
+func (p *ParquetWriter) Write(rows ...Row) error {
+	if p.closed {
+		return errors.New("export: write to closed ParquetWriter")
+	}
+	for _, r := range rows {
+		if p.err != nil {
+			return p.err
+		}
+		p.rows = append(p.rows, r)
+		p.size += len(r.Query) + len(r.URL) + len(r.Domain) + len(r.Title) + len(r.Content) + len(r.Depth) + len(r.Kind) + 64
+		if len(p.rows) >= rowGroupRows || p.size >= rowGroupBytes {
+			p.flush()
+		}
+	}
+	return p.err
+}
+
+// Close flushes the buffered rows and writes the footer.
+func (p *ParquetWriter) Close() error {
+	if p.closed {
+		return p.err
+	}
+	p.closed = true
+	p.flush()
+	if p.err != nil {
+		return p.err
+	}
+	if p.off == 0 {
+		p.write(parquetMagic)
+	}
+	footer := p.footer()
+	p.write(footer)
+	p.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))))
+	p.write(parquetMagic)
+	return p.err
+}
+
+func (p *ParquetWriter) write(b []byte) {
+	if p.err != nil {
+		return
+	}
+	n, err := p.w.Write(b)
+	p.off += int64(n)
+	p.err = err
+}
+
+// flush writes the buffered rows as a row group.
+func (p *ParquetWriter) flush() {
+	if len(p.rows) == 0 || p.err != nil {
+		return
+	}
+	if p.off == 0 {
+		p.write(parquetMagic)
+	}
+	g := rowGroupMeta{rows: int64(len(p.rows))}
+	var b []byte
+	var packed bytes.Buffer
+	for _, col := range parquetColumns {
+		b = b[:0]
+		for i := range p.rows {
+			b = col.encode(b, &p.rows[i])
+		}
+		page := b
+		if p.codec == codecGzip {
+			packed.Reset()
+			zw := gzip.NewWriter(&packed)
+			zw.Write(b)
+			if err := zw.Close(); err != nil {
+				p.err = err
+				return
+			}
+			page = packed.Bytes()
+		}
+
+		var h thriftWriter
+		h.begin()
+		h.i32(1, pageData)
+		h.i32(2, int32(len(b)))
+		h.i32(3, int32(len(page)))
+		h.beginStruct(5) // DataPageHeader
+		h.i32(1, int32(len(p.rows)))
+		h.i32(2, encodingPlain)
+		h.i32(3, encodingRLE)
+		h.i32(4, encodingRLE)
+		h.end()
+		h.end()
+
+		c := chunkMeta{
+			offset:       p.off,
+			uncompressed: int64(len(h.b) + len(b)),
+			compressed:   int64(len(h.b) + len(page)),
+		}
+		p.write(h.b)
+		p.write(page)
+		g.chunks = append(g.chunks, c)
+	}
+	p.groups = append(p.groups, g)
+	p.total += g.rows
+	p.rows, p.size = p.rows[:0], 0
+}
+
+// footer encodes the FileMetaData.
+func (p *ParquetWriter) footer() []byte {
+	var t thriftWriter
+	t.begin()
+	t.i32(1, 1) // version
+	t.list(2, thriftStruct, len(Columns)+1)
+	t.beginElem() // root
+	t.binary(4, "schema")
+	t.i32(5, int32(len(Columns)))
+	t.end()
+	for i, col := range parquetColumns {
+		t.beginElem()
+		t.i32(1, col.typ)
+		t.i32(3, repetitionRequired)
+		t.binary(4, Columns[i])
+		switch {
+		case col.typ == typeByteArray:
+			t.i32(6, convertedUTF8)
+			t.beginStruct(10) // LogicalType
+			t.beginStruct(1)  // STRING
+			t.end()
+			t.end()
+		case col.timestamp:
+			t.i32(6, convertedTimestampMicros)
+			t.beginStruct(10) // LogicalType
+			t.beginStruct(8)  // TIMESTAMP
+			t.boolean(1, true)
+			t.beginStruct(2) // unit
+			t.beginStruct(2) // MICROS
+			t.end()
+			t.end()
+			t.end()
+			t.end()
+		}
+		t.end()
+	}
+	t.i64(3, p.total)
+	t.list(4, thriftStruct, len(p.groups))
+	for _, g := range p.groups {
+		t.beginElem()
+		t.list(1, thriftStruct, len(g.chunks))
+		var size, compressed int64
+		for i, c := range g.chunks {
+			size += c.uncompressed
+			compressed += c.compressed
+			t.beginElem() // ColumnChunk
+			t.i64(2, c.offset)
+			t.beginStruct(3) // ColumnMetaData
+			t.i32(1, parquetColumns[i].typ)
+			t.list(2, thriftI32, 2)
+			t.elemI32(encodingPlain)
+			t.elemI32(encodingRLE)
+			t.list(3, thriftBinary, 1)
+			t.elemBinary(Columns[i])
+			t.i32(4, p.codec)
+			t.i64(5, g.rows)
+			t.i64(6, c.uncompressed)
+			t.i64(7, c.compressed)
+			t.i64(9, c.offset)
+			t.end()
+			t.end()
+		}
+		t.i64(2, size)
+		t.i64(3, g.rows)
+		t.i64(5, g.chunks[0].offset)
+		t.i64(6, compressed)
+		t.end()
+	}
+	t.binary(6, "linkup-go")
+	t.end()
+	return t.b
+}
+
+// Thrift compact protocol type codes.
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs in the Thrift compact protocol. Field IDs
+// must be written in increasing order within a struct.
+type thriftWriter struct {
+	b    []byte
+	last []int16 // previous field ID of each open struct
+}
+
+func (t *thriftWriter) begin() { t.last = append(t.last, 0) }
+
+func (t *thriftWriter) end() {
+	t.b = append(t.b, 0) // stop field
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	last := &t.last[len(t.last)-1]
+	if d := id - *last; d > 0 && d <= 15 {
+		t.b = append(t.b, byte(d)<<4|typ)
+	} else {
+		t.b = append(t.b, typ)
+		t.b = binary.AppendUvarint(t.b, uint64(uint16(id<<1^id>>15)))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.elemI32(v)
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.b = binary.AppendUvarint(t.b, uint64(v<<1^v>>63))
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.elemBinary(s)
+}
+
+func (t *thriftWriter) boolean(id int16, v bool) {
+	if v {
+		t.field(id, thriftTrue)
+	} else {
+		t.field(id, thriftFalse)
+	}
+}
+
+func (t *thriftWriter) beginStruct(id int16) {
+	t.field(id, thriftStruct)
+	t.begin()
+}
+
+func (t *thriftWriter) list(id int16, elem byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.b = append(t.b, byte(n)<<4|elem)
+	} else {
+		t.b = append(t.b, 0xf0|elem)
+		t.b = binary.AppendUvarint(t.b, uint64(n))
+	}
+}
+
+// beginElem starts a struct element of a list.
+func (t *thriftWriter) beginElem() { t.begin() }
+
+func (t *thriftWriter) elemI32(v int32) {
+	t.b = binary.AppendUvarint(t.b, uint64(uint32(v<<1^v>>31)))
+}
+
+func (t *thriftWriter) elemBinary(s string) {
+	t.b = binary.AppendUvarint(t.b, uint64(len(s)))
+	t.b = append(t.b, s...)
+}
@@ -0,0 +1,66 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+)
+
+// CSVWriter writes rows as CSV with the Columns header. Times are RFC 3339
+// in UTC.
+type CSVWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+// NewCSVWriter returns a CSVWriter; header writes the Columns row first.
+func NewCSVWriter(w io.Writer, header bool) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w), header: header}
+}
+
+func (c *CSVWriter) Write(rows ...Row) error {
+	if c.header {
+		c.header = false
+		if err := c.w.Write(Columns); err != nil {
+			return err
+		}
+	}
+	for _, r := range rows {
+		if err := c.w.Write(r.record()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close writes the header if no row was written, and flushes.
+func (c *CSVWriter) Close() error {
+	if c.header {
+		c.Write()
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// NDJSONWriter writes one JSON object per row, keyed by Columns.
+type NDJSONWriter struct {
+	enc *json.Encoder
+}
+
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &NDJSONWriter{enc: enc}
+}
+
+func (n *NDJSONWriter) Write(rows ...Row) error {
+	for _, r := range rows {
+		r.FetchedAt = r.FetchedAt.UTC()
+		if err := n.enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *NDJSONWriter) Close() error { return nil }
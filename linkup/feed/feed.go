@@ -0,0 +1,402 @@
+// Package feed turns Linkup search results, or a monitor's accumulated
+// history, into RSS 2.0, Atom 1.0 and JSON Feed 1.1 documents. Item IDs are
+// derived from canonical URLs, so the same page keeps the same GUID across
+// runs and tracking-parameter variants.
+package feed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/canonical"
+	"github.com/raezil/linkup-go/linkup/monitor"
+)
+
+// Format is a feed format.
+type Format string
+
+const (
+	RSS  Format = "rss"
+	Atom Format = "atom"
+	JSON Format = "json"
+)
+
+// ParseFormat accepts rss, atom and json (also "jsonfeed").
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "rss", "rss2":
+		return RSS, nil
+	case "atom":
+		return Atom, nil
+	case "json", "jsonfeed":
+		return JSON, nil
+	}
+	return "", fmt.Errorf("feed: unknown format %q (want rss, atom or json)", s)
+}
+
+// ContentType returns the media type of f.
+func (f Format) ContentType() string {
+	switch f {
+	case Atom:
+		return "application/atom+xml; charset=utf-8"
+	case JSON:
+		return "application/feed+json; charset=utf-8"
+	}
+	return "application/rss+xml; charset=utf-8"
+}
+
+// DefaultLink is used when a feed has no Link of its own.
+const DefaultLink = "https://linkup.so"
+
+// SummaryChars caps item summaries.
+var SummaryChars = 500
+
+// Feed is a format-independent feed.
+type Feed struct {
+	ID          string // stable feed identifier; see SearchID
+	Title       string
+	Description string
+	Link        string // home page; default DefaultLink
+	SelfURL     string // where the feed itself is served, if known
+	Updated     time.Time
+	Items       []Item
+}
+
+// Item is one feed entry.
+type Item struct {
+	ID        string // default: GUID(URL)
+	Title     string
+	URL       string
+	Summary   string
+	Published time.Time
+}
+
+// GUID returns a stable identifier for a page: its canonical URL.
+func GUID(rawURL string) string {
+	return canonical.Key(rawURL)
+}
+
+// SearchID returns a stable feed ID for a query.
+func SearchID(q string) string {
+	sum := sha256.Sum256([]byte(q))
+	return "urn:linkup:search:" + hex.EncodeToString(sum[:8])
+}
+
+// FromResults builds a feed from search results, deduplicated by canonical
+// URL and in rank order. Results carry no dates, so items are stamped with
+// now.
+func FromResults(title string, q string, results []linkup.SearchResult, now time.Time) *Feed {
+	f := &Feed{
+		ID:          SearchID(q),
+		Title:       title,
+		Description: fmt.Sprintf("Linkup results for %q", q),
+		Updated:     now,
+	}
+	for _, r := range linkup.DedupeResults(results) {
+		f.Items = append(f.Items, Item{Title: r.Name, URL: r.URL, Summary: r.Content, Published: now})
+	}
+	return f
+}
+
+// FromRecords builds a feed from a monitor's history, newest first, with
+// each item dated when it was first seen. Answer records become items that
+// link to DefaultLink. limit <= 0 keeps every item.
+func FromRecords(title, q string, recs []monitor.Record, limit int) *Feed {
+	f := &Feed{
+		ID:          SearchID(q),
+		Title:       title,
+		Description: fmt.Sprintf("New Linkup results for %q", q),
+	}
+	for _, r := range recs {
+		switch r.Kind {
+		case monitor.KindResult, monitor.KindSource:
+			f.Items = append(f.Items, Item{Title: r.Title, URL: r.URL, Summary: r.Content, Published: r.Time})
+		case monitor.KindAnswer:
+			if r.Content == "" {
+				continue
+			}
+			f.Items = append(f.Items, Item{
+				ID:        fmt.Sprintf("urn:linkup:answer:%s:%d", r.Search, r.Time.Unix()),
+				Title:     "Answer updated: " + firstLine(r.Content),
+				Summary:   r.Content,
+				Published: r.Time,
+			})
+		}
+	}
+	sort.SliceStable(f.Items, func(i, j int) bool { return f.Items[i].Published.After(f.Items[j].Published) })
+	if limit > 0 && len(f.Items) > limit {
+		f.Items = f.Items[:limit]
+	}
+	if len(f.Items) > 0 {
+		f.Updated = f.Items[0].Published
+	}
+	return f
+}
+
+// Write renders f in the given format.
+func (f *Feed) Write(w io.Writer, format Format) error {
+	switch format {
+	case RSS:
+		return f.writeRSS(w)
+	case Atom:
+		return f.writeAtom(w)
+	case JSON:
+		return f.writeJSON(w)
+	}
+	return fmt.Errorf("feed: unknown format %q", format)
+}
+
+func (f *Feed) link() string {
+	if f.Link != "" {
+		return f.Link
+	}
+	return DefaultLink
+}
+
+func (f *Feed) updated() time.Time {
+	if f.Updated.IsZero() {
+		return time.Now()
+	}
+	return f.Updated
+}
+
+func (it *Item) id() string {
+	if it.ID != "" {
+		return it.ID
+	}
+	return GUID(it.URL)
+}
+
+func (it *Item) title() string {
+	if t := firstLine(it.Title); t != "" {
+		return t
+	}
+	return it.URL
+}
+
+func (it *Item) summary() string {
+	return truncate(strings.TrimSpace(it.Summary), SummaryChars)
+}
+
+// RSS 2.0
+
+type rssDoc struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr,omitempty"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	SelfLink      *atomLink `xml:"atom:link,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Generator     string    `xml:"generator"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	Description string  `xml:"description,omitempty"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func (f *Feed) writeRSS(w io.Writer) error {
+	doc := rssDoc{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.link(),
+			Description:   f.Description,
+			LastBuildDate: f.updated().Format(time.RFC1123Z),
+			Generator:     "linkup-go",
+		},
+	}
+	if f.SelfURL != "" {
+		doc.AtomNS = atomNS
+		doc.Channel.SelfLink = &atomLink{Href: f.SelfURL, Rel: "self", Type: RSS.mediaType()}
+	}
+	for _, it := range f.Items {
+		ri := rssItem{
+			Title:       it.title(),
+			Link:        it.URL,
+			GUID:        rssGUID{Value: it.id()},
+			Description: it.summary(),
+		}
+		if !it.Published.IsZero() {
+			ri.PubDate = it.Published.Format(time.RFC1123Z)
+		}
+		doc.Channel.Items = append(doc.Channel.Items, ri)
+	}
+	return writeXML(w, doc)
+}
+
+// Atom 1.0
+
+const atomNS = "http://www.w3.org/2005/Atom"
+
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"feed"`
+	NS        string      `xml:"xmlns,attr"`
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Subtitle  string      `xml:"subtitle,omitempty"`
+	Updated   string      `xml:"updated"`
+	Links     []atomLink  `xml:"link"`
+	Author    atomAuthor  `xml:"author"`
+	Generator string      `xml:"generator"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID        string    `xml:"id"`
+	Title     string    `xml:"title"`
+	Link      *atomLink `xml:"link,omitempty"`
+	Updated   string    `xml:"updated"`
+	Published string    `xml:"published,omitempty"`
+	Summary   string    `xml:"summary,omitempty"`
+}
+
+func (f *Feed) writeAtom(w io.Writer) error {
+	updated := f.updated()
+	doc := atomFeed{
+		NS:        atomNS,
+		ID:        f.ID,
+		Title:     f.Title,
+		Subtitle:  f.Description,
+		Updated:   updated.UTC().Format(time.RFC3339),
+		Links:     []atomLink{{Href: f.link(), Rel: "alternate"}},
+		Author:    atomAuthor{Name: "Linkup"},
+		Generator: "linkup-go",
+	}
+	if doc.ID == "" {
+		doc.ID = f.link()
+	}
+	if f.SelfURL != "" {
+		doc.Links = append(doc.Links, atomLink{Href: f.SelfURL, Rel: "self", Type: Atom.mediaType()})
+	}
+	for _, it := range f.Items {
+		at := it.Published
+		if at.IsZero() {
+			at = updated
+		}
+		e := atomEntry{
+			ID:        it.id(),
+			Title:     it.title(),
+			Updated:   at.UTC().Format(time.RFC3339),
+			Published: at.UTC().Format(time.RFC3339),
+			Summary:   it.summary(),
+		}
+		if it.URL != "" {
+			e.Link = &atomLink{Href: it.URL, Rel: "alternate"}
+		}
+		doc.Entries = append(doc.Entries, e)
+	}
+	return writeXML(w, doc)
+}
+
+// JSON Feed 1.1
+
+type jsonFeed struct {
+	Version     string     `json:"version"`
+	Title       string     `json:"title"`
+	HomePageURL string     `json:"home_page_url,omitempty"`
+	FeedURL     string     `json:"feed_url,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Items       []jsonItem `json:"items"`
+}
+
+type jsonItem struct {
+	ID            string `json:"id"`
+	URL           string `json:"url,omitempty"`
+	Title         string `json:"title,omitempty"`
+	ContentText   string `json:"content_text"`
+	Summary       string `json:"summary,omitempty"`
+	DatePublished string `json:"date_published,omitempty"`
+}
+
+func (f *Feed) writeJSON(w io.Writer) error {
+	doc := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		HomePageURL: f.link(),
+		FeedURL:     f.SelfURL,
+		Description: f.Description,
+		Items:       []jsonItem{},
+	}
+	for _, it := range f.Items {
+		ji := jsonItem{
+			ID:          it.id(),
+			URL:         it.URL,
+			Title:       it.title(),
+			ContentText: strings.TrimSpace(it.Summary),
+			Summary:     it.summary(),
+		}
+		if !it.Published.IsZero() {
+			ji.DatePublished = it.Published.UTC().Format(time.RFC3339)
+		}
+		doc.Items = append(doc.Items, ji)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func (f Format) mediaType() string {
+	mt, _, _ := strings.Cut(f.ContentType(), ";")
+	return mt
+}
+
+func writeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func firstLine(s string) string {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(s)
+}
+
+func truncate(s string, n int) string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return strings.TrimSpace(string(r[:n])) + "…"
+}
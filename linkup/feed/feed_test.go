@@ -0,0 +1,172 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/monitor"
+)
+
+var now = time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+
+func sample() *Feed {
+	return FromResults("Go releases", "go release", []linkup.SearchResult{
+		{Name: "Go 1.22 <released>", URL: "https://go.dev/blog/go1.22?utm_source=x", Content: "Go 1.22 & more"},
+		{Name: "dup", URL: "https://www.go.dev/blog/go1.22"},
+		{Name: "Notes", URL: "https://go.dev/doc/go1.22"},
+	}, now)
+}
+
+func TestGUID_StableAcrossVariants(t *testing.T) {
+	if GUID("https://www.go.dev/blog/?utm_source=a#x") != GUID("http://go.dev/blog") {
+		t.Fatal("GUIDs differ for the same page")
+	}
+}
+
+func TestRSS(t *testing.T) {
+	var buf bytes.Buffer
+	f := sample()
+	f.SelfURL = "http://localhost/go.rss"
+	if err := f.Write(&buf, RSS); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Channel struct {
+			Title string `xml:"title"`
+			Items []struct {
+				Title string `xml:"title"`
+				GUID  struct {
+					Perma string `xml:"isPermaLink,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"guid"`
+				PubDate string `xml:"pubDate"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("%v\n%s", err, buf.String())
+	}
+	items := doc.Channel.Items
+	if len(items) != 2 {
+		t.Fatalf("items = %d, want 2 (deduplicated)", len(items))
+	}
+	if items[0].Title != "Go 1.22 <released>" || items[0].GUID.Value != "https://go.dev/blog/go1.22" || items[0].GUID.Perma != "false" {
+		t.Fatalf("item = %+v", items[0])
+	}
+	if items[0].PubDate != "Mon, 06 May 2024 07:08:09 +0000" {
+		t.Fatalf("pubDate = %q", items[0].PubDate)
+	}
+	if !strings.Contains(buf.String(), `<atom:link href="http://localhost/go.rss" rel="self"`) {
+		t.Fatalf("missing self link:\n%s", buf.String())
+	}
+}
+
+func TestAtom(t *testing.T) {
+	var buf bytes.Buffer
+	if err := sample().Write(&buf, Atom); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		XMLName xml.Name
+		ID      string `xml:"id"`
+		Updated string `xml:"updated"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Updated string `xml:"updated"`
+			Link    struct {
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.XMLName.Space != atomNS || doc.ID != SearchID("go release") || doc.Updated != "2024-05-06T07:08:09Z" {
+		t.Fatalf("feed = %+v", doc)
+	}
+	if len(doc.Entries) != 2 || doc.Entries[1].ID != "https://go.dev/doc/go1.22" || doc.Entries[1].Link.Href != "https://go.dev/doc/go1.22" {
+		t.Fatalf("entries = %+v", doc.Entries)
+	}
+}
+
+func TestJSONFeed(t *testing.T) {
+	var buf bytes.Buffer
+	if err := sample().Write(&buf, JSON); err != nil {
+		t.Fatal(err)
+	}
+	var doc jsonFeed
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Version != "https://jsonfeed.org/version/1.1" || len(doc.Items) != 2 {
+		t.Fatalf("doc = %+v", doc)
+	}
+	if it := doc.Items[0]; it.ContentText != "Go 1.22 & more" || it.DatePublished != "2024-05-06T07:08:09Z" {
+		t.Fatalf("item = %+v", it)
+	}
+}
+
+func TestFromRecords_NewestFirst(t *testing.T) {
+	recs := []monitor.Record{
+		{Search: "s", Kind: monitor.KindResult, URL: "https://a.com", Title: "A", Time: now},
+		{Search: "s", Kind: monitor.KindRun, Time: now},
+		{Search: "s", Kind: monitor.KindAnswer, Content: "New answer\nmore", Time: now.Add(time.Hour)},
+		{Search: "s", Kind: monitor.KindResult, URL: "https://b.com", Title: "B", Time: now.Add(2 * time.Hour)},
+	}
+	f := FromRecords("t", "q", recs, 2)
+	if len(f.Items) != 2 || f.Items[0].Title != "B" || f.Items[1].Title != "Answer updated: New answer" {
+		t.Fatalf("items = %+v", f.Items)
+	}
+	if !f.Updated.Equal(now.Add(2 * time.Hour)) {
+		t.Fatalf("updated = %v", f.Updated)
+	}
+}
+
+func TestHandler_CachesAcrossFormats(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(`{"results":[{"type":"text","name":"A","url":"https://a.com","content":"x"}]}`))
+	}))
+	defer srv.Close()
+	c := linkup.NewClient("k", linkup.WithBaseURL(srv.URL))
+	h := &Handler{Feeds: map[string]Source{
+		"go": SearchSource(c, "Go", linkup.SearchRequest{Q: "go"}),
+	}}
+
+	for path, ct := range map[string]string{
+		"/feeds/go.rss":           "application/rss+xml",
+		"/feeds/go.atom":          "application/atom+xml",
+		"/feeds/go?format=json":   "application/feed+json",
+		"/feeds/go.rss?format=xx": "",
+	} {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		if ct == "" {
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("%s: code %d", path, rr.Code)
+			}
+			continue
+		}
+		if rr.Code != 200 || !strings.HasPrefix(rr.Header().Get("Content-Type"), ct) {
+			t.Errorf("%s: %d %s", path, rr.Code, rr.Header().Get("Content-Type"))
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("searched %d times, want 1", n)
+	}
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/feeds/nope.rss", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("unknown feed: %d", rr.Code)
+	}
+}
@@ -0,0 +1,177 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/monitor"
+)
+
+// Source produces a feed on demand.
+type Source func(ctx context.Context) (*Feed, error)
+
+// SearchSource runs req on every call and turns the results into a feed.
+// req.OutputType is forced to searchResults.
+func SearchSource(c *linkup.Client, title string, req linkup.SearchRequest) Source {
+	req.OutputType = linkup.OutputSearchResults
+	if req.Depth == "" {
+		req.Depth = linkup.DepthStandard
+	}
+	return func(ctx context.Context) (*Feed, error) {
+		resp, err := c.Search(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		results, err := resp.Results()
+		if err != nil {
+			return nil, err
+		}
+		return FromResults(title, req.Q, results, time.Now()), nil
+	}
+}
+
+// HistorySource serves the records of one monitor search from the store
+// file at storePath, newest first.
+func HistorySource(storePath, search, title, q string, limit int) Source {
+	return func(context.Context) (*Feed, error) {
+		recs, err := monitor.ReadRecords(storePath, search)
+		if err != nil {
+			return nil, err
+		}
+		return FromRecords(title, q, recs, limit), nil
+	}
+}
+
+// Handler serves named feeds at /<name>.rss, /<name>.atom and
+// /<name>.json (or /<name>?format=...). A name's feed is built at most once
+// per TTL regardless of format, so polling readers do not spend credits on
+// every request.
+type Handler struct {
+	Feeds map[string]Source
+	// TTL caches each built feed. Default 15m; negative disables caching.
+	TTL time.Duration
+	// Timeout bounds building a feed. Default 60s.
+	Timeout time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedFeed
+}
+
+type cachedFeed struct {
+	feed *Feed
+	at   time.Time
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if strings.HasSuffix(r.URL.Path, "/") {
+		h.index(w)
+		return
+	}
+	base := path.Base(r.URL.Path)
+	name, ext := base, ""
+	if i := strings.LastIndexByte(base, '.'); i > 0 {
+		name, ext = base[:i], base[i+1:]
+	}
+	if q := r.URL.Query().Get("format"); q != "" {
+		ext = q
+	}
+	if ext == "" {
+		ext = string(RSS)
+	}
+	format, err := ParseFormat(ext)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	src, ok := h.Feeds[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := h.get(r.Context(), name, src)
+	if err != nil {
+		http.Error(w, "feed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	// Copy so concurrent requests can set different self URLs.
+	out := *f
+	out.SelfURL = selfURL(r)
+
+	var buf bytes.Buffer
+	if err := out.Write(&buf, format); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	if !out.Updated.IsZero() {
+		w.Header().Set("Last-Modified", out.Updated.UTC().Format(http.TimeFormat))
+	}
+	w.Write(buf.Bytes())
+}
+
+func (h *Handler) get(ctx context.Context, name string, src Source) (*Feed, error) {
+	ttl := h.TTL
+	if ttl == 0 {
+		ttl = 15 * time.Minute
+	}
+	h.mu.Lock()
+	if c, ok := h.cache[name]; ok && ttl > 0 && time.Since(c.at) < ttl {
+		h.mu.Unlock()
+		return c.feed, nil
+	}
+	h.mu.Unlock()
+
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	f, err := src(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ttl > 0 {
+		h.mu.Lock()
+		if h.cache == nil {
+			h.cache = map[string]cachedFeed{}
+		}
+		h.cache[name] = cachedFeed{feed: f, at: time.Now()}
+		h.mu.Unlock()
+	}
+	return f, nil
+}
+
+// index lists the available feeds as plain text.
+func (h *Handler) index(w http.ResponseWriter) {
+	names := make([]string, 0, len(h.Feeds))
+	for n := range h.Feeds {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, n := range names {
+		w.Write([]byte(n + ".rss  " + n + ".atom  " + n + ".json\n"))
+	}
+}
+
+func selfURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
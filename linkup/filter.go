@@ -0,0 +1,217 @@
+package linkup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/raezil/linkup-go/linkup/internal/citation"
+)
+
+// ResultFilter post-filters search results on the client, after the API's
+// own IncludeDomains/ExcludeDomains (see package filter for a rule-based
+// implementation). FilterResults returns the results to keep, in order,
+// with Filter set on those it only annotates, and a report explaining every
+// result it dropped or annotated.
+type ResultFilter interface {
+	FilterResults(ctx context.Context, req SearchRequest, results []SearchResult) ([]SearchResult, FilterReport)
+}
+
+// FilterReport explains what a ResultFilter did to one search.
+type FilterReport struct {
+	Query     string           `json:"query"`
+	Total     int              `json:"total"`
+	Dropped   int              `json:"dropped"`
+	Annotated int              `json:"annotated"`
+	Decisions []FilterDecision `json:"decisions,omitempty"` // results that failed a check, by rank
+	// AnswerCitesDropped lists the ranks of dropped sources that a
+	// sourcedAnswer cited. Their citations are removed from the answer, but
+	// its text may still rely on them.
+	AnswerCitesDropped []int `json:"answerCitesDropped,omitempty"`
+}
+
+// FilterDecision is the verdict on one result that failed a check.
+type FilterDecision struct {
+	Rank    int            `json:"rank"` // 1-based, in the unfiltered list
+	URL     string         `json:"url"`
+	Name    string         `json:"name,omitempty"`
+	Dropped bool           `json:"dropped"` // false: kept and annotated
+	Reasons []FilterReason `json:"reasons"`
+}
+
+// FilterReason is one failed check.
+type FilterReason struct {
+	Check  string `json:"check"`          // e.g. "blocklist", "rule", "language"
+	Rule   string `json:"rule,omitempty"` // the rule's name, for named rules
+	Detail string `json:"detail"`
+}
+
+func (r FilterReason) String() string {
+	if r.Rule != "" {
+		return fmt.Sprintf("%s %q: %s", r.Check, r.Rule, r.Detail)
+	}
+	return r.Check + ": " + r.Detail
+}
+
+// String renders the report for people, one line per decision.
+func (r FilterReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%q: %d results, %d dropped, %d annotated\n", r.Query, r.Total, r.Dropped, r.Annotated)
+	for _, d := range r.Decisions {
+		verdict := "annotated"
+		if d.Dropped {
+			verdict = "dropped"
+		}
+		reasons := make([]string, len(d.Reasons))
+		for i, reason := range d.Reasons {
+			reasons[i] = reason.String()
+		}
+		fmt.Fprintf(&b, "  #%d %s %s: %s\n", d.Rank, verdict, d.URL, strings.Join(reasons, "; "))
+	}
+	if len(r.AnswerCitesDropped) > 0 {
+		fmt.Fprintf(&b, "  warning: the answer cites dropped sources %s; their citations were removed\n", ranks(r.AnswerCitesDropped))
+	}
+	return b.String()
+}
+
+func ranks(ns []int) string {
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = fmt.Sprintf("#%d", n)
+	}
+	return strings.Join(s, ", ")
+}
+
+// WithResultFilter runs every searchResults and sourcedAnswer search through
+// f: dropped results and answer sources are removed from the response, and
+// annotated ones carry their reasons in a "filter" field. Structured output
+// is returned as is. report, if not nil, receives the report of every
+// filtered search. Citations of dropped sources are removed from the answer
+// and listed in FilterReport.AnswerCitesDropped. Recorders still archive the
+// unfiltered response.
+func WithResultFilter(f ResultFilter, report func(context.Context, FilterReport)) Option {
+	return func(c *Client) { c.filter, c.filterReport = f, report }
+}
+
+// filterResponse applies the result filter to resp.
+func (c *Client) filterResponse(ctx context.Context, req SearchRequest, resp SearchResponse) (SearchResponse, error) {
+	var b []byte
+	var report FilterReport
+	switch req.OutputType {
+	case OutputSearchResults, "":
+		results, err := resp.Results()
+		if err != nil {
+			return SearchResponse{}, err
+		}
+		results, report = c.filter.FilterResults(ctx, req, results)
+		if b, err = json.Marshal(SearchResults{Results: results}); err != nil {
+			return SearchResponse{}, err
+		}
+	case OutputSourcedAnswer:
+		// Sources are filtered as results; other fields are kept as sent.
+		filtered := false
+		out, cited, err := dropSources(resp, func(sources []AnswerSource) []AnswerSource {
+			results := make([]SearchResult, len(sources))
+			for i, s := range sources {
+				results[i] = SearchResult{Type: "text", Name: s.Label(), URL: s.URL, Content: s.Snippet}
+			}
+			results, report = c.filter.FilterResults(ctx, req, results)
+			filtered = true
+			kept := make([]AnswerSource, 0, len(results))
+			i := 0
+			for _, r := range results {
+				// Kept results are in order, so find each one's source.
+				for i < len(sources) && sources[i].URL != r.URL {
+					i++
+				}
+				if i == len(sources) {
+					break
+				}
+				s := sources[i]
+				s.Filter = r.Filter
+				kept = append(kept, s)
+				i++
+			}
+			return kept
+		})
+		if err != nil || !filtered {
+			return out, err
+		}
+		report.AnswerCitesDropped = cited
+		b = out.Raw
+	default:
+		return resp, nil
+	}
+	if c.filterReport != nil {
+		c.filterReport(ctx, report)
+	}
+	return SearchResponse{Raw: b}, nil
+}
+
+// dropSources replaces the sources of a sourcedAnswer response with those
+// keep returns, a subsequence of them in order, and renumbers the answer's
+// citations to match: citations of dropped sources are removed. It returns
+// the 1-based indexes of the dropped sources the answer cited. Other fields
+// are kept as sent, and a response without sources is returned as is,
+// without calling keep.
+func dropSources(resp SearchResponse, keep func([]AnswerSource) []AnswerSource) (SearchResponse, []int, error) {
+	var payload map[string]json.RawMessage
+	var sources []AnswerSource
+	if err := json.Unmarshal(resp.Raw, &payload); err != nil {
+		return SearchResponse{}, nil, err
+	}
+	raw, ok := payload["sources"]
+	if !ok {
+		return resp, nil, nil
+	}
+	if err := json.Unmarshal(raw, &sources); err != nil {
+		return SearchResponse{}, nil, err
+	}
+	kept := keep(sources)
+
+	// index maps the 1-based index of each source to its new one, or 0.
+	index := make(map[int]int, len(kept))
+	i := 0
+	for j, k := range kept {
+		for i < len(sources) && sources[i].URL != k.URL {
+			i++
+		}
+		if i == len(sources) {
+			break
+		}
+		index[i+1] = j + 1
+		i++
+	}
+	var cited []int
+	if a, ok := payload["answer"]; ok && len(kept) < len(sources) {
+		var answer string
+		if err := json.Unmarshal(a, &answer); err != nil {
+			return SearchResponse{}, nil, err
+		}
+		answer = citation.Renumber(answer, func(n int) int {
+			if n < 1 || n > len(sources) {
+				return n // an orphan stays as written
+			}
+			if index[n] == 0 && !slices.Contains(cited, n) {
+				cited = append(cited, n)
+			}
+			return index[n]
+		})
+		slices.Sort(cited)
+		var err error
+		if payload["answer"], err = json.Marshal(answer); err != nil {
+			return SearchResponse{}, nil, err
+		}
+	}
+	var err error
+	if payload["sources"], err = json.Marshal(kept); err != nil {
+		return SearchResponse{}, nil, err
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return SearchResponse{}, nil, err
+	}
+	return SearchResponse{Raw: b}, cited, nil
+}
@@ -0,0 +1,103 @@
+package filter
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+)
+
+// dateHead bounds how much of the content Published looks at: bylines and
+// datelines come first, dates further down are usually about something
+// else.
+const dateHead = 400
+
+var (
+	urlDate     = regexp.MustCompile(`/((?:19|20)\d\d)[/-](\d\d?)(?:[/-](\d\d?))?(?:[/.-]|$)`)
+	isoDate     = regexp.MustCompile(`\b((?:19|20)\d\d)-(\d\d)-(\d\d)\b`)
+	monthFirst  = regexp.MustCompile(`(?i)\b` + monthName + `\.? (\d\d?)(?:st|nd|rd|th)?,? ((?:19|20)\d\d)\b`)
+	dayFirst    = regexp.MustCompile(`(?i)\b(\d\d?) ` + monthName + `\.? ((?:19|20)\d\d)\b`)
+	monthPrefix = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+)
+
+const monthName = `(jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sep(?:t(?:ember)?)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)`
+
+// Published guesses when r was published, from a date in its URL path
+// (/2024/05/12/, /2024-05-12-title, or /2024/05/ for the first of the
+// month) or else near the start of its content ("2024-05-12", "May 12,
+// 2024", "12 May 2024"). Dates are UTC midnight.
+func Published(r linkup.SearchResult) (time.Time, bool) {
+	path := r.URL
+	if i := strings.Index(path, "://"); i >= 0 {
+		if j := strings.IndexByte(path[i+3:], '/'); j >= 0 {
+			path = path[i+3+j:]
+		} else {
+			path = ""
+		}
+	}
+	if m := urlDate.FindStringSubmatch(path); m != nil {
+		if m[3] == "" {
+			m[3] = "1"
+		}
+		if t, ok := mkdate(m[1], m[2], m[3]); ok {
+			return t, true
+		}
+	}
+
+	head := r.Content
+	if len(head) > dateHead {
+		head = head[:dateHead]
+	}
+	type found struct {
+		at int
+		t  time.Time
+	}
+	var first *found
+	try := func(loc []int, t time.Time, ok bool) {
+		if ok && (first == nil || loc[0] < first.at) {
+			first = &found{loc[0], t}
+		}
+	}
+	if m := isoDate.FindStringSubmatchIndex(head); m != nil {
+		t, ok := mkdate(head[m[2]:m[3]], head[m[4]:m[5]], head[m[6]:m[7]])
+		try(m, t, ok)
+	}
+	if m := monthFirst.FindStringSubmatchIndex(head); m != nil {
+		t, ok := mkdate(head[m[6]:m[7]], month(head[m[2]:m[3]]), head[m[4]:m[5]])
+		try(m, t, ok)
+	}
+	if m := dayFirst.FindStringSubmatchIndex(head); m != nil {
+		t, ok := mkdate(head[m[6]:m[7]], month(head[m[4]:m[5]]), head[m[2]:m[3]])
+		try(m, t, ok)
+	}
+	if first == nil {
+		return time.Time{}, false
+	}
+	return first.t, true
+}
+
+func month(name string) string {
+	for i, p := range monthPrefix {
+		if strings.EqualFold(name[:3], p) {
+			return strconv.Itoa(i + 1)
+		}
+	}
+	return ""
+}
+
+// mkdate builds a date, rejecting ones that do not exist (2023-02-30).
+func mkdate(y, m, d string) (time.Time, bool) {
+	year, err1 := strconv.Atoi(y)
+	mon, err2 := strconv.Atoi(m)
+	day, err3 := strconv.Atoi(d)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return time.Time{}, false
+	}
+	t := time.Date(year, time.Month(mon), day, 0, 0, 0, 0, time.UTC)
+	if t.Year() != year || int(t.Month()) != mon || t.Day() != day {
+		return time.Time{}, false
+	}
+	return t, true
+}
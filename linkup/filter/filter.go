@@ -0,0 +1,286 @@
+// Package filter is a local result-filter stage for compliance and quality
+// rules the API's IncludeDomains/ExcludeDomains cannot express: domain
+// block and allow lists, regular expressions on titles, content or URLs, a
+// minimum content length, language detection and publish-date windows. A
+// Filter drops or annotates each failing result and explains why in a
+// linkup.FilterReport. Install it with linkup.WithResultFilter, or call
+// FilterResults directly.
+package filter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/canonical"
+	"github.com/raezil/linkup-go/linkup/domains"
+)
+
+// Check names, as reported in linkup.FilterReason.Check.
+const (
+	CheckAllowlist = "allowlist"
+	CheckBlocklist = "blocklist"
+	CheckRule      = "rule"
+	CheckLength    = "min-length"
+	CheckLanguage  = "language"
+	CheckDate      = "date"
+)
+
+// Action is what happens to a result that fails a check.
+type Action string
+
+const (
+	Drop     Action = "drop"     // remove it (the default)
+	Annotate Action = "annotate" // keep it, with the reason in SearchResult.Filter
+)
+
+// Field is the part of a result a Rule matches.
+type Field string
+
+const (
+	FieldAny     Field = ""        // title or content
+	FieldTitle   Field = "title"   // SearchResult.Name
+	FieldContent Field = "content" // SearchResult.Content
+	FieldURL     Field = "url"
+)
+
+// Rule fails results whose Field matches Pattern.
+type Rule struct {
+	Name    string // reported as FilterReason.Rule; default: the pattern
+	Field   Field
+	Pattern string // RE2 syntax, e.g. `(?i)\bcasino\b`
+	Action  Action // default: Config.Action
+}
+
+// Config configures a Filter. Every check is off unless configured.
+type Config struct {
+	// BlockDomains fails results on these domains or their subdomains.
+	// AllowDomains, if set, fails results on any other domain. Entries are
+	// domains.Expand entries: domains, @name for a list in Groups, or @path
+	// for a list file.
+	BlockDomains []string
+	AllowDomains []string
+	Groups       map[string][]string
+
+	Rules []Rule
+
+	// MinContentLength fails text results with fewer characters of
+	// content, counting runs of whitespace as one.
+	MinContentLength int
+
+	// Languages, if set, fails text results detected (see Detect) as a
+	// language not listed, as ISO 639-1 codes. Undetected results pass.
+	Languages []string
+
+	// PublishedAfter, PublishedBefore and MaxAge fail results dated (see
+	// Published) outside the window. Undated results pass unless
+	// RequireDate is set.
+	PublishedAfter  time.Time
+	PublishedBefore time.Time
+	MaxAge          time.Duration
+	RequireDate     bool
+
+	// Action applies to failed checks, and to rules without their own.
+	// Default Drop.
+	Action Action
+
+	// Now is the reference time for MaxAge. Default time.Now.
+	Now func() time.Time
+}
+
+// Filter applies a Config. It is safe for concurrent use.
+type Filter struct {
+	cfg   Config
+	block []string
+	allow []string
+	rules []rule
+	langs []string
+}
+
+type rule struct {
+	Rule
+	re *regexp.Regexp
+}
+
+var _ linkup.ResultFilter = (*Filter)(nil)
+
+// New validates cfg and expands its domain lists.
+func New(cfg Config) (*Filter, error) {
+	if cfg.Action == "" {
+		cfg.Action = Drop
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	var errs []error
+	if !validAction(cfg.Action) {
+		errs = append(errs, fmt.Errorf("filter: unknown action %q (drop|annotate)", cfg.Action))
+	}
+	if cfg.MinContentLength < 0 || cfg.MaxAge < 0 {
+		errs = append(errs, errors.New("filter: MinContentLength and MaxAge must not be negative"))
+	}
+	f := &Filter{cfg: cfg}
+	var err error
+	if len(cfg.BlockDomains) > 0 {
+		if f.block, err = domains.Expand(cfg.BlockDomains, cfg.Groups); err != nil {
+			errs = append(errs, fmt.Errorf("filter: block domains: %w", err))
+		}
+	}
+	if len(cfg.AllowDomains) > 0 {
+		if f.allow, err = domains.Expand(cfg.AllowDomains, cfg.Groups); err != nil {
+			errs = append(errs, fmt.Errorf("filter: allow domains: %w", err))
+		}
+	}
+	for i, r := range cfg.Rules {
+		if r.Name == "" {
+			r.Name = r.Pattern
+		}
+		if r.Action == "" {
+			r.Action = cfg.Action
+		}
+		re, err := regexp.Compile(r.Pattern)
+		switch {
+		case r.Pattern == "":
+			errs = append(errs, fmt.Errorf("filter: rule %d: empty pattern", i+1))
+		case err != nil:
+			errs = append(errs, fmt.Errorf("filter: rule %q: %w", r.Name, err))
+		case !validAction(r.Action):
+			errs = append(errs, fmt.Errorf("filter: rule %q: unknown action %q", r.Name, r.Action))
+		case !slices.Contains([]Field{FieldAny, FieldTitle, FieldContent, FieldURL}, r.Field):
+			errs = append(errs, fmt.Errorf("filter: rule %q: unknown field %q (title|content|url)", r.Name, r.Field))
+		}
+		f.rules = append(f.rules, rule{r, re})
+	}
+	for _, l := range cfg.Languages {
+		f.langs = append(f.langs, strings.ToLower(strings.TrimSpace(l)))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func validAction(a Action) bool { return a == Drop || a == Annotate }
+
+// FilterResults implements linkup.ResultFilter.
+func (f *Filter) FilterResults(_ context.Context, req linkup.SearchRequest, results []linkup.SearchResult) ([]linkup.SearchResult, linkup.FilterReport) {
+	report := linkup.FilterReport{Query: req.Q, Total: len(results)}
+	kept := make([]linkup.SearchResult, 0, len(results))
+	for i, r := range results {
+		reasons, drop := f.Check(r)
+		if len(reasons) == 0 {
+			kept = append(kept, r)
+			continue
+		}
+		report.Decisions = append(report.Decisions, linkup.FilterDecision{
+			Rank: i + 1, URL: r.URL, Name: r.Name, Dropped: drop, Reasons: reasons,
+		})
+		if drop {
+			report.Dropped++
+			continue
+		}
+		report.Annotated++
+		r.Filter = append(slices.Clip(r.Filter), reasons...)
+		kept = append(kept, r)
+	}
+	return kept, report
+}
+
+// Check runs every check on r and returns the failures, and whether any of
+// them drops r.
+func (f *Filter) Check(r linkup.SearchResult) (reasons []linkup.FilterReason, drop bool) {
+	fail := func(a Action, check, rule, format string, args ...any) {
+		reasons = append(reasons, linkup.FilterReason{Check: check, Rule: rule, Detail: fmt.Sprintf(format, args...)})
+		drop = drop || a == Drop
+	}
+	host := canonical.Host(r.URL)
+	if f.allow != nil && !domains.Match(host, f.allow) {
+		fail(f.cfg.Action, CheckAllowlist, "", "%s is not an allowed domain", host)
+	}
+	if d := matchDomain(host, f.block); d != "" {
+		fail(f.cfg.Action, CheckBlocklist, "", "%s is blocked (%s)", host, d)
+	}
+	for _, ru := range f.rules {
+		if m, field, ok := ru.match(r); ok {
+			fail(ru.Action, CheckRule, ru.Name, "%s matches %q", field, m)
+		}
+	}
+	if r.Type != "image" {
+		if n := f.cfg.MinContentLength; n > 0 {
+			if got := utf8.RuneCountInString(strings.Join(strings.Fields(r.Content), " ")); got < n {
+				fail(f.cfg.Action, CheckLength, "", "content has %d characters, want at least %d", got, n)
+			}
+		}
+		if len(f.langs) > 0 {
+			if lang := Detect(r.Name + "\n" + r.Content); lang != "" && !slices.Contains(f.langs, lang) {
+				fail(f.cfg.Action, CheckLanguage, "", "detected %q, want %s", lang, strings.Join(f.langs, " or "))
+			}
+		}
+	}
+	f.checkDate(r, fail)
+	return reasons, drop
+}
+
+func (f *Filter) checkDate(r linkup.SearchResult, fail func(a Action, check, rule, format string, args ...any)) {
+	c := f.cfg
+	if c.PublishedAfter.IsZero() && c.PublishedBefore.IsZero() && c.MaxAge == 0 && !c.RequireDate {
+		return
+	}
+	t, ok := Published(r)
+	if !ok {
+		if c.RequireDate {
+			fail(c.Action, CheckDate, "", "no publish date found")
+		}
+		return
+	}
+	day := t.Format(time.DateOnly)
+	switch {
+	case !c.PublishedAfter.IsZero() && t.Before(c.PublishedAfter):
+		fail(c.Action, CheckDate, "", "published %s, before %s", day, c.PublishedAfter.Format(time.DateOnly))
+	case !c.PublishedBefore.IsZero() && !t.Before(c.PublishedBefore):
+		fail(c.Action, CheckDate, "", "published %s, not before %s", day, c.PublishedBefore.Format(time.DateOnly))
+	case c.MaxAge > 0 && t.Before(c.Now().Add(-c.MaxAge)):
+		fail(c.Action, CheckDate, "", "published %s, older than %s", day, age(c.MaxAge))
+	}
+}
+
+// age formats d in days when it is a whole number of them.
+func age(d time.Duration) string {
+	const day = 24 * time.Hour
+	if d%day == 0 {
+		return fmt.Sprintf("%d days", d/day)
+	}
+	return d.String()
+}
+
+// matchDomain returns the entry of list that host is on, if any.
+func matchDomain(host string, list []string) string {
+	for _, d := range list {
+		if domains.Match(host, []string{d}) {
+			return d
+		}
+	}
+	return ""
+}
+
+// match returns the first match of the rule in r and the field it is in.
+func (ru rule) match(r linkup.SearchResult) (string, Field, bool) {
+	fields := []struct {
+		f Field
+		v string
+	}{{FieldTitle, r.Name}, {FieldContent, r.Content}, {FieldURL, r.URL}}
+	for _, fv := range fields {
+		if ru.Field == fv.f || ru.Field == FieldAny && fv.f != FieldURL {
+			if loc := ru.re.FindStringIndex(fv.v); loc != nil {
+				return fv.v[loc[0]:loc[1]], fv.f, true
+			}
+		}
+	}
+	return "", "", false
+}
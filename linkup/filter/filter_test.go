@@ -0,0 +1,163 @@
+package filter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+)
+
+const (
+	english = "The committee said that the new rules are in force from this week, and it is expected to review them by the end of the year."
+	german  = "Der Ausschuss sagte, dass die neuen Regeln ab dieser Woche gelten und dass sie bis zum Ende des Jahres nicht geändert werden."
+	french  = "Le comité a déclaré que les nouvelles règles sont en vigueur dans la semaine et qu'il les examinera avant la fin de l'année."
+)
+
+func TestFilterResults(t *testing.T) {
+	dir := t.TempDir()
+	list := filepath.Join(dir, "blocked.txt")
+	os.WriteFile(list, []byte("# spam\ncontent-farm.io\n"), 0o644)
+	f, err := New(Config{
+		BlockDomains: []string{"@lowquality", "@" + list},
+		Groups:       map[string][]string{"lowquality": {"*.spam.com"}},
+		Rules: []Rule{
+			{Name: "gambling", Pattern: `(?i)\bcasino\b`},
+			{Name: "sponsored", Field: FieldURL, Pattern: `[?&]sponsored=`, Action: Annotate},
+		},
+		MinContentLength: 40,
+		Languages:        []string{"en", "DE"},
+		MaxAge:           365 * 24 * time.Hour,
+		Now:              func() time.Time { return time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := []linkup.SearchResult{
+		{Type: "text", Name: "Rules", URL: "https://ok.org/2025/03/01/rules", Content: english},
+		{Type: "text", Name: "Regeln", URL: "https://ok.de/regeln", Content: german},
+		{Type: "text", Name: "Règles", URL: "https://ok.fr/regles", Content: french},
+		{Type: "text", Name: "Spam", URL: "https://www.news.spam.com/x", Content: english},
+		{Type: "text", Name: "Farm", URL: "https://content-farm.io/y", Content: english},
+		{Type: "text", Name: "Best Casino bonuses", URL: "https://ok.org/c", Content: english},
+		{Type: "text", Name: "Short", URL: "https://ok.org/s", Content: "Too   short."},
+		{Type: "text", Name: "Old", URL: "https://ok.org/2020/01/02/old", Content: english},
+		{Type: "text", Name: "Ad", URL: "https://ok.org/p?sponsored=1", Content: english},
+		{Type: "image", Name: "Chart", URL: "https://ok.org/chart.png"},
+	}
+	kept, report := f.FilterResults(context.Background(), linkup.SearchRequest{Q: "rules"}, results)
+
+	var names []string
+	for _, r := range kept {
+		names = append(names, r.Name)
+	}
+	if got := strings.Join(names, ","); got != "Rules,Regeln,Ad,Chart" {
+		t.Fatalf("kept %s", got)
+	}
+	if len(kept[2].Filter) != 1 || kept[2].Filter[0].Rule != "sponsored" || kept[0].Filter != nil {
+		t.Fatalf("annotations = %+v / %+v", kept[2].Filter, kept[0].Filter)
+	}
+	if report.Query != "rules" || report.Total != 10 || report.Dropped != 6 || report.Annotated != 1 || len(report.Decisions) != 7 {
+		t.Fatalf("report = %+v", report)
+	}
+	want := map[int]string{
+		3: `language: detected "fr", want en or de`,
+		4: "blocklist: news.spam.com is blocked (spam.com)",
+		5: "blocklist: content-farm.io is blocked (content-farm.io)",
+		6: `rule "gambling": title matches "Casino"`,
+		7: "min-length: content has 10 characters, want at least 40",
+		8: "date: published 2020-01-02, older than 365 days",
+		9: `rule "sponsored": url matches "?sponsored="`,
+	}
+	for _, d := range report.Decisions {
+		if len(d.Reasons) != 1 || d.Reasons[0].String() != want[d.Rank] {
+			t.Errorf("#%d: %v, want %s", d.Rank, d.Reasons, want[d.Rank])
+		}
+		if d.Dropped != (d.Rank != 9) {
+			t.Errorf("#%d dropped = %v", d.Rank, d.Dropped)
+		}
+	}
+	if s := report.String(); !strings.Contains(s, "#4 dropped https://www.news.spam.com/x: blocklist") {
+		t.Errorf("String() =\n%s", s)
+	}
+}
+
+func TestFilterAllowAndAnnotate(t *testing.T) {
+	f, err := New(Config{
+		AllowDomains: []string{"gov.uk"},
+		RequireDate:  true,
+		Action:       Annotate,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reasons, drop := f.Check(linkup.SearchResult{URL: "https://example.com/a", Content: "undated"})
+	if drop || len(reasons) != 2 || reasons[0].Check != CheckAllowlist || reasons[1].Check != CheckDate {
+		t.Fatalf("reasons = %v, drop = %v", reasons, drop)
+	}
+	if reasons, _ := f.Check(linkup.SearchResult{URL: "https://www.ons.gov.uk/x", Content: "Published 3 March 2024."}); len(reasons) != 0 {
+		t.Fatalf("reasons = %v", reasons)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	for _, cfg := range []Config{
+		{Action: "delete"},
+		{Rules: []Rule{{Pattern: "("}}},
+		{Rules: []Rule{{Pattern: ""}}},
+		{Rules: []Rule{{Pattern: "x", Field: "body"}}},
+		{BlockDomains: []string{"@missing"}},
+		{MinContentLength: -1},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("New(%+v): want error", cfg)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for text, want := range map[string]string{
+		english: "en",
+		german:  "de",
+		french:  "fr",
+		"El comité dijo que las nuevas normas están en vigor desde esta semana y que se revisarán antes del final del año.":                 "es",
+		"Il comitato ha detto che le nuove regole sono in vigore da questa settimana e che non saranno riviste prima della fine dell'anno.": "it",
+		"Комитет заявил, что новые правила вступают в силу на этой неделе.":                                                                 "ru",
+		"Комітет заявив, що нові правила набувають чинності цього тижня.":                                                                   "uk",
+		"委員会は、新しい規則が今週から施行されると述べました。これは重要な変更です。":                                                                                            "ja",
+		"委员会表示新规定将从本周开始生效并将在年底之前进行审查这是一个重要的变化我们会继续关注":                                                                                       "zh",
+		"위원회는 새로운 규칙이 이번 주부터 시행된다고 말했습니다 이것은 중요한 변화입니다":                                                                                     "ko",
+		"Too short":             "",
+		"1234 5678 9999 -- ...": "",
+		"Lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor": "",
+	} {
+		if got := Detect(text); got != want {
+			t.Errorf("Detect(%.30q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestPublished(t *testing.T) {
+	for _, tc := range []struct {
+		url, content, want string
+	}{
+		{"https://ex.com/2024/05/12/post", "", "2024-05-12"},
+		{"https://ex.com/blog/2024-05-12-post.html", "", "2024-05-12"},
+		{"https://ex.com/2024/05/", "", "2024-05-01"},
+		{"https://ex.com/products/2024-1000", "", ""},
+		{"https://2024.ex.com/a", "", ""},
+		{"https://ex.com/a", "Updated 2023-11-02 by staff, first seen May 1, 2020", "2023-11-02"},
+		{"https://ex.com/a", "By Jane Doe | September 3rd, 2022 | 5 min read", "2022-09-03"},
+		{"https://ex.com/a", "Posted 14 Feb. 2021", "2021-02-14"},
+		{"https://ex.com/a", "The marketing 5 2020 plan, 2023-02-30", ""},
+		{"https://ex.com/a", strings.Repeat("x", dateHead) + " 2020-01-01", ""},
+	} {
+		got, ok := Published(linkup.SearchResult{URL: tc.url, Content: tc.content})
+		if s := got.Format(time.DateOnly); ok != (tc.want != "") || ok && s != tc.want {
+			t.Errorf("Published(%s, %.30q) = %s, %v; want %q", tc.url, tc.content, s, ok, tc.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package filter
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopwords are frequent short words of Latin-script languages, chosen to
+// overlap as little as possible.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "for", "with", "are", "this", "was", "it", "on", "be", "by", "or", "from", "which", "have"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "mit", "den", "ein", "eine", "auf", "für", "sich", "dem", "auch", "wird", "sind", "von", "zu", "im"},
+	"fr": {"le", "la", "les", "et", "des", "est", "une", "du", "pour", "dans", "que", "qui", "pas", "sur", "au", "avec", "sont", "ce", "il", "aux"},
+	"es": {"el", "los", "las", "y", "del", "es", "por", "con", "una", "para", "que", "se", "su", "como", "más", "pero", "sus", "al", "lo", "fue"},
+	"it": {"il", "di", "che", "è", "della", "per", "non", "gli", "una", "con", "sono", "delle", "nel", "alla", "anche", "più", "dei", "questo", "lo", "ha"},
+	"pt": {"o", "os", "e", "do", "da", "em", "não", "uma", "com", "para", "que", "dos", "das", "se", "mais", "por", "são", "como", "ao", "é"},
+	"nl": {"de", "het", "een", "en", "van", "is", "niet", "dat", "op", "te", "zijn", "voor", "met", "ook", "wordt", "aan", "maar", "bij", "er", "naar"},
+}
+
+var stopwordLangs = func() map[string][]string {
+	m := map[string][]string{}
+	for lang, words := range stopwords {
+		for _, w := range words {
+			m[w] = append(m[w], lang)
+		}
+	}
+	return m
+}()
+
+// scripts maps writing systems used by a single common language to it.
+var scripts = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Han, "zh"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+}
+
+// Detect guesses the language of text as an ISO 639-1 code. Text mostly in
+// a non-Latin script is identified by its script (Japanese by kana among
+// Han, Ukrainian by its letters among Cyrillic); Latin text by counting
+// stopwords of en, de, fr, es, it, pt and nl. Detect returns "" when the
+// text is too short or the guess is not clear.
+func Detect(text string) string {
+	letters, latin := 0, 0
+	counts := map[string]int{}
+	uk := false
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.Is(unicode.Latin, r) {
+			latin++
+			continue
+		}
+		for _, s := range scripts {
+			if unicode.Is(s.table, r) {
+				counts[s.lang]++
+				break
+			}
+		}
+		uk = uk || strings.ContainsRune("іїєґІЇЄҐ", r)
+	}
+	if letters < 20 {
+		return ""
+	}
+	if latin*2 < letters {
+		if counts["ja"] > 0 && counts["ja"]*10 >= counts["zh"] {
+			counts["ja"] += counts["zh"]
+		}
+		best, n := best(counts)
+		if n*2 < letters {
+			return ""
+		}
+		if best == "ru" && uk {
+			return "uk"
+		}
+		return best
+	}
+
+	hits := map[string]int{}
+	words := 0
+	for w := range strings.FieldsFuncSeq(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		words++
+		for _, lang := range stopwordLangs[w] {
+			hits[lang]++
+		}
+	}
+	lang, n := best(hits)
+	second := 0
+	for l, c := range hits {
+		if l != lang && c > second {
+			second = c
+		}
+	}
+	// At least 3 hits, 1 in 20 words and a clear lead over the runner-up.
+	if n < 3 || n*20 < words || n*2 < second*3 {
+		return ""
+	}
+	return lang
+}
+
+// best returns the key with the highest count, the smallest on ties.
+func best(counts map[string]int) (string, int) {
+	lang, n := "", 0
+	for l, c := range counts {
+		if c > n || c == n && l < lang {
+			lang, n = l, c
+		}
+	}
+	return lang, n
+}
@@ -0,0 +1,92 @@
+package linkup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// hostFilter drops results on drop and annotates those on flag.
+type hostFilter struct{ drop, flag string }
+
+func (f hostFilter) FilterResults(_ context.Context, req SearchRequest, results []SearchResult) ([]SearchResult, FilterReport) {
+	report := FilterReport{Query: req.Q, Total: len(results)}
+	var kept []SearchResult
+	for i, r := range results {
+		switch {
+		case strings.Contains(r.URL, f.drop):
+			report.Dropped++
+			report.Decisions = append(report.Decisions, FilterDecision{Rank: i + 1, URL: r.URL, Dropped: true,
+				Reasons: []FilterReason{{Check: "blocklist", Detail: "blocked"}}})
+			continue
+		case strings.Contains(r.URL, f.flag):
+			report.Annotated++
+			r.Filter = []FilterReason{{Check: "rule", Rule: "flag", Detail: "flagged"}}
+			report.Decisions = append(report.Decisions, FilterDecision{Rank: i + 1, URL: r.URL, Reasons: r.Filter})
+		}
+		kept = append(kept, r)
+	}
+	return kept, report
+}
+
+func TestResultFilter(t *testing.T) {
+	c, srv := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req SearchRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.OutputType == OutputSourcedAnswer {
+			fmt.Fprint(w, `{"answer":"X [1]. Y [2, 3].","sources":[{"name":"a","url":"https://bad.com/1"},{"name":"b","url":"https://flag.org/2"},{"name":"c","url":"https://ok.org/3"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"results":[{"type":"text","url":"https://ok.org/1"},{"type":"text","url":"https://bad.com/2"},{"type":"text","url":"https://flag.org/3"}]}`)
+	})
+	defer srv.Close()
+	var log callLog
+	var reports []FilterReport
+	WithRecorder(&log)(c)
+	WithResultFilter(hostFilter{drop: "bad.com", flag: "flag.org"}, func(_ context.Context, r FilterReport) { reports = append(reports, r) })(c)
+	ctx := context.Background()
+
+	resp, err := c.Search(ctx, SearchRequest{Q: "q", OutputType: OutputSearchResults})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := resp.Results()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[1].URL != "https://flag.org/3" || len(results[1].Filter) != 1 || results[0].Filter != nil {
+		t.Fatalf("results = %+v", results)
+	}
+	if len(reports) != 1 || reports[0].Dropped != 1 || reports[0].Annotated != 1 || reports[0].Decisions[0].Rank != 2 {
+		t.Fatalf("reports = %+v", reports)
+	}
+	if !strings.Contains(string(log[0].Response), "bad.com") {
+		t.Fatal("the recorder should archive the unfiltered response")
+	}
+
+	resp, err = c.Search(ctx, SearchRequest{Q: "q", OutputType: OutputSourcedAnswer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ans, err := resp.SourcedAnswer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ans.Answer != "X. Y [1, 2]." || len(ans.Sources) != 2 || ans.Sources[0].Name != "b" || ans.Sources[0].Filter[0].Rule != "flag" || ans.Sources[1].Filter != nil {
+		t.Fatalf("answer = %s", resp.Raw)
+	}
+	if r := reports[1]; len(r.AnswerCitesDropped) != 1 || r.AnswerCitesDropped[0] != 1 || !strings.Contains(r.String(), "answer cites dropped sources #1") {
+		t.Fatalf("report = %+v", r)
+	}
+
+	// Structured output passes through untouched and unreported.
+	if _, err := c.Search(ctx, SearchRequest{Q: "q", OutputType: OutputStructured}); err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("%d reports, want 2", len(reports))
+	}
+}
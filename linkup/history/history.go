@@ -0,0 +1,497 @@
+// Package history archives Linkup calls locally so past results can be
+// searched without spending credits again. A Store is an append-only set of
+// JSONL segment files holding each call's request, response, time and
+// estimated credits, plus an in-memory inverted index over queries,
+// answers, titles, snippets and fetched markdown.
+//
+// A Store implements linkup.Recorder:
+//
+//	store, _ := history.Open(dir, history.Options{})
+//	client := linkup.NewClient(key, linkup.WithRecorder(store))
+package history
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+)
+
+// ErrNotFound is returned by Get for unknown IDs.
+var ErrNotFound = errors.New("history: entry not found")
+
+// Entry is one archived call.
+type Entry struct {
+	ID         string          `json:"id"`
+	Time       time.Time       `json:"time"`
+	Op         string          `json:"op"` // linkup.OpSearch or linkup.OpFetch
+	Request    json.RawMessage `json:"request"`
+	Response   json.RawMessage `json:"response"`
+	Credits    float64         `json:"credits"`
+	DurationMS int64           `json:"durationMs"`
+}
+
+// Subject is the query of a search or the URL of a fetch.
+func (e *Entry) Subject() string {
+	var req struct {
+		Q   string `json:"q"`
+		URL string `json:"url"`
+	}
+	json.Unmarshal(e.Request, &req)
+	if e.Op == linkup.OpFetch {
+		return req.URL
+	}
+	return req.Q
+}
+
+// SearchRequest decodes the request of a search entry.
+func (e *Entry) SearchRequest() (linkup.SearchRequest, error) {
+	var r linkup.SearchRequest
+	err := json.Unmarshal(e.Request, &r)
+	return r, err
+}
+
+// FetchRequest decodes the request of a fetch entry.
+func (e *Entry) FetchRequest() (linkup.FetchRequest, error) {
+	var r linkup.FetchRequest
+	err := json.Unmarshal(e.Request, &r)
+	return r, err
+}
+
+// SearchResponse returns the archived response.
+func (e *Entry) SearchResponse() linkup.SearchResponse {
+	return linkup.SearchResponse{Raw: e.Response}
+}
+
+// Options tunes a Store.
+type Options struct {
+	// SegmentBytes starts a new segment file once the current one exceeds
+	// it. Default 64 MiB.
+	SegmentBytes int64
+}
+
+// Store is an append-only archive of calls. It is safe for concurrent use.
+// The index is built on the first read, so a Store that only records stays
+// cheap to open.
+type Store struct {
+	dir     string
+	segSize int64
+
+	mu     sync.Mutex
+	f      *os.File
+	seg    int
+	size   int64
+	last   int64 // UnixNano of the last ID handed out
+	err    error
+	loaded bool
+	locs   []loc
+	byID   map[string]int
+	byKey  map[string][]int // exact lookup key -> docs, oldest first
+	idx    *index
+}
+
+// loc is where an entry lives, plus the fields needed to filter without
+// reading it.
+type loc struct {
+	seg  int
+	off  int64
+	n    int
+	id   string
+	time time.Time
+	op   string
+	// Lookup keys; see requestKeys.
+	group string
+	terms []string
+}
+
+const segmentExt = ".jsonl"
+
+// Open opens (creating if needed) the archive in dir.
+func Open(dir string, opts Options) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	s := &Store{dir: dir, segSize: opts.SegmentBytes}
+	if s.segSize <= 0 {
+		s.segSize = 64 << 20
+	}
+	segs, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	s.seg = 1
+	if len(segs) > 0 {
+		s.seg = segs[len(segs)-1]
+	}
+	if err := s.openSegment(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) segmentPath(n int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%06d%s", n, segmentExt))
+}
+
+// segments lists segment numbers in ascending order.
+func (s *Store) segments() ([]int, error) {
+	des, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []int
+	for _, de := range des {
+		name, ok := strings.CutSuffix(de.Name(), segmentExt)
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(name); err == nil && n > 0 {
+			out = append(out, n)
+		}
+	}
+	sort.Ints(out)
+	return out, nil
+}
+
+func (s *Store) openSegment() error {
+	f, err := os.OpenFile(s.segmentPath(s.seg), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.f, s.size = f, fi.Size()
+	if s.size > 0 {
+		// Terminate a line torn by a crash so the next entry starts clean.
+		last := make([]byte, 1)
+		if r, err := os.Open(s.segmentPath(s.seg)); err == nil {
+			_, err = r.ReadAt(last, s.size-1)
+			r.Close()
+			if err == nil && last[0] != '\n' {
+				if _, err := f.Write([]byte{'\n'}); err == nil {
+					s.size++
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Record archives a call. It implements linkup.Recorder; failures are kept
+// and reported by Err.
+func (s *Store) Record(_ context.Context, call linkup.Call) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// IDs are time-ordered; bumping past the last one keeps them unique
+	// under coarse clocks.
+	n := call.Time.UnixNano()
+	if n <= s.last {
+		n = s.last + 1
+	}
+	s.last = n
+	e := Entry{
+		ID:         strconv.FormatInt(n, 36),
+		Time:       call.Time.UTC(),
+		Op:         call.Op,
+		Request:    call.Request,
+		Response:   call.Response,
+		Credits:    call.Credits,
+		DurationMS: call.Duration.Milliseconds(),
+	}
+	if _, err := s.append(&e); err != nil {
+		s.err = err
+	}
+}
+
+// Err returns the last error encountered by Record, if any.
+func (s *Store) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *Store) append(e *Entry) (loc, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return loc{}, err
+	}
+	b = append(b, '\n')
+	if s.size > 0 && s.size+int64(len(b)) > s.segSize {
+		if err := s.f.Close(); err != nil {
+			return loc{}, err
+		}
+		s.seg++
+		if err := s.openSegment(); err != nil {
+			return loc{}, err
+		}
+	}
+	l := loc{seg: s.seg, off: s.size, n: len(b), id: e.ID, time: e.Time, op: e.Op}
+	if _, err := s.f.Write(b); err != nil {
+		return loc{}, err
+	}
+	s.size += int64(len(b))
+	if s.loaded {
+		s.add(l, e)
+	}
+	return l, nil
+}
+
+// Close closes the active segment.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
+
+// load reads every segment and builds the index. Callers hold s.mu.
+func (s *Store) load() error {
+	if s.loaded {
+		return nil
+	}
+	s.locs, s.byID, s.byKey, s.idx = nil, map[string]int{}, map[string][]int{}, newIndex()
+	segs, err := s.segments()
+	if err != nil {
+		return err
+	}
+	for _, n := range segs {
+		if err := s.loadSegment(n); err != nil {
+			return err
+		}
+	}
+	s.loaded = true
+	return nil
+}
+
+func (s *Store) loadSegment(n int) error {
+	f, err := os.Open(s.segmentPath(n))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReaderSize(f, 256*1024)
+	var off int64
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			var e Entry
+			// A torn final line after a crash is skipped.
+			if line[len(line)-1] == '\n' && json.Unmarshal(line, &e) == nil {
+				s.add(loc{seg: n, off: off, n: len(line), id: e.ID, time: e.Time, op: e.Op}, &e)
+			}
+			off += int64(len(line))
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("history: read segment %d: %w", n, err)
+		}
+	}
+}
+
+func (s *Store) add(l loc, e *Entry) {
+	doc := len(s.locs)
+	k := requestKeys(e.Op, e.Request)
+	l.group, l.terms = k.group, k.terms
+	s.locs = append(s.locs, l)
+	s.byID[l.id] = doc
+	if k.exact != "" {
+		s.byKey[k.exact] = append(s.byKey[k.exact], doc)
+	}
+	s.idx.add(doc, entryText(e))
+}
+
+func (s *Store) read(l loc) (*Entry, error) {
+	f, err := os.Open(s.segmentPath(l.seg))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	buf := make([]byte, l.n)
+	if _, err := f.ReadAt(buf, l.off); err != nil {
+		return nil, err
+	}
+	var e Entry
+	if err := json.Unmarshal(buf, &e); err != nil {
+		return nil, fmt.Errorf("history: entry %s: %w", l.id, err)
+	}
+	return &e, nil
+}
+
+// Get returns the entry with the given ID.
+func (s *Store) Get(id string) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	doc, ok := s.byID[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return s.read(s.locs[doc])
+}
+
+// Filter restricts List and Search.
+type Filter struct {
+	Op    string    // linkup.OpSearch or linkup.OpFetch; empty for both
+	Since time.Time // zero for no lower bound
+	Until time.Time // zero for no upper bound
+	Limit int       // 0 means 20; negative means no limit
+}
+
+func (f Filter) match(l loc) bool {
+	return (f.Op == "" || f.Op == l.op) &&
+		(f.Since.IsZero() || !l.time.Before(f.Since)) &&
+		(f.Until.IsZero() || l.time.Before(f.Until))
+}
+
+func (f Filter) limit() int {
+	if f.Limit == 0 {
+		return 20
+	}
+	return f.Limit
+}
+
+// List returns matching entries, newest first.
+func (s *Store) List(f Filter) ([]*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	var out []*Entry
+	for i := len(s.locs) - 1; i >= 0; i-- {
+		if !f.match(s.locs[i]) {
+			continue
+		}
+		if n := f.limit(); n > 0 && len(out) >= n {
+			break
+		}
+		e, err := s.read(s.locs[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// entryText is what gets indexed: the query or URL plus every string in
+// the response except raw HTML.
+func entryText(e *Entry) string {
+	var b strings.Builder
+	b.WriteString(e.Subject())
+	var v any
+	if json.Unmarshal(e.Response, &v) == nil {
+		collectStrings(&b, v)
+	}
+	return b.String()
+}
+
+func collectStrings(b *strings.Builder, v any) {
+	switch t := v.(type) {
+	case string:
+		b.WriteByte('\n')
+		b.WriteString(t)
+	case []any:
+		for _, x := range t {
+			collectStrings(b, x)
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			if k != "rawHtml" {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			collectStrings(b, t[k])
+		}
+	}
+}
+
+// Hit is a search result.
+type Hit struct {
+	Entry   *Entry
+	Score   float64
+	Snippet string // text around the first matching term
+}
+
+// Search finds entries containing every word of q (case-insensitive).
+// A trailing * matches a prefix ("kube*") and a leading - excludes a word
+// ("-reddit"). Hits are ordered by relevance, newest first on ties.
+func (s *Store) Search(q string, f Filter) ([]Hit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	pq := parseQuery(q)
+	scores := s.idx.search(pq, func(doc int) bool { return f.match(s.locs[doc]) })
+	var hits []Hit
+	for _, doc := range ranked(scores) {
+		if n := f.limit(); n > 0 && len(hits) >= n {
+			break
+		}
+		e, err := s.read(s.locs[doc])
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, Hit{Entry: e, Score: scores[doc], Snippet: snippet(entryText(e), pq.must, 80)})
+	}
+	return hits, nil
+}
+
+// snippet returns up to radius runes either side of the first occurrence
+// of any term, on one line.
+func snippet(text string, terms []string, radius int) string {
+	lower := strings.ToLower(text)
+	at := -1
+	for _, t := range terms {
+		t = strings.TrimSuffix(t, "*")
+		if i := strings.Index(lower, t); i >= 0 && (at < 0 || i < at) {
+			at = i
+		}
+	}
+	if at < 0 {
+		at = 0
+	}
+	// ToLower can change byte lengths; fall back to the start if so.
+	if len(lower) != len(text) {
+		at = 0
+	}
+	r := []rune(text[:at])
+	start := len(r) - radius
+	if start < 0 {
+		start = 0
+	}
+	rest := []rune(text[at:])
+	end := radius
+	if end > len(rest) {
+		end = len(rest)
+	}
+	out := strings.Join(strings.Fields(string(r[start:])+string(rest[:end])), " ")
+	if start > 0 {
+		out = "…" + out
+	}
+	if end < len(rest) {
+		out += "…"
+	}
+	return out
+}
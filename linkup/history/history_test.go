@@ -0,0 +1,205 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+)
+
+func record(s *Store, op, req, resp string, at time.Time) {
+	s.Record(context.Background(), linkup.Call{Op: op, Time: at, Request: []byte(req), Response: []byte(resp), Credits: 0.005})
+}
+
+func TestSearch_RanksAndFilters(t *testing.T) {
+	s, err := Open(t.TempDir(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	record(s, linkup.OpSearch, `{"q":"kubernetes release"}`,
+		`{"results":[{"type":"text","name":"Kubernetes 1.30 released","url":"https://k8s.io/blog","content":"Kubernetes 1.30 brings sidecars"}]}`, t0)
+	record(s, linkup.OpSearch, `{"q":"rust async"}`,
+		`{"answer":"Rust async uses futures; kubernetes is unrelated.","sources":[{"name":"Async book","url":"https://rust-lang.github.io/async-book"}]}`, t0.Add(time.Hour))
+	record(s, linkup.OpFetch, `{"url":"https://k8s.io/docs"}`,
+		`{"markdown":"# Kubernetes docs\nPods and sidecars.","rawHtml":"<p>zebra</p>"}`, t0.Add(2*time.Hour))
+
+	hits, err := s.Search("Kubernetes", Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 3 || hits[0].Entry.Subject() != "kubernetes release" {
+		t.Fatalf("hits = %+v", hits)
+	}
+	if !strings.Contains(strings.ToLower(hits[0].Snippet), "kubernetes") {
+		t.Fatalf("snippet = %q", hits[0].Snippet)
+	}
+
+	for _, tt := range []struct {
+		q    string
+		f    Filter
+		want []string
+	}{
+		// The shorter fetched page scores higher for the same terms.
+		{"kubernetes sidecars", Filter{}, []string{"https://k8s.io/docs", "kubernetes release"}},
+		{"kubernetes -rust", Filter{Op: linkup.OpSearch}, []string{"kubernetes release"}},
+		{"side*", Filter{Since: t0.Add(90 * time.Minute)}, []string{"https://k8s.io/docs"}},
+		{"zebra", Filter{}, nil}, // raw HTML is not indexed
+	} {
+		hits, err := s.Search(tt.q, tt.f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, h := range hits {
+			got = append(got, h.Entry.Subject())
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%q: got %v, want %v", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestStore_ReopenRotateAndTornLine(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, Options{SegmentBytes: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		record(s, linkup.OpSearch, `{"q":"alpha beta"}`, `{"answer":"gamma"}`, now)
+	}
+	s.Close()
+	segs, _ := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if len(segs) < 2 {
+		t.Fatalf("expected rotation, got %v", segs)
+	}
+	// Simulate a crash mid-write.
+	last := segs[len(segs)-1]
+	f, _ := os.OpenFile(last, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString(`{"id":"torn","op":"sea`)
+	f.Close()
+
+	s, err = Open(dir, Options{SegmentBytes: 1 << 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	record(s, linkup.OpSearch, `{"q":"delta"}`, `{"answer":"after crash"}`, now.Add(time.Second))
+	all, err := s.List(Filter{Limit: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 6 || all[0].Subject() != "delta" {
+		t.Fatalf("entries = %d, newest %q", len(all), all[0].Subject())
+	}
+	seen := map[string]bool{}
+	for _, e := range all {
+		if seen[e.ID] {
+			t.Fatalf("duplicate id %s", e.ID)
+		}
+		seen[e.ID] = true
+	}
+	e, err := s.Get(all[0].ID)
+	if err != nil || string(e.Response) != `{"answer":"after crash"}` {
+		t.Fatalf("Get = %v, %v", e, err)
+	}
+	if _, err := s.Get("nope"); err != ErrNotFound {
+		t.Fatalf("Get(nope) = %v", err)
+	}
+}
+
+func TestClientRecorder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"results":[{"type":"text","name":"Archived page","url":"https://a.com","content":"quokka facts"}]}`))
+	}))
+	defer srv.Close()
+	s, err := Open(t.TempDir(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c := linkup.NewClient("k", linkup.WithBaseURL(srv.URL), linkup.WithRecorder(s))
+	if _, err := c.Search(context.Background(), linkup.SearchRequest{Q: "animals", Depth: linkup.DepthDeep}); err != nil {
+		t.Fatal(err)
+	}
+	hits, err := s.Search("quokka", Filter{})
+	if err != nil || len(hits) != 1 {
+		t.Fatalf("hits = %v, %v", hits, err)
+	}
+	e := hits[0].Entry
+	if e.Op != linkup.OpSearch || e.Credits != linkup.CostDeepSearch || e.Subject() != "animals" {
+		t.Fatalf("entry = %+v", e)
+	}
+	if s.Err() != nil {
+		t.Fatal(s.Err())
+	}
+}
+
+func TestLookup(t *testing.T) {
+	s, err := Open(t.TempDir(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	record(s, linkup.OpSearch, `{"q":"Go generics tutorial","depth":"standard","outputType":"searchResults"}`, `{"results":[],"v":1}`, t0)
+	record(s, linkup.OpSearch, `{"q":"go generics  tutorial","depth":"standard","outputType":"searchResults"}`, `{"results":[],"v":2}`, t0.Add(time.Hour))
+	record(s, linkup.OpSearch, `{"q":"go generics tutorial","depth":"standard","outputType":"sourcedAnswer"}`, `{"answer":"a"}`, t0.Add(2*time.Hour))
+	record(s, linkup.OpFetch, `{"url":"https://example.com/a?utm_source=x"}`, `{"markdown":"plain"}`, t0)
+	record(s, linkup.OpFetch, `{"url":"https://example.com/b","includeRawHtml":true}`, `{"markdown":"b","rawHtml":"b-html"}`, t0)
+
+	ctx := context.Background()
+	lookup := func(op, req string, min float64) string {
+		t.Helper()
+		c, err := s.Lookup(ctx, linkup.LookupRequest{Op: op, Request: []byte(req), MinSimilarity: min})
+		if errors.Is(err, linkup.ErrNotCached) {
+			return "miss"
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(c.Response)
+	}
+	for _, tt := range []struct {
+		op, req string
+		min     float64
+		want    string
+	}{
+		// Case and spacing are ignored; the newest match wins.
+		{linkup.OpSearch, `{"q":"GO Generics Tutorial","depth":"standard","outputType":"searchResults"}`, 0, `{"results":[],"v":2}`},
+		{linkup.OpSearch, `{"q":"go generics tutorial","depth":"deep","outputType":"searchResults"}`, 0, "miss"},
+		{linkup.OpSearch, `{"q":"generics tutorial for go beginners","depth":"standard","outputType":"searchResults"}`, 0, "miss"},
+		// Fuzzy matching stays within the output type.
+		{linkup.OpSearch, `{"q":"generics tutorial for go beginners","depth":"deep","outputType":"searchResults"}`, 0.5, `{"results":[],"v":2}`},
+		{linkup.OpSearch, `{"q":"go generics","outputType":"sourcedAnswer"}`, 0.5, `{"answer":"a"}`},
+		{linkup.OpSearch, `{"q":"rust lifetimes","outputType":"searchResults"}`, 0.5, "miss"},
+		// Fetches match on the canonical URL, with the extras asked for.
+		{linkup.OpFetch, `{"url":"https://EXAMPLE.com/a"}`, 0, `{"markdown":"plain"}`},
+		{linkup.OpFetch, `{"url":"https://example.com/a","includeRawHtml":true}`, 0, "miss"},
+		{linkup.OpFetch, `{"url":"https://example.com/b"}`, 0, `{"markdown":"b","rawHtml":"b-html"}`},
+	} {
+		if got := lookup(tt.op, tt.req, tt.min); got != tt.want {
+			t.Errorf("Lookup(%s, %s, %v) = %s, want %s", tt.op, tt.req, tt.min, got, tt.want)
+		}
+	}
+
+	// End to end through an offline client.
+	c := linkup.NewClient("", linkup.WithRecorder(s), linkup.WithOfflineMode())
+	resp, err := c.Fetch(ctx, linkup.FetchRequest{URL: "https://example.com/a"})
+	if err != nil || string(resp.Raw) != `{"markdown":"plain"}` {
+		t.Fatalf("Fetch = %s, %v", resp.Raw, err)
+	}
+	if _, err := c.Fetch(ctx, linkup.FetchRequest{URL: "https://example.com/c"}); !errors.Is(err, linkup.ErrNotCached) {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package history
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// index is an inverted index from lowercased terms to the documents (entry
+// positions) containing them.
+type index struct {
+	postings map[string][]posting
+	lengths  []int // terms per document
+}
+
+type posting struct {
+	doc int
+	tf  int
+}
+
+func newIndex() *index {
+	return &index{postings: map[string][]posting{}}
+}
+
+// add indexes text as document doc. Documents are added in increasing
+// order, so posting lists stay sorted.
+func (ix *index) add(doc int, text string) {
+	counts := map[string]int{}
+	n := 0
+	for _, t := range tokenize(text) {
+		counts[t]++
+		n++
+	}
+	for t, c := range counts {
+		ix.postings[t] = append(ix.postings[t], posting{doc: doc, tf: c})
+	}
+	for len(ix.lengths) <= doc {
+		ix.lengths = append(ix.lengths, 0)
+	}
+	ix.lengths[doc] = n
+}
+
+// tokenize splits s into lowercased words of letters and digits.
+func tokenize(s string) []string {
+	var out []string
+	for _, w := range strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if n := utf8.RuneCountInString(w); n < 2 || n > 40 {
+			continue
+		}
+		out = append(out, strings.ToLower(w))
+	}
+	return out
+}
+
+// query is a parsed search expression: all of must (a trailing * makes a
+// term a prefix), none of not.
+type query struct {
+	must []string
+	not  []string
+}
+
+func parseQuery(s string) query {
+	var q query
+	for _, f := range strings.Fields(s) {
+		neg := strings.HasPrefix(f, "-")
+		f = strings.TrimPrefix(f, "-")
+		prefix := strings.HasSuffix(f, "*")
+		for _, t := range tokenize(f) {
+			if prefix {
+				t += "*"
+			}
+			if neg {
+				q.not = append(q.not, t)
+			} else {
+				q.must = append(q.must, t)
+			}
+		}
+	}
+	return q
+}
+
+// terms expands a term, possibly a prefix, to indexed terms.
+func (ix *index) terms(t string) []string {
+	p, ok := strings.CutSuffix(t, "*")
+	if !ok {
+		return []string{t}
+	}
+	var out []string
+	for term := range ix.postings {
+		if strings.HasPrefix(term, p) {
+			out = append(out, term)
+		}
+	}
+	return out
+}
+
+// search scores documents matching every must term with BM25 and drops
+// those matching a not term. keep filters candidates before scoring.
+func (ix *index) search(q query, keep func(doc int) bool) map[int]float64 {
+	if len(q.must) == 0 {
+		return nil
+	}
+	docs := len(ix.lengths)
+	var avg float64
+	for _, n := range ix.lengths {
+		avg += float64(n)
+	}
+	if docs > 0 {
+		avg /= float64(docs)
+	}
+	const k1, b = 1.2, 0.75
+
+	var scores map[int]float64
+	for i, t := range q.must {
+		termScores := map[int]float64{}
+		for _, term := range ix.terms(t) {
+			ps := ix.postings[term]
+			idf := math.Log(1 + (float64(docs)-float64(len(ps))+0.5)/(float64(len(ps))+0.5))
+			for _, p := range ps {
+				if i > 0 && !contains(scores, p.doc) || !keep(p.doc) {
+					continue
+				}
+				tf := float64(p.tf)
+				norm := tf * (k1 + 1) / (tf + k1*(1-b+b*float64(ix.lengths[p.doc])/avg))
+				termScores[p.doc] += idf * norm
+			}
+		}
+		if i == 0 {
+			scores = termScores
+			continue
+		}
+		for doc := range scores {
+			if s, ok := termScores[doc]; ok {
+				scores[doc] += s
+			} else {
+				delete(scores, doc)
+			}
+		}
+	}
+	for _, t := range q.not {
+		for _, term := range ix.terms(t) {
+			for _, p := range ix.postings[term] {
+				delete(scores, p.doc)
+			}
+		}
+	}
+	return scores
+}
+
+func contains(m map[int]float64, k int) bool {
+	_, ok := m[k]
+	return ok
+}
+
+// ranked orders scores by score, newest (highest doc) first on ties.
+func ranked(scores map[int]float64) []int {
+	docs := make([]int, 0, len(scores))
+	for d := range scores {
+		docs = append(docs, d)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		if scores[docs[i]] != scores[docs[j]] {
+			return scores[docs[i]] > scores[docs[j]]
+		}
+		return docs[i] > docs[j]
+	})
+	return docs
+}
@@ -0,0 +1,155 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/canonical"
+)
+
+// Lookup returns the newest stored call answering req, so a Store can back
+// an offline Client (linkup.WithOfflineMode). Searches match when every
+// parameter is equal and the queries are equal ignoring case and spacing;
+// with req.MinSimilarity > 0 the most similar stored query of the same
+// output type is used otherwise. Fetches match on the canonical URL, as
+// long as the stored call included whatever raw HTML or images req asks
+// for. A miss returns linkup.ErrNotCached.
+func (s *Store) Lookup(_ context.Context, req linkup.LookupRequest) (linkup.Call, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return linkup.Call{}, err
+	}
+	k := requestKeys(req.Op, req.Request)
+	var want linkup.FetchRequest
+	if req.Op == linkup.OpFetch {
+		json.Unmarshal(req.Request, &want)
+	}
+	docs := s.byKey[k.exact]
+	for i := len(docs) - 1; i >= 0; i-- {
+		e, err := s.read(s.locs[docs[i]])
+		if err != nil {
+			return linkup.Call{}, err
+		}
+		if req.Op == linkup.OpFetch {
+			got, _ := e.FetchRequest()
+			if want.IncludeRawHTML && !got.IncludeRawHTML || want.ExtractImages && !got.ExtractImages {
+				continue
+			}
+		}
+		return e.call(), nil
+	}
+
+	if req.Op == linkup.OpSearch && req.MinSimilarity > 0 {
+		best, bestScore := -1, 0.0
+		for doc, l := range s.locs {
+			if l.op != linkup.OpSearch || l.group != k.group {
+				continue
+			}
+			// >= prefers the newest of equally similar entries.
+			if sc := similarity(k.terms, l.terms); sc >= req.MinSimilarity && sc >= bestScore {
+				best, bestScore = doc, sc
+			}
+		}
+		if best >= 0 {
+			e, err := s.read(s.locs[best])
+			if err != nil {
+				return linkup.Call{}, err
+			}
+			return e.call(), nil
+		}
+	}
+	return linkup.Call{}, linkup.ErrNotCached
+}
+
+func (e *Entry) call() linkup.Call {
+	return linkup.Call{
+		Op:       e.Op,
+		Time:     e.Time,
+		Duration: time.Duration(e.DurationMS) * time.Millisecond,
+		Request:  e.Request,
+		Response: e.Response,
+		Credits:  e.Credits,
+	}
+}
+
+// keys identify a request for Lookup: exact is equal for interchangeable
+// requests, group for searches whose responses share a shape, and terms
+// are the query's distinct words.
+type keys struct {
+	exact string
+	group string
+	terms []string
+}
+
+func requestKeys(op string, raw json.RawMessage) keys {
+	switch op {
+	case linkup.OpSearch:
+		var r linkup.SearchRequest
+		if json.Unmarshal(raw, &r) != nil {
+			return keys{}
+		}
+		r.Q = strings.ToLower(strings.Join(strings.Fields(r.Q), " "))
+		r.IncludeDomains = sortedCopy(r.IncludeDomains)
+		r.ExcludeDomains = sortedCopy(r.ExcludeDomains)
+		b, _ := json.Marshal(r)
+		group := string(r.OutputType)
+		if r.StructuredOutputSchema != nil {
+			group += "\x00" + *r.StructuredOutputSchema
+		}
+		return keys{exact: op + "\x00" + string(b), group: group, terms: distinct(tokenize(r.Q))}
+	case linkup.OpFetch:
+		var r linkup.FetchRequest
+		if json.Unmarshal(raw, &r) != nil {
+			return keys{}
+		}
+		return keys{exact: op + "\x00" + canonical.Key(r.URL)}
+	}
+	return keys{}
+}
+
+func sortedCopy(in []string) []string {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make([]string, len(in))
+	for i, s := range in {
+		out[i] = strings.ToLower(s)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func distinct(terms []string) []string {
+	seen := map[string]bool{}
+	out := terms[:0]
+	for _, t := range terms {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// similarity is the Jaccard index of two term sets.
+func similarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	in := map[string]bool{}
+	for _, t := range a {
+		in[t] = true
+	}
+	common := 0
+	for _, t := range b {
+		if in[t] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
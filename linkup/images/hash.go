@@ -0,0 +1,102 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"math/bits"
+)
+
+// maxHashPixels bounds the images decoded for hashing, so a small file
+// declaring a huge canvas cannot exhaust memory.
+const maxHashPixels = 40 << 20
+
+// dHash is the difference hash of img: img shrunk to 9x8 grayscale cells,
+// one bit per horizontally adjacent pair set when the left cell is darker.
+// Resized, recompressed or slightly retouched copies hash within a few
+// bits of each other.
+func dHash(img image.Image) uint64 {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return 0
+	}
+	var cells [8][9]float64
+	for y := range 8 {
+		y0, y1 := span(b.Min.Y, h, y, 8)
+		for x := range 9 {
+			x0, x1 := span(b.Min.X, w, x, 9)
+			// Sample at most ~16x16 pixels per cell.
+			sx, sy := max(1, (x1-x0)/16), max(1, (y1-y0)/16)
+			var sum float64
+			var n int
+			for py := y0; py < y1; py += sy {
+				for px := x0; px < x1; px += sx {
+					r, g, bl, _ := img.At(px, py).RGBA()
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)
+					n++
+				}
+			}
+			cells[y][x] = sum / float64(n)
+		}
+	}
+	var hash uint64
+	for y := range 8 {
+		for x := range 8 {
+			hash <<= 1
+			if cells[y][x] < cells[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// span returns the pixel range [lo, hi) of cell i of n along a side of
+// length size starting at origin; every cell covers at least one pixel.
+func span(origin, size, i, n int) (lo, hi int) {
+	lo = origin + i*size/n
+	hi = origin + (i+1)*size/n
+	if lo >= origin+size {
+		lo = origin + size - 1
+	}
+	if hi <= lo {
+		hi = lo + 1
+	}
+	return lo, hi
+}
+
+// distance is the Hamming distance between two hashes.
+func distance(a, b uint64) int { return bits.OnesCount64(a ^ b) }
+
+// dimensions returns the size of an image of the given sniffed type, or
+// zeros when it cannot be read.
+func dimensions(b []byte, typ string) (width, height int) {
+	if typ == "image/webp" {
+		return webpSize(b)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return 0, 0
+	}
+	return cfg.Width, cfg.Height
+}
+
+// webpSize reads the canvas size from a WebP header (lossy, lossless or
+// extended format), which the standard library cannot decode.
+func webpSize(b []byte) (width, height int) {
+	if len(b) < 30 || string(b[:4]) != "RIFF" || string(b[8:12]) != "WEBP" {
+		return 0, 0
+	}
+	switch string(b[12:16]) {
+	case "VP8 ": // key frame header, then 14-bit sizes
+		return int(binary.LittleEndian.Uint16(b[26:]) & 0x3fff), int(binary.LittleEndian.Uint16(b[28:]) & 0x3fff)
+	case "VP8L": // signature byte, then 14-bit sizes minus one
+		v := binary.LittleEndian.Uint32(b[21:])
+		return int(v&0x3fff) + 1, int(v>>14&0x3fff) + 1
+	case "VP8X": // flags, then 24-bit sizes minus one
+		return int(uint32(b[24])|uint32(b[25])<<8|uint32(b[26])<<16) + 1,
+			int(uint32(b[27])|uint32(b[28])<<8|uint32(b[29])<<16) + 1
+	}
+	return 0, 0
+}
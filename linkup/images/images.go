@@ -0,0 +1,409 @@
+// Package images downloads the images behind search results and fetched
+// pages. Image URLs are collected from SearchRequest.IncludeImages results
+// or FetchRequest.ExtractImages pages, downloaded concurrently within size
+// and type limits (the type is sniffed from the bytes, not taken from the
+// server), deduplicated by content and by perceptual hash, and written to a
+// directory with a manifest.json describing each file.
+package images
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // register decoders for image.Decode
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+)
+
+// ManifestName is the manifest file written to Config.Dir.
+const ManifestName = "manifest.json"
+
+// DefaultMaxDistance is a Config.MaxDistance that treats resized and
+// recompressed copies as near-duplicates.
+const DefaultMaxDistance = 5
+
+// DefaultTypes are the image types kept when Config.Types is empty.
+var DefaultTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// extensions maps sniffable image types to file extensions.
+var extensions = map[string]string{
+	"image/jpeg":   ".jpg",
+	"image/png":    ".png",
+	"image/gif":    ".gif",
+	"image/webp":   ".webp",
+	"image/bmp":    ".bmp",
+	"image/x-icon": ".ico",
+}
+
+// Source is an image to download.
+type Source struct {
+	URL  string
+	Page string // the page it appears on, when known
+	Alt  string
+}
+
+// FromResults returns the image results of an OutputSearchResults search.
+func FromResults(results []linkup.SearchResult) []Source {
+	var out []Source
+	for _, r := range results {
+		if r.Type == "image" && r.URL != "" {
+			out = append(out, Source{URL: r.URL, Alt: r.Name})
+		}
+	}
+	return out
+}
+
+// FromPage returns the images of a page fetched with ExtractImages.
+func FromPage(pageURL string, fr linkup.FetchResult) []Source {
+	out := make([]Source, 0, len(fr.Images))
+	for _, img := range fr.Images {
+		if img.URL != "" {
+			out = append(out, Source{URL: img.URL, Page: pageURL, Alt: img.Alt})
+		}
+	}
+	return out
+}
+
+// Search runs req as an OutputSearchResults search with images and returns
+// the image results.
+func Search(ctx context.Context, client *linkup.Client, req linkup.SearchRequest) ([]Source, error) {
+	req.OutputType = linkup.OutputSearchResults
+	req.IncludeImages = true
+	resp, err := client.Search(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	results, err := resp.Results()
+	if err != nil {
+		return nil, err
+	}
+	return FromResults(results), nil
+}
+
+// Config configures a Downloader.
+type Config struct {
+	// Dir receives the images, named by content hash, and the manifest.
+	// Required.
+	Dir string
+	// HTTPClient downloads images. Default: a client with a 30s timeout.
+	HTTPClient *http.Client
+	UserAgent  string // default "linkup-go"
+	// Concurrency is how many images are downloaded at once. Default 4.
+	Concurrency int
+	// MaxBytes skips larger images. Default 10 MiB.
+	MaxBytes int64
+	// MinWidth and MinHeight skip smaller images, e.g. icons and tracking
+	// pixels. Images whose size cannot be read are kept.
+	MinWidth, MinHeight int
+	// Types are the sniffed MIME types to keep. Default DefaultTypes.
+	Types []string
+	// MaxDistance is the largest Hamming distance between the dHashes of
+	// two images that are near-duplicates; only the one with the most
+	// pixels is kept. 0 matches identical dHashes only, negative drops only
+	// identical files; DefaultMaxDistance suits most uses.
+	MaxDistance int
+	// Logf receives diagnostics. Default: discard.
+	Logf func(format string, args ...any)
+}
+
+// Entry describes a downloaded image in the manifest.
+type Entry struct {
+	File   string `json:"file"` // relative to Config.Dir
+	URL    string `json:"url"`
+	Page   string `json:"page,omitempty"`
+	Alt    string `json:"alt,omitempty"`
+	Type   string `json:"type"` // sniffed MIME type
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+	Bytes  int    `json:"bytes"`
+	SHA256 string `json:"sha256"`
+	DHash  string `json:"dhash,omitempty"` // 16 hex digits; empty for types Go cannot decode
+	// Duplicates are the URLs of identical or near-duplicate images
+	// dropped in favor of this one.
+	Duplicates []string `json:"duplicates,omitempty"`
+}
+
+// Skip records an image that was not kept.
+type Skip struct {
+	URL    string `json:"url"`
+	Reason string `json:"reason"`
+}
+
+// Manifest is written to Config.Dir as ManifestName.
+type Manifest struct {
+	Images  []Entry   `json:"images"`
+	Skipped []Skip    `json:"skipped,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+// Downloader downloads images to a directory.
+type Downloader struct {
+	cfg   Config
+	types map[string]bool
+}
+
+// New validates cfg and returns a Downloader.
+func New(cfg Config) (*Downloader, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("images: Config.Dir is required")
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = "linkup-go"
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 10 << 20
+	}
+	if len(cfg.Types) == 0 {
+		cfg.Types = DefaultTypes
+	}
+	if cfg.Logf == nil {
+		cfg.Logf = func(string, ...any) {}
+	}
+	d := &Downloader{cfg: cfg, types: map[string]bool{}}
+	for _, t := range cfg.Types {
+		if extensions[t] == "" {
+			return nil, fmt.Errorf("images: unsupported type %q", t)
+		}
+		d.types[t] = true
+	}
+	return d, nil
+}
+
+// download is the outcome of one Source.
+type download struct {
+	entry  Entry
+	hash   uint64
+	hashed bool
+	skip   string // reason, when not kept
+}
+
+// Download fetches srcs, drops duplicates and writes the kept images and
+// the manifest to Config.Dir. Failed, filtered and duplicate images are
+// listed in Manifest.Skipped or Entry.Duplicates. The error is non-nil
+// only when the directory or manifest cannot be written, or ctx ends.
+func (d *Downloader) Download(ctx context.Context, srcs []Source) (*Manifest, error) {
+	if err := os.MkdirAll(d.cfg.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	srcs = uniqueSources(srcs)
+	results := make([]download, len(srcs))
+	sem := make(chan struct{}, d.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for i, src := range srcs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			results[i] = d.fetch(ctx, src)
+			if r := results[i]; r.skip != "" {
+				d.cfg.Logf("skip %s: %s", src.URL, r.skip)
+			} else {
+				d.cfg.Logf("got %s (%s, %dx%d)", src.URL, r.entry.Type, r.entry.Width, r.entry.Height)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{Images: []Entry{}, Created: time.Now().UTC()}
+	kept := d.dedupe(results)
+	for _, r := range results {
+		if r.skip != "" {
+			m.Skipped = append(m.Skipped, Skip{URL: r.entry.URL, Reason: r.skip})
+		}
+	}
+	for _, i := range kept {
+		m.Images = append(m.Images, results[i].entry)
+	}
+	return m, writeManifest(filepath.Join(d.cfg.Dir, ManifestName), m)
+}
+
+// dedupe returns the indexes of the results to keep, in input order. Each
+// group of identical or near-duplicate images keeps its largest member;
+// files only the others used are removed.
+func (d *Downloader) dedupe(results []download) []int {
+	var kept []int
+	var dropped []string
+	for i := range results {
+		r := &results[i]
+		if r.skip != "" {
+			continue
+		}
+		k := slices.IndexFunc(kept, func(k int) bool {
+			o := &results[k]
+			if o.entry.SHA256 == r.entry.SHA256 {
+				return true
+			}
+			return d.cfg.MaxDistance >= 0 && o.hashed && r.hashed && distance(o.hash, r.hash) <= d.cfg.MaxDistance
+		})
+		if k < 0 {
+			kept = append(kept, i)
+			continue
+		}
+		winner, loser := &results[kept[k]], r
+		if r.entry.Width*r.entry.Height > winner.entry.Width*winner.entry.Height {
+			winner, loser = r, winner
+			kept[k] = i
+		}
+		winner.entry.Duplicates = append(append(winner.entry.Duplicates, loser.entry.URL), loser.entry.Duplicates...)
+		loser.entry.Duplicates = nil
+		dropped = append(dropped, loser.entry.File)
+	}
+	slices.Sort(kept)
+	for _, f := range dropped {
+		if !slices.ContainsFunc(kept, func(k int) bool { return results[k].entry.File == f }) {
+			os.Remove(filepath.Join(d.cfg.Dir, f))
+		}
+	}
+	return kept
+}
+
+// fetch downloads, checks and writes one image.
+func (d *Downloader) fetch(ctx context.Context, src Source) download {
+	r := download{entry: Entry{URL: src.URL, Page: src.Page, Alt: src.Alt}}
+	u, err := url.Parse(src.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		r.skip = "not an http(s) URL"
+		return r
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.URL, nil)
+	if err != nil {
+		r.skip = err.Error()
+		return r
+	}
+	req.Header.Set("User-Agent", d.cfg.UserAgent)
+	req.Header.Set("Accept", "image/*")
+	if src.Page != "" {
+		req.Header.Set("Referer", src.Page)
+	}
+	res, err := d.cfg.HTTPClient.Do(req)
+	if err != nil {
+		r.skip = err.Error()
+		return r
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		r.skip = "http " + strconv.Itoa(res.StatusCode)
+		return r
+	}
+	if res.ContentLength > d.cfg.MaxBytes {
+		r.skip = fmt.Sprintf("larger than %d bytes", d.cfg.MaxBytes)
+		return r
+	}
+	b, err := io.ReadAll(io.LimitReader(res.Body, d.cfg.MaxBytes+1))
+	if err != nil {
+		r.skip = err.Error()
+		return r
+	}
+	if int64(len(b)) > d.cfg.MaxBytes {
+		r.skip = fmt.Sprintf("larger than %d bytes", d.cfg.MaxBytes)
+		return r
+	}
+
+	e := &r.entry
+	e.Type = sniff(b)
+	if !d.types[e.Type] {
+		r.skip = "type " + e.Type
+		return r
+	}
+	e.Width, e.Height = dimensions(b, e.Type)
+	if e.Width > 0 && (e.Width < d.cfg.MinWidth || e.Height < d.cfg.MinHeight) {
+		r.skip = fmt.Sprintf("%dx%d is below the minimum size", e.Width, e.Height)
+		return r
+	}
+	if e.Width*e.Height <= maxHashPixels {
+		if img, _, err := image.Decode(bytes.NewReader(b)); err == nil {
+			r.hash, r.hashed = dHash(img), true
+			e.DHash = fmt.Sprintf("%016x", r.hash)
+		}
+	}
+	sum := sha256.Sum256(b)
+	e.SHA256 = hex.EncodeToString(sum[:])
+	e.Bytes = len(b)
+	e.File = e.SHA256[:16] + extensions[e.Type]
+	if err := writeFile(filepath.Join(d.cfg.Dir, e.File), b); err != nil {
+		r.skip = err.Error()
+	}
+	return r
+}
+
+// sniff returns the MIME type of b per http.DetectContentType, without
+// parameters.
+func sniff(b []byte) string {
+	t := http.DetectContentType(b)
+	if i := bytes.IndexByte([]byte(t), ';'); i >= 0 {
+		t = t[:i]
+	}
+	return t
+}
+
+// uniqueSources drops repeated URLs, keeping the first.
+func uniqueSources(srcs []Source) []Source {
+	seen := make(map[string]bool, len(srcs))
+	out := make([]Source, 0, len(srcs))
+	for _, s := range srcs {
+		if !seen[s.URL] {
+			seen[s.URL] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// writeFile writes b to path atomically. Files are named by content, so an
+// existing one is left alone.
+func writeFile(path string, b []byte) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".image-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func writeManifest(path string, m *Manifest) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
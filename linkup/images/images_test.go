@@ -0,0 +1,230 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+)
+
+// scene draws a w x h checkerboard of shaded blocks, or its upside-down
+// image; the pattern is the same at any size.
+func scene(w, h int, flipped bool) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			bx, by := x*9/w, y*8/h
+			if flipped {
+				by = 7 - by
+			}
+			v := uint8(40 + 120*((bx+by)%2) + bx*8 + by*4)
+			img.Set(x, y, color.RGBA{v, v / 2, 255 - v, 255})
+		}
+	}
+	return img
+}
+
+// marked is scene(64, 48, false) with a white corner, one dHash bit away.
+func marked() image.Image {
+	img := scene(64, 48, false).(*image.RGBA)
+	for y := range 6 {
+		for x := range 7 {
+			img.Set(x, y, color.White)
+		}
+	}
+	return img
+}
+
+func encodePNG(img image.Image) []byte {
+	var b bytes.Buffer
+	png.Encode(&b, img)
+	return b.Bytes()
+}
+
+func encodeJPEG(img image.Image) []byte {
+	var b bytes.Buffer
+	jpeg.Encode(&b, img, &jpeg.Options{Quality: 60})
+	return b.Bytes()
+}
+
+func TestDownload(t *testing.T) {
+	files := map[string][]byte{
+		"/small.png":  encodePNG(scene(64, 48, false)),
+		"/large.png":  encodePNG(scene(128, 96, false)),
+		"/copy.png":   encodePNG(scene(64, 48, false)),
+		"/photo.jpg":  encodeJPEG(scene(100, 75, false)),
+		"/other.png":  encodePNG(scene(64, 48, true)),
+		"/marked.png": encodePNG(marked()),
+		"/pixel.png":  encodePNG(scene(1, 1, false)),
+		"/huge.png":   append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 1<<20)...),
+		"/page.html":  []byte("<!doctype html><html><body>not an image</body></html>"),
+		"/vector.svg": []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg") // never trusted
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	var srcs []Source
+	for _, p := range []string{"/small.png", "/large.png", "/copy.png", "/photo.jpg", "/other.png", "/pixel.png",
+		"/huge.png", "/page.html", "/vector.svg", "/missing.png", "/small.png"} {
+		srcs = append(srcs, Source{URL: srv.URL + p, Page: "https://example.com/post", Alt: strings.Trim(p, "/")})
+	}
+	srcs = append(srcs, Source{URL: "data:image/png;base64,AAAA"})
+
+	dir := t.TempDir()
+	d, err := New(Config{Dir: dir, MinWidth: 8, MinHeight: 8, MaxBytes: 1 << 19, Concurrency: 3, MaxDistance: DefaultMaxDistance})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := d.Download(context.Background(), srcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.Images) != 2 {
+		t.Fatalf("images = %+v", m.Images)
+	}
+	kept, other := m.Images[0], m.Images[1]
+	if kept.URL != srv.URL+"/large.png" || kept.Width != 128 || kept.Height != 96 || kept.Type != "image/png" ||
+		kept.Page != "https://example.com/post" || kept.Alt != "large.png" || len(kept.DHash) != 16 {
+		t.Fatalf("kept = %+v", kept)
+	}
+	dups := slices.Clone(kept.Duplicates)
+	slices.Sort(dups)
+	if want := []string{srv.URL + "/copy.png", srv.URL + "/photo.jpg", srv.URL + "/small.png"}; !slices.Equal(dups, want) {
+		t.Fatalf("duplicates = %v", dups)
+	}
+	if other.URL != srv.URL+"/other.png" || other.Duplicates != nil {
+		t.Fatalf("other = %+v", other)
+	}
+
+	reasons := map[string]string{}
+	for _, s := range m.Skipped {
+		reasons[strings.TrimPrefix(s.URL, srv.URL)] = s.Reason
+	}
+	for path, want := range map[string]string{
+		"/pixel.png":                 "below the minimum size",
+		"/huge.png":                  "larger than",
+		"/page.html":                 "type text/html",
+		"/vector.svg":                "type text/",
+		"/missing.png":               "http 404",
+		"data:image/png;base64,AAAA": "not an http(s) URL",
+	} {
+		if !strings.Contains(reasons[path], want) {
+			t.Errorf("%s: reason %q, want %q", path, reasons[path], want)
+		}
+	}
+	if len(m.Skipped) != 6 {
+		t.Errorf("skipped = %+v", m.Skipped)
+	}
+
+	// Only the kept files remain, next to the manifest.
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{kept.File, other.File, ManifestName}; len(names) != 3 || !slices.Contains(names, want[0]) || !slices.Contains(names, want[1]) {
+		t.Fatalf("dir = %v, want %v", names, want)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, kept.File))
+	if err != nil || !bytes.Equal(b, files["/large.png"]) {
+		t.Fatalf("file %s: %v", kept.File, err)
+	}
+	var onDisk Manifest
+	b, _ = os.ReadFile(filepath.Join(dir, ManifestName))
+	if err := json.Unmarshal(b, &onDisk); err != nil || len(onDisk.Images) != 2 || onDisk.Images[0].SHA256 != kept.SHA256 {
+		t.Fatalf("manifest = %s (%v)", b, err)
+	}
+
+	// A negative distance keeps near-duplicates but still drops copies.
+	d, _ = New(Config{Dir: t.TempDir(), MaxDistance: -1})
+	m, err = d.Download(context.Background(), srcs[:4])
+	if err != nil || len(m.Images) != 3 || !slices.Equal(m.Images[0].Duplicates, []string{srv.URL + "/copy.png"}) {
+		t.Fatalf("images = %+v, err = %v", m.Images, err)
+	}
+
+	// 0 is a distance like any other: a copy one bit away is kept.
+	for _, tc := range []struct{ dist, images int }{{0, 2}, {1, 1}} {
+		d, _ = New(Config{Dir: t.TempDir(), MaxDistance: tc.dist})
+		m, err = d.Download(context.Background(), []Source{srcs[0], {URL: srv.URL + "/marked.png"}})
+		if err != nil || len(m.Images) != tc.images {
+			t.Fatalf("distance %d: images = %+v, err = %v", tc.dist, m.Images, err)
+		}
+	}
+}
+
+func TestDHash(t *testing.T) {
+	a := dHash(scene(64, 48, false))
+	if d := distance(a, dHash(scene(640, 480, false))); d > 2 {
+		t.Errorf("resized distance = %d", d)
+	}
+	if d := distance(a, dHash(scene(64, 48, true))); d < 20 {
+		t.Errorf("flipped distance = %d", d)
+	}
+	// Images smaller than the 9x8 grid still hash.
+	dHash(scene(3, 2, false))
+}
+
+func TestWebPSize(t *testing.T) {
+	header := func(chunk string, payload ...byte) []byte {
+		b := append([]byte("RIFF\x00\x00\x00\x00WEBP"+chunk+"\x00\x00\x00\x00"), payload...)
+		return append(b, make([]byte, 32)...)
+	}
+	for _, tc := range []struct {
+		name string
+		b    []byte
+		w, h int
+	}{
+		// Frame tag and start code, then 640x480.
+		{"lossy", header("VP8 ", 0, 0, 0, 0x9d, 0x01, 0x2a, 0x80, 0x02, 0xe0, 0x01), 640, 480},
+		// Signature 0x2f, then (w-1) | (h-1)<<14 = 99 | 49<<14.
+		{"lossless", header("VP8L", 0x2f, 0x63, 0x40, 0x0c, 0x00), 100, 50},
+		// Flags and reserved bytes, then 24-bit w-1 = 1999 and h-1 = 999.
+		{"extended", header("VP8X", 0, 0, 0, 0, 0xcf, 0x07, 0x00, 0xe7, 0x03, 0x00), 2000, 1000},
+		{"truncated", []byte("RIFF"), 0, 0},
+	} {
+		if w, h := webpSize(tc.b); w != tc.w || h != tc.h {
+			t.Errorf("%s: %dx%d, want %dx%d", tc.name, w, h, tc.w, tc.h)
+		}
+	}
+}
+
+func TestSources(t *testing.T) {
+	srcs := FromResults([]linkup.SearchResult{
+		{Type: "text", URL: "https://a.com/"},
+		{Type: "image", Name: "A cat", URL: "https://a.com/cat.jpg"},
+	})
+	if len(srcs) != 1 || srcs[0] != (Source{URL: "https://a.com/cat.jpg", Alt: "A cat"}) {
+		t.Fatalf("FromResults = %+v", srcs)
+	}
+	srcs = FromPage("https://a.com/", linkup.FetchResult{Images: []linkup.FetchImage{{URL: "https://a.com/x.png", Alt: "x"}, {}}})
+	if len(srcs) != 1 || srcs[0] != (Source{URL: "https://a.com/x.png", Page: "https://a.com/", Alt: "x"}) {
+		t.Fatalf("FromPage = %+v", srcs)
+	}
+	if _, err := New(Config{}); err == nil {
+		t.Fatal("New without Dir succeeded")
+	}
+	if _, err := New(Config{Dir: "x", Types: []string{"image/svg+xml"}}); err == nil {
+		t.Fatal("New with an unsniffable type succeeded")
+	}
+}
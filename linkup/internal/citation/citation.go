@@ -0,0 +1,80 @@
+// Package citation finds and rewrites the inline citation markers ("[1]",
+// "[2, 3]") of sourced answers. It is shared by package cite and by the
+// client, which renumbers answers whose sources it drops.
+package citation
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// markerRe matches "[1]" and "[1, 2]" style markers.
+var markerRe = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// Marker is one citation group found in an answer.
+type Marker struct {
+	Start, End int // byte offsets of the marker in the answer
+	Refs       []int
+}
+
+// Scan returns the citation markers of answer in order.
+func Scan(answer string) []Marker {
+	var out []Marker
+	for _, m := range markerRe.FindAllStringSubmatchIndex(answer, -1) {
+		// "[1](https://...)" is a markdown link, not a citation.
+		if m[1] < len(answer) && answer[m[1]] == '(' {
+			continue
+		}
+		var refs []int
+		for _, p := range strings.Split(answer[m[2]:m[3]], ",") {
+			n, _ := strconv.Atoi(strings.TrimSpace(p))
+			refs = append(refs, n)
+		}
+		out = append(out, Marker{Start: m[0], End: m[1], Refs: refs})
+	}
+	return out
+}
+
+// Replace rewrites every marker with repl, passing the text between markers
+// through esc (when non-nil).
+func Replace(s string, markers []Marker, esc func(string) string, repl func(Marker) string) string {
+	if esc == nil {
+		esc = func(s string) string { return s }
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range markers {
+		b.WriteString(esc(s[last:m.Start]))
+		b.WriteString(repl(m))
+		last = m.End
+	}
+	b.WriteString(esc(s[last:]))
+	return b.String()
+}
+
+// Renumber rewrites every citation index n in answer to mapping(n). A
+// mapping of 0 removes the reference, and a marker left with none is
+// removed along with the spaces before it.
+func Renumber(answer string, mapping func(n int) int) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range Scan(answer) {
+		var refs []string
+		for _, n := range m.Refs {
+			if n = mapping(n); n != 0 {
+				refs = append(refs, strconv.Itoa(n))
+			}
+		}
+		text := answer[last:m.Start]
+		if refs == nil {
+			b.WriteString(strings.TrimRight(text, " \t"))
+		} else {
+			b.WriteString(text)
+			b.WriteString("[" + strings.Join(refs, ", ") + "]")
+		}
+		last = m.End
+	}
+	b.WriteString(answer[last:])
+	return b.String()
+}
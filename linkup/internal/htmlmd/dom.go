@@ -0,0 +1,145 @@
+package htmlmd
+
+import (
+	"strings"
+
+	"github.com/raezil/linkup-go/linkup/internal/htmltok"
+)
+
+// node is an element or, when tag is "", a text node.
+type node struct {
+	tag      string
+	attrs    []htmltok.Attr
+	text     string
+	parent   *node
+	children []*node
+}
+
+func (n *node) attr(key string) string {
+	for _, a := range n.attrs {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func (n *node) append(c *node) {
+	c.parent = n
+	n.children = append(n.children, c)
+}
+
+// walk calls fn for n and its descendants in document order, skipping the
+// children of nodes for which fn returns false.
+func (n *node) walk(fn func(*node) bool) {
+	if !fn(n) {
+		return
+	}
+	for _, c := range n.children {
+		c.walk(fn)
+	}
+}
+
+// textContent returns the concatenated text of n.
+func (n *node) textContent() string {
+	var b strings.Builder
+	n.walk(func(c *node) bool {
+		if c.tag == "" {
+			b.WriteString(c.text)
+		}
+		return true
+	})
+	return b.String()
+}
+
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// autoClose lists, for an element, the open elements its start tag
+// implicitly closes, and the elements that bound that search.
+var autoClose = map[string]struct{ closes, scope []string }{
+	"li":     {[]string{"li"}, []string{"ul", "ol", "menu"}},
+	"dt":     {[]string{"dt", "dd"}, []string{"dl"}},
+	"dd":     {[]string{"dt", "dd"}, []string{"dl"}},
+	"tr":     {[]string{"tr", "td", "th"}, []string{"table", "thead", "tbody", "tfoot"}},
+	"td":     {[]string{"td", "th"}, []string{"tr", "table"}},
+	"th":     {[]string{"td", "th"}, []string{"tr", "table"}},
+	"thead":  {[]string{"thead", "tbody", "tr", "td", "th"}, []string{"table"}},
+	"tbody":  {[]string{"thead", "tbody", "tr", "td", "th"}, []string{"table"}},
+	"tfoot":  {[]string{"thead", "tbody", "tr", "td", "th"}, []string{"table"}},
+	"option": {[]string{"option"}, []string{"select", "datalist"}},
+}
+
+// blockElements close an open <p>.
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "details": true, "div": true,
+	"dl": true, "fieldset": true, "figure": true, "footer": true, "form": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true, "main": true, "nav": true,
+	"ol": true, "p": true, "pre": true, "section": true, "table": true, "ul": true,
+}
+
+// parse builds a tree from an HTML document. It is forgiving rather than
+// spec-complete: unmatched end tags are ignored and the common implied
+// end tags (p, li, td, ...) are inserted.
+func parse(doc string) *node {
+	root := &node{tag: "#document"}
+	stack := []*node{root}
+	top := func() *node { return stack[len(stack)-1] }
+	// closeTo pops the stack through the innermost open element named
+	// tag, unless one of the scope elements is found first.
+	closeTo := func(tags []string, scope []string) {
+		for i := len(stack) - 1; i > 0; i-- {
+			t := stack[i].tag
+			if contains(scope, t) {
+				return
+			}
+			if contains(tags, t) {
+				stack = stack[:i]
+				return
+			}
+		}
+	}
+
+	z := htmltok.New(doc)
+	for {
+		tok, ok := z.Next()
+		if !ok {
+			break
+		}
+		switch tok.Type {
+		case htmltok.Text:
+			top().append(&node{text: tok.Data})
+		case htmltok.StartTag, htmltok.SelfClosingTag:
+			if ac, ok := autoClose[tok.Name]; ok {
+				closeTo(ac.closes, ac.scope)
+			}
+			if blockElements[tok.Name] {
+				closeTo([]string{"p"}, []string{"button", "table", "li", "td", "th", "blockquote", "div", "section", "article"})
+			}
+			n := &node{tag: tok.Name, attrs: tok.Attrs}
+			top().append(n)
+			if tok.Type == htmltok.StartTag && !voidElements[tok.Name] {
+				stack = append(stack, n)
+			}
+		case htmltok.EndTag:
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].tag == tok.Name {
+					stack = stack[:i]
+					break
+				}
+			}
+		}
+	}
+	return root
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
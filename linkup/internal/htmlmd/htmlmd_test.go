@@ -0,0 +1,130 @@
+package htmlmd
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const article = `<html><head><title>T</title><style>x{}</style></head><body>
+<nav class="menu"><a href="/">Home</a><a href="/about">About</a></nav>
+<div class="sidebar"><p>Subscribe to our newsletter, it is great, really, trust us.</p></div>
+<article>
+<header><h1>Release   notes</h1></header>
+<p>Go 1.22 brings <strong>range over int</strong>, <em>loop var</em> changes and <code>math/rand/v2</code>.
+See <a href="/doc/go1.22">the notes</a> for details, caveats, and more.</p>
+<h2>Example</h2>
+<pre><code class="language-go">for i := range 10 {
+	fmt.Println(i)
+}
+
+
+// done
+</code></pre>
+<ul>
+<li>First item
+<li>Second item with <a href="https://x.org">link</a>
+  <ul><li>Nested</li><li>Nested two<pre>code
+  in list</pre></li></ul>
+<li><p>Para item</p><p>second para</p></li>
+</ul>
+<ol start="3"><li>three</li><li>four</li></ol>
+<blockquote><p>Quoted text</p><p>More</p></blockquote>
+<table><tr><th>Name</th><th>Value</th></tr><tr><td>a|b</td><td>1</td></tr><tr><td>c</td></tr></table>
+<img src="/img/a.png" alt="Diagram">
+<p>Closing paragraph with enough words to count as content, of course.</p>
+</article>
+<footer>Copyright</footer>
+</body></html>`
+
+const wantArticle = "# Release notes\n" +
+	"\n" +
+	"Go 1.22 brings **range over int**, _loop var_ changes and `math/rand/v2`. See [the notes](https://go.dev/doc/go1.22) for details, caveats, and more.\n" +
+	"\n" +
+	"## Example\n" +
+	"\n" +
+	"```go\n" +
+	"for i := range 10 {\n" +
+	"	fmt.Println(i)\n" +
+	"}\n" +
+	"\n" +
+	"\n" +
+	"// done\n" +
+	"```\n" +
+	"\n" +
+	"- First item\n" +
+	"- Second item with [link](https://x.org)\n" +
+	"  - Nested\n" +
+	"  - Nested two\n" +
+	"    ```\n" +
+	"    code\n" +
+	"      in list\n" +
+	"    ```\n" +
+	"- Para item\n" +
+	"  second para\n" +
+	"\n" +
+	"3. three\n" +
+	"4. four\n" +
+	"\n" +
+	"> Quoted text\n" +
+	">\n" +
+	"> More\n" +
+	"\n" +
+	"| Name | Value |\n" +
+	"| --- | --- |\n" +
+	"| a\\|b | 1 |\n" +
+	"| c |  |\n" +
+	"\n" +
+	"![Diagram](https://go.dev/img/a.png)\n" +
+	"\n" +
+	"Closing paragraph with enough words to count as content, of course.\n" +
+	""
+
+func TestConvert_Article(t *testing.T) {
+	base, _ := url.Parse("https://go.dev/blog/go1.22")
+	r := Convert(article, base)
+	if r.Markdown != wantArticle {
+		t.Fatalf("got:\n%s\nwant:\n%s", r.Markdown, wantArticle)
+	}
+	if len(r.Images) != 1 || r.Images[0].URL != "https://go.dev/img/a.png" || r.Images[0].Alt != "Diagram" {
+		t.Fatalf("images = %+v", r.Images)
+	}
+}
+
+func TestConvert_ScoresContent(t *testing.T) {
+	para := "<p>This paragraph is long enough to count, with commas, clauses, and words that make it content.</p>"
+	doc := `<body><div id="top"><a href="/a">A</a> <a href="/b">B</a></div>
+<div class="share-widget">` + para + `</div>
+<div class="post-body">` + strings.Repeat(para, 4) + `<p>Read <a href="x">more</a>.</p></div>
+<div class="comments">` + para + `</div>
+<p hidden>secret</p><span style="display: none">hidden</span></body>`
+	md := Convert(doc, nil).Markdown
+	if strings.Count(md, "This paragraph") != 4 || strings.Contains(md, "secret") || strings.Contains(md, "hidden") || strings.Contains(md, "[A]") {
+		t.Fatalf("markdown:\n%s", md)
+	}
+}
+
+func TestConvert_ShortPageKeepsBody(t *testing.T) {
+	md := Convert(`<html><body><h1>Hi</h1><p>Short <b>page</b>.</p><ul><li>one</li><li>two</li></ul></body></html>`, nil).Markdown
+	if md != "# Hi\n\nShort **page**.\n\n- one\n- two\n" {
+		t.Fatalf("markdown = %q", md)
+	}
+}
+
+func TestParse_ImpliedEndTags(t *testing.T) {
+	root := parse("<ul><li>a<li>b</ul><p>one<p>two<div>three</div></p></span>")
+	var tags []string
+	root.walk(func(n *node) bool {
+		if n.tag != "" && n.tag != "#document" {
+			depth := 0
+			for p := n.parent; p != root; p = p.parent {
+				depth++
+			}
+			tags = append(tags, strings.Repeat(".", depth)+n.tag)
+		}
+		return true
+	})
+	if got := strings.Join(tags, " "); got != "ul .li .li p p div" {
+		t.Fatalf("tree = %s", got)
+	}
+}
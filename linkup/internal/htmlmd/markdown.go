@@ -0,0 +1,371 @@
+// Package htmlmd converts HTML pages to markdown locally. It keeps the
+// main content, found with Readability-style heuristics, and renders
+// headings, paragraphs, emphasis, links, images, lists, block quotes,
+// tables and fenced code blocks.
+package htmlmd
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Image is an image found in the converted content.
+type Image struct {
+	URL string
+	Alt string
+}
+
+// Result is a converted page.
+type Result struct {
+	Markdown string
+	Images   []Image
+}
+
+// Convert renders the main content of doc as markdown, resolving links
+// and images against base (which a <base> element overrides).
+func Convert(doc string, base *url.URL) Result {
+	if base == nil {
+		base = &url.URL{}
+	}
+	root := parse(doc)
+	root.walk(func(n *node) bool {
+		if n.tag == "base" {
+			if b, err := base.Parse(n.attr("href")); err == nil && n.attr("href") != "" {
+				base = b
+			}
+			return false
+		}
+		return n.tag != "body"
+	})
+	r := &renderer{base: base}
+	md := r.render(mainContent(root))
+	return Result{Markdown: r.finish(md), Images: r.images}
+}
+
+// Markers kept out of whitespace normalization: indentation added by
+// lists, and placeholders for code blocks.
+const (
+	indentMark = '\x01'
+	codeMark   = "\x02"
+)
+
+type renderer struct {
+	base   *url.URL
+	images []Image
+	code   []string
+	seen   map[string]bool
+}
+
+func (r *renderer) render(n *node) string {
+	if n.tag == "" {
+		return whitespaceRe.ReplaceAllString(n.text, " ")
+	}
+	switch n.tag {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level, _ := strconv.Atoi(n.tag[1:])
+		t := oneLine(r.children(n))
+		if t == "" {
+			return ""
+		}
+		return block(strings.Repeat("#", level) + " " + t)
+	case "p", "div", "section", "article", "main", "header", "figure", "figcaption",
+		"address", "details", "summary", "dl", "dd", "center", "body", "html", "#document", "fieldset":
+		return block(r.children(n))
+	case "dt":
+		return block(wrap("**", oneLine(r.children(n))))
+	case "br":
+		return "\n"
+	case "hr":
+		return block("---")
+	case "strong", "b":
+		return wrap("**", r.children(n))
+	case "em", "i", "cite":
+		return wrap("_", r.children(n))
+	case "del", "s", "strike":
+		return wrap("~~", r.children(n))
+	case "code", "kbd", "samp", "tt":
+		t := collapseSpace(n.textContent())
+		switch {
+		case t == "":
+			return ""
+		case strings.Contains(t, "`"):
+			return "`` " + t + " ``"
+		}
+		return "`" + t + "`"
+	case "a":
+		return r.link(n)
+	case "img":
+		return r.image(n)
+	case "pre":
+		return r.pre(n)
+	case "ul", "ol", "menu":
+		return r.list(n)
+	case "blockquote":
+		inner := r.finishBlock(r.children(n))
+		if inner == "" {
+			return ""
+		}
+		lines := strings.Split(inner, "\n")
+		for i, l := range lines {
+			lines[i] = strings.TrimRight("> "+l, " ")
+		}
+		return block(strings.Join(lines, "\n"))
+	case "table":
+		return r.table(n)
+	case "li":
+		// Outside a list.
+		return block(r.children(n))
+	}
+	return r.children(n)
+}
+
+func (r *renderer) children(n *node) string {
+	var b strings.Builder
+	for _, c := range n.children {
+		b.WriteString(r.render(c))
+	}
+	return b.String()
+}
+
+func (r *renderer) link(n *node) string {
+	text := oneLine(r.children(n))
+	href := r.resolve(n.attr("href"))
+	if href == "" || text == "" {
+		return text
+	}
+	if t := n.attr("title"); t != "" {
+		return fmt.Sprintf("[%s](%s %q)", text, href, t)
+	}
+	return "[" + text + "](" + href + ")"
+}
+
+func (r *renderer) image(n *node) string {
+	src := n.attr("src")
+	for _, lazy := range []string{"data-src", "data-original", "data-lazy-src"} {
+		if v := n.attr(lazy); v != "" && (src == "" || strings.HasPrefix(src, "data:")) {
+			src = v
+		}
+	}
+	src = r.resolve(src)
+	if src == "" {
+		return ""
+	}
+	alt := collapseSpace(n.attr("alt"))
+	if r.seen == nil {
+		r.seen = map[string]bool{}
+	}
+	if !r.seen[src] {
+		r.seen[src] = true
+		r.images = append(r.images, Image{URL: src, Alt: alt})
+	}
+	return "![" + strings.ReplaceAll(alt, "]", "") + "](" + src + ")"
+}
+
+var langRe = regexp.MustCompile(`(?:^|\s)(?:language|lang)-([\w+#.-]+)`)
+
+func (r *renderer) pre(n *node) string {
+	text := strings.Trim(n.textContent(), "\n")
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+	lang := ""
+	classes := n.attr("class")
+	for _, c := range n.children {
+		if c.tag == "code" {
+			classes += " " + c.attr("class")
+		}
+	}
+	if m := langRe.FindStringSubmatch(classes); m != nil {
+		lang = m[1]
+	}
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	r.code = append(r.code, fence+lang+"\n"+text+"\n"+fence)
+	return block(codeMark + strconv.Itoa(len(r.code)-1) + codeMark)
+}
+
+func (r *renderer) list(n *node) string {
+	ordered := n.tag == "ol"
+	num := 1
+	if s, err := strconv.Atoi(n.attr("start")); err == nil {
+		num = s
+	}
+	var items []string
+	for _, c := range n.children {
+		var body string
+		switch c.tag {
+		case "li":
+			body = r.finishBlock(r.children(c))
+		case "ul", "ol":
+			// A list nested without an <li>: indent it under the previous item.
+			body = r.finishBlock(r.render(c))
+		default:
+			continue
+		}
+		if body == "" {
+			continue
+		}
+		// Keep lists tight and indent continuation lines under the marker.
+		body = blankLinesRe.ReplaceAllString(body, "\n")
+		marker := "- "
+		if ordered {
+			marker = strconv.Itoa(num) + ". "
+		}
+		pad := strings.Repeat(string(indentMark), len(marker))
+		if c.tag != "li" {
+			items = append(items, pad+strings.ReplaceAll(body, "\n", "\n"+pad))
+			continue
+		}
+		num++
+		items = append(items, marker+strings.ReplaceAll(body, "\n", "\n"+pad))
+	}
+	if len(items) == 0 {
+		return ""
+	}
+	return block(strings.Join(items, "\n"))
+}
+
+func (r *renderer) table(n *node) string {
+	var rows [][]string
+	header := false
+	n.walk(func(c *node) bool {
+		if c != n && c.tag == "table" {
+			return false
+		}
+		if c.tag != "tr" {
+			return true
+		}
+		var row []string
+		for _, cell := range c.children {
+			if cell.tag != "td" && cell.tag != "th" {
+				continue
+			}
+			if len(rows) == 0 && cell.tag == "th" {
+				header = true
+			}
+			t := oneLine(r.finishBlock(r.children(cell)))
+			row = append(row, strings.ReplaceAll(t, "|", `\|`))
+		}
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+		return false
+	})
+	cols := 0
+	for _, row := range rows {
+		cols = max(cols, len(row))
+	}
+	if cols == 0 {
+		return ""
+	}
+	if cols == 1 {
+		// A layout table: keep the cells as paragraphs.
+		var b strings.Builder
+		for _, row := range rows {
+			b.WriteString(block(row[0]))
+		}
+		return b.String()
+	}
+	if !header {
+		rows = append([][]string{make([]string, cols)}, rows...)
+	}
+	var b strings.Builder
+	for i, row := range rows {
+		for len(row) < cols {
+			row = append(row, "")
+		}
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			b.WriteString("|" + strings.Repeat(" --- |", cols) + "\n")
+		}
+	}
+	return block(strings.TrimRight(b.String(), "\n"))
+}
+
+func (r *renderer) resolve(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "#") {
+		return ""
+	}
+	u, err := r.base.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	switch u.Scheme {
+	case "http", "https", "mailto":
+		return strings.ReplaceAll(strings.ReplaceAll(u.String(), "(", "%28"), ")", "%29")
+	}
+	return ""
+}
+
+var (
+	whitespaceRe = regexp.MustCompile(`[ \t\r\n\f]+`)
+	blankLinesRe = regexp.MustCompile(`\n{2,}`)
+	manyLinesRe  = regexp.MustCompile(`\n{3,}`)
+	codeRefRe    = regexp.MustCompile("^(.*?)" + codeMark + `(\d+)` + codeMark + "$")
+)
+
+// finishBlock normalizes rendered markdown that will be nested in a list,
+// quote or table: lines trimmed, runs of blank lines collapsed.
+func (r *renderer) finishBlock(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(l)
+	}
+	return strings.TrimSpace(manyLinesRe.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// finish normalizes the final document and expands code blocks, keeping
+// the indentation of lists they sit in.
+func (r *renderer) finish(s string) string {
+	s = r.finishBlock(s)
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		if m := codeRefRe.FindStringSubmatch(l); m != nil {
+			idx, _ := strconv.Atoi(m[2])
+			prefix := strings.ReplaceAll(m[1], string(indentMark), " ")
+			// Quote markers repeat on every line; a list marker is
+			// replaced by spaces after the first.
+			rest := prefix
+			if strings.Trim(prefix, "> ") != "" {
+				rest = strings.Repeat(" ", len(prefix))
+			}
+			code := strings.Split(r.code[idx], "\n")
+			for j := range code {
+				p := rest
+				if j == 0 {
+					p = prefix
+				}
+				code[j] = strings.TrimRight(p+code[j], " ")
+			}
+			lines[i] = strings.Join(code, "\n")
+			continue
+		}
+		lines[i] = strings.ReplaceAll(l, string(indentMark), " ")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func block(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return ""
+	}
+	return "\n\n" + s + "\n\n"
+}
+
+func wrap(mark, s string) string {
+	t := strings.TrimSpace(s)
+	if t == "" {
+		return s
+	}
+	lead := s[:len(s)-len(strings.TrimLeft(s, " "))]
+	trail := s[len(strings.TrimRight(s, " ")):]
+	return lead + mark + t + mark + trail
+}
+
+func oneLine(s string) string { return collapseSpace(s) }
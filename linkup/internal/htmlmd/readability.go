@@ -0,0 +1,201 @@
+package htmlmd
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// unlikelyRe and likelyRe match class and id values of boilerplate
+	// and of content containers, after Readability.
+	unlikelyRe = regexp.MustCompile(`(?i)-ad-|\bads?\b|adbox|advert|banner|breadcrumb|combx|comment|community|cookie|consent|disqus|footer|gdpr|masthead|menu|modal|\bnav|newsletter|outbrain|pager|pagination|popup|promo|related|remark|replies|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|taboola|toolbar|tweet|widget`)
+	likelyRe   = regexp.MustCompile(`(?i)article|\bbody\b|content|entry|main|post|story|text|blog|markdown|prose|doc`)
+)
+
+// dropTags never carry readable content.
+var dropTags = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "iframe": true, "svg": true,
+	"canvas": true, "form": true, "button": true, "input": true, "select": true, "textarea": true,
+	"nav": true, "aside": true, "footer": true, "template": true, "object": true, "embed": true,
+	"dialog": true, "menu": true,
+}
+
+// clean removes boilerplate below n in place.
+func clean(n *node) {
+	kept := n.children[:0]
+	for _, c := range n.children {
+		if c.tag != "" && boilerplate(c) {
+			continue
+		}
+		clean(c)
+		kept = append(kept, c)
+	}
+	n.children = kept
+}
+
+func boilerplate(n *node) bool {
+	if dropTags[n.tag] {
+		return true
+	}
+	if _, hidden := attrOK(n, "hidden"); hidden || n.attr("aria-hidden") == "true" {
+		return true
+	}
+	if style := strings.ReplaceAll(strings.ToLower(n.attr("style")), " ", ""); strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+		return true
+	}
+	switch n.attr("role") {
+	case "navigation", "banner", "contentinfo", "complementary", "dialog", "alert", "menu", "menubar":
+		return true
+	}
+	if n.tag == "header" && !insideTag(n, "article") {
+		return true
+	}
+	switch n.tag {
+	case "body", "html", "article", "main", "table", "tbody", "tr", "td", "th", "pre", "code", "a", "li":
+		return false
+	}
+	id := n.attr("class") + " " + n.attr("id")
+	return unlikelyRe.MatchString(id) && !likelyRe.MatchString(id)
+}
+
+func attrOK(n *node, key string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func insideTag(n *node, tag string) bool {
+	for p := n.parent; p != nil; p = p.parent {
+		if p.tag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// minContent is the text length below which a chosen container is
+// distrusted and the whole body used instead.
+const minContent = 250
+
+// mainContent returns the node holding the page's main content: a
+// single <article> or <main>, else the best-scoring container, else the
+// body. The tree is cleaned of boilerplate first.
+func mainContent(root *node) *node {
+	body := root
+	root.walk(func(n *node) bool {
+		if n.tag == "body" {
+			body = n
+			return false
+		}
+		return body == root
+	})
+	clean(body)
+	bodyLen := textLen(body)
+
+	for _, sel := range []func(*node) bool{
+		func(n *node) bool { return n.tag == "article" },
+		func(n *node) bool { return n.tag == "main" || n.attr("role") == "main" },
+	} {
+		var found []*node
+		body.walk(func(n *node) bool {
+			if sel(n) {
+				found = append(found, n)
+				return false
+			}
+			return true
+		})
+		if len(found) == 1 && textLen(found[0]) >= minContent {
+			return found[0]
+		}
+	}
+
+	if best := bestCandidate(body); best != nil && textLen(best) >= minContent && textLen(best)*4 >= bodyLen {
+		return best
+	}
+	return body
+}
+
+// bestCandidate scores paragraph containers as Readability does: each
+// paragraph adds to its parent and half as much to its grandparent,
+// weighted by commas and length; the total is scaled down by link density.
+func bestCandidate(body *node) *node {
+	scores := map[*node]float64{}
+	var order []*node
+	init := func(n *node) {
+		if _, ok := scores[n]; ok {
+			return
+		}
+		order = append(order, n)
+		s := 0.0
+		switch n.tag {
+		case "div", "article", "section", "main":
+			s = 5
+		case "pre", "td", "blockquote":
+			s = 3
+		case "ol", "ul", "dl", "form":
+			s = -3
+		case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+			s = -5
+		}
+		id := n.attr("class") + " " + n.attr("id")
+		if likelyRe.MatchString(id) {
+			s += 25
+		}
+		if unlikelyRe.MatchString(id) {
+			s -= 25
+		}
+		scores[n] = s
+	}
+	body.walk(func(n *node) bool {
+		switch n.tag {
+		case "p", "pre", "td":
+		default:
+			return true
+		}
+		text := collapseSpace(n.textContent())
+		if len(text) < 25 || n.parent == nil {
+			return false
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+		init(n.parent)
+		scores[n.parent] += score
+		if gp := n.parent.parent; gp != nil {
+			init(gp)
+			scores[gp] += score / 2
+		}
+		return false
+	})
+	var best *node
+	bestScore := 0.0
+	for _, n := range order {
+		s := scores[n] * (1 - linkDensity(n))
+		if best == nil || s > bestScore {
+			best, bestScore = n, s
+		}
+	}
+	return best
+}
+
+func textLen(n *node) int { return len(collapseSpace(n.textContent())) }
+
+// linkDensity is the share of n's text inside links.
+func linkDensity(n *node) float64 {
+	total := textLen(n)
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	n.walk(func(c *node) bool {
+		if c.tag == "a" {
+			links += textLen(c)
+			return false
+		}
+		return true
+	})
+	return float64(links) / float64(total)
+}
+
+func collapseSpace(s string) string { return strings.Join(strings.Fields(s), " ") }
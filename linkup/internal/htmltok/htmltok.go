@@ -0,0 +1,271 @@
+// Package htmltok is a small, forgiving HTML tokenizer. It splits a
+// document into tags, text and comments the way browsers do for the
+// purposes the SDK needs (reading metadata and links): tag and attribute
+// names are lowercased, character references are decoded, and the
+// contents of script, style, textarea and title are returned as a single
+// text token. It does not build a tree or fix up unbalanced markup.
+package htmltok
+
+import (
+	"html"
+	"strings"
+)
+
+// Type is the kind of a Token.
+type Type int
+
+const (
+	Text Type = iota
+	StartTag
+	EndTag
+	SelfClosingTag
+	Comment
+	Doctype
+)
+
+func (t Type) String() string {
+	switch t {
+	case Text:
+		return "Text"
+	case StartTag:
+		return "StartTag"
+	case EndTag:
+		return "EndTag"
+	case SelfClosingTag:
+		return "SelfClosingTag"
+	case Comment:
+		return "Comment"
+	case Doctype:
+		return "Doctype"
+	}
+	return "Unknown"
+}
+
+// Attr is a tag attribute. Key is lowercased; Val is unescaped.
+type Attr struct {
+	Key, Val string
+}
+
+// Token is a piece of the document. Name is the lowercased tag name for
+// tags; Data is the text, comment or doctype content.
+type Token struct {
+	Type  Type
+	Name  string
+	Attrs []Attr
+	Data  string
+}
+
+// Attr returns the value of the named attribute.
+func (t *Token) Attr(key string) (string, bool) {
+	for _, a := range t.Attrs {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// rawText elements hold text up to their end tag; for the escapable ones
+// character references are still decoded.
+var rawText = map[string]bool{"script": false, "style": false, "xmp": false, "textarea": true, "title": true}
+
+// Tokenizer reads tokens from a string.
+type Tokenizer struct {
+	s   string
+	pos int
+	raw string // element whose raw text comes next
+}
+
+// New returns a Tokenizer over s.
+func New(s string) *Tokenizer {
+	return &Tokenizer{s: s}
+}
+
+// Next returns the next token, or false at the end of the input.
+func (z *Tokenizer) Next() (Token, bool) {
+	if z.raw != "" {
+		name := z.raw
+		z.raw = ""
+		end := z.findEndTag(name)
+		text := z.s[z.pos:end]
+		z.pos = end
+		if text != "" {
+			if rawText[name] {
+				text = html.UnescapeString(text)
+			}
+			return Token{Type: Text, Data: text}, true
+		}
+	}
+	for z.pos < len(z.s) {
+		if z.s[z.pos] == '<' {
+			if tok, ok := z.tag(); ok {
+				return tok, true
+			}
+		}
+		// Text runs to the next '<' that starts markup.
+		start := z.pos
+		z.pos++
+		for z.pos < len(z.s) && !(z.s[z.pos] == '<' && z.startsMarkup()) {
+			z.pos++
+		}
+		return Token{Type: Text, Data: html.UnescapeString(z.s[start:z.pos])}, true
+	}
+	return Token{}, false
+}
+
+// startsMarkup reports whether the '<' at z.pos begins a tag, comment or
+// declaration rather than literal text.
+func (z *Tokenizer) startsMarkup() bool {
+	if z.pos+1 >= len(z.s) {
+		return false
+	}
+	c := z.s[z.pos+1]
+	if c == '/' && z.pos+2 < len(z.s) {
+		c = z.s[z.pos+2]
+		return isLetter(c) || c == '>'
+	}
+	return isLetter(c) || c == '!' || c == '?'
+}
+
+// tag parses the markup at z.pos, which is '<'. It returns false, leaving
+// z.pos alone, when the '<' is literal text.
+func (z *Tokenizer) tag() (Token, bool) {
+	if !z.startsMarkup() {
+		return Token{}, false
+	}
+	rest := z.s[z.pos:]
+	switch {
+	case strings.HasPrefix(rest, "<!--"):
+		body, n := until(rest[4:], "-->")
+		z.pos += 4 + n
+		return Token{Type: Comment, Data: body}, true
+	case strings.HasPrefix(rest, "<![CDATA["):
+		body, n := until(rest[9:], "]]>")
+		z.pos += 9 + n
+		return Token{Type: Text, Data: body}, true
+	case rest[1] == '!':
+		body, n := until(rest[2:], ">")
+		z.pos += 2 + n
+		if len(body) >= 7 && strings.EqualFold(body[:7], "doctype") {
+			return Token{Type: Doctype, Data: strings.TrimSpace(body[7:])}, true
+		}
+		return Token{Type: Comment, Data: body}, true
+	case rest[1] == '?':
+		body, n := until(rest[2:], ">")
+		z.pos += 2 + n
+		return Token{Type: Comment, Data: body}, true
+	case rest[1] == '/':
+		if rest[2] == '>' {
+			// "</>" is dropped.
+			z.pos += 3
+			return z.Next()
+		}
+		z.pos += 2
+		name := z.name()
+		_, n := until(z.s[z.pos:], ">")
+		z.pos += n
+		return Token{Type: EndTag, Name: name}, true
+	}
+
+	z.pos++
+	tok := Token{Type: StartTag, Name: z.name()}
+	for z.pos < len(z.s) {
+		c := z.s[z.pos]
+		switch {
+		case isSpace(c):
+			z.pos++
+			continue
+		case c == '>':
+			z.pos++
+			if _, ok := rawText[tok.Name]; ok && tok.Type == StartTag {
+				z.raw = tok.Name
+			}
+			return tok, true
+		case c == '/':
+			z.pos++
+			if z.pos < len(z.s) && z.s[z.pos] == '>' {
+				tok.Type = SelfClosingTag
+			}
+			continue
+		}
+		tok.Type = StartTag
+		tok.Attrs = append(tok.Attrs, z.attr())
+	}
+	return tok, true
+}
+
+// name reads a tag name, lowercased.
+func (z *Tokenizer) name() string {
+	start := z.pos
+	for z.pos < len(z.s) && !isSpace(z.s[z.pos]) && z.s[z.pos] != '/' && z.s[z.pos] != '>' {
+		z.pos++
+	}
+	return strings.ToLower(z.s[start:z.pos])
+}
+
+func (z *Tokenizer) attr() Attr {
+	start := z.pos
+	z.pos++ // the first character may be '=' itself
+	for z.pos < len(z.s) && !isSpace(z.s[z.pos]) && !strings.ContainsRune("/>=", rune(z.s[z.pos])) {
+		z.pos++
+	}
+	a := Attr{Key: strings.ToLower(z.s[start:z.pos])}
+	p := z.pos
+	for p < len(z.s) && isSpace(z.s[p]) {
+		p++
+	}
+	if p >= len(z.s) || z.s[p] != '=' {
+		return a
+	}
+	p++
+	for p < len(z.s) && isSpace(z.s[p]) {
+		p++
+	}
+	z.pos = p
+	if p < len(z.s) && (z.s[p] == '"' || z.s[p] == '\'') {
+		q := z.s[p]
+		end := strings.IndexByte(z.s[p+1:], q)
+		if end < 0 {
+			end = len(z.s) - p - 1
+			z.pos = len(z.s)
+		} else {
+			z.pos = p + 1 + end + 1
+		}
+		a.Val = html.UnescapeString(z.s[p+1 : p+1+end])
+		return a
+	}
+	for z.pos < len(z.s) && !isSpace(z.s[z.pos]) && z.s[z.pos] != '>' {
+		z.pos++
+	}
+	a.Val = html.UnescapeString(z.s[p:z.pos])
+	return a
+}
+
+// findEndTag returns the offset of the "</name" that closes a raw text
+// element, or the end of the input.
+func (z *Tokenizer) findEndTag(name string) int {
+	for i := z.pos; ; {
+		j := strings.Index(z.s[i:], "</")
+		if j < 0 {
+			return len(z.s)
+		}
+		i += j
+		k := i + 2 + len(name)
+		if k <= len(z.s) && strings.EqualFold(z.s[i+2:k], name) && (k == len(z.s) || isSpace(z.s[k]) || z.s[k] == '>' || z.s[k] == '/') {
+			return i
+		}
+		i += 2
+	}
+}
+
+// until returns s up to sep and how many bytes to consume, including sep.
+func until(s, sep string) (string, int) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], i + len(sep)
+	}
+	return s, len(s)
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+
+func isSpace(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' }
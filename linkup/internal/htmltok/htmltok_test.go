@@ -0,0 +1,48 @@
+package htmltok
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func dump(s string) string {
+	var out []string
+	z := New(s)
+	for {
+		tok, ok := z.Next()
+		if !ok {
+			break
+		}
+		switch tok.Type {
+		case Text, Comment, Doctype:
+			out = append(out, fmt.Sprintf("%s(%q)", tok.Type, tok.Data))
+		default:
+			var attrs []string
+			for _, a := range tok.Attrs {
+				attrs = append(attrs, a.Key+"="+a.Val)
+			}
+			out = append(out, fmt.Sprintf("%s(%s %s)", tok.Type, tok.Name, strings.Join(attrs, ",")))
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func TestTokenizer(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{`<!DOCTYPE html><HTML Lang="en">`, `Doctype("html") StartTag(html lang=en)`},
+		{`<a href='x?a=1&amp;b=2' data-x=y disabled>T &lt;1&gt;</A>`, `StartTag(a href=x?a=1&b=2,data-x=y,disabled=) Text("T <1>") EndTag(a )`},
+		{`<br/><img src=a.png alt="x" />`, `SelfClosingTag(br ) SelfClosingTag(img src=a.png,alt=x)`},
+		{`a < b <3 <!-- c --> d`, `Text("a < b <3 ") Comment(" c ") Text(" d")`},
+		{`<script>if (a<b) { x("</div>") }</script><p>`, `StartTag(script ) Text("if (a<b) { x(\"</div>\") }") EndTag(script ) StartTag(p )`},
+		{`<title>A &amp; B <i>c</i></TITLE>`, `StartTag(title ) Text("A & B <i>c</i>") EndTag(title )`},
+		{`<style></style>x`, `StartTag(style ) EndTag(style ) Text("x")`},
+		{`<![CDATA[x<y]]><?xml v?>`, `Text("x<y") Comment("xml v?")`},
+		{`<meta content="unterminated`, `StartTag(meta content=unterminated)`},
+		{`<script>never closed`, `StartTag(script ) Text("never closed")`},
+	} {
+		if got := dump(tt.in); got != tt.want {
+			t.Errorf("%s\n got: %s\nwant: %s", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type sample struct {
+	Name    string            `json:"name" desc:"who"`
+	Tags    []string          `json:"tags,omitempty"`
+	Limit   int               `json:"limit,omitempty"`
+	Ratio   float64           `json:"ratio"`
+	Opt     *string           `json:"opt"`
+	Nested  struct{ On bool } `json:"nested"`
+	Ignored string            `json:"-"`
+	hidden  string
+}
+
+func TestReflect(t *testing.T) {
+	s := Reflect(sample{}).Strict()
+	if s.Type != "object" || len(s.Properties) != 6 {
+		t.Fatalf("unexpected: %+v", s)
+	}
+	if s.Property("name").Description != "who" || s.Property("tags").Items.Type != "string" {
+		t.Fatalf("bad props")
+	}
+	if s.Property("limit").Type != "integer" || s.Property("ratio").Type != "number" || s.Property("opt").Type != "string" {
+		t.Fatalf("bad scalar types")
+	}
+	if s.Property("nested").Property("On").Type != "boolean" {
+		t.Fatalf("nested not reflected")
+	}
+	want := []string{"name", "ratio", "nested"}
+	if len(s.Required) != len(want) {
+		t.Fatalf("required = %v", s.Required)
+	}
+	for i := range want {
+		if s.Required[i] != want[i] {
+			t.Fatalf("required = %v", s.Required)
+		}
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back map[string]any
+	if err := json.Unmarshal(b, &back); err != nil || back["additionalProperties"] != false {
+		t.Fatalf("marshal: %s", b)
+	}
+}
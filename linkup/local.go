@@ -0,0 +1,143 @@
+package linkup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/raezil/linkup-go/linkup/internal/htmlmd"
+)
+
+// SourceLocal is the FetchResult.Source of pages fetched and converted by
+// the local fallback rather than by the API.
+const SourceLocal = "local"
+
+// LocalFallback configures WithLocalFallback. The zero value is usable.
+type LocalFallback struct {
+	// When reports whether a failed Fetch should fall back. Nil means on
+	// every API error (403, 402, timeouts, 5xx after retries, ...) but
+	// ErrUnauthorized and 400 Bad Request: a local fetch would only hide a
+	// wrong key or a malformed request.
+	When func(error) bool
+	// MaxBytes bounds the page body read. Default 5 MiB.
+	MaxBytes int64
+	// UserAgent sent to the site. Default: the client's User-Agent.
+	UserAgent string
+}
+
+// WithLocalFallback makes Fetch, when the API call fails, GET the URL itself
+// with the client's http.Client and convert the HTML to markdown locally:
+// headings, lists, links, tables and code blocks are kept, navigation and
+// other boilerplate dropped. The result has Source "local", honors
+// IncludeRawHTML and ExtractImages but not RenderJS, and is recorded with
+// zero credits. If the fallback fails too, the API error is returned with
+// the fallback's appended. Offline clients never fall back.
+func WithLocalFallback(f LocalFallback) Option {
+	return func(c *Client) {
+		if f.MaxBytes <= 0 {
+			f.MaxBytes = 5 << 20
+		}
+		if f.When == nil {
+			f.When = fallsBack
+		}
+		c.local = &f
+	}
+}
+
+// fallsBack is the default LocalFallback.When.
+func fallsBack(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusBadRequest {
+		return false
+	}
+	return !errors.Is(err, ErrUnauthorized)
+}
+
+// fetchLocal is Fetch's fallback after apiErr.
+func (c *Client) fetchLocal(ctx context.Context, req FetchRequest, body []byte, apiErr error) (SearchResponse, error) {
+	start := time.Now()
+	fr, err := c.getLocal(ctx, req)
+	if err != nil {
+		return SearchResponse{}, fmt.Errorf("%w (local fallback: %v)", apiErr, err)
+	}
+	b, err := json.Marshal(fr)
+	if err != nil {
+		return SearchResponse{}, err
+	}
+	c.record(ctx, OpFetch, start, body, b, 0)
+	return SearchResponse{Raw: b}, nil
+}
+
+func (c *Client) getLocal(ctx context.Context, req FetchRequest) (FetchResult, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, req.URL, nil)
+	if err != nil {
+		return FetchResult{}, err
+	}
+	httpReq.Header.Set("User-Agent", firstNonEmpty(c.local.UserAgent, c.ua))
+	httpReq.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.8,*/*;q=0.1")
+	res, err := c.http.Do(httpReq)
+	if err != nil {
+		return FetchResult{}, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return FetchResult{}, fmt.Errorf("http %d", res.StatusCode)
+	}
+	b, err := io.ReadAll(io.LimitReader(res.Body, c.local.MaxBytes))
+	if err != nil {
+		return FetchResult{}, err
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	doc := decodeText(b, params["charset"])
+	fr := FetchResult{Source: SourceLocal}
+	switch {
+	case mediaType == "text/html", mediaType == "application/xhtml+xml",
+		mediaType == "" && looksLikeHTML(doc):
+		conv := htmlmd.Convert(doc, res.Request.URL)
+		fr.Markdown = conv.Markdown
+		if req.IncludeRawHTML {
+			fr.RawHTML = doc
+		}
+		if req.ExtractImages {
+			for _, img := range conv.Images {
+				fr.Images = append(fr.Images, FetchImage{URL: img.URL, Alt: img.Alt})
+			}
+		}
+	case strings.HasPrefix(mediaType, "text/"), mediaType == "":
+		fr.Markdown = doc
+	default:
+		return FetchResult{}, fmt.Errorf("unsupported content type %q", mediaType)
+	}
+	return fr, nil
+}
+
+// decodeText returns b as UTF-8. Bodies declared Latin-1 (or Windows-1252,
+// approximated as Latin-1), and others that are not valid UTF-8, are
+// converted byte by byte.
+func decodeText(b []byte, charset string) string {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252", "cp1252":
+	default:
+		if utf8.Valid(b) {
+			return string(b)
+		}
+	}
+	r := make([]rune, len(b))
+	for i, c := range b {
+		r[i] = rune(c)
+	}
+	return string(r)
+}
+
+func looksLikeHTML(s string) bool {
+	head := strings.ToLower(strings.TrimSpace(s[:min(len(s), 512)]))
+	return strings.HasPrefix(head, "<!doctype html") || strings.Contains(head, "<html")
+}
@@ -0,0 +1,131 @@
+package linkup
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type callLog []Call
+
+func (l *callLog) Record(_ context.Context, c Call) { *l = append(*l, c) }
+
+func TestLocalFallback(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/post":
+			if r.Header.Get("User-Agent") != "site-ua" {
+				t.Errorf("User-Agent = %q", r.Header.Get("User-Agent"))
+			}
+			w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+			w.Write([]byte("<html><head><title>Post</title></head><body><nav><a href=\"/\">Home</a></nav>" +
+				"<article><h1>Caf\xe9</h1><p>See <a href=\"/docs\">the docs</a>.</p><img src=\"a.png\" alt=\"A\"></article></body></html>"))
+		case "/notes.txt":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("plain notes"))
+		case "/file.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write([]byte("%PDF"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer site.Close()
+
+	api, srv := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	defer srv.Close()
+	var log callLog
+	c := NewClient("test-key", WithBaseURL(srv.URL), WithRecorder(&log),
+		WithLocalFallback(LocalFallback{UserAgent: "site-ua"}))
+	ctx := context.Background()
+
+	resp, err := c.Fetch(ctx, FetchRequest{URL: site.URL + "/post", ExtractImages: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := resp.Page()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Café\n\nSee [the docs](" + site.URL + "/docs).\n\n![A](" + site.URL + "/a.png)\n"
+	if page.Source != SourceLocal || page.Markdown != want || page.RawHTML != "" {
+		t.Fatalf("page = %+v", page)
+	}
+	if len(page.Images) != 1 || page.Images[0].URL != site.URL+"/a.png" {
+		t.Fatalf("images = %+v", page.Images)
+	}
+	if len(log) != 1 || log[0].Op != OpFetch || log[0].Credits != 0 {
+		t.Fatalf("recorded = %+v", log)
+	}
+
+	page, err = c.FetchPage(ctx, FetchRequest{URL: site.URL + "/post", IncludeRawHTML: true})
+	if err != nil || page.Title != "Post" || !strings.Contains(page.RawHTML, "<nav>") {
+		t.Fatalf("page = %+v, err = %v", page, err)
+	}
+
+	resp, err = c.Fetch(ctx, FetchRequest{URL: site.URL + "/notes.txt"})
+	if page, _ := resp.Page(); err != nil || page.Markdown != "plain notes" {
+		t.Fatalf("text = %s, err = %v", resp.Raw, err)
+	}
+
+	// Fallback failures keep the API error.
+	for _, path := range []string{"/file.pdf", "/missing"} {
+		_, err = c.Fetch(ctx, FetchRequest{URL: site.URL + path})
+		if !errors.Is(err, ErrForbidden) || !strings.Contains(err.Error(), "local fallback") {
+			t.Fatalf("%s: err = %v", path, err)
+		}
+	}
+
+	// When filters errors; without the option nothing falls back.
+	c = NewClient("test-key", WithBaseURL(srv.URL), WithRetry(0, time.Millisecond, time.Millisecond),
+		WithLocalFallback(LocalFallback{When: func(err error) bool { return errors.Is(err, ErrUnauthorized) }}))
+	if _, err := c.Fetch(ctx, FetchRequest{URL: site.URL + "/post"}); !errors.Is(err, ErrForbidden) || strings.Contains(err.Error(), "local") {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := api.Fetch(ctx, FetchRequest{URL: site.URL + "/post"}); err != ErrForbidden {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestLocalFallback_DefaultWhen(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("local page"))
+	}))
+	defer site.Close()
+
+	// A wrong key or a malformed request is the caller's to fix.
+	for status, local := range map[int]bool{
+		http.StatusBadRequest:          false,
+		http.StatusUnauthorized:        false,
+		http.StatusPaymentRequired:     true,
+		http.StatusForbidden:           true,
+		http.StatusNotFound:            true,
+		http.StatusUnprocessableEntity: true,
+		http.StatusTooManyRequests:     true,
+		http.StatusBadGateway:          true,
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			w.Write([]byte(`{"message":"no"}`))
+		}))
+		c := NewClient("test-key", WithBaseURL(srv.URL), WithRetry(0, time.Millisecond, time.Millisecond), WithLocalFallback(LocalFallback{}))
+		resp, err := c.Fetch(context.Background(), FetchRequest{URL: site.URL})
+		srv.Close()
+		if !local {
+			if err == nil {
+				t.Errorf("%d: fell back", status)
+			}
+			continue
+		}
+		if page, _ := resp.Page(); err != nil || page.Source != SourceLocal || page.Markdown != "local page" {
+			t.Errorf("%d: page = %s, err = %v", status, resp.Raw, err)
+		}
+	}
+}
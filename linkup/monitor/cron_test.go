@@ -0,0 +1,57 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	base := time.Date(2024, 1, 31, 10, 17, 30, 0, time.UTC) // a Wednesday
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"* * * * *", "2024-01-31T10:18"},
+		{"*/15 * * * *", "2024-01-31T10:30"},
+		{"0 * * * *", "2024-01-31T11:00"},
+		{"@hourly", "2024-01-31T11:00"},
+		{"@daily", "2024-02-01T00:00"},
+		{"30 9 * * mon-fri", "2024-02-01T09:30"},
+		{"0 0 29 2 *", "2024-02-29T00:00"},
+		{"0 0 31 * *", "2024-03-31T00:00"},
+		{"0 12 * jun *", "2024-06-01T12:00"},
+		{"0 8 * * 7", "2024-02-04T08:00"},
+		{"5,10 10-11 * * *", "2024-01-31T11:05"},
+		// Both day fields restricted: either matches (the 1st or a Friday).
+		{"0 0 1 * fri", "2024-02-01T00:00"},
+		{"0 0 15 * fri", "2024-02-02T00:00"},
+		{"@every 90m", "2024-01-31T11:47"},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.spec, err)
+		}
+		if got := s.Next(base).Format("2006-01-02T15:04"); got != tt.want {
+			t.Errorf("%q: Next = %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestSchedule_Never(t *testing.T) {
+	s, err := ParseSchedule("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Next(time.Now()); !got.IsZero() {
+		t.Fatalf("Next = %v, want zero", got)
+	}
+}
+
+func TestParseSchedule_Errors(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *", "@every 10ms"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
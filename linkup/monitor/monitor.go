@@ -0,0 +1,450 @@
+// Package monitor runs saved Linkup searches on cron schedules, records
+// what they return in an append-only store and notifies about results that
+// were not seen before (and, for sourced answers, about changed answers).
+// Searching pauses while the account balance is below a threshold.
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/canonical"
+)
+
+// ErrLowBalance is reported for runs skipped because the balance is below
+// Config.MinBalance.
+var ErrLowBalance = errors.New("monitor: balance below threshold")
+
+// Search is a saved search.
+type Search struct {
+	Name     string
+	Schedule *Schedule
+	Request  linkup.SearchRequest
+	// Notifiers overrides Config.Notifiers for this search when non-nil.
+	Notifiers []Notifier
+}
+
+// Config configures a Monitor.
+type Config struct {
+	Store     *Store
+	Searches  []Search
+	Notifiers []Notifier
+	// MinBalance pauses searching while the credit balance is below it;
+	// 0 disables the check.
+	MinBalance float64
+	// BalanceInterval is how often the balance is re-read. Between reads
+	// the estimated cost of each search is subtracted. Default 10m.
+	BalanceInterval time.Duration
+	// Timeout bounds each search. Default 60s.
+	Timeout time.Duration
+	// NotifyInitial also notifies about the first run of a search, which
+	// otherwise only records a baseline.
+	NotifyInitial bool
+	// Logf receives diagnostics. Default: discard.
+	Logf func(format string, args ...any)
+}
+
+// Item is a result or source that was not seen before.
+type Item struct {
+	Title   string `json:"title,omitempty"`
+	URL     string `json:"url"`
+	Content string `json:"content,omitempty"`
+}
+
+// Event describes what changed in one run of a search.
+type Event struct {
+	Search         string    `json:"search"`
+	Query          string    `json:"query"`
+	Time           time.Time `json:"time"`
+	New            []Item    `json:"new,omitempty"`
+	AnswerChanged  bool      `json:"answerChanged,omitempty"`
+	Answer         string    `json:"answer,omitempty"`
+	PreviousAnswer string    `json:"previousAnswer,omitempty"`
+}
+
+// Empty reports whether nothing changed.
+func (ev *Event) Empty() bool { return len(ev.New) == 0 && !ev.AnswerChanged }
+
+// Subject is a one-line summary, used as the mail subject.
+func (ev *Event) Subject() string {
+	var parts []string
+	if n := len(ev.New); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d new", n))
+	}
+	if ev.AnswerChanged {
+		parts = append(parts, "answer changed")
+	}
+	return fmt.Sprintf("[linkup] %s: %s", ev.Search, strings.Join(parts, ", "))
+}
+
+// Text renders the event for humans.
+func (ev *Event) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "== %s (%q) at %s\n", ev.Search, ev.Query, ev.Time.Format(time.RFC3339))
+	for _, it := range ev.New {
+		fmt.Fprintf(&b, "+ %s\n  %s\n", firstLine(it.Title), it.URL)
+	}
+	if ev.AnswerChanged {
+		fmt.Fprintf(&b, "answer changed:\n%s\n", ev.Answer)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func firstLine(s string) string {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "\n")
+	return s
+}
+
+// SearchStatus is the state of one saved search.
+type SearchStatus struct {
+	Name      string    `json:"name"`
+	Schedule  string    `json:"schedule"`
+	Query     string    `json:"query"`
+	LastRun   time.Time `json:"lastRun,omitempty"`
+	NextRun   time.Time `json:"nextRun,omitempty"`
+	LastError string    `json:"lastError,omitempty"`
+	Runs      int       `json:"runs"`
+	Skipped   int       `json:"skipped"`
+	NewItems  int       `json:"newItems"`
+}
+
+// Status is a snapshot of the monitor, served by Handler.
+type Status struct {
+	Started        time.Time      `json:"started"`
+	Balance        *float64       `json:"balance,omitempty"`
+	BalanceChecked time.Time      `json:"balanceChecked,omitempty"`
+	MinBalance     float64        `json:"minBalance,omitempty"`
+	Paused         bool           `json:"paused"`
+	Searches       []SearchStatus `json:"searches"`
+}
+
+// Monitor schedules and runs saved searches.
+type Monitor struct {
+	client *linkup.Client
+	cfg    Config
+
+	mu        sync.Mutex
+	started   time.Time
+	balance   *float64
+	balanceAt time.Time
+	status    map[string]*SearchStatus
+}
+
+// New validates cfg and returns a Monitor.
+func New(client *linkup.Client, cfg Config) (*Monitor, error) {
+	if cfg.Store == nil {
+		return nil, errors.New("monitor: Config.Store is required")
+	}
+	if cfg.BalanceInterval <= 0 {
+		cfg.BalanceInterval = 10 * time.Minute
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 60 * time.Second
+	}
+	if cfg.Logf == nil {
+		cfg.Logf = func(string, ...any) {}
+	}
+	m := &Monitor{client: client, cfg: cfg, status: map[string]*SearchStatus{}}
+	for _, s := range cfg.Searches {
+		switch {
+		case s.Name == "":
+			return nil, errors.New("monitor: search without a name")
+		case s.Schedule == nil:
+			return nil, fmt.Errorf("monitor: search %q: no schedule", s.Name)
+		case s.Request.Q == "":
+			return nil, fmt.Errorf("monitor: search %q: empty query", s.Name)
+		case s.Request.OutputType == linkup.OutputStructured:
+			return nil, fmt.Errorf("monitor: search %q: structured output is not supported", s.Name)
+		}
+		if _, dup := m.status[s.Name]; dup {
+			return nil, fmt.Errorf("monitor: duplicate search name %q", s.Name)
+		}
+		m.status[s.Name] = &SearchStatus{Name: s.Name, Schedule: s.Schedule.String(), Query: s.Request.Q}
+	}
+	return m, nil
+}
+
+// Run executes searches on their schedules until ctx is done. Searches that
+// are due at the same time run one after another.
+func (m *Monitor) Run(ctx context.Context) error {
+	m.mu.Lock()
+	m.started = time.Now()
+	m.mu.Unlock()
+
+	next := make(map[string]time.Time, len(m.cfg.Searches))
+	schedule := func(s Search, after time.Time) {
+		t := s.Schedule.Next(after)
+		next[s.Name] = t
+		m.mu.Lock()
+		m.status[s.Name].NextRun = t
+		m.mu.Unlock()
+		if t.IsZero() {
+			m.cfg.Logf("search %q: schedule %q never fires", s.Name, s.Schedule)
+		}
+	}
+	now := time.Now()
+	for _, s := range m.cfg.Searches {
+		schedule(s, now)
+	}
+
+	for {
+		var wake time.Time
+		for _, t := range next {
+			if !t.IsZero() && (wake.IsZero() || t.Before(wake)) {
+				wake = t
+			}
+		}
+		if wake.IsZero() {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		timer := time.NewTimer(time.Until(wake))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		now := time.Now()
+		for _, s := range m.cfg.Searches {
+			if t := next[s.Name]; t.IsZero() || t.After(now) {
+				continue
+			}
+			if _, err := m.RunSearch(ctx, s.Name); err != nil && ctx.Err() == nil {
+				m.cfg.Logf("search %q: %v", s.Name, err)
+			}
+			schedule(s, time.Now())
+		}
+	}
+}
+
+// RunSearch runs the named search once, records its results and notifies
+// about changes. It returns the event, which is nil for a baseline run.
+func (m *Monitor) RunSearch(ctx context.Context, name string) (*Event, error) {
+	var s *Search
+	for i := range m.cfg.Searches {
+		if m.cfg.Searches[i].Name == name {
+			s = &m.cfg.Searches[i]
+		}
+	}
+	if s == nil {
+		return nil, fmt.Errorf("monitor: unknown search %q", name)
+	}
+
+	if err := m.checkBalance(ctx); err != nil {
+		m.update(name, func(st *SearchStatus) {
+			st.Skipped++
+			st.LastError = err.Error()
+		})
+		return nil, err
+	}
+
+	ev, err := m.search(ctx, s)
+	m.update(name, func(st *SearchStatus) {
+		st.LastRun = time.Now()
+		st.Runs++
+		st.LastError = ""
+		if err != nil {
+			st.LastError = err.Error()
+		}
+		if ev != nil {
+			st.NewItems += len(ev.New)
+		}
+	})
+	if err != nil || ev == nil || ev.Empty() {
+		return ev, err
+	}
+
+	notifiers := s.Notifiers
+	if notifiers == nil {
+		notifiers = m.cfg.Notifiers
+	}
+	var errs []error
+	for _, n := range notifiers {
+		if err := n.Notify(ctx, ev); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return ev, errors.Join(errs...)
+}
+
+func (m *Monitor) search(ctx context.Context, s *Search) (*Event, error) {
+	store := m.cfg.Store
+	baseline := !store.HasHistory(s.Name) && !m.cfg.NotifyInitial
+
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	defer cancel()
+	req := s.Request
+	if req.OutputType == "" {
+		req.OutputType = linkup.OutputSearchResults
+	}
+	if req.Depth == "" {
+		req.Depth = linkup.DepthStandard
+	}
+	if req.OutputType == linkup.OutputSourcedAnswer {
+		req.IncludeSources = true
+	}
+	resp, err := m.client.Search(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	m.spend(linkup.SearchCost(req))
+
+	now := time.Now().UTC()
+	ev := &Event{Search: s.Name, Query: req.Q, Time: now}
+	var recs []Record
+	seen := make(map[string]bool) // canonical URLs of this run
+	add := func(kind, title, url, content string) {
+		// A response often has several chunks of one page.
+		k := canonical.Key(url)
+		if seen[k] || store.Seen(s.Name, url) {
+			return
+		}
+		seen[k] = true
+		recs = append(recs, Record{Search: s.Name, Time: now, Kind: kind, URL: url, Title: title, Content: content})
+		ev.New = append(ev.New, Item{Title: title, URL: url, Content: content})
+	}
+
+	switch req.OutputType {
+	case linkup.OutputSourcedAnswer:
+		ans, err := resp.SourcedAnswer()
+		if err != nil {
+			return nil, err
+		}
+		for _, src := range ans.Sources {
+			add(KindSource, src.Label(), src.URL, src.Snippet)
+		}
+		prev, ok := store.LastAnswer(s.Name)
+		if !ok || normalizeSpace(prev) != normalizeSpace(ans.Answer) {
+			recs = append(recs, Record{Search: s.Name, Time: now, Kind: KindAnswer, Content: ans.Answer})
+			ev.AnswerChanged = ok
+			ev.Answer = ans.Answer
+			ev.PreviousAnswer = prev
+		}
+	default:
+		results, err := resp.Results()
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range linkup.DedupeResults(results) {
+			add(KindResult, r.Name, r.URL, r.Content)
+		}
+	}
+	if len(recs) == 0 && !store.HasHistory(s.Name) {
+		// Remember that the search ran, so the next run is not a baseline.
+		recs = append(recs, Record{Search: s.Name, Time: now, Kind: KindRun})
+	}
+	if len(recs) > 0 {
+		if err := store.Append(recs...); err != nil {
+			return nil, err
+		}
+	}
+	if baseline {
+		return nil, nil
+	}
+	if !ev.AnswerChanged {
+		ev.Answer = ""
+	}
+	return ev, nil
+}
+
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// checkBalance returns ErrLowBalance when searching should pause. The
+// balance is refreshed every BalanceInterval; if it cannot be read the
+// last known value is used.
+func (m *Monitor) checkBalance(ctx context.Context) error {
+	if m.cfg.MinBalance <= 0 {
+		return nil
+	}
+	m.mu.Lock()
+	stale := m.balance == nil || time.Since(m.balanceAt) >= m.cfg.BalanceInterval
+	m.mu.Unlock()
+	if stale {
+		if err := m.refreshBalance(ctx); err != nil {
+			m.cfg.Logf("balance: %v", err)
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.balance != nil && *m.balance < m.cfg.MinBalance {
+		return fmt.Errorf("%w (%.3f < %.3f)", ErrLowBalance, *m.balance, m.cfg.MinBalance)
+	}
+	return nil
+}
+
+func (m *Monitor) refreshBalance(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	defer cancel()
+	bal, err := m.client.GetBalance(ctx)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.balance = &bal.Balance
+	m.balanceAt = time.Now()
+	m.mu.Unlock()
+	return nil
+}
+
+// spend subtracts an estimated cost from the cached balance.
+func (m *Monitor) spend(credits float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.balance != nil {
+		b := *m.balance - credits
+		m.balance = &b
+	}
+}
+
+func (m *Monitor) update(name string, f func(*SearchStatus)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f(m.status[name])
+}
+
+// Status returns a snapshot of the monitor.
+func (m *Monitor) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := Status{
+		Started:        m.started,
+		BalanceChecked: m.balanceAt,
+		MinBalance:     m.cfg.MinBalance,
+	}
+	if m.balance != nil {
+		b := *m.balance
+		st.Balance = &b
+		st.Paused = m.cfg.MinBalance > 0 && b < m.cfg.MinBalance
+	}
+	for _, s := range m.status {
+		st.Searches = append(st.Searches, *s)
+	}
+	sort.Slice(st.Searches, func(i, j int) bool { return st.Searches[i].Name < st.Searches[j].Name })
+	return st
+}
+
+// Handler serves the status as JSON.
+func (m *Monitor) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(m.Status())
+	})
+}
@@ -0,0 +1,194 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+)
+
+type fakeAPI struct {
+	mu       sync.Mutex
+	results  []linkup.SearchResult
+	answer   linkup.SourcedAnswer
+	balance  float64
+	searches int32
+}
+
+func (f *fakeAPI) client(t *testing.T) *linkup.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		switch r.URL.Path {
+		case "/search":
+			atomic.AddInt32(&f.searches, 1)
+			var req linkup.SearchRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.OutputType == linkup.OutputSourcedAnswer {
+				json.NewEncoder(w).Encode(f.answer)
+				return
+			}
+			json.NewEncoder(w).Encode(linkup.SearchResults{Results: f.results})
+		case "/credits/balance":
+			json.NewEncoder(w).Encode(linkup.BalanceResponse{Balance: f.balance})
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return linkup.NewClient("k", linkup.WithBaseURL(srv.URL), linkup.WithRetry(0, time.Millisecond, time.Millisecond))
+}
+
+type recorder struct{ events []*Event }
+
+func (r *recorder) Notify(_ context.Context, ev *Event) error {
+	r.events = append(r.events, ev)
+	return nil
+}
+
+func openStore(t *testing.T, path string) *Store {
+	t.Helper()
+	st, err := OpenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+func newMonitor(t *testing.T, c *linkup.Client, store *Store, n Notifier, req linkup.SearchRequest, minBalance float64) *Monitor {
+	t.Helper()
+	sched, _ := ParseSchedule("@hourly")
+	m, err := New(c, Config{
+		Store:      store,
+		Searches:   []Search{{Name: "s", Schedule: sched, Request: req}},
+		Notifiers:  []Notifier{n},
+		MinBalance: minBalance,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestRunSearch_BaselineThenNewResults(t *testing.T) {
+	api := &fakeAPI{
+		balance: 10,
+		results: []linkup.SearchResult{{Name: "A", URL: "https://a.com/x"}},
+	}
+	c := api.client(t)
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	rec := &recorder{}
+	req := linkup.SearchRequest{Q: "cve"}
+	m := newMonitor(t, c, openStore(t, path), rec, req, 0)
+	ctx := context.Background()
+
+	if ev, err := m.RunSearch(ctx, "s"); err != nil || ev != nil {
+		t.Fatalf("baseline: ev=%v err=%v", ev, err)
+	}
+	api.mu.Lock()
+	api.results = append(api.results,
+		linkup.SearchResult{Name: "A again", URL: "https://www.a.com/x?utm_source=feed"},
+		linkup.SearchResult{Name: "B", URL: "https://b.com/"},
+	)
+	api.mu.Unlock()
+	ev, err := m.RunSearch(ctx, "s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ev.New) != 1 || ev.New[0].URL != "https://b.com/" {
+		t.Fatalf("new = %+v", ev.New)
+	}
+	if len(rec.events) != 1 {
+		t.Fatalf("notified %d times", len(rec.events))
+	}
+
+	// History survives a restart.
+	m.cfg.Store.Close()
+	m2 := newMonitor(t, c, openStore(t, path), rec, req, 0)
+	if ev, err := m2.RunSearch(ctx, "s"); err != nil || !ev.Empty() {
+		t.Fatalf("after restart: ev=%+v err=%v", ev, err)
+	}
+	if len(rec.events) != 1 {
+		t.Fatalf("empty event was notified")
+	}
+}
+
+func TestRunSearch_AnswerChanged(t *testing.T) {
+	api := &fakeAPI{answer: linkup.SourcedAnswer{Answer: "v1 is out", Sources: []linkup.AnswerSource{{Name: "S", URL: "https://s.com"}}}}
+	rec := &recorder{}
+	m := newMonitor(t, api.client(t), openStore(t, filepath.Join(t.TempDir(), "r.jsonl")), rec,
+		linkup.SearchRequest{Q: "latest", OutputType: linkup.OutputSourcedAnswer}, 0)
+	ctx := context.Background()
+	m.RunSearch(ctx, "s")
+	if ev, _ := m.RunSearch(ctx, "s"); !ev.Empty() {
+		t.Fatalf("whitespace-identical answer reported: %+v", ev)
+	}
+	api.mu.Lock()
+	api.answer.Answer = "v2 is out"
+	api.mu.Unlock()
+	ev, err := m.RunSearch(ctx, "s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ev.AnswerChanged || ev.PreviousAnswer != "v1 is out" || ev.Answer != "v2 is out" || len(ev.New) != 0 {
+		t.Fatalf("event = %+v", ev)
+	}
+	if !strings.Contains(ev.Subject(), "answer changed") {
+		t.Fatalf("subject = %q", ev.Subject())
+	}
+
+	// Two chunks of one new page are one new item.
+	api.mu.Lock()
+	api.answer.Sources = append(api.answer.Sources,
+		linkup.AnswerSource{Name: "N", URL: "https://n.com/page", Snippet: "part 1"},
+		linkup.AnswerSource{Name: "N", URL: "https://www.n.com/page/", Snippet: "part 2"})
+	api.mu.Unlock()
+	if ev, err = m.RunSearch(ctx, "s"); err != nil || len(ev.New) != 1 || ev.New[0].Content != "part 1" {
+		t.Fatalf("event = %+v, %v", ev, err)
+	}
+	if ev, _ = m.RunSearch(ctx, "s"); !ev.Empty() {
+		t.Fatalf("event = %+v", ev)
+	}
+}
+
+func TestRunSearch_LowBalancePauses(t *testing.T) {
+	api := &fakeAPI{balance: 0.5}
+	m := newMonitor(t, api.client(t), openStore(t, filepath.Join(t.TempDir(), "r.jsonl")), &recorder{},
+		linkup.SearchRequest{Q: "q"}, 1)
+	_, err := m.RunSearch(context.Background(), "s")
+	if !errors.Is(err, ErrLowBalance) {
+		t.Fatalf("err = %v", err)
+	}
+	if n := atomic.LoadInt32(&api.searches); n != 0 {
+		t.Fatalf("searched %d times while paused", n)
+	}
+
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/status", nil))
+	var st Status
+	if err := json.Unmarshal(rr.Body.Bytes(), &st); err != nil {
+		t.Fatal(err)
+	}
+	if !st.Paused || len(st.Searches) != 1 || st.Searches[0].Skipped != 1 {
+		t.Fatalf("status = %s", rr.Body.String())
+	}
+}
+
+func TestWriterNotifier(t *testing.T) {
+	var buf bytes.Buffer
+	ev := &Event{Search: "s", Query: "q", New: []Item{{Title: "T", URL: "https://t.com"}}}
+	(&WriterNotifier{W: &buf}).Notify(context.Background(), ev)
+	if !strings.Contains(buf.String(), "+ T\n  https://t.com") {
+		t.Fatalf("text = %q", buf.String())
+	}
+}
@@ -0,0 +1,134 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Notifier delivers change events.
+type Notifier interface {
+	Notify(ctx context.Context, ev *Event) error
+}
+
+// WriterNotifier writes events to W as text, or as JSON lines when JSON is
+// set.
+type WriterNotifier struct {
+	W    io.Writer
+	JSON bool
+
+	mu sync.Mutex
+}
+
+func (n *WriterNotifier) Notify(_ context.Context, ev *Event) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.JSON {
+		b, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(n.W, string(b))
+		return err
+	}
+	_, err := io.WriteString(n.W, ev.Text()+"\n")
+	return err
+}
+
+// FileNotifier appends events as JSON lines to Path.
+type FileNotifier struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func (n *FileNotifier) Notify(_ context.Context, ev *Event) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	f, err := os.OpenFile(n.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WebhookNotifier POSTs each event as JSON to URL.
+type WebhookNotifier struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client // default: 15s timeout
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, ev *Event) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range n.Headers {
+		req.Header.Set(k, v)
+	}
+	hc := n.Client
+	if hc == nil {
+		hc = &http.Client{Timeout: 15 * time.Second}
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook: http %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SMTPNotifier mails events through an SMTP relay, typically a local one.
+type SMTPNotifier struct {
+	Addr string // host:port
+	From string
+	To   []string
+	Auth smtp.Auth // optional
+}
+
+func (n *SMTPNotifier) Notify(_ context.Context, ev *Event) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", n.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", headerSafe(ev.Subject())))
+	fmt.Fprintf(&msg, "Date: %s\r\n", ev.Time.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(ev.Text(), "\n", "\r\n"))
+	msg.WriteString("\r\n")
+	if err := smtp.SendMail(n.Addr, n.Auth, n.From, n.To, []byte(msg.String())); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	return nil
+}
+
+func headerSafe(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
@@ -0,0 +1,179 @@
+package monitor
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/raezil/linkup-go/linkup/canonical"
+)
+
+// Record kinds.
+const (
+	KindResult = "result"
+	KindSource = "source"
+	KindAnswer = "answer"
+	// KindRun marks a run that produced no other record.
+	KindRun = "run"
+)
+
+// Record is one line of the store.
+type Record struct {
+	Search  string    `json:"search"`
+	Time    time.Time `json:"time"`
+	Kind    string    `json:"kind"`
+	URL     string    `json:"url,omitempty"`
+	Title   string    `json:"title,omitempty"`
+	Content string    `json:"content,omitempty"`
+}
+
+// Store is an append-only JSONL file of everything a monitor has seen. The
+// set of known URLs and the latest answer of each search are kept in memory.
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	f       *os.File
+	seen    map[string]map[string]bool // search -> canonical URL key
+	answers map[string]string          // search -> latest answer
+	runs    map[string]bool            // searches with at least one record
+}
+
+// OpenStore opens (creating if needed) the store at path and loads its
+// history.
+func OpenStore(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	s := &Store{
+		path:    path,
+		seen:    map[string]map[string]bool{},
+		answers: map[string]string{},
+		runs:    map[string]bool{},
+	}
+	if err := s.load(path); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s.f = f
+	return s, nil
+}
+
+func (s *Store) load(path string) error {
+	recs, err := ReadRecords(path, "")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("monitor: read %s: %w", path, err)
+	}
+	for _, r := range recs {
+		s.index(r)
+	}
+	return nil
+}
+
+func (s *Store) index(r Record) {
+	s.runs[r.Search] = true
+	switch r.Kind {
+	case KindAnswer:
+		s.answers[r.Search] = r.Content
+	default:
+		if r.URL == "" {
+			return
+		}
+		m := s.seen[r.Search]
+		if m == nil {
+			m = map[string]bool{}
+			s.seen[r.Search] = m
+		}
+		m[canonical.Key(r.URL)] = true
+	}
+}
+
+// Seen reports whether url (compared canonically) was recorded for search.
+func (s *Store) Seen(search, url string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seen[search][canonical.Key(url)]
+}
+
+// LastAnswer returns the most recent answer recorded for search.
+func (s *Store) LastAnswer(search string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.answers[search]
+	return a, ok
+}
+
+// HasHistory reports whether anything was recorded for search.
+func (s *Store) HasHistory(search string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.runs[search]
+}
+
+// Append writes records and adds them to the in-memory index.
+func (s *Store) Append(recs ...Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var buf []byte
+	for _, r := range recs {
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		buf = append(append(buf, b...), '\n')
+	}
+	if _, err := s.f.Write(buf); err != nil {
+		return err
+	}
+	for _, r := range recs {
+		s.index(r)
+	}
+	return nil
+}
+
+// Records returns the records of search, oldest first. An empty search
+// returns every record.
+func (s *Store) Records(search string) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return ReadRecords(s.path, search)
+}
+
+// ReadRecords reads the records of search from the store file at path
+// without opening it for writing.
+func ReadRecords(path, search string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var out []Record
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var r Record
+		if json.Unmarshal(sc.Bytes(), &r) != nil {
+			// A torn final line after a crash is expected; skip it.
+			continue
+		}
+		if search == "" || r.Search == search {
+			out = append(out, r)
+		}
+	}
+	return out, sc.Err()
+}
+
+// Close closes the underlying file.
+func (s *Store) Close() error {
+	return s.f.Close()
+}
@@ -0,0 +1,167 @@
+package linkup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/raezil/linkup-go/linkup/canonical"
+)
+
+// DefaultRRFK is the reciprocal rank fusion constant used when
+// MultiSearchOptions.K is zero.
+const DefaultRRFK = 60
+
+// MultiSearchOptions tunes Client.MultiSearch.
+type MultiSearchOptions struct {
+	// K is the RRF constant: a result at rank r (1-based) in a query scores
+	// weight/(K+r). Larger K flattens the head of each list. Default 60.
+	K int
+	// Weights optionally scales each query's contribution; Weights[i] applies
+	// to queries[i]. Missing or non-positive entries default to 1.
+	Weights []float64
+	// Concurrency caps in-flight searches. Default: all queries at once.
+	Concurrency int
+}
+
+// Contribution records where a fused result appeared in one query's list.
+type Contribution struct {
+	Query string  `json:"query"`
+	Rank  int     `json:"rank"` // 1-based
+	Score float64 `json:"score"`
+}
+
+// FusedResult is a result merged across queries by canonical URL.
+type FusedResult struct {
+	SearchResult
+	Score         float64        `json:"score"`
+	Contributions []Contribution `json:"contributions"`
+}
+
+// Queries returns the queries that contributed to r, best rank first.
+func (r FusedResult) Queries() []string {
+	out := make([]string, len(r.Contributions))
+	for i, c := range r.Contributions {
+		out[i] = c.Query
+	}
+	return out
+}
+
+// MultiSearchResult is returned by Client.MultiSearch.
+type MultiSearchResult struct {
+	Results []FusedResult `json:"results"`
+	// Errors holds per-query failures keyed by query text. Those queries
+	// contributed nothing to Results.
+	Errors map[string]error `json:"-"`
+}
+
+// MultiSearch runs base once per query (overriding Q and forcing
+// OutputSearchResults) and merges the result lists with reciprocal rank
+// fusion. Results are keyed by canonical URL, so the same page found by
+// several queries accumulates score. An error is returned only when every
+// query fails; partial failures are reported in MultiSearchResult.Errors.
+func (c *Client) MultiSearch(ctx context.Context, base SearchRequest, queries []string, opts MultiSearchOptions) (MultiSearchResult, error) {
+	if len(queries) == 0 {
+		return MultiSearchResult{}, errors.New("linkup: no queries")
+	}
+	k := opts.K
+	if k <= 0 {
+		k = DefaultRRFK
+	}
+	conc := opts.Concurrency
+	if conc <= 0 || conc > len(queries) {
+		conc = len(queries)
+	}
+
+	lists := make([][]SearchResult, len(queries))
+	errs := make([]error, len(queries))
+	sem := make(chan struct{}, conc)
+	var wg sync.WaitGroup
+	for i, q := range queries {
+		wg.Add(1)
+		go func(i int, q string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+
+			req := base
+			req.Q = q
+			req.OutputType = OutputSearchResults
+			resp, err := c.Search(ctx, req)
+			if err == nil {
+				lists[i], err = resp.Results()
+			}
+			errs[i] = err
+		}(i, q)
+	}
+	wg.Wait()
+
+	out := MultiSearchResult{}
+	var failed []error
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if out.Errors == nil {
+			out.Errors = make(map[string]error)
+		}
+		out.Errors[queries[i]] = err
+		failed = append(failed, fmt.Errorf("query %q: %w", queries[i], err))
+	}
+	if len(failed) == len(queries) {
+		return out, errors.Join(failed...)
+	}
+	out.Results = fuseRRF(queries, lists, opts.Weights, k)
+	return out, nil
+}
+
+// fuseRRF merges ranked lists with reciprocal rank fusion.
+func fuseRRF(queries []string, lists [][]SearchResult, weights []float64, k int) []FusedResult {
+	var fused []*FusedResult
+	index := make(map[string]*FusedResult)
+
+	for qi, list := range lists {
+		w := 1.0
+		if qi < len(weights) && weights[qi] > 0 {
+			w = weights[qi]
+		}
+		seen := make(map[string]bool, len(list))
+		rank := 0
+		for _, r := range list {
+			key := canonical.Key(r.URL)
+			if seen[key] {
+				continue // only the best rank of a page counts per query
+			}
+			seen[key] = true
+			rank++
+			score := w / float64(k+rank)
+
+			f, ok := index[key]
+			if !ok {
+				f = &FusedResult{SearchResult: r}
+				index[key] = f
+				fused = append(fused, f)
+			} else {
+				f.SearchResult = mergeResult(f.SearchResult, r)
+			}
+			f.Score += score
+			f.Contributions = append(f.Contributions, Contribution{Query: queries[qi], Rank: rank, Score: score})
+		}
+	}
+
+	// Stable: ties keep first-seen order.
+	sort.SliceStable(fused, func(i, j int) bool { return fused[i].Score > fused[j].Score })
+	out := make([]FusedResult, len(fused))
+	for i, f := range fused {
+		sort.SliceStable(f.Contributions, func(a, b int) bool { return f.Contributions[a].Rank < f.Contributions[b].Rank })
+		out[i] = *f
+	}
+	return out
+}
@@ -0,0 +1,69 @@
+package linkup
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestMultiSearch_RRF(t *testing.T) {
+	lists := map[string]string{
+		"q1": `{"results":[{"type":"text","name":"A","url":"https://a.com/x"},{"type":"text","name":"B","url":"https://b.com/"}]}`,
+		"q2": `{"results":[{"type":"text","name":"B","url":"http://www.b.com","content":"longer"},{"type":"text","name":"C","url":"https://c.com"}]}`,
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var req SearchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if req.OutputType != OutputSearchResults || req.Depth != DepthDeep {
+			t.Errorf("base not applied: %+v", req)
+		}
+		body, ok := lists[req.Q]
+		if !ok {
+			http.Error(w, `{"message":"bad"}`, http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(body))
+	}
+	client, srv := newTestClient(t, handler)
+	defer srv.Close()
+
+	res, err := client.MultiSearch(context.Background(), SearchRequest{Depth: DepthDeep}, []string{"q1", "q2", "broken"}, MultiSearchOptions{K: 1})
+	if err != nil {
+		t.Fatalf("MultiSearch: %v", err)
+	}
+	if _, ok := res.Errors["broken"]; !ok || len(res.Errors) != 1 {
+		t.Fatalf("errors = %v", res.Errors)
+	}
+	if len(res.Results) != 3 {
+		t.Fatalf("len = %d", len(res.Results))
+	}
+	top := res.Results[0]
+	// B: 1/(1+2) + 1/(1+1) beats A: 1/(1+1).
+	if top.Name != "B" || len(top.Contributions) != 2 || top.Content != "longer" {
+		t.Fatalf("unexpected top: %+v", top)
+	}
+	if q := top.Queries(); q[0] != "q2" || q[1] != "q1" {
+		t.Fatalf("queries = %v", q)
+	}
+}
+
+func TestMultiSearch_WeightsAndAllFailed(t *testing.T) {
+	got := fuseRRF([]string{"a", "b"}, [][]SearchResult{
+		{{URL: "https://x.com"}},
+		{{URL: "https://y.com"}},
+	}, []float64{1, 3}, 60)
+	if got[0].URL != "https://y.com" {
+		t.Fatalf("weight ignored: %+v", got)
+	}
+
+	client, srv := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusUnauthorized)
+	})
+	defer srv.Close()
+	if _, err := client.MultiSearch(context.Background(), SearchRequest{}, []string{"a", "b"}, MultiSearchOptions{}); err == nil {
+		t.Fatal("expected error when all queries fail")
+	}
+}
@@ -0,0 +1,73 @@
+package linkup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrNotCached matches (with errors.Is) the *NotCachedError returned by an
+// offline Client when no stored response answers a call.
+var ErrNotCached = errors.New("linkup: not cached")
+
+// NotCachedError reports an offline miss.
+type NotCachedError struct {
+	Op  string // OpSearch, OpFetch or "balance"
+	Key string // the query or URL
+}
+
+func (e *NotCachedError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("linkup: offline: no stored %s response", e.Op)
+	}
+	return fmt.Sprintf("linkup: offline: no stored %s response for %q", e.Op, e.Key)
+}
+
+func (e *NotCachedError) Is(target error) bool { return target == ErrNotCached }
+
+// LookupRequest asks a Lookup for a stored response.
+type LookupRequest struct {
+	Op      string
+	Request json.RawMessage // request body, as Search or Fetch would send it
+	// MinSimilarity enables fuzzy matching of search queries when > 0:
+	// the most similar stored query scoring at least this much (0..1)
+	// answers the call if there is no exact match.
+	MinSimilarity float64
+}
+
+// Lookup finds stored responses. It returns an error matching
+// ErrNotCached on a miss. history.Store implements it.
+type Lookup interface {
+	Lookup(ctx context.Context, req LookupRequest) (Call, error)
+}
+
+// WithOfflineMode makes Search and Fetch answer strictly from stored
+// responses, never touching the network. The Recorder set with
+// WithRecorder is used when it also implements Lookup (as history.Store
+// does); otherwise every call fails with ErrNotCached. GetBalance fails
+// with ErrNotCached as well.
+func WithOfflineMode() Option {
+	return func(c *Client) { c.offline = true }
+}
+
+// WithFuzzyMatch lets offline lookups fall back to the most similar stored
+// search query with a similarity of at least min (0..1, e.g. 0.6).
+func WithFuzzyMatch(min float64) Option {
+	return func(c *Client) { c.fuzzy = min }
+}
+
+func (c *Client) replay(ctx context.Context, op, key string, body []byte) (SearchResponse, error) {
+	l, ok := c.recorder.(Lookup)
+	if !ok {
+		return SearchResponse{}, &NotCachedError{Op: op, Key: key}
+	}
+	call, err := l.Lookup(ctx, LookupRequest{Op: op, Request: body, MinSimilarity: c.fuzzy})
+	if errors.Is(err, ErrNotCached) {
+		return SearchResponse{}, &NotCachedError{Op: op, Key: key}
+	}
+	if err != nil {
+		return SearchResponse{}, err
+	}
+	return SearchResponse{Raw: call.Response}, nil
+}
@@ -0,0 +1,464 @@
+package linkup
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/raezil/linkup-go/linkup/internal/htmltok"
+)
+
+// Link is an outbound link of a fetched page.
+type Link struct {
+	URL  string `json:"url"`            // absolute, without fragment
+	Text string `json:"text,omitempty"` // anchor text, or the alt text of a linked image
+	Rel  string `json:"rel,omitempty"`  // e.g. "nofollow"
+}
+
+// OpenGraph holds a page's og:* properties.
+type OpenGraph struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Image       string `json:"image,omitempty"`
+	SiteName    string `json:"siteName,omitempty"`
+	Locale      string `json:"locale,omitempty"`
+}
+
+// TwitterCard holds a page's twitter:* properties.
+type TwitterCard struct {
+	Card        string `json:"card,omitempty"`
+	Site        string `json:"site,omitempty"`
+	Creator     string `json:"creator,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+}
+
+// FetchPage calls Fetch and returns the decoded page with its metadata and
+// links extracted (see FetchResult.Extract). Set req.IncludeRawHTML for
+// everything but the title and links, which markdown alone provides.
+func (c *Client) FetchPage(ctx context.Context, req FetchRequest) (FetchResult, error) {
+	resp, err := c.Fetch(ctx, req)
+	if err != nil {
+		return FetchResult{}, err
+	}
+	fr, err := resp.Page()
+	if err != nil {
+		return FetchResult{}, err
+	}
+	fr.Extract(req.URL)
+	return fr, nil
+}
+
+// Extract fills the metadata fields and Links of r from RawHTML, resolving
+// relative URLs against pageURL (or the document's <base>). Without raw
+// HTML, the title and links come from Markdown. Fields already set are
+// kept.
+func (r *FetchResult) Extract(pageURL string) {
+	base, _ := url.Parse(pageURL)
+	if base == nil {
+		base = &url.URL{}
+	}
+	if r.RawHTML == "" {
+		if r.Title == "" {
+			r.Title = markdownTitle(r.Markdown)
+		}
+		if r.Links == nil {
+			r.Links = markdownLinks(base, r.Markdown)
+		}
+		return
+	}
+	var m pageMeta
+	m.parse(base, r.RawHTML)
+	setIfEmpty(&r.Title, firstNonEmpty(m.title, m.og.Title, m.twitter.Title))
+	setIfEmpty(&r.Description, firstNonEmpty(m.description, m.og.Description, m.twitter.Description))
+	setIfEmpty(&r.Canonical, firstNonEmpty(m.canonical, m.og.URL))
+	setIfEmpty(&r.Language, firstNonEmpty(m.lang, m.contentLang, localeLang(m.og.Locale)))
+	setIfEmpty(&r.Author, m.author)
+	if r.Published.IsZero() {
+		r.Published = m.published
+	}
+	if r.Modified.IsZero() {
+		r.Modified = m.modified
+	}
+	if r.OpenGraph == nil && m.og != (OpenGraph{}) {
+		r.OpenGraph = &m.og
+	}
+	if r.Twitter == nil && m.twitter != (TwitterCard{}) {
+		r.Twitter = &m.twitter
+	}
+	if r.JSONLD == nil {
+		r.JSONLD = m.jsonLD
+	}
+	if r.Links == nil {
+		r.Links = m.links
+	}
+	if r.Title == "" {
+		r.Title = markdownTitle(r.Markdown)
+	}
+}
+
+// ExtractLinks returns the links of an HTML document, resolved against
+// pageURL; see FetchResult.Extract.
+func ExtractLinks(pageURL, rawHTML string) []Link {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	var m pageMeta
+	m.parse(base, rawHTML)
+	return m.links
+}
+
+// pageMeta is the intermediate state of one pass over a document.
+type pageMeta struct {
+	title, description, canonical string
+	lang, contentLang, author     string
+	published, modified           time.Time
+	og                            OpenGraph
+	twitter                       TwitterCard
+	jsonLD                        []json.RawMessage
+	links                         []Link
+}
+
+// dateMetas are <meta name=...> and property values carrying the
+// publication date, most reliable first.
+var dateMetas = []string{
+	"article:published_time", "og:article:published_time", "datepublished",
+	"citation_publication_date", "dc.date.issued", "dcterms.issued", "dc.date", "dcterms.created",
+	"date", "pubdate", "publish-date", "publish_date", "sailthru.date", "parsely-pub-date",
+}
+
+func (m *pageMeta) parse(base *url.URL, doc string) {
+	var (
+		dates    = map[string]string{}
+		inTitle  bool
+		inLDJSON bool
+		link     = -1 // index in m.links of the open <a>, or -1
+		text     strings.Builder
+		seen     = map[string]int{}
+		linkAlt  string
+		baseSet  bool
+	)
+	closeLink := func() {
+		if link < 0 {
+			return
+		}
+		t := collapse(text.String())
+		if t == "" {
+			t = linkAlt
+		}
+		if m.links[link].Text == "" {
+			m.links[link].Text = t
+		}
+		link, linkAlt = -1, ""
+		text.Reset()
+	}
+
+	z := htmltok.New(doc)
+	for {
+		tok, ok := z.Next()
+		if !ok {
+			break
+		}
+		switch tok.Type {
+		case htmltok.Text:
+			switch {
+			case inTitle:
+				if m.title == "" {
+					m.title = collapse(tok.Data)
+				}
+			case inLDJSON:
+				if b := []byte(strings.TrimSpace(tok.Data)); json.Valid(b) {
+					m.jsonLD = append(m.jsonLD, json.RawMessage(b))
+				}
+			case link >= 0:
+				text.WriteString(tok.Data)
+				text.WriteByte(' ')
+			}
+			continue
+		case htmltok.EndTag:
+			switch tok.Name {
+			case "a":
+				closeLink()
+			case "title":
+				inTitle = false
+			case "script":
+				inLDJSON = false
+			}
+			continue
+		case htmltok.StartTag, htmltok.SelfClosingTag:
+		default:
+			continue
+		}
+
+		attr := func(k string) string { v, _ := tok.Attr(k); return strings.TrimSpace(v) }
+		switch tok.Name {
+		case "html":
+			m.lang = attr("lang")
+		case "base":
+			if h := attr("href"); h != "" && !baseSet {
+				if b, err := base.Parse(h); err == nil {
+					base, baseSet = b, true
+				}
+			}
+		case "title":
+			inTitle = tok.Type == htmltok.StartTag
+		case "script":
+			inLDJSON = tok.Type == htmltok.StartTag && strings.EqualFold(attr("type"), "application/ld+json")
+		case "meta":
+			m.meta(strings.ToLower(firstNonEmpty(attr("property"), attr("name"), attr("itemprop"))), attr("content"), dates)
+			if strings.EqualFold(attr("http-equiv"), "content-language") {
+				m.contentLang, _, _ = strings.Cut(attr("content"), ",")
+			}
+		case "link":
+			if hasToken(attr("rel"), "canonical") && m.canonical == "" {
+				m.canonical = resolve(base, attr("href"))
+			}
+		case "time":
+			if _, pub := tok.Attr("pubdate"); pub || strings.EqualFold(attr("itemprop"), "datepublished") {
+				if _, ok := dates["time"]; !ok {
+					dates["time"] = attr("datetime")
+				}
+			}
+		case "img":
+			if link >= 0 && linkAlt == "" {
+				linkAlt = collapse(attr("alt"))
+			}
+		case "a", "area":
+			closeLink()
+			u := resolve(base, attr("href"))
+			if u == "" {
+				continue
+			}
+			i, dup := seen[u]
+			if !dup {
+				i = len(m.links)
+				seen[u] = i
+				m.links = append(m.links, Link{URL: u, Rel: attr("rel")})
+			}
+			if tok.Name == "a" && tok.Type == htmltok.StartTag {
+				link = i
+			}
+		}
+	}
+	closeLink()
+
+	for _, ld := range m.jsonLD {
+		ldDates(ld, dates, &m.author)
+	}
+	for _, k := range append(dateMetas, "ld:datepublished", "time") {
+		if t, ok := parseDate(dates[k]); ok {
+			m.published = t
+			break
+		}
+	}
+	for _, k := range []string{"article:modified_time", "og:updated_time", "datemodified", "ld:datemodified", "last-modified"} {
+		if t, ok := parseDate(dates[k]); ok {
+			m.modified = t
+			break
+		}
+	}
+}
+
+// meta records one <meta> element.
+func (m *pageMeta) meta(key, content string, dates map[string]string) {
+	if key == "" || content == "" {
+		return
+	}
+	set := func(dst *string) { setIfEmpty(dst, content) }
+	switch key {
+	case "description":
+		set(&m.description)
+	case "author", "article:author", "dc.creator", "citation_author":
+		set(&m.author)
+	case "og:title":
+		set(&m.og.Title)
+	case "og:description":
+		set(&m.og.Description)
+	case "og:type":
+		set(&m.og.Type)
+	case "og:url":
+		set(&m.og.URL)
+	case "og:image", "og:image:url", "og:image:secure_url":
+		set(&m.og.Image)
+	case "og:site_name":
+		set(&m.og.SiteName)
+	case "og:locale":
+		set(&m.og.Locale)
+	case "twitter:card":
+		set(&m.twitter.Card)
+	case "twitter:site":
+		set(&m.twitter.Site)
+	case "twitter:creator":
+		set(&m.twitter.Creator)
+	case "twitter:title":
+		set(&m.twitter.Title)
+	case "twitter:description":
+		set(&m.twitter.Description)
+	case "twitter:image", "twitter:image:src":
+		set(&m.twitter.Image)
+	default:
+		if _, ok := dates[key]; !ok {
+			dates[key] = content
+		}
+	}
+}
+
+// ldDates looks for datePublished, dateModified and author in a JSON-LD
+// block, including @graph arrays.
+func ldDates(raw json.RawMessage, dates map[string]string, author *string) {
+	var v any
+	if json.Unmarshal(raw, &v) != nil {
+		return
+	}
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case []any:
+			for _, e := range v {
+				walk(e)
+			}
+		case map[string]any:
+			for k, dst := range map[string]string{"datePublished": "ld:datepublished", "dateModified": "ld:datemodified"} {
+				if s, ok := v[k].(string); ok {
+					if _, set := dates[dst]; !set {
+						dates[dst] = s
+					}
+				}
+			}
+			if *author == "" {
+				*author = ldName(v["author"])
+			}
+			if g, ok := v["@graph"]; ok {
+				walk(g)
+			}
+		}
+	}
+	walk(v)
+}
+
+func ldName(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case map[string]any:
+		s, _ := v["name"].(string)
+		return s
+	case []any:
+		if len(v) > 0 {
+			return ldName(v[0])
+		}
+	}
+	return ""
+}
+
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"2006/01/02",
+	time.RFC1123Z,
+	time.RFC1123,
+	"January 2, 2006",
+	"2 January 2006",
+	"Jan 2, 2006",
+}
+
+func parseDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// resolve makes href absolute, dropping the fragment. It returns "" for
+// empty, non-http(s) and unparsable references.
+func resolve(base *url.URL, href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") {
+		return ""
+	}
+	u, err := base.Parse(href)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	u.Fragment, u.RawFragment = "", ""
+	return u.String()
+}
+
+var (
+	mdLinkRe  = regexp.MustCompile(`(!?)\[([^\]]*)\]\(\s*<?([^)\s>]+)>?(?:\s+["'(][^)]*)?\)`)
+	mdTitleRe = regexp.MustCompile(`(?m)^\s{0,3}#\s+(.+?)\s*#*\s*$`)
+)
+
+func markdownLinks(base *url.URL, md string) []Link {
+	var links []Link
+	seen := map[string]bool{}
+	for _, m := range mdLinkRe.FindAllStringSubmatch(md, -1) {
+		if m[1] == "!" {
+			continue // an image, not a link
+		}
+		u := resolve(base, m[3])
+		if u == "" || seen[u] {
+			continue
+		}
+		seen[u] = true
+		links = append(links, Link{URL: u, Text: collapse(stripImages(m[2]))})
+	}
+	return links
+}
+
+func stripImages(s string) string {
+	return strings.NewReplacer("![", "", "[", "", "]", "").Replace(s)
+}
+
+func markdownTitle(md string) string {
+	if m := mdTitleRe.FindStringSubmatch(md); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// localeLang turns an og:locale such as "en_US" into "en-US".
+func localeLang(s string) string { return strings.ReplaceAll(s, "_", "-") }
+
+func hasToken(list, tok string) bool {
+	for _, f := range strings.Fields(list) {
+		if strings.EqualFold(f, tok) {
+			return true
+		}
+	}
+	return false
+}
+
+func collapse(s string) string { return strings.Join(strings.Fields(s), " ") }
+
+func setIfEmpty(dst *string, v string) {
+	if *dst == "" {
+		*dst = v
+	}
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
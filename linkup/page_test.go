@@ -0,0 +1,107 @@
+package linkup
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const articleHTML = `<!doctype html>
+<html lang="en-GB"><head>
+<base href="https://example.com/blog/">
+<title> Release   notes &amp; more </title>
+<meta name="description" content="What changed in 2.0">
+<meta property="og:title" content="Release 2.0">
+<meta property="og:image" content="https://example.com/og.png">
+<meta property="og:locale" content="en_GB">
+<meta name="twitter:card" content="summary_large_image">
+<meta name="twitter:site" content="@example">
+<link rel="alternate canonical" href="/blog/release-2">
+<script type="application/ld+json">
+{"@context":"https://schema.org","@graph":[{"@type":"Article","datePublished":"2025-03-04T10:00:00Z","dateModified":"2025-03-05","author":{"@type":"Person","name":"Ada"}}]}
+</script>
+<script type="application/ld+json">{not json</script>
+<script>var a = "<a href='/nope'>";</script>
+</head><body>
+<a href="changes#api">API <b>changes</b></a>
+<a href="https://other.org/x" rel="nofollow"><img src="x.png" alt="Other site"></a>
+<a href="/blog/changes">again</a>
+<a href="mailto:a@example.com">mail</a> <a href="#top">top</a>
+<time pubdate datetime="2020-01-01">old</time>
+</body></html>`
+
+func TestExtract_HTML(t *testing.T) {
+	fr := FetchResult{RawHTML: articleHTML, Markdown: "# Markdown title"}
+	fr.Extract("https://example.com/blog/release-2?utm_source=x")
+
+	if fr.Title != "Release notes & more" || fr.Description != "What changed in 2.0" || fr.Language != "en-GB" || fr.Author != "Ada" {
+		t.Fatalf("fields = %q %q %q %q", fr.Title, fr.Description, fr.Language, fr.Author)
+	}
+	if fr.Canonical != "https://example.com/blog/release-2" {
+		t.Fatalf("canonical = %q", fr.Canonical)
+	}
+	if !fr.Published.Equal(time.Date(2025, 3, 4, 10, 0, 0, 0, time.UTC)) || !fr.Modified.Equal(time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("dates = %v %v", fr.Published, fr.Modified)
+	}
+	if fr.OpenGraph == nil || fr.OpenGraph.Title != "Release 2.0" || fr.OpenGraph.Locale != "en_GB" {
+		t.Fatalf("og = %+v", fr.OpenGraph)
+	}
+	if fr.Twitter == nil || fr.Twitter.Card != "summary_large_image" || fr.Twitter.Site != "@example" {
+		t.Fatalf("twitter = %+v", fr.Twitter)
+	}
+	if len(fr.JSONLD) != 1 {
+		t.Fatalf("jsonld = %d blocks", len(fr.JSONLD))
+	}
+	want := []Link{
+		{URL: "https://example.com/blog/changes", Text: "API changes"},
+		{URL: "https://other.org/x", Text: "Other site", Rel: "nofollow"},
+	}
+	if !reflect.DeepEqual(fr.Links, want) {
+		t.Fatalf("links = %+v", fr.Links)
+	}
+}
+
+func TestExtract_MetaDatesAndLanguageFallbacks(t *testing.T) {
+	fr := FetchResult{RawHTML: `<head><meta http-equiv="Content-Language" content="de, en">
+<meta name="citation_publication_date" content="2024/05/06"></head><body><time itemprop="datePublished" datetime="2019-01-01">x</time></body>`}
+	fr.Extract("https://example.com/")
+	if fr.Language != "de" || !fr.Published.Equal(time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("lang = %q, published = %v", fr.Language, fr.Published)
+	}
+	fr = FetchResult{RawHTML: `<time itemprop="datePublished" datetime="2019-01-01">x</time>`}
+	fr.Extract("https://example.com/")
+	if fr.Published.Year() != 2019 {
+		t.Fatalf("published = %v", fr.Published)
+	}
+}
+
+func TestExtract_Markdown(t *testing.T) {
+	fr := FetchResult{Markdown: "Intro\n\n# Getting started #\n\nSee [the guide](guide \"Guide\"), ![logo](logo.png) and [](https://x.org/).\n[again](guide#part)"}
+	fr.Extract("https://example.com/docs/")
+	want := []Link{{URL: "https://example.com/docs/guide", Text: "the guide"}, {URL: "https://x.org/"}}
+	if fr.Title != "Getting started" || !reflect.DeepEqual(fr.Links, want) {
+		t.Fatalf("title = %q, links = %+v", fr.Title, fr.Links)
+	}
+}
+
+func TestFetchPage(t *testing.T) {
+	c, srv := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req FetchRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if !req.IncludeRawHTML {
+			t.Errorf("req = %+v", req)
+		}
+		json.NewEncoder(w).Encode(FetchResult{Markdown: "x", RawHTML: `<title>T</title><a href="b">B</a>`})
+	})
+	defer srv.Close()
+	fr, err := c.FetchPage(context.Background(), FetchRequest{URL: "https://example.com/a/", IncludeRawHTML: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fr.Title != "T" || len(fr.Links) != 1 || fr.Links[0].URL != "https://example.com/a/b" {
+		t.Fatalf("page = %+v", fr)
+	}
+}
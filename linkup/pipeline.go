@@ -0,0 +1,173 @@
+package linkup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/raezil/linkup-go/linkup/canonical"
+)
+
+// ErrDomainSkipped is returned for a URL that was not fetched because an
+// earlier fetch from the same host failed.
+var ErrDomainSkipped = errors.New("linkup: skipped after earlier failure on domain")
+
+// FetchOptions tunes Client.SearchAndFetch.
+type FetchOptions struct {
+	// TopN is how many (deduplicated, text) results to fetch. Default 5.
+	TopN int
+	// RenderJS is passed through to every FetchRequest.
+	RenderJS bool
+	// Concurrency caps in-flight fetches. Default 4.
+	Concurrency int
+}
+
+// FetchedResult pairs a search result with its fetched page, or with the
+// error that prevented fetching it.
+type FetchedResult struct {
+	Result SearchResult
+	Page   FetchResult
+	Err    error
+}
+
+// SearchAndFetch runs req as an OutputSearchResults search, then fetches the
+// top opts.TopN result URLs with bounded parallelism. Once the API fails a
+// fetch from a host with a 4xx or 5xx, or the fetch times out, remaining URLs
+// on that host are skipped with ErrDomainSkipped rather than spending more
+// credits. Rate limiting (429) is handled by the client's retry policy.
+// Results are returned in rank order; per-URL failures are reported in
+// FetchedResult.Err. An error about the account rather than the URL
+// (ErrUnauthorized, ErrForbidden, a 402 or a 429 left after retries) stops
+// the remaining fetches: they report it too, and SearchAndFetch returns it
+// along with the results.
+func (c *Client) SearchAndFetch(ctx context.Context, req SearchRequest, opts FetchOptions) ([]FetchedResult, error) {
+	topN := opts.TopN
+	if topN <= 0 {
+		topN = 5
+	}
+	conc := opts.Concurrency
+	if conc <= 0 {
+		conc = 4
+	}
+
+	req.OutputType = OutputSearchResults
+	resp, err := c.Search(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	results, err := resp.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	var picked []SearchResult
+	for _, r := range DedupeResults(results) {
+		if r.Type == "image" || r.URL == "" {
+			continue
+		}
+		picked = append(picked, r)
+		if len(picked) == topN {
+			break
+		}
+	}
+
+	out := make([]FetchedResult, len(picked))
+	fetchCtx, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
+	var (
+		mu     sync.Mutex
+		failed = make(map[string]bool)
+		next   = make(chan int)
+		wg     sync.WaitGroup
+	)
+	for w := 0; w < conc && w < len(picked); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if fetchCtx.Err() != nil {
+					out[i].Err = context.Cause(fetchCtx)
+					continue
+				}
+				out[i].Err = c.fetchResult(fetchCtx, &out[i], opts, &mu, failed)
+				if accountError(out[i].Err) {
+					abort(out[i].Err)
+				}
+			}
+		}()
+	}
+	// Hand out work in rank order so earlier results are fetched first.
+	for i, r := range picked {
+		out[i].Result = r
+		if fetchCtx.Err() != nil {
+			out[i].Err = context.Cause(fetchCtx)
+			continue
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	if err := context.Cause(fetchCtx); err != nil && ctx.Err() == nil {
+		// Fetches in flight when the account error came were cancelled.
+		for i := range out {
+			if errors.Is(out[i].Err, context.Canceled) || errors.Is(out[i].Err, err) {
+				out[i].Err = err
+			}
+		}
+		return out, err
+	}
+	return out, nil
+}
+
+func (c *Client) fetchResult(ctx context.Context, fr *FetchedResult, opts FetchOptions, mu *sync.Mutex, failed map[string]bool) error {
+	host := canonical.Host(fr.Result.URL)
+	mu.Lock()
+	skip := failed[host]
+	mu.Unlock()
+	if skip {
+		return fmt.Errorf("%w: %s", ErrDomainSkipped, host)
+	}
+
+	resp, err := c.Fetch(ctx, FetchRequest{URL: fr.Result.URL, RenderJS: opts.RenderJS})
+	if err == nil {
+		fr.Page, err = resp.Page()
+	}
+	if err != nil && ctx.Err() == nil && hostFailure(err) {
+		mu.Lock()
+		failed[host] = true
+		mu.Unlock()
+	}
+	return err
+}
+
+// hostFailure reports whether err, from fetching one URL, counts against its
+// host: the API's 4xx or 5xx for the URL, or a timeout.
+func hostFailure(err error) bool {
+	var apiErr *APIError
+	var netErr net.Error
+	switch {
+	case accountError(err):
+		return false
+	case errors.As(err, &apiErr):
+		return apiErr.Status >= 400
+	case errors.As(err, &netErr):
+		return netErr.Timeout()
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// accountError reports whether err says no fetch can succeed, whatever the
+// URL: the key is rejected, the credits are spent or the rate limit is hit.
+func accountError(err error) bool {
+	var apiErr *APIError
+	switch {
+	case errors.Is(err, ErrUnauthorized), errors.Is(err, ErrForbidden):
+		return true
+	case errors.As(err, &apiErr):
+		return apiErr.Status == http.StatusPaymentRequired || apiErr.Status == http.StatusTooManyRequests
+	}
+	return false
+}
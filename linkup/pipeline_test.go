@@ -0,0 +1,177 @@
+package linkup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSearchAndFetch(t *testing.T) {
+	var fetches int32
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search":
+			w.Write([]byte(`{"results":[
+				{"type":"text","name":"One","url":"https://good.com/1"},
+				{"type":"image","name":"Img","url":"https://img.com/a.png"},
+				{"type":"text","name":"Bad","url":"https://bad.com/1"},
+				{"type":"text","name":"Dup","url":"https://www.good.com/1?utm_source=x"},
+				{"type":"text","name":"Bad2","url":"https://bad.com/2"},
+				{"type":"text","name":"Two","url":"https://good.com/2"}
+			]}`))
+		case "/fetch":
+			atomic.AddInt32(&fetches, 1)
+			var req FetchRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if !req.RenderJS {
+				t.Errorf("RenderJS not passed through")
+			}
+			if req.URL == "https://bad.com/1" {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				w.Write([]byte(`{"message":"blocked"}`))
+				return
+			}
+			json.NewEncoder(w).Encode(FetchResult{Markdown: "# " + req.URL})
+		}
+	}
+	client, srv := newTestClient(t, handler)
+	defer srv.Close()
+
+	got, err := client.SearchAndFetch(context.Background(), SearchRequest{Q: "x"}, FetchOptions{TopN: 4, RenderJS: true, Concurrency: 1})
+	if err != nil {
+		t.Fatalf("SearchAndFetch: %v", err)
+	}
+	if len(got) != 4 {
+		t.Fatalf("len = %d", len(got))
+	}
+	if got[0].Err != nil || got[0].Page.Markdown != "# https://good.com/1" {
+		t.Fatalf("first: %+v", got[0])
+	}
+	if _, ok := got[1].Err.(*APIError); !ok {
+		t.Fatalf("second err = %v", got[1].Err)
+	}
+	if !errors.Is(got[2].Err, ErrDomainSkipped) {
+		t.Fatalf("third err = %v", got[2].Err)
+	}
+	if got[3].Err != nil || got[3].Result.Name != "Two" {
+		t.Fatalf("fourth: %+v", got[3])
+	}
+	if n := atomic.LoadInt32(&fetches); n != 3 {
+		t.Fatalf("fetches = %d", n)
+	}
+}
+
+func TestSearchAndFetch_Failures(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		status  int   // of the fetch of https://bad.com/1; 0 times out
+		err     error // SearchAndFetch's
+		skipped bool  // https://bad.com/2
+		fetches int32 // URLs fetched
+	}{
+		{"422", http.StatusUnprocessableEntity, nil, true, 2},
+		{"500", http.StatusInternalServerError, nil, true, 2},
+		{"timeout", 0, nil, true, 2},
+		{"401", http.StatusUnauthorized, ErrUnauthorized, false, 1},
+		{"403", http.StatusForbidden, ErrForbidden, false, 1},
+		{"402", http.StatusPaymentRequired, &APIError{}, false, 1},
+		{"429", http.StatusTooManyRequests, &APIError{}, false, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var fetches atomic.Int32
+			client, srv := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/search" {
+					w.Write([]byte(`{"results":[
+						{"type":"text","url":"https://bad.com/1"},
+						{"type":"text","url":"https://bad.com/2"},
+						{"type":"text","url":"https://good.com/1"}
+					]}`))
+					return
+				}
+				var req FetchRequest
+				json.NewDecoder(r.Body).Decode(&req)
+				if req.URL != "https://bad.com/1" {
+					fetches.Add(1)
+					json.NewEncoder(w).Encode(FetchResult{Markdown: "ok"})
+					return
+				}
+				if fetches.Load() == 0 {
+					fetches.Add(1) // retries are not counted
+				}
+				if tc.status == 0 {
+					time.Sleep(50 * time.Millisecond)
+					return
+				}
+				w.WriteHeader(tc.status)
+				w.Write([]byte(`{"message":"no"}`))
+			})
+			defer srv.Close()
+			WithHTTPClient(&http.Client{Timeout: 20 * time.Millisecond})(client)
+
+			got, err := client.SearchAndFetch(context.Background(), SearchRequest{Q: "x"}, FetchOptions{TopN: 3, Concurrency: 1})
+			switch want := tc.err.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("err = %v", err)
+				}
+			case *APIError:
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) || apiErr.Status != tc.status {
+					t.Fatalf("err = %v, want status %d", err, tc.status)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Fatalf("err = %v, want %v", err, want)
+				}
+			}
+			if len(got) != 3 || got[0].Err == nil {
+				t.Fatalf("results = %+v", got)
+			}
+			if skipped := errors.Is(got[1].Err, ErrDomainSkipped); skipped != tc.skipped {
+				t.Errorf("second err = %v", got[1].Err)
+			}
+			if tc.err == nil {
+				// A failing host does not stop the others.
+				if got[2].Err != nil || got[2].Page.Markdown != "ok" {
+					t.Errorf("third = %+v", got[2])
+				}
+			} else {
+				// An account error stops every remaining fetch.
+				for _, r := range got {
+					if r.Err != err {
+						t.Errorf("%s: err = %v, want %v", r.Result.URL, r.Err, err)
+					}
+				}
+			}
+			if n := fetches.Load(); n != tc.fetches {
+				t.Errorf("fetches = %d, want %d", n, tc.fetches)
+			}
+		})
+	}
+}
+
+func TestFetch_RetriesOn429(t *testing.T) {
+	var calls int32
+	client, srv := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"markdown":"ok"}`))
+	})
+	defer srv.Close()
+
+	resp, err := client.Fetch(context.Background(), FetchRequest{URL: "https://x"})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if p, _ := resp.Page(); p.Markdown != "ok" {
+		t.Fatalf("page = %+v", p)
+	}
+}
@@ -0,0 +1,50 @@
+package linkup
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Operations reported to a Recorder.
+const (
+	OpSearch = "search"
+	OpFetch  = "fetch"
+)
+
+// Call is a completed Search or Fetch.
+type Call struct {
+	Op       string
+	Time     time.Time // when the call started
+	Duration time.Duration
+	Request  json.RawMessage // request body as sent
+	Response json.RawMessage
+	Credits  float64 // estimated; see SearchCost and FetchCost
+}
+
+// Recorder receives every successful Search and Fetch, e.g. to archive
+// them (see package history). Record is called synchronously before the
+// call returns; failures are the recorder's to report, they never fail the
+// call.
+type Recorder interface {
+	Record(ctx context.Context, call Call)
+}
+
+// WithRecorder archives successful calls to r.
+func WithRecorder(r Recorder) Option {
+	return func(c *Client) { c.recorder = r }
+}
+
+func (c *Client) record(ctx context.Context, op string, start time.Time, req, resp []byte, credits float64) {
+	if c.recorder == nil {
+		return
+	}
+	c.recorder.Record(ctx, Call{
+		Op:       op,
+		Time:     start,
+		Duration: time.Since(start),
+		Request:  req,
+		Response: resp,
+		Credits:  credits,
+	})
+}
@@ -0,0 +1,102 @@
+package research
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/raezil/linkup-go/linkup/canonical"
+	"github.com/raezil/linkup-go/linkup/cite"
+)
+
+// Report is the outcome of a run.
+type Report struct {
+	Question string `json:"question"`
+	// Findings holds one entry per successful step, with citations
+	// renumbered to index Bibliography (1-based).
+	Findings     []Finding `json:"findings"`
+	Bibliography []Entry   `json:"bibliography"`
+	Credits      float64   `json:"credits"`
+	StopReason   string    `json:"stopReason,omitempty"`
+}
+
+// Finding is the answer to one (follow-up) question.
+type Finding struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+	// Unsourced are citations of the step's answer that match none of its
+	// sources, as written; they are removed from Answer.
+	Unsourced []int `json:"unsourced,omitempty"`
+}
+
+// Entry is a deduplicated bibliography entry.
+type Entry struct {
+	Title   string `json:"title,omitempty"`
+	URL     string `json:"url"`
+	Excerpt string `json:"excerpt,omitempty"`
+	Steps   []int  `json:"steps"` // steps that cited this source
+}
+
+// BuildReport assembles a report from st. Sources are merged on canonical
+// URL across steps.
+func BuildReport(st *State) *Report {
+	rep := &Report{Question: st.Question, Credits: st.Credits, StopReason: st.StopReason}
+	index := make(map[string]int) // canonical URL -> bibliography position
+	for _, s := range st.Steps {
+		if s.Error != "" && s.Answer == "" {
+			continue
+		}
+		local := make(map[int]int) // step source number -> global number
+		for i, src := range s.Sources {
+			k := canonical.Key(src.URL)
+			pos, ok := index[k]
+			if !ok {
+				pos = len(rep.Bibliography)
+				index[k] = pos
+				rep.Bibliography = append(rep.Bibliography, Entry{Title: src.Title, URL: src.URL})
+			}
+			e := &rep.Bibliography[pos]
+			if e.Excerpt == "" {
+				e.Excerpt = src.Excerpt
+			}
+			if len(e.Steps) == 0 || e.Steps[len(e.Steps)-1] != s.Index {
+				e.Steps = append(e.Steps, s.Index)
+			}
+			local[i+1] = pos + 1
+		}
+		f := Finding{Question: s.Question}
+		f.Answer = cite.Renumber(s.Answer, func(n int) int {
+			g, ok := local[n]
+			if !ok && !slices.Contains(f.Unsourced, n) {
+				// Left as is, n would point at another step's source.
+				f.Unsourced = append(f.Unsourced, n)
+			}
+			return g
+		})
+		rep.Findings = append(rep.Findings, f)
+	}
+	return rep
+}
+
+// Markdown renders the report with a numbered bibliography.
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", r.Question)
+	for i, f := range r.Findings {
+		if i > 0 {
+			fmt.Fprintf(&b, "## %s\n\n", f.Question)
+		}
+		fmt.Fprintf(&b, "%s\n\n", strings.TrimSpace(f.Answer))
+	}
+	if len(r.Bibliography) > 0 {
+		b.WriteString("## Bibliography\n\n")
+		for i, e := range r.Bibliography {
+			title := e.Title
+			if title == "" {
+				title = e.URL
+			}
+			fmt.Fprintf(&b, "%d. [%s](%s)\n", i+1, title, e.URL)
+		}
+	}
+	return b.String()
+}
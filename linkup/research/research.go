@@ -0,0 +1,375 @@
+// Package research runs an iterative research loop on top of Linkup: it
+// answers a question with a sourced-answer search, fetches the top sources,
+// derives follow-up questions and searches again until a step or credit
+// budget is spent, then assembles a report with a deduplicated
+// bibliography. Progress is observable through hooks and the loop can be
+// resumed from a persisted State.
+package research
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/canonical"
+)
+
+// ErrBudget is recorded in State.StopReason when the credit budget stopped
+// the loop.
+var ErrBudget = errors.New("research: credit budget exhausted")
+
+// Config tunes a Researcher. The zero value is usable.
+type Config struct {
+	// MaxSteps caps the number of searches. Default 4.
+	MaxSteps int
+	// MaxCredits caps estimated spending (see linkup.SearchCost and
+	// linkup.FetchCost). 0 means no limit.
+	MaxCredits float64
+	// Depth of each search. Default linkup.DepthStandard.
+	Depth linkup.Depth
+	// Base carries search options shared by every step (domains, dates).
+	// Q, Depth and OutputType are set by the loop.
+	Base linkup.SearchRequest
+	// FetchTopN sources of each answer are fetched. Default 2; -1 disables.
+	FetchTopN int
+	// FollowUpsPerStep caps follow-up questions taken from one answer.
+	// Default 2.
+	FollowUpsPerStep int
+	// ExcerptChars bounds the fetched markdown kept per source. Default 4000.
+	ExcerptChars int
+	// FollowUps extracts follow-up questions from an answer. Default
+	// HeuristicFollowUps.
+	FollowUps func(ctx context.Context, question string, ans linkup.SourcedAnswer) ([]string, error)
+	// Hooks observe progress.
+	Hooks Hooks
+	// Checkpoint, when set, is called with the state after every step so the
+	// run can be resumed; see SaveState.
+	Checkpoint func(*State) error
+}
+
+// Hooks are optional progress callbacks.
+type Hooks struct {
+	OnStepStart func(index int, question string)
+	OnFetch     func(step int, src Source)
+	OnStepDone  func(step Step)
+}
+
+// Source is an answer source, with its fetched content when available.
+type Source struct {
+	Title   string `json:"title,omitempty"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet,omitempty"`
+	Excerpt string `json:"excerpt,omitempty"` // fetched markdown, truncated
+	Error   string `json:"error,omitempty"`   // fetch error
+}
+
+// Step is one search of the loop.
+type Step struct {
+	Index     int       `json:"index"`
+	Question  string    `json:"question"`
+	Answer    string    `json:"answer"`
+	Sources   []Source  `json:"sources,omitempty"`
+	FollowUps []string  `json:"followUps,omitempty"`
+	Credits   float64   `json:"credits"`
+	Error     string    `json:"error,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// State is the resumable progress of a run.
+type State struct {
+	Question   string   `json:"question"`
+	Queue      []string `json:"queue"` // questions still to search
+	Asked      []string `json:"asked"` // normalized questions already searched
+	Steps      []Step   `json:"steps"`
+	Credits    float64  `json:"credits"`
+	Done       bool     `json:"done"`
+	StopReason string   `json:"stopReason,omitempty"`
+}
+
+// NewState starts a run for question.
+func NewState(question string) *State {
+	return &State{Question: question, Queue: []string{question}}
+}
+
+// Researcher drives the loop.
+type Researcher struct {
+	client *linkup.Client
+	cfg    Config
+}
+
+// New returns a Researcher using client.
+func New(client *linkup.Client, cfg Config) *Researcher {
+	if cfg.MaxSteps <= 0 {
+		cfg.MaxSteps = 4
+	}
+	if cfg.Depth == "" {
+		cfg.Depth = linkup.DepthStandard
+	}
+	if cfg.FetchTopN == 0 {
+		cfg.FetchTopN = 2
+	}
+	if cfg.FollowUpsPerStep <= 0 {
+		cfg.FollowUpsPerStep = 2
+	}
+	if cfg.ExcerptChars <= 0 {
+		cfg.ExcerptChars = 4000
+	}
+	if cfg.FollowUps == nil {
+		cfg.FollowUps = HeuristicFollowUps
+	}
+	return &Researcher{client: client, cfg: cfg}
+}
+
+// Run researches question from scratch.
+func (r *Researcher) Run(ctx context.Context, question string) (*Report, error) {
+	return r.Resume(ctx, NewState(question))
+}
+
+// Resume continues st until the queue is empty or a budget is reached, and
+// returns the report. On context cancellation the partial state is kept (and
+// checkpointed) so a later Resume picks up where it stopped.
+func (r *Researcher) Resume(ctx context.Context, st *State) (*Report, error) {
+	if strings.TrimSpace(st.Question) == "" {
+		return nil, errors.New("research: empty question")
+	}
+	for !st.Done {
+		if len(st.Queue) == 0 {
+			r.stop(st, "no more questions")
+			break
+		}
+		if len(st.Steps) >= r.cfg.MaxSteps {
+			r.stop(st, "step budget reached")
+			break
+		}
+		if r.cfg.MaxCredits > 0 && st.Credits+r.stepCost() > r.cfg.MaxCredits+1e-9 {
+			r.stop(st, ErrBudget.Error())
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return BuildReport(st), err
+		}
+
+		q := st.Queue[0]
+		step := r.step(ctx, st, q)
+		if ctx.Err() != nil {
+			// Leave q queued so Resume retries it.
+			return BuildReport(st), ctx.Err()
+		}
+		st.Queue = st.Queue[1:]
+		st.Asked = append(st.Asked, normalize(q))
+		st.Steps = append(st.Steps, step)
+		st.Credits += step.Credits
+		for _, f := range step.FollowUps {
+			if !st.asked(f) {
+				st.Queue = append(st.Queue, f)
+			}
+		}
+		if h := r.cfg.Hooks.OnStepDone; h != nil {
+			h(step)
+		}
+		if err := r.checkpoint(st); err != nil {
+			return BuildReport(st), err
+		}
+	}
+	return BuildReport(st), r.checkpoint(st)
+}
+
+func (r *Researcher) stop(st *State, reason string) {
+	st.Done = true
+	st.StopReason = reason
+}
+
+func (r *Researcher) checkpoint(st *State) error {
+	if r.cfg.Checkpoint == nil {
+		return nil
+	}
+	return r.cfg.Checkpoint(st)
+}
+
+// stepCost is the worst-case estimated cost of one step.
+func (r *Researcher) stepCost() float64 {
+	cost := linkup.SearchCost(linkup.SearchRequest{Depth: r.cfg.Depth})
+	if r.cfg.FetchTopN > 0 {
+		cost += float64(r.cfg.FetchTopN) * linkup.FetchCost(linkup.FetchRequest{})
+	}
+	return cost
+}
+
+func (r *Researcher) step(ctx context.Context, st *State, q string) Step {
+	step := Step{Index: len(st.Steps), Question: q, Time: time.Now().UTC()}
+	if h := r.cfg.Hooks.OnStepStart; h != nil {
+		h(step.Index, q)
+	}
+
+	req := r.cfg.Base
+	req.Q = q
+	req.Depth = r.cfg.Depth
+	req.OutputType = linkup.OutputSourcedAnswer
+	req.IncludeInlineCitations = true
+	resp, err := r.client.Search(ctx, req)
+	if err != nil {
+		step.Error = err.Error()
+		return step
+	}
+	step.Credits += linkup.SearchCost(req)
+	ans, err := resp.SourcedAnswer()
+	if err != nil {
+		step.Error = err.Error()
+		return step
+	}
+	step.Answer = ans.Answer
+	for _, s := range ans.Sources {
+		step.Sources = append(step.Sources, Source{Title: s.Label(), URL: s.URL, Snippet: s.Snippet})
+	}
+
+	fetched := 0
+	for i := range step.Sources {
+		if fetched >= r.cfg.FetchTopN || ctx.Err() != nil {
+			break
+		}
+		src := &step.Sources[i]
+		if src.URL == "" || st.fetched(src.URL) {
+			continue
+		}
+		fetched++
+		freq := linkup.FetchRequest{URL: src.URL}
+		page, err := r.client.Fetch(ctx, freq)
+		if err == nil {
+			step.Credits += linkup.FetchCost(freq)
+			var p linkup.FetchResult
+			if p, err = page.Page(); err == nil {
+				src.Excerpt = truncate(p.Markdown, r.cfg.ExcerptChars)
+			}
+		}
+		if err != nil {
+			src.Error = err.Error()
+		}
+		if h := r.cfg.Hooks.OnFetch; h != nil {
+			h(step.Index, *src)
+		}
+	}
+
+	fups, err := r.cfg.FollowUps(ctx, q, ans)
+	if err != nil {
+		step.Error = err.Error()
+	}
+	for _, f := range fups {
+		if len(step.FollowUps) == r.cfg.FollowUpsPerStep {
+			break
+		}
+		if f = strings.TrimSpace(f); f != "" && !st.asked(f) && normalize(f) != normalize(q) {
+			step.FollowUps = append(step.FollowUps, f)
+		}
+	}
+	return step
+}
+
+func (st *State) asked(q string) bool {
+	n := normalize(q)
+	for _, a := range st.Asked {
+		if a == n {
+			return true
+		}
+	}
+	for _, p := range st.Queue {
+		if normalize(p) == n {
+			return true
+		}
+	}
+	return false
+}
+
+// fetched reports whether an earlier step already fetched url.
+func (st *State) fetched(url string) bool {
+	k := canonical.Key(url)
+	for _, s := range st.Steps {
+		for _, src := range s.Sources {
+			if (src.Excerpt != "" || src.Error != "") && canonical.Key(src.URL) == k {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func normalize(q string) string {
+	return strings.ToLower(strings.Join(strings.Fields(strings.TrimRight(q, "?.! ")), " "))
+}
+
+var (
+	sentenceRe  = regexp.MustCompile(`[^.!?\n]+[.!?]`)
+	citationRe  = regexp.MustCompile(`\s*\[\d+(?:\s*,\s*\d+)*\]`)
+	uncertainRe = regexp.MustCompile(`(?i)\b(unclear|unknown|not (?:yet )?(?:known|clear|specified|disclosed|confirmed)|remains to be seen|debated|disputed|further research|no (?:public )?information|limited data)\b`)
+)
+
+// HeuristicFollowUps extracts follow-ups without spending credits: explicit
+// questions in the answer, then sentences flagging open points ("unclear",
+// "not yet known", "debated", ...), which are returned as search queries.
+func HeuristicFollowUps(_ context.Context, _ string, ans linkup.SourcedAnswer) ([]string, error) {
+	text := citationRe.ReplaceAllString(ans.Answer, "")
+	var questions, open []string
+	for _, s := range sentenceRe.FindAllString(text, -1) {
+		s = strings.TrimSpace(s)
+		switch {
+		case strings.HasSuffix(s, "?"):
+			questions = append(questions, s)
+		case uncertainRe.MatchString(s):
+			open = append(open, strings.TrimRight(s, "."))
+		}
+	}
+	return append(questions, open...), nil
+}
+
+// SaveState writes st as JSON to path atomically.
+func SaveState(path string, st *State) error {
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".research-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadState reads a State written by SaveState.
+func LoadState(path string) (*State, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	st := new(State)
+	if err := json.Unmarshal(b, st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// FileCheckpoint returns a Config.Checkpoint that saves to path.
+func FileCheckpoint(path string) func(*State) error {
+	return func(st *State) error { return SaveState(path, st) }
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,142 @@
+package research
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+)
+
+func newClient(t *testing.T) (*linkup.Client, *int32) {
+	t.Helper()
+	var searches int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search":
+			atomic.AddInt32(&searches, 1)
+			var req linkup.SearchRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.OutputType != linkup.OutputSourcedAnswer || !req.IncludeInlineCitations {
+				t.Errorf("unexpected request %+v", req)
+			}
+			ans := linkup.SourcedAnswer{
+				Answer: "Findings for step [1]. The release date is still unclear [2].",
+				Sources: []linkup.AnswerSource{
+					{Name: "Shared", URL: "https://shared.example/page"},
+					{Name: "Own " + req.Q, URL: "https://example.com/" + strings.ReplaceAll(req.Q, " ", "-")},
+				},
+			}
+			json.NewEncoder(w).Encode(ans)
+		case "/fetch":
+			w.Write([]byte(`{"markdown":"page body"}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return linkup.NewClient("k", linkup.WithBaseURL(srv.URL), linkup.WithRetry(0, time.Millisecond, time.Millisecond)), &searches
+}
+
+func TestRun_FollowUpsAndBibliography(t *testing.T) {
+	client, _ := newClient(t)
+	var started, done int
+	r := New(client, Config{
+		MaxSteps: 3,
+		Hooks: Hooks{
+			OnStepStart: func(int, string) { started++ },
+			OnStepDone:  func(Step) { done++ },
+		},
+	})
+	rep, err := r.Run(context.Background(), "what is X")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if started != 2 || done != 2 {
+		// step 1 yields one follow-up; step 2 yields the same one again.
+		t.Fatalf("started=%d done=%d", started, done)
+	}
+	if len(rep.Findings) != 2 || rep.Findings[1].Question != "The release date is still unclear" {
+		t.Fatalf("findings = %+v", rep.Findings)
+	}
+	// Shared source merged: 1 shared + 2 own.
+	if len(rep.Bibliography) != 3 || len(rep.Bibliography[0].Steps) != 2 {
+		t.Fatalf("bibliography = %+v", rep.Bibliography)
+	}
+	if !strings.Contains(rep.Findings[1].Answer, "[3]") {
+		t.Fatalf("citations not renumbered: %q", rep.Findings[1].Answer)
+	}
+	if rep.Bibliography[0].Excerpt != "page body" {
+		t.Fatalf("excerpt missing")
+	}
+	if !strings.Contains(rep.Markdown(), "## Bibliography\n\n1. [Shared](https://shared.example/page)") {
+		t.Fatalf("markdown:\n%s", rep.Markdown())
+	}
+}
+
+func TestBuildReport_UnsourcedCitations(t *testing.T) {
+	var first, second []Source
+	for _, h := range []string{"d", "e", "f", "g", "h"} {
+		first = append(first, Source{URL: "https://" + h + ".com/"})
+	}
+	for _, h := range []string{"a", "b", "c"} {
+		second = append(second, Source{URL: "https://" + h + ".com/"})
+	}
+	rep := BuildReport(&State{Question: "q", Steps: []Step{
+		{Index: 1, Question: "q", Answer: "One [5].", Sources: first},
+		{Index: 2, Question: "r", Answer: "A [3]. B [5]. C [2, 5].", Sources: second},
+	}})
+	// Step 2 has 3 sources: left as is, [5] would cite h.com from step 1.
+	if f := rep.Findings[1]; f.Answer != "A [8]. B. C [7]." || !slices.Equal(f.Unsourced, []int{5}) {
+		t.Fatalf("finding = %+v", f)
+	}
+	if f := rep.Findings[0]; f.Answer != "One [5]." || f.Unsourced != nil {
+		t.Fatalf("finding = %+v", f)
+	}
+}
+
+func TestRun_CreditBudgetAndResume(t *testing.T) {
+	client, searches := newClient(t)
+	path := filepath.Join(t.TempDir(), "state.json")
+	cfg := Config{FetchTopN: -1, MaxCredits: linkup.CostStandardSearch, Checkpoint: FileCheckpoint(path)}
+
+	rep, err := New(client, cfg).Run(context.Background(), "topic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.StopReason != ErrBudget.Error() || *searches != 1 {
+		t.Fatalf("stop=%q searches=%d", rep.StopReason, *searches)
+	}
+
+	st, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Done || len(st.Queue) != 1 {
+		t.Fatalf("state = %+v", st)
+	}
+	// Raise the budget and continue from the saved state.
+	st.Done, st.StopReason = false, ""
+	cfg.MaxCredits = 1
+	rep, err = New(client, cfg).Resume(context.Background(), st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *searches != 2 || len(rep.Findings) != 2 {
+		t.Fatalf("searches=%d findings=%d", *searches, len(rep.Findings))
+	}
+}
+
+func TestHeuristicFollowUps(t *testing.T) {
+	got, _ := HeuristicFollowUps(context.Background(), "", linkup.SourcedAnswer{
+		Answer: "A is known [1]. Is B related? Its impact remains to be seen [2, 3]. C is fine.",
+	})
+	if len(got) != 2 || got[0] != "Is B related?" || got[1] != "Its impact remains to be seen" {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,24 @@
+package linkup
+
+import "github.com/raezil/linkup-go/linkup/canonical"
+
+// DedupeResults merges results that point at the same page once URLs are
+// canonicalized (tracking params, www./AMP/mobile variants, http vs https,
+// trailing slashes). The first occurrence keeps its rank; it inherits the
+// longest content and a name from its duplicates when its own is missing.
+func DedupeResults(results []SearchResult) []SearchResult {
+	return canonical.Dedupe(results,
+		func(r SearchResult) string { return r.URL },
+		mergeResult,
+	)
+}
+
+func mergeResult(kept, dup SearchResult) SearchResult {
+	if len(dup.Content) > len(kept.Content) {
+		kept.Content = dup.Content
+	}
+	if kept.Name == "" {
+		kept.Name = dup.Name
+	}
+	return kept
+}
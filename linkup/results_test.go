@@ -0,0 +1,29 @@
+package linkup
+
+import "testing"
+
+func TestDedupeResults(t *testing.T) {
+	in := []SearchResult{
+		{Type: "text", URL: "https://www.example.com/post?utm_campaign=x", Content: "short"},
+		{Type: "text", Name: "Other", URL: "https://other.org/page"},
+		{Type: "text", Name: "Post", URL: "http://example.com/post/", Content: "longer content"},
+	}
+	out := DedupeResults(in)
+	if len(out) != 2 {
+		t.Fatalf("len = %d: %+v", len(out), out)
+	}
+	if out[0].Name != "Post" || out[0].Content != "longer content" {
+		t.Fatalf("merge failed: %+v", out[0])
+	}
+}
+
+func TestSearchResponse_Results(t *testing.T) {
+	resp := SearchResponse{Raw: []byte(`{"results":[{"type":"text","name":"A","url":"https://a","content":"c"}]}`)}
+	rs, err := resp.Results()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 1 || rs[0].Name != "A" {
+		t.Fatalf("unexpected %+v", rs)
+	}
+}
@@ -0,0 +1,311 @@
+// Package robots parses robots.txt files (RFC 9309) and answers whether a
+// crawler may fetch a path. It also collects the file's Sitemap
+// directives. Fetcher downloads and caches the file of each origin.
+package robots
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxSize is how much of a robots.txt file is parsed; RFC 9309 requires
+// at least 500 KiB.
+const MaxSize = 512 << 10
+
+// Rules are the parsed contents of a robots.txt file. The zero value (and
+// a nil *Rules) allows everything.
+type Rules struct {
+	groups      []group
+	sitemaps    []string
+	disallowAll bool
+}
+
+type group struct {
+	agents []string // lowercased product tokens, "*" for any
+	rules  []rule
+	delay  time.Duration
+}
+
+type rule struct {
+	allow   bool
+	pattern string
+}
+
+// AllowAll permits every path; it stands for a missing robots.txt.
+var AllowAll = &Rules{}
+
+// DisallowAll forbids every path except /robots.txt; it stands for a
+// robots.txt that could not be read because the server failed.
+var DisallowAll = &Rules{disallowAll: true}
+
+// Parse reads a robots.txt file. Unknown and malformed lines are ignored,
+// as are bytes beyond MaxSize.
+func Parse(r io.Reader) *Rules {
+	rs := &Rules{}
+	var cur *group
+	inAgents := false // the previous directive was user-agent
+	sc := bufio.NewScanner(io.LimitReader(r, MaxSize))
+	sc.Buffer(make([]byte, 0, 4096), MaxSize)
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		key, val, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		val = strings.TrimSpace(val)
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				rs.groups = append(rs.groups, group{})
+				cur = &rs.groups[len(rs.groups)-1]
+			}
+			cur.agents = append(cur.agents, strings.ToLower(productToken(val)))
+			inAgents = true
+			continue
+		case "allow", "disallow":
+			if cur != nil && val != "" {
+				cur.rules = append(cur.rules, rule{allow: key == "allow", pattern: normalize(val)})
+			}
+		case "sitemap":
+			// Not part of any group: it neither starts nor ends one.
+			if val != "" {
+				rs.sitemaps = append(rs.sitemaps, val)
+			}
+			continue
+		case "crawl-delay":
+			if cur != nil {
+				if secs, err := strconv.ParseFloat(val, 64); err == nil && secs >= 0 {
+					cur.delay = time.Duration(secs * float64(time.Second))
+				}
+			}
+		}
+		inAgents = false
+	}
+	return rs
+}
+
+// productToken returns the name part of a user agent such as
+// "linkup-go/1.2 (+https://...)".
+func productToken(ua string) string {
+	ua = strings.TrimSpace(ua)
+	if i := strings.IndexAny(ua, "/ \t"); i >= 0 {
+		ua = ua[:i]
+	}
+	return ua
+}
+
+// match returns the groups that apply to agent: those naming its product
+// token, or else those for "*". Groups for the same agent are merged, as
+// the RFC requires.
+func (r *Rules) match(agent string) []*group {
+	tok := strings.ToLower(productToken(agent))
+	var named, star []*group
+	for i := range r.groups {
+		g := &r.groups[i]
+		for _, a := range g.agents {
+			if a == tok && tok != "" {
+				named = append(named, g)
+				break
+			}
+			if a == "*" {
+				star = append(star, g)
+				break
+			}
+		}
+	}
+	if len(named) > 0 {
+		return named
+	}
+	return star
+}
+
+// Allowed reports whether agent may fetch path (which may carry a query).
+// The longest matching pattern decides; Allow wins ties.
+func (r *Rules) Allowed(agent, path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	if path == "/robots.txt" || r == nil {
+		return true
+	}
+	if r.disallowAll {
+		return false
+	}
+	path = normalize(path)
+	best, allowed := -1, true
+	for _, g := range r.match(agent) {
+		for _, rl := range g.rules {
+			if n := len(rl.pattern); n >= best && matches(rl.pattern, path) {
+				if n > best || rl.allow {
+					allowed = rl.allow
+				}
+				best = n
+			}
+		}
+	}
+	return allowed
+}
+
+// AllowedURL is Allowed for the path and query of an absolute URL.
+func (r *Rules) AllowedURL(agent, rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return r.Allowed(agent, u.RequestURI())
+}
+
+// Sitemaps returns the URLs of the file's Sitemap directives, in order.
+func (r *Rules) Sitemaps() []string {
+	if r == nil {
+		return nil
+	}
+	return r.sitemaps
+}
+
+// CrawlDelay returns the Crawl-delay that applies to agent, or 0.
+func (r *Rules) CrawlDelay(agent string) time.Duration {
+	if r == nil {
+		return 0
+	}
+	var d time.Duration
+	for _, g := range r.match(agent) {
+		d = max(d, g.delay)
+	}
+	return d
+}
+
+// matches reports whether path matches pattern, where * matches any run of
+// characters and a trailing $ anchors the end.
+func matches(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	for i, p := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			// The last part must sit at the very end.
+			return len(path)-len(p) >= pos && strings.HasSuffix(path, p)
+		}
+		j := strings.Index(path[pos:], p)
+		if j < 0 {
+			return false
+		}
+		pos += j + len(p)
+	}
+	return !anchored || pos == len(path)
+}
+
+// normalize percent-encodes characters outside the printable ASCII range
+// and uppercases existing escapes, so patterns and paths compare alike.
+func normalize(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '%' && i+2 < len(s):
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(s[i+1 : i+3]))
+			i += 2
+		case c <= ' ' || c >= 0x7f:
+			fmt.Fprintf(&b, "%%%02X", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// Fetcher downloads robots.txt files and caches them per origin.
+type Fetcher struct {
+	// Client fetches the files. Default: a client with a 10s timeout.
+	Client *http.Client
+	// UserAgent is sent with each request.
+	UserAgent string
+	// TTL is how long a file is cached. Default 24h, the RFC's upper bound.
+	TTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cached
+}
+
+type cached struct {
+	rules *Rules
+	at    time.Time
+}
+
+// Get returns the rules for the origin of rawURL. Following RFC 9309, a
+// 4xx response yields AllowAll, and a 5xx response or a network failure
+// yields DisallowAll together with the error (which is not cached).
+func (f *Fetcher) Get(ctx context.Context, rawURL string) (*Rules, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("robots: bad url %q", rawURL)
+	}
+	origin := strings.ToLower(u.Scheme + "://" + u.Host)
+	ttl := f.TTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	f.mu.Lock()
+	if c, ok := f.cache[origin]; ok && time.Since(c.at) < ttl {
+		f.mu.Unlock()
+		return c.rules, nil
+	}
+	f.mu.Unlock()
+
+	rules, err := f.fetch(ctx, origin+"/robots.txt")
+	if err != nil {
+		return DisallowAll, err
+	}
+	f.mu.Lock()
+	if f.cache == nil {
+		f.cache = map[string]cached{}
+	}
+	f.cache[origin] = cached{rules: rules, at: time.Now()}
+	f.mu.Unlock()
+	return rules, nil
+}
+
+func (f *Fetcher) fetch(ctx context.Context, robotsURL string) (*Rules, error) {
+	hc := f.Client
+	if hc == nil {
+		hc = &http.Client{Timeout: 10 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if f.UserAgent != "" {
+		req.Header.Set("User-Agent", f.UserAgent)
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("robots: %w", err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500:
+		return nil, fmt.Errorf("robots: %s: %s", robotsURL, resp.Status)
+	case resp.StatusCode >= 400:
+		return AllowAll, nil
+	case resp.StatusCode >= 300:
+		// Redirects the client did not follow.
+		return AllowAll, nil
+	}
+	return Parse(resp.Body), nil
+}
@@ -0,0 +1,123 @@
+package robots
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const sample = `# example
+Sitemap: https://example.com/sitemap.xml
+User-agent: *
+Disallow: /private/
+Allow: /private/public
+Disallow: /*.pdf$
+Disallow: /search?
+Crawl-delay: 2
+
+User-agent: linkup-go
+Sitemap: https://example.com/news.xml.gz
+User-agent: other
+Disallow: /tmp   # trailing comment
+Crawl-delay: 0.5
+
+user-agent: LINKUP-GO
+disallow: /drafts/
+`
+
+func TestAllowed(t *testing.T) {
+	r := Parse(strings.NewReader(sample))
+	for _, tt := range []struct {
+		agent, path string
+		want        bool
+	}{
+		{"somebot", "/", true},
+		{"somebot", "/private/x", false},
+		{"somebot", "/private/public/x", true}, // longer Allow wins
+		{"somebot", "/docs/a.pdf", false},
+		{"somebot", "/docs/a.pdf?x=1", true}, // $ anchors
+		{"somebot", "/search?q=go", false},
+		{"somebot", "/search", true},
+		{"somebot", "/robots.txt", true},
+		// Named groups replace "*" and are merged with each other.
+		{"linkup-go/1.0 (+https://linkup.so)", "/private/x", true},
+		{"Linkup-Go", "/tmp/a", false},
+		{"linkup-go", "/drafts/b", false},
+		{"other", "/drafts/b", true},
+	} {
+		if got := r.Allowed(tt.agent, tt.path); got != tt.want {
+			t.Errorf("Allowed(%q, %q) = %v", tt.agent, tt.path, got)
+		}
+	}
+	if sm := r.Sitemaps(); len(sm) != 2 || sm[1] != "https://example.com/news.xml.gz" {
+		t.Fatalf("sitemaps = %v", sm)
+	}
+	if d := r.CrawlDelay("somebot"); d != 2*time.Second {
+		t.Fatalf("delay = %v", d)
+	}
+	if d := r.CrawlDelay("linkup-go"); d != 500*time.Millisecond {
+		t.Fatalf("delay = %v", d)
+	}
+	if !AllowAll.Allowed("x", "/a") || DisallowAll.Allowed("x", "/a") || !DisallowAll.Allowed("x", "/robots.txt") {
+		t.Fatal("AllowAll/DisallowAll")
+	}
+}
+
+func TestMatches(t *testing.T) {
+	for _, tt := range []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/a", "/abc", true},
+		{"/a$", "/abc", false},
+		{"/a$", "/a", true},
+		{"/*/b", "/x/y/b", true},
+		{"/*.php$", "/x.php", true},
+		{"/*.php$", "/x.php5", false},
+		{"/fish*", "/Fish", false},
+	} {
+		if got := matches(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("matches(%q, %q) = %v", tt.pattern, tt.path, got)
+		}
+	}
+}
+
+func TestFetcher(t *testing.T) {
+	var calls atomic.Int32
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path != "/robots.txt" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte("User-agent: *\nDisallow: /no\n"))
+	}))
+	defer srv.Close()
+	f := &Fetcher{}
+	ctx := context.Background()
+	for range 2 {
+		r, err := f.Get(ctx, srv.URL+"/a/b")
+		if err != nil || r.AllowedURL("x", srv.URL+"/no/1") {
+			t.Fatalf("rules = %+v, %v", r, err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("fetched %d times", calls.Load())
+	}
+
+	f = &Fetcher{}
+	status = http.StatusNotFound
+	if r, err := f.Get(ctx, srv.URL); err != nil || r != AllowAll {
+		t.Fatalf("404: %v, %v", r, err)
+	}
+	f = &Fetcher{}
+	status = http.StatusServiceUnavailable
+	if r, err := f.Get(ctx, srv.URL); err == nil || r != DisallowAll {
+		t.Fatalf("503: %v, %v", r, err)
+	}
+}
@@ -0,0 +1,49 @@
+package linkup
+
+import "github.com/raezil/linkup-go/linkup/jsonschema"
+
+// SearchRequestSchema describes SearchRequest as a JSON Schema, with enums
+// and descriptions suitable for LLM tool definitions.
+func SearchRequestSchema() *jsonschema.Schema {
+	s := jsonschema.Reflect(SearchRequest{}).Strict()
+	describe(s, map[string]string{
+		"q":                      "The search query in natural language.",
+		"depth":                  "standard is fast; deep runs an agentic multi-step search and costs more.",
+		"outputType":             "searchResults returns a ranked list, sourcedAnswer a written answer with sources, structured JSON matching structuredOutputSchema.",
+		"includeImages":          "Include image results.",
+		"fromDate":               "Only content published on or after this date (YYYY-MM-DD).",
+		"toDate":                 "Only content published on or before this date (YYYY-MM-DD).",
+		"excludeDomains":         "Domains to exclude, e.g. [\"wikipedia.org\"].",
+		"includeDomains":         "Restrict results to these domains, e.g. [\"arxiv.org\"].",
+		"includeInlineCitations": "For sourcedAnswer: add [n] citation markers to the answer.",
+		"structuredOutputSchema": "For structured: a JSON Schema (as a string) the output must match.",
+		"includeSources":         "For structured: also return the sources used.",
+	})
+	s.Property("depth").Enum = []any{string(DepthStandard), string(DepthDeep)}
+	s.Property("outputType").Enum = []any{string(OutputSearchResults), string(OutputSourcedAnswer), string(OutputStructured)}
+	for _, d := range []string{"fromDate", "toDate"} {
+		s.Property(d).Pattern = `^\d{4}-\d{2}-\d{2}$`
+	}
+	return s
+}
+
+// FetchRequestSchema describes FetchRequest as a JSON Schema.
+func FetchRequestSchema() *jsonschema.Schema {
+	s := jsonschema.Reflect(FetchRequest{}).Strict()
+	describe(s, map[string]string{
+		"url":            "Absolute URL of the page to fetch.",
+		"includeRawHtml": "Also return the raw HTML of the page.",
+		"renderJs":       "Render JavaScript before extracting content (slower, costs more).",
+		"extractImages":  "Return the images found on the page.",
+	})
+	s.Property("url").Format = "uri"
+	return s
+}
+
+func describe(s *jsonschema.Schema, docs map[string]string) {
+	for name, d := range docs {
+		if p := s.Property(name); p != nil {
+			p.Description = d
+		}
+	}
+}
@@ -0,0 +1,350 @@
+// Package sitemap reads sitemaps (sitemaps.org): XML urlsets, sitemap
+// indexes and plain-text URL lists, gzipped or not. Fetcher follows
+// indexes, discovers sitemaps through robots.txt, and filters entries by
+// lastmod so that only pages changed since a date are fetched.
+package sitemap
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/raezil/linkup-go/linkup/canonical"
+	"github.com/raezil/linkup-go/linkup/robots"
+)
+
+// MaxSize caps the uncompressed size of one sitemap; the protocol allows
+// 50 MB.
+const MaxSize = 50 << 20
+
+// Entry is a page of a urlset, or a child sitemap of an index.
+type Entry struct {
+	Loc        string    `json:"loc"`
+	LastMod    time.Time `json:"lastmod,omitempty"` // zero when absent or unparseable
+	ChangeFreq string    `json:"changefreq,omitempty"`
+	Priority   float64   `json:"priority,omitempty"`
+}
+
+// Sitemap is one parsed file: either a urlset (URLs) or an index
+// (Sitemaps).
+type Sitemap struct {
+	URLs     []Entry
+	Sitemaps []Entry
+}
+
+type xmlEntry struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod"`
+	ChangeFreq string `xml:"changefreq"`
+	Priority   string `xml:"priority"`
+}
+
+func (x xmlEntry) entry() Entry {
+	e := Entry{Loc: strings.TrimSpace(x.Loc), ChangeFreq: strings.TrimSpace(x.ChangeFreq)}
+	e.LastMod, _ = ParseLastMod(x.LastMod)
+	e.Priority, _ = strconv.ParseFloat(strings.TrimSpace(x.Priority), 64)
+	return e
+}
+
+// Parse reads a sitemap, urlset or index, in XML or as a plain-text list
+// of URLs. Gzipped input is detected and decompressed.
+func Parse(r io.Reader) (*Sitemap, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("sitemap: %w", err)
+		}
+		defer zr.Close()
+		br = bufio.NewReader(zr)
+	}
+	b, err := io.ReadAll(io.LimitReader(br, MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("sitemap: %w", err)
+	}
+	if len(b) > MaxSize {
+		return nil, fmt.Errorf("sitemap: larger than %d bytes", MaxSize)
+	}
+	b = bytes.TrimPrefix(bytes.TrimSpace(b), []byte("\xef\xbb\xbf"))
+	if len(b) > 0 && b[0] != '<' {
+		return parseText(b), nil
+	}
+	return parseXML(b)
+}
+
+func parseXML(b []byte) (*Sitemap, error) {
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	// Sitemaps are UTF-8 by definition; accept mislabeled ones as is.
+	dec.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) { return r, nil }
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("sitemap: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		sm := &Sitemap{}
+		switch start.Name.Local {
+		case "urlset":
+			var doc struct {
+				URLs []xmlEntry `xml:"url"`
+			}
+			if err := dec.DecodeElement(&doc, &start); err != nil {
+				return nil, fmt.Errorf("sitemap: %w", err)
+			}
+			for _, x := range doc.URLs {
+				if e := x.entry(); e.Loc != "" {
+					sm.URLs = append(sm.URLs, e)
+				}
+			}
+		case "sitemapindex":
+			var doc struct {
+				Sitemaps []xmlEntry `xml:"sitemap"`
+			}
+			if err := dec.DecodeElement(&doc, &start); err != nil {
+				return nil, fmt.Errorf("sitemap: %w", err)
+			}
+			for _, x := range doc.Sitemaps {
+				if e := x.entry(); e.Loc != "" {
+					sm.Sitemaps = append(sm.Sitemaps, e)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("sitemap: unexpected root element <%s>", start.Name.Local)
+		}
+		return sm, nil
+	}
+}
+
+func parseText(b []byte) *Sitemap {
+	sm := &Sitemap{}
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "http://") || strings.HasPrefix(line, "https://") {
+			sm.URLs = append(sm.URLs, Entry{Loc: line})
+		}
+	}
+	return sm
+}
+
+// lastModLayouts are the W3C datetime forms the protocol allows, plus a
+// few common deviations.
+var lastModLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// ParseLastMod parses a lastmod value. Values without a zone are UTC.
+func ParseLastMod(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range lastModLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("sitemap: bad lastmod %q", s)
+}
+
+// Options filter the entries Fetcher returns.
+type Options struct {
+	// Since keeps pages modified at or after it. Child sitemaps of an
+	// index whose lastmod is earlier are not downloaded at all.
+	Since time.Time
+	// IncludeUndated keeps pages without a lastmod when Since is set.
+	IncludeUndated bool
+}
+
+func (o Options) keep(e Entry) bool {
+	if o.Since.IsZero() {
+		return true
+	}
+	if e.LastMod.IsZero() {
+		return o.IncludeUndated
+	}
+	return !e.LastMod.Before(o.Since)
+}
+
+// Filter returns the entries of es that opts keeps.
+func Filter(es []Entry, opts Options) []Entry {
+	var out []Entry
+	for _, e := range es {
+		if opts.keep(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Fetcher downloads sitemaps.
+type Fetcher struct {
+	// Client fetches sitemaps and robots.txt. Default: a client with a
+	// 30s timeout.
+	Client *http.Client
+	// UserAgent is sent with each request.
+	UserAgent string
+	// MaxDepth bounds how deeply indexes may nest. Default 3.
+	MaxDepth int
+	// MaxURLs stops collecting after this many pages; 0 means no limit.
+	MaxURLs int
+	// Logf receives diagnostics, such as child sitemaps that failed.
+	// Default: discard.
+	Logf func(format string, args ...any)
+}
+
+// Get downloads and parses one sitemap file.
+func (f *Fetcher) Get(ctx context.Context, sitemapURL string) (*Sitemap, error) {
+	hc := f.Client
+	if hc == nil {
+		hc = &http.Client{Timeout: 30 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sitemapURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if f.UserAgent != "" {
+		req.Header.Set("User-Agent", f.UserAgent)
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sitemap: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sitemap: %s: %s", sitemapURL, resp.Status)
+	}
+	sm, err := Parse(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w (%s)", err, sitemapURL)
+	}
+	return sm, nil
+}
+
+// Fetch returns the pages listed by the sitemap at sitemapURL, following
+// indexes, deduplicated by canonical URL (keeping the latest lastmod) and
+// filtered by opts, newest first with undated pages last. Child sitemaps
+// that fail are logged and skipped; only a failure of sitemapURL itself
+// is returned.
+func (f *Fetcher) Fetch(ctx context.Context, sitemapURL string, opts Options) ([]Entry, error) {
+	maxDepth := f.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = 3
+	}
+	logf := f.Logf
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+	var (
+		out     []Entry
+		byKey   = map[string]int{}
+		visited = map[string]bool{}
+		full    = errors.New("full")
+	)
+	var walk func(u string, depth int) error
+	walk = func(u string, depth int) error {
+		if visited[u] {
+			return nil
+		}
+		visited[u] = true
+		sm, err := f.Get(ctx, u)
+		if err != nil {
+			return err
+		}
+		for _, e := range sm.URLs {
+			if !opts.keep(e) {
+				continue
+			}
+			k := canonical.Key(e.Loc)
+			if i, ok := byKey[k]; ok {
+				if e.LastMod.After(out[i].LastMod) {
+					out[i] = e
+				}
+				continue
+			}
+			if f.MaxURLs > 0 && len(out) >= f.MaxURLs {
+				return full
+			}
+			byKey[k] = len(out)
+			out = append(out, e)
+		}
+		for _, child := range sm.Sitemaps {
+			if !opts.Since.IsZero() && !child.LastMod.IsZero() && child.LastMod.Before(opts.Since) {
+				continue // nothing in it changed since
+			}
+			if depth >= maxDepth {
+				logf("sitemap: %s: index nested deeper than %d, skipped", child.Loc, maxDepth)
+				continue
+			}
+			loc, err := resolve(u, child.Loc)
+			if err != nil {
+				logf("sitemap: %v", err)
+				continue
+			}
+			switch err := walk(loc, depth+1); {
+			case err == full || ctx.Err() != nil:
+				return err
+			case err != nil:
+				logf("%v", err)
+			}
+		}
+		return nil
+	}
+	if err := walk(sitemapURL, 0); err != nil && err != full {
+		return nil, err
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i].LastMod, out[j].LastMod
+		if a.IsZero() != b.IsZero() {
+			return b.IsZero()
+		}
+		return a.After(b)
+	})
+	return out, nil
+}
+
+// Discover returns the sitemaps a site declares in its robots.txt, or
+// <origin>/sitemap.xml when it declares none.
+func (f *Fetcher) Discover(ctx context.Context, siteURL string) ([]string, error) {
+	u, err := url.Parse(siteURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("sitemap: bad url %q", siteURL)
+	}
+	rf := &robots.Fetcher{Client: f.Client, UserAgent: f.UserAgent}
+	rules, err := rf.Get(ctx, siteURL)
+	if err != nil && f.Logf != nil {
+		f.Logf("sitemap: %v", err)
+	}
+	if sm := rules.Sitemaps(); len(sm) > 0 {
+		return sm, nil
+	}
+	return []string{u.Scheme + "://" + u.Host + "/sitemap.xml"}, nil
+}
+
+func resolve(base, ref string) (string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	u, err := b.Parse(ref)
+	if err != nil {
+		return "", fmt.Errorf("bad sitemap url %q: %w", ref, err)
+	}
+	return u.String(), nil
+}
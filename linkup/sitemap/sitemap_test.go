@@ -0,0 +1,133 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func gz(s string) []byte {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	w.Write([]byte(s))
+	w.Close()
+	return b.Bytes()
+}
+
+const urlset = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.com/a</loc><lastmod>2025-03-01</lastmod><priority>0.8</priority></url>
+  <url><loc> https://example.com/b </loc><lastmod>2024-06-01T10:00:00+02:00</lastmod></url>
+  <url><loc>https://example.com/c</loc></url>
+</urlset>`
+
+func TestParse(t *testing.T) {
+	for name, in := range map[string][]byte{"xml": []byte(urlset), "gzip": gz(urlset)} {
+		sm, err := Parse(bytes.NewReader(in))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(sm.URLs) != 3 || sm.URLs[1].Loc != "https://example.com/b" || sm.URLs[0].Priority != 0.8 {
+			t.Fatalf("%s: %+v", name, sm.URLs)
+		}
+		if want := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC); !sm.URLs[1].LastMod.Equal(want) {
+			t.Fatalf("%s: lastmod = %v", name, sm.URLs[1].LastMod)
+		}
+	}
+
+	sm, err := Parse(strings.NewReader(`<sitemapindex><sitemap><loc>/s1.xml</loc><lastmod>2025-01</lastmod></sitemap></sitemapindex>`))
+	if err != nil || len(sm.Sitemaps) != 1 || sm.Sitemaps[0].LastMod.Month() != time.January {
+		t.Fatalf("index = %+v, %v", sm, err)
+	}
+	sm, err = Parse(strings.NewReader("https://example.com/x\n\nnot a url\nhttps://example.com/y\n"))
+	if err != nil || len(sm.URLs) != 2 {
+		t.Fatalf("text = %+v, %v", sm, err)
+	}
+	if _, err := Parse(strings.NewReader(`<html><body/></html>`)); err == nil {
+		t.Fatal("want error for html")
+	}
+}
+
+func TestParseLastMod(t *testing.T) {
+	for _, s := range []string{"2025", "2025-02", "2025-02-03", "2025-02-03T04:05Z", "2025-02-03T04:05:06.7+01:00", "2025-02-03T04:05:06"} {
+		if _, err := ParseLastMod(s); err != nil {
+			t.Errorf("ParseLastMod(%q): %v", s, err)
+		}
+	}
+	if _, err := ParseLastMod("yesterday"); err == nil {
+		t.Error("want error")
+	}
+}
+
+func TestFetch(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		requested []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow:\nSitemap: http://" + r.Host + "/index.xml\n"))
+		case "/index.xml":
+			w.Write([]byte(`<sitemapindex>
+<sitemap><loc>/new.xml.gz</loc><lastmod>2025-02-01</lastmod></sitemap>
+<sitemap><loc>/old.xml</loc><lastmod>2023-01-01</lastmod></sitemap>
+<sitemap><loc>/missing.xml</loc></sitemap>
+</sitemapindex>`))
+		case "/new.xml.gz":
+			w.Write(gz(`<urlset>
+<url><loc>https://example.com/a</loc><lastmod>2025-01-10</lastmod></url>
+<url><loc>https://www.example.com/a/</loc><lastmod>2025-01-20</lastmod></url>
+<url><loc>https://example.com/b</loc><lastmod>2024-12-01</lastmod></url>
+<url><loc>https://example.com/c</loc><lastmod>2025-01-05</lastmod></url>
+<url><loc>https://example.com/undated</loc></url>
+</urlset>`))
+		case "/old.xml":
+			t.Error("old child sitemap fetched despite its lastmod")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	var logs []string
+	f := &Fetcher{Logf: func(format string, args ...any) { logs = append(logs, format) }}
+	ctx := context.Background()
+	maps, err := f.Discover(ctx, srv.URL+"/docs/")
+	if err != nil || len(maps) != 1 || maps[0] != srv.URL+"/index.xml" {
+		t.Fatalf("Discover = %v, %v", maps, err)
+	}
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	es, err := f.Fetch(ctx, maps[0], Options{Since: since})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range es {
+		got = append(got, e.Loc)
+	}
+	// Deduplicated keeping the latest lastmod, newest first.
+	if strings.Join(got, " ") != "https://www.example.com/a/ https://example.com/c" {
+		t.Fatalf("entries = %v", got)
+	}
+	if len(logs) != 1 {
+		t.Fatalf("logs = %v", logs) // the missing child
+	}
+
+	es, _ = f.Fetch(ctx, maps[0], Options{Since: since, IncludeUndated: true})
+	if len(es) != 3 || es[2].Loc != "https://example.com/undated" {
+		t.Fatalf("with undated = %+v", es)
+	}
+	if _, err := f.Fetch(ctx, srv.URL+"/nope.xml", Options{}); err == nil {
+		t.Fatal("want error for a missing root")
+	}
+}
@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"strings"
+
+	"github.com/raezil/linkup-go/linkup/jsonschema"
+)
+
+// OpenAITool is a tool entry for the OpenAI Chat Completions "tools" array.
+type OpenAITool struct {
+	Type     string         `json:"type"` // always "function"
+	Function OpenAIFunction `json:"function"`
+}
+
+// OpenAIFunction is the function part of an OpenAITool.
+type OpenAIFunction struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Parameters  *jsonschema.Schema `json:"parameters"`
+}
+
+// OpenAI converts defs to OpenAI function tools.
+func OpenAI(defs []Definition) []OpenAITool {
+	out := make([]OpenAITool, len(defs))
+	for i, d := range defs {
+		out[i] = OpenAITool{Type: "function", Function: OpenAIFunction{Name: d.Name, Description: d.Description, Parameters: d.Parameters}}
+	}
+	return out
+}
+
+// AnthropicTool is a tool entry for the Anthropic Messages API "tools" array.
+type AnthropicTool struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	InputSchema *jsonschema.Schema `json:"input_schema"`
+}
+
+// Anthropic converts defs to Anthropic tools.
+func Anthropic(defs []Definition) []AnthropicTool {
+	out := make([]AnthropicTool, len(defs))
+	for i, d := range defs {
+		out[i] = AnthropicTool{Name: d.Name, Description: d.Description, InputSchema: d.Parameters}
+	}
+	return out
+}
+
+// GeminiTool is an entry for the Gemini API "tools" array.
+type GeminiTool struct {
+	FunctionDeclarations []GeminiFunction `json:"functionDeclarations"`
+}
+
+// GeminiFunction is a Gemini function declaration.
+type GeminiFunction struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Parameters  *jsonschema.Schema `json:"parameters,omitempty"`
+}
+
+// Gemini converts defs to a single Gemini tool. Gemini accepts an OpenAPI
+// subset, so keywords it rejects (additionalProperties, pattern, default,
+// most formats) are dropped, and object schemas without properties are
+// omitted entirely.
+func Gemini(defs []Definition) GeminiTool {
+	out := GeminiTool{FunctionDeclarations: make([]GeminiFunction, len(defs))}
+	for i, d := range defs {
+		fn := GeminiFunction{Name: d.Name, Description: d.Description}
+		if d.Parameters != nil && len(d.Parameters.Properties) > 0 {
+			fn.Parameters = geminiSchema(d.Parameters)
+		}
+		out.FunctionDeclarations[i] = fn
+	}
+	return out
+}
+
+func geminiSchema(s *jsonschema.Schema) *jsonschema.Schema {
+	if s == nil {
+		return nil
+	}
+	g := &jsonschema.Schema{
+		Type:        strings.ToUpper(s.Type),
+		Description: s.Description,
+		Required:    s.Required,
+		Enum:        s.Enum,
+		Items:       geminiSchema(s.Items),
+	}
+	if s.Format == "date-time" || s.Format == "enum" {
+		g.Format = s.Format
+	}
+	if len(s.Properties) > 0 {
+		g.Properties = make(map[string]*jsonschema.Schema, len(s.Properties))
+		for name, p := range s.Properties {
+			g.Properties[name] = geminiSchema(p)
+		}
+	}
+	return g
+}
@@ -0,0 +1,380 @@
+// Package tools exposes Linkup search, fetch and balance as LLM tools: it
+// emits function definitions in OpenAI, Anthropic and Gemini shapes, and
+// dispatches tool-call arguments onto a Client, returning compact text that
+// fits in a model's context window.
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+	"github.com/raezil/linkup-go/linkup/cite"
+	"github.com/raezil/linkup-go/linkup/jsonschema"
+)
+
+// Tool names.
+const (
+	Search  = "linkup_search"
+	Fetch   = "linkup_fetch"
+	Balance = "linkup_balance"
+)
+
+// ErrUnknownTool is returned by Dispatch for names other than Search, Fetch
+// and Balance.
+var ErrUnknownTool = errors.New("tools: unknown tool")
+
+// ArgumentError reports tool-call arguments that failed validation. Its
+// message is meant to be shown to the model so it can correct the call.
+type ArgumentError struct {
+	Tool string
+	Err  error
+}
+
+func (e *ArgumentError) Error() string {
+	return fmt.Sprintf("invalid arguments for %s: %v", e.Tool, e.Err)
+}
+
+func (e *ArgumentError) Unwrap() error { return e.Err }
+
+// Definition is a provider-neutral tool definition.
+type Definition struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Parameters  *jsonschema.Schema `json:"parameters"`
+}
+
+// Dispatcher validates tool calls and executes them with Client.
+type Dispatcher struct {
+	Client *linkup.Client
+	// Defaults are per-tool default arguments, keyed by tool name then JSON
+	// field name, e.g. {"linkup_search": {"depth": "deep"}}. Defaulted fields
+	// become optional in Definitions.
+	Defaults map[string]map[string]any
+	// AllowDomains restricts searches and fetches to these domains and their
+	// subdomains. Empty allows everything.
+	AllowDomains []string
+	// MaxResults, MaxChars and MaxFetchChars bound the result text: results
+	// listed per search (default 10), content characters per result (default
+	// 600) and fetched markdown characters (default 12000).
+	MaxResults    int
+	MaxChars      int
+	MaxFetchChars int
+}
+
+// builtinDefaults apply when neither the caller nor Defaults set a field.
+var builtinDefaults = map[string]map[string]any{
+	Search: {"depth": string(linkup.DepthStandard), "outputType": string(linkup.OutputSearchResults)},
+}
+
+// Has reports whether name is a tool handled by Dispatch.
+func (d *Dispatcher) Has(name string) bool {
+	return name == Search || name == Fetch || name == Balance
+}
+
+// Definitions returns the search, fetch and balance tool definitions.
+func (d *Dispatcher) Definitions() []Definition {
+	note := ""
+	if len(d.AllowDomains) > 0 {
+		note = " Only these domains are allowed: " + strings.Join(d.AllowDomains, ", ") + "."
+	}
+	return []Definition{
+		{
+			Name:        Search,
+			Description: "Search the web with Linkup and return ranked results or a sourced answer." + note,
+			Parameters:  d.withDefaults(Search, linkup.SearchRequestSchema()),
+		},
+		{
+			Name:        Fetch,
+			Description: "Fetch a web page with Linkup and return its content as markdown." + note,
+			Parameters:  d.withDefaults(Fetch, linkup.FetchRequestSchema()),
+		},
+		{
+			Name:        Balance,
+			Description: "Return the remaining Linkup credit balance.",
+			Parameters:  (&jsonschema.Schema{Type: "object", Properties: map[string]*jsonschema.Schema{}}).Strict(),
+		},
+	}
+}
+
+// withDefaults drops defaulted fields from Required and records the default.
+func (d *Dispatcher) withDefaults(tool string, s *jsonschema.Schema) *jsonschema.Schema {
+	defs := d.mergedDefaults(tool)
+	req := s.Required[:0]
+	for _, name := range s.Required {
+		if _, ok := defs[name]; !ok {
+			req = append(req, name)
+		}
+	}
+	s.Required = req
+	for name, v := range defs {
+		if p := s.Property(name); p != nil {
+			p.Default = v
+		}
+	}
+	return s
+}
+
+func (d *Dispatcher) mergedDefaults(tool string) map[string]any {
+	out := map[string]any{}
+	for k, v := range builtinDefaults[tool] {
+		out[k] = v
+	}
+	for k, v := range d.Defaults[tool] {
+		out[k] = v
+	}
+	return out
+}
+
+// decodeArgs overlays args on the tool's defaults and decodes into v,
+// rejecting unknown fields.
+func (d *Dispatcher) decodeArgs(tool string, args json.RawMessage, v any) error {
+	merged := d.mergedDefaults(tool)
+	if len(bytes.TrimSpace(args)) > 0 && string(args) != "null" {
+		var m map[string]any
+		if err := json.Unmarshal(args, &m); err != nil {
+			return &ArgumentError{Tool: tool, Err: err}
+		}
+		for k, val := range m {
+			merged[k] = val
+		}
+	}
+	b, err := json.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return &ArgumentError{Tool: tool, Err: err}
+	}
+	return nil
+}
+
+// DecodeSearch validates tool-call arguments and maps them onto a
+// SearchRequest, applying defaults and the domain allowlist.
+func (d *Dispatcher) DecodeSearch(args json.RawMessage) (linkup.SearchRequest, error) {
+	var req linkup.SearchRequest
+	if err := d.decodeArgs(Search, args, &req); err != nil {
+		return req, err
+	}
+	bad := func(format string, a ...any) error {
+		return &ArgumentError{Tool: Search, Err: fmt.Errorf(format, a...)}
+	}
+	if strings.TrimSpace(req.Q) == "" {
+		return req, bad("q is required")
+	}
+	switch req.Depth {
+	case linkup.DepthStandard, linkup.DepthDeep:
+	default:
+		return req, bad("depth must be standard or deep, got %q", req.Depth)
+	}
+	switch req.OutputType {
+	case linkup.OutputSearchResults, linkup.OutputSourcedAnswer:
+	case linkup.OutputStructured:
+		if req.StructuredOutputSchema == nil || *req.StructuredOutputSchema == "" {
+			return req, bad("structuredOutputSchema is required when outputType is structured")
+		}
+	default:
+		return req, bad("outputType must be searchResults, sourcedAnswer or structured, got %q", req.OutputType)
+	}
+	for _, date := range []string{req.FromDate, req.ToDate} {
+		if date != "" && !dateRe.MatchString(date) {
+			return req, bad("dates must be YYYY-MM-DD, got %q", date)
+		}
+	}
+	if len(d.AllowDomains) > 0 {
+		if len(req.IncludeDomains) == 0 {
+			req.IncludeDomains = d.AllowDomains
+		}
+		for _, dom := range req.IncludeDomains {
+			if !d.allowed(dom) {
+				return req, bad("domain %q is not allowed", dom)
+			}
+		}
+	}
+	return req, nil
+}
+
+var dateRe = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// DecodeFetch validates tool-call arguments and maps them onto a
+// FetchRequest, applying defaults and the domain allowlist.
+func (d *Dispatcher) DecodeFetch(args json.RawMessage) (linkup.FetchRequest, error) {
+	var req linkup.FetchRequest
+	if err := d.decodeArgs(Fetch, args, &req); err != nil {
+		return req, err
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return req, &ArgumentError{Tool: Fetch, Err: fmt.Errorf("url must be an absolute http(s) URL, got %q", req.URL)}
+	}
+	if !d.allowedURL(req.URL) {
+		return req, &ArgumentError{Tool: Fetch, Err: fmt.Errorf("fetching %s is not allowed", u.Host)}
+	}
+	return req, nil
+}
+
+// Dispatch validates args for the named tool, executes it and returns a
+// model-ready text result. Validation failures are *ArgumentError.
+func (d *Dispatcher) Dispatch(ctx context.Context, name string, args json.RawMessage) (string, error) {
+	switch name {
+	case Search:
+		req, err := d.DecodeSearch(args)
+		if err != nil {
+			return "", err
+		}
+		return d.search(ctx, req)
+	case Fetch:
+		req, err := d.DecodeFetch(args)
+		if err != nil {
+			return "", err
+		}
+		return d.fetch(ctx, req)
+	case Balance:
+		bal, err := d.Client.GetBalance(ctx)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Remaining Linkup credits: %.4g", bal.Balance), nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownTool, name)
+}
+
+func (d *Dispatcher) search(ctx context.Context, req linkup.SearchRequest) (string, error) {
+	resp, err := d.Client.Search(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	switch req.OutputType {
+	case linkup.OutputSearchResults:
+		results, err := resp.Results()
+		if err != nil {
+			return "", err
+		}
+		maxResults := orDefault(d.MaxResults, 10)
+		var b strings.Builder
+		n := 0
+		for _, r := range linkup.DedupeResults(results) {
+			if !d.allowedURL(r.URL) {
+				continue
+			}
+			n++
+			fmt.Fprintf(&b, "%d. %s\n   %s\n", n, oneLine(r.Name), r.URL)
+			if c := truncate(oneLine(r.Content), orDefault(d.MaxChars, 600)); c != "" {
+				fmt.Fprintf(&b, "   %s\n", c)
+			}
+			if n == maxResults {
+				break
+			}
+		}
+		if n == 0 {
+			return "No results.", nil
+		}
+		return b.String(), nil
+	case linkup.OutputSourcedAnswer:
+		ans, err := resp.SourcedAnswer()
+		if err != nil {
+			return "", err
+		}
+		// Drop disallowed sources and renumber the citations to match.
+		var kept []linkup.AnswerSource
+		index := make(map[int]int)
+		for i, s := range ans.Sources {
+			if d.allowedURL(s.URL) {
+				kept = append(kept, s)
+				index[i+1] = len(kept)
+			}
+		}
+		if len(kept) < len(ans.Sources) {
+			n := len(ans.Sources)
+			ans.Answer = cite.Renumber(ans.Answer, func(i int) int {
+				if i < 1 || i > n {
+					return i
+				}
+				return index[i]
+			})
+		}
+		ans.Sources = kept
+		out, _ := cite.Render(ans, cite.Text)
+		return out, nil
+	default:
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, resp.RawJSON()); err != nil {
+			return string(resp.RawJSON()), nil
+		}
+		return buf.String(), nil
+	}
+}
+
+func (d *Dispatcher) fetch(ctx context.Context, req linkup.FetchRequest) (string, error) {
+	resp, err := d.Client.Fetch(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	page, err := resp.Page()
+	if err != nil {
+		return "", err
+	}
+	max := orDefault(d.MaxFetchChars, 12000)
+	var b strings.Builder
+	fmt.Fprintf(&b, "Source: %s\n\n%s", req.URL, truncate(page.Markdown, max))
+	if req.IncludeRawHTML && page.RawHTML != "" {
+		fmt.Fprintf(&b, "\n\nRaw HTML:\n%s", truncate(page.RawHTML, max))
+	}
+	if len(page.Images) > 0 {
+		b.WriteString("\n\nImages:\n")
+		for _, img := range page.Images {
+			fmt.Fprintf(&b, "- %s %s\n", img.URL, oneLine(img.Alt))
+		}
+	}
+	return b.String(), nil
+}
+
+// allowed reports whether domain dom is within the allowlist.
+func (d *Dispatcher) allowed(dom string) bool {
+	if len(d.AllowDomains) == 0 {
+		return true
+	}
+	dom = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(dom)), "www.")
+	return slices.ContainsFunc(d.AllowDomains, func(a string) bool {
+		a = strings.ToLower(a)
+		return dom == a || strings.HasSuffix(dom, "."+a)
+	})
+}
+
+func (d *Dispatcher) allowedURL(raw string) bool {
+	if len(d.AllowDomains) == 0 {
+		return true
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	return d.allowed(u.Hostname())
+}
+
+func orDefault(v, def int) int {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
+
+func oneLine(s string) string { return strings.Join(strings.Fields(s), " ") }
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if n <= 0 || len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + fmt.Sprintf("… [truncated %d chars]", len(r)-n)
+}
@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	linkup "github.com/raezil/linkup-go/linkup"
+)
+
+func newDispatcher(t *testing.T, handler http.HandlerFunc) (*Dispatcher, *httptest.Server) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	c := linkup.NewClient("test-key", linkup.WithBaseURL(srv.URL), linkup.WithRetry(0, time.Millisecond, time.Millisecond))
+	return &Dispatcher{Client: c}, srv
+}
+
+func TestDefinitions_DefaultsMakeFieldsOptional(t *testing.T) {
+	d := &Dispatcher{Defaults: map[string]map[string]any{Search: {"depth": "deep"}}}
+	defs := d.Definitions()
+	if len(defs) != 3 || defs[0].Name != Search {
+		t.Fatalf("unexpected defs: %+v", defs)
+	}
+	s := defs[0].Parameters
+	if len(s.Required) != 1 || s.Required[0] != "q" {
+		t.Fatalf("required = %v", s.Required)
+	}
+	if s.Property("depth").Default != "deep" {
+		t.Fatalf("default not recorded")
+	}
+}
+
+func TestProviderShapes(t *testing.T) {
+	defs := (&Dispatcher{}).Definitions()
+
+	b, _ := json.Marshal(OpenAI(defs))
+	if !strings.Contains(string(b), `"type":"function","function":{"name":"linkup_search"`) {
+		t.Fatalf("openai: %s", b)
+	}
+	b, _ = json.Marshal(Anthropic(defs))
+	if !strings.Contains(string(b), `"input_schema":{"type":"object"`) {
+		t.Fatalf("anthropic: %s", b)
+	}
+	g := Gemini(defs)
+	b, _ = json.Marshal(g)
+	if strings.Contains(string(b), "additionalProperties") || strings.Contains(string(b), "pattern") {
+		t.Fatalf("gemini kept unsupported keywords: %s", b)
+	}
+	if g.FunctionDeclarations[0].Parameters.Type != "OBJECT" || g.FunctionDeclarations[2].Parameters != nil {
+		t.Fatalf("gemini: %s", b)
+	}
+}
+
+func TestDispatch_Validation(t *testing.T) {
+	d := &Dispatcher{AllowDomains: []string{"go.dev"}}
+	cases := map[string]string{
+		Search: `{"q":"x","depth":"shallow"}`,
+		Fetch:  `{"url":"https://evil.com/"}`,
+	}
+	for tool, args := range cases {
+		_, err := d.Dispatch(context.Background(), tool, json.RawMessage(args))
+		var ae *ArgumentError
+		if !errors.As(err, &ae) {
+			t.Fatalf("%s: want ArgumentError, got %v", tool, err)
+		}
+	}
+	if _, err := d.Dispatch(context.Background(), Search, json.RawMessage(`{"q":"x","nope":1}`)); err == nil {
+		t.Fatal("unknown field accepted")
+	}
+	if _, err := d.Dispatch(context.Background(), "other", nil); !errors.Is(err, ErrUnknownTool) {
+		t.Fatalf("want ErrUnknownTool, got %v", err)
+	}
+	req, err := d.DecodeSearch(json.RawMessage(`{"q":"go generics"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Depth != linkup.DepthStandard || req.OutputType != linkup.OutputSearchResults || req.IncludeDomains[0] != "go.dev" {
+		t.Fatalf("defaults/allowlist not applied: %+v", req)
+	}
+}
+
+func TestDispatch_SearchCompactText(t *testing.T) {
+	d, srv := newDispatcher(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"results":[
+			{"type":"text","name":"Go  blog","url":"https://go.dev/blog","content":"` + strings.Repeat("a", 50) + `"},
+			{"type":"text","name":"Dup","url":"https://www.go.dev/blog/"},
+			{"type":"text","name":"Other","url":"https://other.org"}]}`))
+	})
+	defer srv.Close()
+	d.MaxChars = 10
+	d.AllowDomains = []string{"go.dev"}
+
+	out, err := d.Dispatch(context.Background(), Search, json.RawMessage(`{"q":"go"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "1. Go blog\n   https://go.dev/blog\n   aaaaaaaaaa… [truncated 40 chars]\n"
+	if out != want {
+		t.Fatalf("got %q", out)
+	}
+}
+
+func TestDispatch_SourcedAnswerRenumbersCitations(t *testing.T) {
+	d, srv := newDispatcher(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"answer":"Go is fast [1]. Rust too [2]. Both compile [1, 3].","sources":[
+			{"name":"Go","url":"https://go.dev/a"},
+			{"name":"Rust","url":"https://rust-lang.org/b"},
+			{"name":"Blog","url":"https://go.dev/c"}]}`))
+	})
+	defer srv.Close()
+	d.AllowDomains = []string{"go.dev"}
+
+	out, err := d.Dispatch(context.Background(), Search, json.RawMessage(`{"q":"go","outputType":"sourcedAnswer"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Go is fast [1]. Rust too. Both compile [1, 2].\n\nSources:\n[1] Go - https://go.dev/a\n[2] Blog - https://go.dev/c\n"
+	if out != want {
+		t.Fatalf("got %q", out)
+	}
+}
+
+func TestDispatch_FetchAndBalance(t *testing.T) {
+	d, srv := newDispatcher(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fetch":
+			w.Write([]byte(`{"markdown":"# Hi"}`))
+		case "/credits/balance":
+			w.Write([]byte(`{"balance":3.5}`))
+		}
+	})
+	defer srv.Close()
+
+	out, err := d.Dispatch(context.Background(), Fetch, json.RawMessage(`{"url":"https://go.dev"}`))
+	if err != nil || out != "Source: https://go.dev\n\n# Hi" {
+		t.Fatalf("fetch = %q, %v", out, err)
+	}
+	out, err = d.Dispatch(context.Background(), Balance, nil)
+	if err != nil || !strings.Contains(out, "3.5") {
+		t.Fatalf("balance = %q, %v", out, err)
+	}
+}
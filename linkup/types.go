@@ -1,5 +1,10 @@
 package linkup
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // You can add stronger-typed models here if you know the exact response shape
 // for each outputType. This SDK returns raw JSON by default to stay forward-compatible.
 //
@@ -7,13 +12,97 @@ package linkup
 type (
 	// SourcedAnswer is an example of a possible high-level shape you might expect.
 	SourcedAnswer struct {
-		Answer  string        `json:"answer,omitempty"`
+		Answer  string         `json:"answer,omitempty"`
 		Sources []AnswerSource `json:"sources,omitempty"`
 	}
 
 	AnswerSource struct {
-		Title string `json:"title,omitempty"`
-		URL   string `json:"url,omitempty"`
+		Name    string `json:"name,omitempty"`
+		Title   string `json:"title,omitempty"`
+		URL     string `json:"url,omitempty"`
 		Snippet string `json:"snippet,omitempty"`
+		// Filter is set by WithResultFilter on sources it annotates.
+		Filter []FilterReason `json:"filter,omitempty"`
+	}
+
+	// SearchResults is the payload returned for OutputSearchResults.
+	SearchResults struct {
+		Results []SearchResult `json:"results"`
+	}
+
+	// SearchResult is a single entry of SearchResults. Type is "text" or
+	// "image"; image results usually carry no Content.
+	SearchResult struct {
+		Type    string `json:"type,omitempty"`
+		Name    string `json:"name,omitempty"`
+		URL     string `json:"url"`
+		Content string `json:"content,omitempty"`
+		// Filter is set by WithResultFilter on results it annotates; the
+		// API never sends it.
+		Filter []FilterReason `json:"filter,omitempty"`
+	}
+
+	// FetchResult is the payload returned by /fetch. The fields after
+	// Source are not sent by the API: Extract (and Client.FetchPage) fill
+	// them from RawHTML, or from Markdown when no HTML was fetched.
+	FetchResult struct {
+		Markdown string       `json:"markdown"`
+		RawHTML  string       `json:"rawHtml,omitempty"`
+		Images   []FetchImage `json:"images,omitempty"`
+		// Source is SourceLocal for pages converted by the local fallback
+		// (see WithLocalFallback), empty for the API's.
+		Source string `json:"source,omitempty"`
+
+		Title       string            `json:"title,omitempty"`
+		Description string            `json:"description,omitempty"`
+		Canonical   string            `json:"canonical,omitempty"` // absolute
+		Language    string            `json:"language,omitempty"`  // e.g. "en" or "pt-BR"
+		Author      string            `json:"author,omitempty"`
+		Published   time.Time         `json:"published,omitzero"`
+		Modified    time.Time         `json:"modified,omitzero"`
+		OpenGraph   *OpenGraph        `json:"openGraph,omitempty"`
+		Twitter     *TwitterCard      `json:"twitter,omitempty"`
+		JSONLD      []json.RawMessage `json:"jsonLd,omitempty"` // each valid application/ld+json block
+		Links       []Link            `json:"links,omitempty"`
+	}
+
+	// FetchImage is an image extracted by /fetch when ExtractImages is set.
+	FetchImage struct {
+		URL string `json:"url"`
+		Alt string `json:"alt,omitempty"`
 	}
 )
+
+// Results decodes an OutputSearchResults payload.
+func (r SearchResponse) Results() ([]SearchResult, error) {
+	var out SearchResults
+	if err := r.DecodeInto(&out); err != nil {
+		return nil, err
+	}
+	return out.Results, nil
+}
+
+// Page decodes a /fetch payload.
+func (r SearchResponse) Page() (FetchResult, error) {
+	var out FetchResult
+	err := r.DecodeInto(&out)
+	return out, err
+}
+
+// SourcedAnswer decodes an OutputSourcedAnswer payload.
+func (r SearchResponse) SourcedAnswer() (SourcedAnswer, error) {
+	var out SourcedAnswer
+	err := r.DecodeInto(&out)
+	return out, err
+}
+
+// Label returns the best human-readable name of s: Name, Title, then URL.
+func (s AnswerSource) Label() string {
+	switch {
+	case s.Name != "":
+		return s.Name
+	case s.Title != "":
+		return s.Title
+	}
+	return s.URL
+}